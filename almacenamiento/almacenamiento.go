@@ -0,0 +1,143 @@
+// Package almacenamiento guarda los archivos de los documentos en el disco del servidor o en un bucket
+// compatible con S3 (AWS, MinIO, DigitalOcean Spaces...). El backend se elige con ALMACENAMIENTO.
+package almacenamiento
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"api-margaritai/config"
+)
+
+// Backends aceptados en ALMACENAMIENTO
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// RutaDescargaLocal es la ruta pública que entrega los archivos del backend local con una URL firmada
+const RutaDescargaLocal = "/api/documentos/descargar"
+
+var (
+	ErrLlaveInvalida  = errors.New("llave de almacenamiento inválida")
+	ErrFirmaInvalida  = errors.New("la firma de la URL no es válida")
+	ErrURLExpirada    = errors.New("la URL de descarga expiró")
+	ErrNoEncontrado   = errors.New("el archivo no existe en el almacenamiento")
+	ErrNoEsLocal      = errors.New("el almacenamiento configurado no es local")
+	errSinVigencia    = errors.New("la vigencia de la URL debe ser positiva")
+	errVigenciaMaxima = errors.New("la vigencia de la URL no puede pasar de 7 días")
+)
+
+// Almacen guarda y borra archivos por llave, y genera URLs de descarga que vencen
+type Almacen interface {
+	// Guardar escribe el contenido en la llave, reemplazando lo que hubiera
+	Guardar(ctx context.Context, llave string, contenido []byte, tipoMIME string) error
+	// Eliminar borra el archivo; no es error si ya no existía
+	Eliminar(ctx context.Context, llave string) error
+	// URLFirmada regresa una URL que descarga el archivo como nombre durante la vigencia
+	URLFirmada(ctx context.Context, llave, nombre string, vigencia time.Duration) (string, error)
+}
+
+// reloj da la hora con que se firman y verifican las URLs
+var reloj = time.Now
+
+var (
+	actualOnce sync.Once
+	actual     Almacen
+	errActual  error
+)
+
+// Actual regresa el almacén configurado en el entorno; se lee la primera vez, cuando config.LoadEnv ya se ejecutó
+func Actual() (Almacen, error) {
+	actualOnce.Do(func() {
+		actual, errActual = desdeEntorno()
+	})
+	return actual, errActual
+}
+
+func desdeEntorno() (Almacen, error) {
+	switch backend := config.GetEnv("ALMACENAMIENTO", BackendLocal); backend {
+	case BackendLocal:
+		secreto, err := secretoDescargas()
+		if err != nil {
+			return nil, err
+		}
+		return &Local{
+			Directorio:  config.GetEnv("ALMACENAMIENTO_DIRECTORIO", "archivos"),
+			Secreto:     secreto,
+			URLDescarga: strings.TrimSuffix(config.GetEnv("ALMACENAMIENTO_URL_BASE", ""), "/") + RutaDescargaLocal,
+		}, nil
+	case BackendS3:
+		endpoint, err := url.Parse(config.GetEnv("S3_ENDPOINT", "https://s3.amazonaws.com"))
+		if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+			return nil, fmt.Errorf("S3_ENDPOINT inválido: %q", config.GetEnv("S3_ENDPOINT", ""))
+		}
+		s := &S3{
+			Endpoint:   endpoint,
+			Region:     config.GetEnv("S3_REGION", "us-east-1"),
+			Bucket:     config.GetEnv("S3_BUCKET", ""),
+			AccessKey:  config.GetEnv("S3_ACCESS_KEY", ""),
+			SecretKey:  config.GetEnv("S3_SECRET_KEY", ""),
+			EstiloRuta: config.GetEnv("S3_ESTILO_RUTA", "false") == "true",
+		}
+		if s.Bucket == "" || s.AccessKey == "" || s.SecretKey == "" {
+			return nil, errors.New("S3_BUCKET, S3_ACCESS_KEY y S3_SECRET_KEY son obligatorios con ALMACENAMIENTO=s3")
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("ALMACENAMIENTO desconocido: %q; use %s o %s", backend, BackendLocal, BackendS3)
+	}
+}
+
+// secretoDescargas es la llave con que se firman las URLs del backend local: ALMACENAMIENTO_SECRETO o, si no
+// está, una derivada de JWT_SECRET. Nunca es JWT_SECRET tal cual, para que una firma de descarga no sirva
+// como firma de token ni al revés.
+func secretoDescargas() ([]byte, error) {
+	if secreto := config.GetEnv("ALMACENAMIENTO_SECRETO", ""); secreto != "" {
+		return []byte(secreto), nil
+	}
+	jwt := config.GetJWTSecret()
+	if jwt == "" {
+		return nil, errors.New("ALMACENAMIENTO_SECRETO o JWT_SECRET son necesarios para firmar las descargas")
+	}
+	mac := hmac.New(sha256.New, []byte(jwt))
+	mac.Write([]byte("api-margaritai/almacenamiento/descargas"))
+	return mac.Sum(nil), nil
+}
+
+// validarVigencia limita la vigencia al máximo que acepta S3 para URLs prefirmadas
+func validarVigencia(vigencia time.Duration) error {
+	if vigencia <= 0 {
+		return errSinVigencia
+	}
+	if vigencia > 7*24*time.Hour {
+		return errVigenciaMaxima
+	}
+	return nil
+}
+
+// validarLlave rechaza llaves vacías, absolutas o que salgan del directorio con ".."
+func validarLlave(llave string) error {
+	if llave == "" || strings.HasPrefix(llave, "/") || strings.Contains(llave, "\\") {
+		return ErrLlaveInvalida
+	}
+	for _, parte := range strings.Split(llave, "/") {
+		if parte == "" || parte == "." || parte == ".." {
+			return ErrLlaveInvalida
+		}
+	}
+	return nil
+}
+
+func hmacSHA256(llave []byte, datos string) []byte {
+	mac := hmac.New(sha256.New, llave)
+	mac.Write([]byte(datos))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,112 @@
+package almacenamiento
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Local guarda los archivos bajo Directorio. Como el disco no tiene URLs propias, las descargas pasan por
+// RutaDescargaLocal con la llave, el nombre y la expiración firmados con HMAC-SHA256.
+type Local struct {
+	Directorio  string
+	Secreto     []byte
+	URLDescarga string // URL (o ruta) de RutaDescargaLocal a la que se agregan los parámetros firmados
+}
+
+// Ruta regresa la ubicación en disco de la llave
+func (l *Local) Ruta(llave string) (string, error) {
+	if err := validarLlave(llave); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Directorio, filepath.FromSlash(llave)), nil
+}
+
+// Guardar escribe primero a un archivo temporal y lo renombra, para que una descarga nunca vea un archivo a medias
+func (l *Local) Guardar(ctx context.Context, llave string, contenido []byte, tipoMIME string) error {
+	ruta, err := l.Ruta(llave)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ruta), 0o750); err != nil {
+		return err
+	}
+	temporal, err := os.CreateTemp(filepath.Dir(ruta), ".subiendo-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporal.Name())
+	if _, err := temporal.Write(contenido); err != nil {
+		temporal.Close()
+		return err
+	}
+	if err := temporal.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporal.Name(), ruta)
+}
+
+func (l *Local) Eliminar(ctx context.Context, llave string) error {
+	ruta, err := l.Ruta(llave)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(ruta); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) URLFirmada(ctx context.Context, llave, nombre string, vigencia time.Duration) (string, error) {
+	if err := validarLlave(llave); err != nil {
+		return "", err
+	}
+	if err := validarVigencia(vigencia); err != nil {
+		return "", err
+	}
+	expira := strconv.FormatInt(reloj().Add(vigencia).Unix(), 10)
+	parametros := url.Values{
+		"llave":  {llave},
+		"nombre": {nombre},
+		"expira": {expira},
+		"firma":  {l.firmar(llave, nombre, expira)},
+	}
+	return l.URLDescarga + "?" + parametros.Encode(), nil
+}
+
+// Verificar revisa la firma y la expiración de una URL generada por URLFirmada y regresa la ruta del archivo
+func (l *Local) Verificar(llave, nombre, expira, firma string) (string, error) {
+	esperada := l.firmar(llave, nombre, expira)
+	if !hmac.Equal([]byte(firma), []byte(esperada)) {
+		return "", ErrFirmaInvalida
+	}
+	segundos, err := strconv.ParseInt(expira, 10, 64)
+	if err != nil {
+		return "", ErrFirmaInvalida
+	}
+	if reloj().Unix() > segundos {
+		return "", ErrURLExpirada
+	}
+	ruta, err := l.Ruta(llave)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(ruta); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", ErrNoEncontrado
+		}
+		return "", err
+	}
+	return ruta, nil
+}
+
+func (l *Local) firmar(llave, nombre, expira string) string {
+	return hex.EncodeToString(hmacSHA256(l.Secreto, llave+"\n"+nombre+"\n"+expira))
+}
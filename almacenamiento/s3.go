@@ -0,0 +1,179 @@
+package almacenamiento
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	algoritmoS3   = "AWS4-HMAC-SHA256"
+	formatoFecha  = "20060102T150405Z"
+	cargaSinFirma = "UNSIGNED-PAYLOAD"
+)
+
+// S3 guarda los archivos en un bucket compatible con S3. Las peticiones se firman con AWS Signature
+// Version 4 y las descargas son URLs prefirmadas que el cliente usa directo contra el bucket.
+type S3 struct {
+	Endpoint   *url.URL // esquema y host del servicio, ej. https://s3.us-east-1.amazonaws.com o http://minio:9000
+	Region     string
+	Bucket     string
+	AccessKey  string
+	SecretKey  string
+	EstiloRuta bool // el bucket va en la ruta (MinIO) en lugar del subdominio
+	Cliente    *http.Client
+}
+
+func (s *S3) Guardar(ctx context.Context, llave string, contenido []byte, tipoMIME string) error {
+	encabezados := http.Header{}
+	if tipoMIME != "" {
+		encabezados.Set("Content-Type", tipoMIME)
+	}
+	return s.enviar(ctx, http.MethodPut, llave, contenido, encabezados)
+}
+
+func (s *S3) Eliminar(ctx context.Context, llave string) error {
+	return s.enviar(ctx, http.MethodDelete, llave, nil, nil)
+}
+
+func (s *S3) URLFirmada(ctx context.Context, llave, nombre string, vigencia time.Duration) (string, error) {
+	if err := validarLlave(llave); err != nil {
+		return "", err
+	}
+	if err := validarVigencia(vigencia); err != nil {
+		return "", err
+	}
+	host, ruta := s.objeto(llave)
+	ahora := reloj().UTC()
+
+	parametros := map[string]string{
+		"X-Amz-Algorithm":     algoritmoS3,
+		"X-Amz-Credential":    s.AccessKey + "/" + s.alcance(ahora),
+		"X-Amz-Date":          ahora.Format(formatoFecha),
+		"X-Amz-Expires":       strconv.Itoa(int(vigencia / time.Second)),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if nombre != "" {
+		parametros["response-content-disposition"] = mime.FormatMediaType("attachment", map[string]string{"filename": nombre})
+	}
+	consulta := consultaCanonica(parametros)
+	canonica := strings.Join([]string{http.MethodGet, escapar(ruta, false), consulta, "host:" + host + "\n", "host", cargaSinFirma}, "\n")
+
+	return s.Endpoint.Scheme + "://" + host + escapar(ruta, false) + "?" + consulta + "&X-Amz-Signature=" + s.firmar(ahora, canonica), nil
+}
+
+// objeto regresa el host y la ruta del objeto según el estilo de direcciones del bucket
+func (s *S3) objeto(llave string) (host, ruta string) {
+	if s.EstiloRuta {
+		return s.Endpoint.Host, "/" + s.Bucket + "/" + llave
+	}
+	return s.Bucket + "." + s.Endpoint.Host, "/" + llave
+}
+
+// enviar hace una petición firmada sobre el objeto; en DELETE un 404 también cuenta como borrado
+func (s *S3) enviar(ctx context.Context, metodo, llave string, cuerpo []byte, encabezados http.Header) error {
+	if err := validarLlave(llave); err != nil {
+		return err
+	}
+	host, ruta := s.objeto(llave)
+	req, err := http.NewRequestWithContext(ctx, metodo, s.Endpoint.Scheme+"://"+host+escapar(ruta, false), bytes.NewReader(cuerpo))
+	if err != nil {
+		return err
+	}
+	for nombre, valores := range encabezados {
+		req.Header[nombre] = valores
+	}
+
+	ahora := reloj().UTC()
+	suma := sha256.Sum256(cuerpo)
+	hashCuerpo := hex.EncodeToString(suma[:])
+	req.Host = host
+	req.ContentLength = int64(len(cuerpo))
+	req.Header.Set("X-Amz-Date", ahora.Format(formatoFecha))
+	req.Header.Set("X-Amz-Content-Sha256", hashCuerpo)
+
+	firmados := "host;x-amz-content-sha256;x-amz-date"
+	canonica := strings.Join([]string{
+		metodo,
+		escapar(ruta, false),
+		"",
+		"host:" + host + "\nx-amz-content-sha256:" + hashCuerpo + "\nx-amz-date:" + ahora.Format(formatoFecha) + "\n",
+		firmados,
+		hashCuerpo,
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algoritmoS3, s.AccessKey, s.alcance(ahora), firmados, s.firmar(ahora, canonica)))
+
+	cliente := s.Cliente
+	if cliente == nil {
+		cliente = http.DefaultClient
+	}
+	resp, err := cliente.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 || (metodo == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		return nil
+	}
+	detalle, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 respondió %d a %s %s: %s", resp.StatusCode, metodo, llave, strings.TrimSpace(string(detalle)))
+}
+
+// alcance es la fecha, región y servicio a los que se limita la firma
+func (s *S3) alcance(t time.Time) string {
+	return t.Format("20060102") + "/" + s.Region + "/s3/aws4_request"
+}
+
+// firmar deriva la llave del día y firma la petición canónica
+func (s *S3) firmar(t time.Time, canonica string) string {
+	suma := sha256.Sum256([]byte(canonica))
+	cadena := algoritmoS3 + "\n" + t.Format(formatoFecha) + "\n" + s.alcance(t) + "\n" + hex.EncodeToString(suma[:])
+
+	llave := hmacSHA256([]byte("AWS4"+s.SecretKey), t.Format("20060102"))
+	llave = hmacSHA256(llave, s.Region)
+	llave = hmacSHA256(llave, "s3")
+	llave = hmacSHA256(llave, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(llave, cadena))
+}
+
+// consultaCanonica ordena los parámetros por nombre y los codifica como pide SigV4
+func consultaCanonica(parametros map[string]string) string {
+	nombres := make([]string, 0, len(parametros))
+	for nombre := range parametros {
+		nombres = append(nombres, nombre)
+	}
+	sort.Strings(nombres)
+	partes := make([]string, len(nombres))
+	for i, nombre := range nombres {
+		partes[i] = escapar(nombre, true) + "=" + escapar(parametros[nombre], true)
+	}
+	return strings.Join(partes, "&")
+}
+
+// escapar codifica todo lo que no sea A-Z, a-z, 0-9, -, _, . o ~; las diagonales solo si se pide (parámetros)
+func escapar(s string, diagonales bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !diagonales:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+// Package cache guarda en memoria datos que cambian poco (catálogos y permisos por rol).
+// Cada valor pertenece a un grupo; al escribir en las tablas de un grupo se invalida el grupo completo.
+// La memoria es por instancia: con varias réplicas, la escritura solo invalida la réplica que la atendió
+// y las demás se ponen al día cuando vence CACHE_TTL.
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"api-margaritai/config"
+)
+
+// Grupos de invalidación
+const (
+	GrupoPermisos         = "permisos" // roles, permisos, categorías y sus asignaciones
+	GrupoGradosAcademicos = "grados_academicos"
+	GrupoPuestos          = "puestos"
+	GrupoEstatusLaborales = "estatus_laborales"
+	GrupoEstatusEmpleados = "estatus_empleados"
+	GrupoGrados           = "grados"
+	GrupoNivelesEscolares = "niveles_escolares"
+	GrupoGeneros          = "generos" // también el género que corresponde a cada sexo de la CURP
+	GrupoTiposContratos   = "tipos_contratos"
+	GrupoAulas            = "aulas"
+	GrupoOrganizaciones   = "organizaciones" // búsqueda de la organización de cada petición
+)
+
+type entrada struct {
+	valor  any
+	creado time.Time
+}
+
+// maxEntradasPorGrupo evita que combinaciones arbitrarias de query string llenen la memoria;
+// al llegar al tope se vacía el grupo
+const maxEntradasPorGrupo = 500
+
+var (
+	mu           sync.RWMutex
+	grupos       = map[string]map[string]entrada{}
+	generaciones = map[string]uint64{} // aumenta con cada invalidación del grupo
+	ttl          time.Duration
+	ttlOnce      sync.Once
+)
+
+func duracion() time.Duration {
+	ttlOnce.Do(func() {
+		ttl = config.GetEnvDuration("CACHE_TTL", 5*time.Minute)
+	})
+	return ttl
+}
+
+// Obtener regresa el valor guardado en el grupo y cuándo se guardó, si existe y no ha vencido
+func Obtener(nombreGrupo, llave string) (any, time.Time, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := grupos[nombreGrupo][llave]
+	if !ok || time.Since(e.creado) > duracion() {
+		return nil, time.Time{}, false
+	}
+	return e.valor, e.creado, true
+}
+
+// Generacion identifica el estado del grupo; se toma antes de consultar la base para pasarla a Guardar
+func Generacion(nombreGrupo string) uint64 {
+	mu.RLock()
+	defer mu.RUnlock()
+	return generaciones[nombreGrupo]
+}
+
+// Guardar agrega o reemplaza un valor del grupo y regresa el momento en que se guardó. Si el grupo se
+// invalidó después de generacion no guarda nada, porque el valor pudo leerse antes de la escritura.
+func Guardar(nombreGrupo, llave string, valor any, generacion uint64) time.Time {
+	mu.Lock()
+	defer mu.Unlock()
+	creado := time.Now()
+	if generaciones[nombreGrupo] != generacion {
+		return creado
+	}
+	g, ok := grupos[nombreGrupo]
+	if !ok || len(g) >= maxEntradasPorGrupo {
+		g = map[string]entrada{}
+		grupos[nombreGrupo] = g
+	}
+	g[llave] = entrada{valor: valor, creado: creado}
+	return creado
+}
+
+// Invalidar descarta todos los valores de los grupos indicados
+func Invalidar(nombresGrupos ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, nombre := range nombresGrupos {
+		delete(grupos, nombre)
+		generaciones[nombre]++
+	}
+}
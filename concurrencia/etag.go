@@ -0,0 +1,115 @@
+// Package concurrencia implementa la concurrencia optimista de las ediciones: cada registro editable
+// tiene una columna version que se publica como ETag y que el cliente devuelve en If-Match al editar.
+package concurrencia
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"api-margaritai/database"
+	"api-margaritai/errores"
+)
+
+// ErrModificado indica que otro usuario guardó el registro después de que se leyó
+var ErrModificado = errors.New("el registro fue modificado por otro usuario")
+
+// ETag formatea la versión de un registro como etiqueta de entidad
+func ETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// EscribirETag agrega el encabezado ETag con la versión del registro
+func EscribirETag(c *gin.Context, version uint) {
+	c.Header("ETag", ETag(version))
+}
+
+// Verificar compara If-Match con la versión leída. Si falta el encabezado responde 428; si no coincide
+// responde 412 con actual, la representación vigente. Regresa false cuando ya respondió.
+func Verificar(c *gin.Context, version uint, actual any) bool {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" {
+		errores.Responder(c, errores.Nuevo(http.StatusPreconditionRequired, errores.IfMatchRequerido,
+			"Envíe el encabezado If-Match con el ETag del registro que está editando"))
+		return false
+	}
+	if ifMatch == "*" {
+		return true
+	}
+	esperado := ETag(version)
+	for _, etiqueta := range strings.Split(ifMatch, ",") {
+		// Se aceptan también las etiquetas débiles (W/"3"): la versión identifica el registro completo
+		if strings.TrimPrefix(strings.TrimSpace(etiqueta), "W/") == esperado {
+			return true
+		}
+	}
+	errores.Responder(c, Modificado(c, version, actual))
+	return false
+}
+
+// Modificado arma el 412 con la representación vigente del registro y su ETag
+func Modificado(c *gin.Context, version uint, actual any) *errores.Error {
+	EscribirETag(c, version)
+	return errores.Nuevo(http.StatusPreconditionFailed, errores.VersionModificada,
+		"El registro fue modificado por otro usuario; revise los cambios y vuelva a intentarlo").ConActual(actual)
+}
+
+// Guardar actualiza todas las columnas de registro (sin sus asociaciones) solo si su versión en la base
+// sigue siendo la que se leyó, e incrementa Version. Si otro guardado ganó la carrera regresa ErrModificado.
+func Guardar(tx *gorm.DB, registro any) error {
+	return conVersion(registro, func(leida uint) *gorm.DB {
+		return tx.Model(registro).Where("version = ?", leida).Select("*").Omit(clause.Associations).Updates(registro)
+	})
+}
+
+// Actualizar es Guardar para las ediciones que solo cambian algunas columnas
+func Actualizar(tx *gorm.DB, registro any, cambios map[string]any) error {
+	return conVersion(registro, func(leida uint) *gorm.DB {
+		cambios["version"] = leida + 1
+		return tx.Model(registro).Where("version = ?", leida).Updates(cambios)
+	})
+}
+
+// conVersion ejecuta la actualización condicionada a la versión leída y deja Version en la nueva si tuvo éxito
+func conVersion(registro any, actualizar func(leida uint) *gorm.DB) error {
+	campo := reflect.ValueOf(registro).Elem().FieldByName("Version")
+	leida := campo.Uint()
+	campo.SetUint(leida + 1)
+
+	resultado := actualizar(uint(leida))
+	if resultado.Error != nil || resultado.RowsAffected == 0 {
+		campo.SetUint(leida)
+		if resultado.Error != nil {
+			return resultado.Error
+		}
+		return ErrModificado
+	}
+	campo.SetUint(leida + 1)
+	return nil
+}
+
+// ErrorAlGuardar traduce el error de Guardar o Actualizar: ErrModificado se vuelve un 412 con el registro recargado
+// (con las relaciones de precargar) y cualquier otro error pasa por errores.BaseDatos con mensaje.
+func ErrorAlGuardar(c *gin.Context, err error, registro any, mensaje string, precargar ...string) *errores.Error {
+	if !errors.Is(err, ErrModificado) {
+		return errores.BaseDatos(err, mensaje)
+	}
+
+	actual := reflect.New(reflect.TypeOf(registro).Elem()).Interface()
+	id := reflect.ValueOf(registro).Elem().FieldByName("ID").Uint()
+	q := database.De(c)
+	for _, relacion := range precargar {
+		q = q.Preload(relacion)
+	}
+	if err := q.First(actual, id).Error; err != nil {
+		return errores.Interno("Error recargando el registro", fmt.Errorf("%w: %v", ErrModificado, err))
+	}
+	return Modificado(c, uint(reflect.ValueOf(actual).Elem().FieldByName("Version").Uint()), actual)
+}
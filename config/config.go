@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,4 +18,40 @@ func LoadEnv() {
 
 func GetJWTSecret() string {
 	return os.Getenv("JWT_SECRET")
-}
\ No newline at end of file
+}
+
+// GetEnv regresa el valor de la variable de entorno o el valor por defecto si está vacía
+func GetEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// GetEnvInt regresa la variable de entorno como entero o el valor por defecto si está vacía o es inválida
+func GetEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Valor inválido para %s (%q), usando %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// GetEnvDuration regresa la variable de entorno como duración (ej. "30s", "5m") o el valor por defecto
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Duración inválida para %s (%q), usando %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
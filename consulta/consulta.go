@@ -0,0 +1,269 @@
+// Package consulta aplica paginación, orden y filtros desde la query string a los listados de la API
+package consulta
+
+import (
+	"encoding/base64"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/errores"
+)
+
+const (
+	TamanoPorDefecto = 50
+	TamanoMaximo     = 200
+
+	ParamPagina = "page"
+	ParamTamano = "page_size"
+	ParamOrden  = "sort"
+	ParamCursor = "cursor"
+)
+
+// Definicion describe qué se puede ordenar y filtrar en un listado.
+// Las columnas deben ir calificadas con su tabla cuando la consulta base incluye joins.
+type Definicion struct {
+	Orden           map[string]string // nombre del parámetro -> columna
+	OrdenPorDefecto string            // por ejemplo "-id"; el prefijo "-" indica descendente
+	Filtros         map[string]Filtro // nombre del parámetro -> filtro
+	Llave           string            // columna del ID; habilita la paginación por cursor. Vacía si el modelo no tiene ID
+	Precargar       []string          // relaciones que se precargan solo para la página obtenida
+	// Incluir son las relaciones que se pueden pedir con ?include=: nombre -> relación de GORM, por ejemplo
+	// "tutores": "EstudianteTutores.Tutor". Nil si el recurso no admite include.
+	Incluir map[string]string
+	Campos  []string // campos propios que se pueden pedir con ?fields=; nil si el recurso no admite fields
+}
+
+// ConPrecarga regresa una copia de la definición que precarga además las relaciones indicadas
+func (def Definicion) ConPrecarga(relaciones ...string) Definicion {
+	def.Precargar = append(append([]string(nil), def.Precargar...), relaciones...)
+	return def
+}
+
+// Paginacion se regresa junto a cada listado
+type Paginacion struct {
+	Pagina          int     `json:"pagina,omitempty"`
+	TamanoPagina    int     `json:"tamano_pagina"`
+	Total           int64   `json:"total"`
+	TotalPaginas    int     `json:"total_paginas,omitempty"`
+	Siguiente       *string `json:"siguiente"`
+	Anterior        *string `json:"anterior"`
+	SiguienteCursor *string `json:"siguiente_cursor,omitempty"`
+}
+
+// orden es un criterio ya validado de ?sort=
+type orden struct {
+	parametro string
+	columna   string
+	desc      bool
+}
+
+func (o orden) sql() string {
+	if o.desc {
+		return o.columna + " DESC"
+	}
+	return o.columna + " ASC"
+}
+
+// Listar ejecuta la consulta base (con sus joins y condiciones fijas) aplicando los parámetros de la solicitud.
+// Con ?cursor= se pagina por ID (más estable para recorrer tablas grandes); si no, por page/page_size.
+// ?include= y ?fields= deciden qué relaciones se precargan; la respuesta se recorta con Definicion.Proyectar.
+func Listar[T any](c *gin.Context, db *gorm.DB, def Definicion) ([]T, Paginacion, *errores.Error) {
+	var pag Paginacion
+
+	tamano, e := entero(c, ParamTamano, TamanoPorDefecto)
+	if e != nil {
+		return nil, pag, e
+	}
+	if tamano < 1 || tamano > TamanoMaximo {
+		return nil, pag, errores.CampoInvalido(ParamTamano, "max", "page_size debe estar entre 1 y "+strconv.Itoa(TamanoMaximo))
+	}
+	pag.TamanoPagina = tamano
+
+	ordenes, e := def.ordenes(c.Query(ParamOrden))
+	if e != nil {
+		return nil, pag, e
+	}
+
+	valorCursor, porCursorID := c.GetQuery(ParamCursor)
+	if porCursorID && def.Llave == "" {
+		return nil, pag, errores.CampoInvalido(ParamCursor, "excluded_with", "Este listado no admite paginación por cursor")
+	}
+	sel, e := def.Seleccionar(c)
+	if e != nil {
+		return nil, pag, e
+	}
+	pagina, e := entero(c, ParamPagina, 1)
+	if e != nil {
+		return nil, pag, e
+	}
+	if pagina < 1 {
+		return nil, pag, errores.CampoInvalido(ParamPagina, "min", "page debe ser mayor o igual a 1")
+	}
+
+	q, e := def.filtrar(c, db.Model(new(T)))
+	if e != nil {
+		return nil, pag, e
+	}
+
+	if err := q.Session(&gorm.Session{}).Count(&pag.Total).Error; err != nil {
+		return nil, pag, errores.Interno("Error contando registros", err)
+	}
+
+	for _, relacion := range sel.Precargar {
+		if q.Statement.Unscoped {
+			// En consultas de la papelera las relaciones también pueden estar eliminadas
+			q = q.Preload(relacion, func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() })
+		} else {
+			q = q.Preload(relacion)
+		}
+	}
+
+	if porCursorID {
+		return porCursor[T](c, q, def, ordenes, valorCursor, pag)
+	}
+
+	pag.Pagina = pagina
+	pag.TotalPaginas = int(math.Ceil(float64(pag.Total) / float64(tamano)))
+
+	for _, o := range def.conDesempate(ordenes) {
+		q = q.Order(o.sql())
+	}
+	// Cualquier página después de la última da una lista vacía; se acota antes de multiplicar para que un
+	// page enorme no desborde el OFFSET
+	var items []T
+	if err := q.Offset((min(pagina, pag.TotalPaginas+1) - 1) * tamano).Limit(tamano).Find(&items).Error; err != nil {
+		return nil, pag, errores.Interno("Error obteniendo registros", err)
+	}
+
+	if pagina < pag.TotalPaginas {
+		pag.Siguiente = enlace(c, url.Values{ParamPagina: {strconv.Itoa(pagina + 1)}})
+	}
+	if pagina > 1 {
+		pag.Anterior = enlace(c, url.Values{ParamPagina: {strconv.Itoa(min(pagina-1, max(pag.TotalPaginas, 1)))}})
+	}
+	return items, pag, nil
+}
+
+// porCursor pagina con WHERE llave > cursor, lo que evita el costo de OFFSET en páginas lejanas.
+// Solo admite ordenar por la llave (?sort=id o ?sort=-id).
+func porCursor[T any](c *gin.Context, q *gorm.DB, def Definicion, ordenes []orden, valor string, pag Paginacion) ([]T, Paginacion, *errores.Error) {
+	desc := false
+	switch {
+	case len(ordenes) == 1 && ordenes[0].columna == def.Llave:
+		desc = ordenes[0].desc
+	case c.Query(ParamOrden) != "":
+		return nil, pag, errores.CampoInvalido(ParamOrden, "oneof", "Con cursor solo se puede ordenar por id o -id")
+	}
+
+	if valor != "" {
+		desde, err := decodificarCursor(valor)
+		if err != nil {
+			return nil, pag, errores.CampoInvalido(ParamCursor, "base64", "cursor inválido")
+		}
+		if desc {
+			q = q.Where(def.Llave+" < ?", desde)
+		} else {
+			q = q.Where(def.Llave+" > ?", desde)
+		}
+	}
+
+	var items []T
+	if err := q.Order(orden{columna: def.Llave, desc: desc}.sql()).Limit(pag.TamanoPagina + 1).Find(&items).Error; err != nil {
+		return nil, pag, errores.Interno("Error obteniendo registros", err)
+	}
+
+	// Se pide un registro de más para saber si hay otra página sin contar de nuevo
+	if len(items) > pag.TamanoPagina {
+		items = items[:pag.TamanoPagina]
+		siguiente := codificarCursor(idDe(&items[len(items)-1]))
+		pag.SiguienteCursor = &siguiente
+		pag.Siguiente = enlace(c, url.Values{ParamCursor: {siguiente}})
+	}
+	return items, pag, nil
+}
+
+func codificarCursor(id uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(id, 10)))
+}
+
+func decodificarCursor(valor string) (uint64, error) {
+	crudo, err := base64.RawURLEncoding.DecodeString(valor)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(crudo), 10, 64)
+}
+
+// ordenes valida ?sort=campo1,-campo2 contra los campos permitidos
+func (def Definicion) ordenes(valor string) ([]orden, *errores.Error) {
+	if valor == "" {
+		valor = def.OrdenPorDefecto
+	}
+	var resultado []orden
+	for _, parte := range strings.Split(valor, ",") {
+		parte = strings.TrimSpace(parte)
+		if parte == "" {
+			continue
+		}
+		o := orden{parametro: strings.TrimPrefix(parte, "-"), desc: strings.HasPrefix(parte, "-")}
+		columna, ok := def.Orden[o.parametro]
+		if !ok {
+			return nil, errores.CampoInvalido(ParamOrden, "oneof", "No se puede ordenar por "+o.parametro+"; campos permitidos: "+strings.Join(ordenadas(def.Orden), ", "))
+		}
+		o.columna = columna
+		resultado = append(resultado, o)
+	}
+	return resultado, nil
+}
+
+// conDesempate agrega la llave al final para que el orden entre páginas sea estable
+func (def Definicion) conDesempate(ordenes []orden) []orden {
+	if def.Llave == "" {
+		return ordenes
+	}
+	for _, o := range ordenes {
+		if o.columna == def.Llave {
+			return ordenes
+		}
+	}
+	return append(ordenes, orden{parametro: "id", columna: def.Llave})
+}
+
+// idDe lee el campo ID del registro para construir el siguiente cursor
+func idDe(item any) uint64 {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	campo := v.FieldByName("ID")
+	if !campo.IsValid() || !campo.CanUint() {
+		return 0
+	}
+	return campo.Uint()
+}
+
+// entero lee un parámetro numérico opcional de la query string
+func entero(c *gin.Context, nombre string, porDefecto int) (int, *errores.Error) {
+	valor := c.Query(nombre)
+	if valor == "" {
+		return porDefecto, nil
+	}
+	n, err := strconv.Atoi(valor)
+	if err != nil {
+		return 0, errores.CampoInvalido(nombre, "number", nombre+" debe ser un número entero")
+	}
+	return n, nil
+}
+
+// enlace construye la ruta de la solicitud actual reemplazando los parámetros indicados
+func enlace(c *gin.Context, cambios url.Values) *string {
+	query := c.Request.URL.Query()
+	for clave, valores := range cambios {
+		query[clave] = valores
+	}
+	ruta := c.Request.URL.Path + "?" + query.Encode()
+	return &ruta
+}
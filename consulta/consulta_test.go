@@ -0,0 +1,242 @@
+package consulta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/database/prueba"
+	"api-margaritai/errores"
+)
+
+type registro struct {
+	ID        uint
+	Nombre    string
+	PlantelID uint
+	Activo    bool
+	CreatedAt time.Time
+}
+
+var definicion = Definicion{
+	Orden:           map[string]string{"id": "registros.id", "nombre": "registros.nombre"},
+	OrdenPorDefecto: "-id",
+	Filtros: map[string]Filtro{
+		"plantel_id": {Columna: "registros.plantel_id", Tipo: Entero},
+		"activo":     {Columna: "registros.activo", Tipo: Booleano},
+		"creado":     {Columna: "registros.created_at", Tipo: Fecha},
+		"nombre":     {Columna: "registros.nombre", Tipo: Texto},
+	},
+	Llave: "registros.id",
+}
+
+// listar ejecuta Listar con la query string dada sobre una base falsa que cuenta total registros y cuya
+// consulta de la página regresa filas registros con IDs consecutivos desde el 1. Regresa además la consulta
+// de la página, vacía si Listar falló antes de llegar a ella.
+func listar(t *testing.T, def Definicion, query string, total int64, filas int) ([]registro, Paginacion, *errores.Error, prueba.Sentencia) {
+	t.Helper()
+	db, conexion, err := prueba.Abrir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conexion.Responder(func(s prueba.Sentencia) prueba.Filas {
+		if strings.Contains(s.SQL, "count(*)") {
+			return prueba.Filas{Columnas: []string{"count"}, Valores: [][]any{{total}}}
+		}
+		r := prueba.Filas{Columnas: []string{"id", "nombre"}}
+		for i := 1; i <= filas; i++ {
+			r.Valores = append(r.Valores, []any{int64(i), "registro"})
+		}
+		return r
+	})
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/registros?"+query, nil)
+	items, pag, e := Listar[registro](c, db, def)
+
+	var pagina prueba.Sentencia
+	for _, s := range conexion.Buscar(`SELECT * FROM "registros"`) {
+		pagina = s
+	}
+	return items, pag, e, pagina
+}
+
+func TestPaginacion(t *testing.T) {
+	_, pag, e, s := listar(t, definicion, "page=2&page_size=50&nombre=ana", 120, 50)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if pag.Pagina != 2 || pag.TamanoPagina != 50 || pag.Total != 120 || pag.TotalPaginas != 3 {
+		t.Errorf("paginación = %+v", pag)
+	}
+	if !strings.HasSuffix(s.SQL, "LIMIT $2 OFFSET $3") || !reflect.DeepEqual(s.Args[1:], []any{50, 50}) {
+		t.Errorf("consulta = %s %v, se esperaba LIMIT 50 OFFSET 50", s.SQL, s.Args)
+	}
+	// Los enlaces conservan los demás parámetros
+	if pag.Siguiente == nil || *pag.Siguiente != "/registros?nombre=ana&page=3&page_size=50" {
+		t.Errorf("siguiente = %v", deref(pag.Siguiente))
+	}
+	if pag.Anterior == nil || *pag.Anterior != "/registros?nombre=ana&page=1&page_size=50" {
+		t.Errorf("anterior = %v", deref(pag.Anterior))
+	}
+
+	_, pag, _, _ = listar(t, definicion, "page=3", 120, 20)
+	if pag.Siguiente != nil || pag.Anterior == nil {
+		t.Errorf("última página: siguiente = %v, anterior = %v", deref(pag.Siguiente), deref(pag.Anterior))
+	}
+}
+
+func TestPaginaDespuesDeLaUltima(t *testing.T) {
+	for _, pagina := range []string{"4", "1000", "9223372036854775807"} {
+		t.Run(pagina, func(t *testing.T) {
+			items, pag, e, s := listar(t, definicion, "page="+pagina+"&page_size=50", 120, 0)
+			if e != nil {
+				t.Fatal(e)
+			}
+			// El OFFSET se acota justo después del último registro: sin desbordes y sin resultados
+			if len(s.Args) != 2 || s.Args[1] != 150 {
+				t.Errorf("consulta = %s %v, se esperaba OFFSET 150", s.SQL, s.Args)
+			}
+			if len(items) != 0 || pag.Siguiente != nil {
+				t.Errorf("items = %d, siguiente = %v", len(items), deref(pag.Siguiente))
+			}
+			// Anterior lleva a la última página que sí tiene registros
+			if pag.Anterior == nil || *pag.Anterior != "/registros?page=3&page_size=50" {
+				t.Errorf("anterior = %v", deref(pag.Anterior))
+			}
+		})
+	}
+}
+
+func TestParametrosInvalidos(t *testing.T) {
+	casos := map[string]string{
+		"page=0":               ParamPagina,
+		"page=-3":              ParamPagina,
+		"page=dos":             ParamPagina,
+		"page_size=0":          ParamTamano,
+		"page_size=201":        ParamTamano,
+		"sort=password":        ParamOrden,
+		"sort=nombre,-clave":   ParamOrden,
+		"plantel_id=1,x":       "plantel_id",
+		"activo=quizas":        "activo",
+		"creado_desde=ayer":    "creado_desde",
+		"creado_hasta=31/1/26": "creado_hasta",
+		"cursor=no-es-base64!": ParamCursor,
+		"cursor=&sort=nombre":  ParamOrden,
+	}
+	for query, campo := range casos {
+		t.Run(query, func(t *testing.T) {
+			_, _, e, s := listar(t, definicion, query, 10, 0)
+			if e == nil || e.Status != http.StatusBadRequest {
+				t.Fatalf("error = %v, se esperaba 400", e)
+			}
+			if len(e.Campos) == 0 || e.Campos[0].Campo != campo {
+				t.Errorf("campos = %+v, se esperaba %s", e.Campos, campo)
+			}
+			if s.SQL != "" {
+				t.Errorf("no debería consultar la página con parámetros inválidos: %s", s.SQL)
+			}
+		})
+	}
+
+	sinLlave := definicion
+	sinLlave.Llave = ""
+	if _, _, e, _ := listar(t, sinLlave, "cursor=", 10, 0); e == nil || e.Campos[0].Campo != ParamCursor {
+		t.Errorf("cursor sin llave: error = %v", e)
+	}
+}
+
+func TestOrden(t *testing.T) {
+	casos := map[string]string{
+		"":                "ORDER BY registros.id DESC",
+		"sort=nombre":     "ORDER BY registros.nombre ASC,registros.id ASC",
+		"sort=-nombre":    "ORDER BY registros.nombre DESC,registros.id ASC",
+		"sort=nombre,-id": "ORDER BY registros.nombre ASC,registros.id DESC",
+		"sort=id":         "ORDER BY registros.id ASC",
+	}
+	for query, esperado := range casos {
+		_, _, e, s := listar(t, definicion, query, 10, 0)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !strings.Contains(s.SQL, esperado+" LIMIT") {
+			t.Errorf("%q: consulta = %s, se esperaba %s", query, s.SQL, esperado)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	// Con page_size=2 se piden 3 registros para saber si hay otra página; el cursor es el último que se entrega
+	items, pag, e, s := listar(t, definicion, "cursor=&page_size=2&sort=id", 10, 3)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !strings.HasSuffix(s.SQL, "ORDER BY registros.id ASC LIMIT $1") || s.Args[0] != 3 {
+		t.Errorf("consulta = %s %v", s.SQL, s.Args)
+	}
+	if len(items) != 2 || pag.SiguienteCursor == nil || *pag.SiguienteCursor != codificarCursor(2) {
+		t.Fatalf("items = %d, siguiente cursor = %v", len(items), deref(pag.SiguienteCursor))
+	}
+	if pag.Pagina != 0 || pag.TotalPaginas != 0 {
+		t.Errorf("con cursor no se informa la página: %+v", pag)
+	}
+
+	_, pag, e, s = listar(t, definicion, "cursor="+*pag.SiguienteCursor+"&page_size=2&sort=-id", 10, 1)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !strings.Contains(s.SQL, "WHERE registros.id < $1 ORDER BY registros.id DESC") || s.Args[0] != uint64(2) {
+		t.Errorf("consulta = %s %v", s.SQL, s.Args)
+	}
+	if pag.SiguienteCursor != nil || pag.Siguiente != nil {
+		t.Errorf("la última página no debería tener siguiente: %v", deref(pag.SiguienteCursor))
+	}
+
+	if id, err := decodificarCursor(codificarCursor(1 << 40)); err != nil || id != 1<<40 {
+		t.Errorf("decodificarCursor = %d, %v", id, err)
+	}
+}
+
+func TestFiltros(t *testing.T) {
+	casos := []struct {
+		query string
+		sql   string
+		args  []any
+	}{
+		{"plantel_id=3", "WHERE registros.plantel_id = $1", []any{uint64(3)}},
+		{"plantel_id=1,%202", "WHERE registros.plantel_id IN ($1,$2)", []any{uint64(1), uint64(2)}},
+		{"activo=false", "WHERE registros.activo = $1", []any{false}},
+		{"nombre=50%25_a", `WHERE registros.nombre ILIKE $1`, []any{`%50\%\_a%`}},
+		// Una fecha sin hora incluye todo el día; con hora el límite es exacto
+		{"creado_desde=2026-01-01&creado_hasta=2026-01-31", "WHERE registros.created_at >= $1 AND registros.created_at < $2",
+			[]any{time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)}},
+		{"creado_hasta=2026-01-31T12:00:00Z", "WHERE registros.created_at <= $1", []any{time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)}},
+		{"desconocido=1&nombre=", "FROM \"registros\" ORDER BY", nil},
+	}
+	for _, c := range casos {
+		t.Run(c.query, func(t *testing.T) {
+			_, _, e, s := listar(t, definicion, c.query, 10, 0)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if !strings.Contains(s.SQL, c.sql) {
+				t.Errorf("consulta = %s, se esperaba %s", s.SQL, c.sql)
+			}
+			if args := s.Args[:len(c.args)]; len(c.args) > 0 && !reflect.DeepEqual(args, c.args) {
+				t.Errorf("argumentos = %v, se esperaba %v", args, c.args)
+			}
+		})
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
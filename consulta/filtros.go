@@ -0,0 +1,133 @@
+package consulta
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/errores"
+)
+
+// Tipo indica cómo se interpreta el valor de un filtro
+type Tipo int
+
+const (
+	Entero   Tipo = iota // igualdad; admite varios valores separados por coma (?plantel_id=1,2)
+	Booleano             // true/false, 1/0
+	Fecha                // rango con los sufijos _desde y _hasta (YYYY-MM-DD o RFC 3339)
+	Texto                // contiene, sin distinguir mayúsculas
+)
+
+const (
+	SufijoDesde = "_desde"
+	SufijoHasta = "_hasta"
+)
+
+// Filtro asocia un parámetro de la query string a una columna
+type Filtro struct {
+	Columna string
+	Tipo    Tipo
+}
+
+// filtrar agrega un WHERE por cada filtro presente en la solicitud; los parámetros desconocidos se ignoran
+func (def Definicion) filtrar(c *gin.Context, q *gorm.DB) (*gorm.DB, *errores.Error) {
+	for _, nombre := range ordenadas(def.Filtros) {
+		f := def.Filtros[nombre]
+
+		if f.Tipo == Fecha {
+			if valor := c.Query(nombre + SufijoDesde); valor != "" {
+				desde, _, err := fecha(valor)
+				if err != nil {
+					return nil, errores.CampoInvalido(nombre+SufijoDesde, "datetime", "Formato de fecha inválido. Use YYYY-MM-DD")
+				}
+				q = q.Where(f.Columna+" >= ?", desde)
+			}
+			if valor := c.Query(nombre + SufijoHasta); valor != "" {
+				hasta, soloDia, err := fecha(valor)
+				if err != nil {
+					return nil, errores.CampoInvalido(nombre+SufijoHasta, "datetime", "Formato de fecha inválido. Use YYYY-MM-DD")
+				}
+				// Una fecha sin hora incluye todo ese día
+				if soloDia {
+					q = q.Where(f.Columna+" < ?", hasta.AddDate(0, 0, 1))
+				} else {
+					q = q.Where(f.Columna+" <= ?", hasta)
+				}
+			}
+			continue
+		}
+
+		valor := strings.TrimSpace(c.Query(nombre))
+		if valor == "" {
+			continue
+		}
+		switch f.Tipo {
+		case Entero:
+			var ids []uint64
+			for _, parte := range strings.Split(valor, ",") {
+				id, err := strconv.ParseUint(strings.TrimSpace(parte), 10, 64)
+				if err != nil {
+					return nil, errores.CampoInvalido(nombre, "number", nombre+" debe ser un número o una lista separada por comas")
+				}
+				ids = append(ids, id)
+			}
+			if len(ids) == 1 {
+				q = q.Where(f.Columna+" = ?", ids[0])
+			} else {
+				q = q.Where(f.Columna+" IN ?", ids)
+			}
+		case Booleano:
+			b, err := strconv.ParseBool(valor)
+			if err != nil {
+				return nil, errores.CampoInvalido(nombre, "boolean", nombre+" debe ser true o false")
+			}
+			q = q.Where(f.Columna+" = ?", b)
+		case Texto:
+			q = q.Where(f.Columna+" ILIKE ?", "%"+EscaparLike(valor)+"%")
+		}
+	}
+	return q, nil
+}
+
+// fecha acepta YYYY-MM-DD o RFC 3339; soloDia indica que no traía hora
+func fecha(valor string) (t time.Time, soloDia bool, err error) {
+	if t, err = time.Parse("2006-01-02", valor); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, valor)
+	return t, false, err
+}
+
+// EscaparLike evita que % y _ del usuario se interpreten como comodines en LIKE/ILIKE
+func EscaparLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ordenadas regresa las llaves del mapa en orden alfabético, para mensajes y documentación estables
+func ordenadas[V any](m map[string]V) []string {
+	llaves := make([]string, 0, len(m))
+	for llave := range m {
+		llaves = append(llaves, llave)
+	}
+	sort.Strings(llaves)
+	return llaves
+}
+
+// CamposOrden lista los campos aceptados por ?sort=
+func (def Definicion) CamposOrden() []string {
+	return ordenadas(def.Orden)
+}
+
+// NombresFiltros lista los filtros en orden alfabético
+func (def Definicion) NombresFiltros() []string {
+	return ordenadas(def.Filtros)
+}
+
+// NombresInclusiones lista las relaciones aceptadas por ?include= en orden alfabético
+func (def Definicion) NombresInclusiones() []string {
+	return ordenadas(def.Incluir)
+}
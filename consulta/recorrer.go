@@ -0,0 +1,65 @@
+package consulta
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/errores"
+)
+
+// TamanoLote es cuántos registros se leen por consulta al recorrer un listado completo
+const TamanoLote = 500
+
+// Recorrer valida los filtros y el orden de la solicitud como Listar, pero en lugar de una página regresa
+// una función que entrega todos los registros en lotes, para exportar tablas grandes sin cargarlas completas.
+// Los errores de parámetros se regresan antes de consultar nada; los de la base de datos, al recorrer.
+func Recorrer[T any](c *gin.Context, db *gorm.DB, def Definicion) (func(procesar func([]T) error) error, *errores.Error) {
+	ordenes, e := def.ordenes(c.Query(ParamOrden))
+	if e != nil {
+		return nil, e
+	}
+	q, e := def.filtrar(c, db.Model(new(T)))
+	if e != nil {
+		return nil, e
+	}
+	for _, relacion := range def.Precargar {
+		q = q.Preload(relacion)
+	}
+
+	// Ordenar solo por la llave permite avanzar con WHERE llave > último, que no se degrada como OFFSET
+	porLlave := len(ordenes) == 1 && ordenes[0].columna == def.Llave
+	ordenes = def.conDesempate(ordenes)
+
+	return func(procesar func([]T) error) error {
+		var ultimo uint64
+		for desde := 0; ; desde += TamanoLote {
+			lote := q.Session(&gorm.Session{})
+			for _, o := range ordenes {
+				lote = lote.Order(o.sql())
+			}
+			switch {
+			case porLlave && desde > 0 && ordenes[0].desc:
+				lote = lote.Where(def.Llave+" < ?", ultimo)
+			case porLlave && desde > 0:
+				lote = lote.Where(def.Llave+" > ?", ultimo)
+			case !porLlave:
+				lote = lote.Offset(desde)
+			}
+
+			var items []T
+			if err := lote.Limit(TamanoLote).Find(&items).Error; err != nil {
+				return err
+			}
+			if len(items) == 0 {
+				return nil
+			}
+			if err := procesar(items); err != nil {
+				return err
+			}
+			if len(items) < TamanoLote {
+				return nil
+			}
+			ultimo = idDe(&items[len(items)-1])
+		}
+	}, nil
+}
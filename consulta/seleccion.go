@@ -0,0 +1,216 @@
+package consulta
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/errores"
+)
+
+const (
+	ParamCampos  = "fields"
+	ParamIncluir = "include"
+)
+
+var tipoFecha = reflect.TypeOf(time.Time{})
+
+// Seleccion es la forma de respuesta que pidió la solicitud con ?fields= e ?include=, ya validada.
+// Si vino alguno de los dos parámetros solo se precargan y se responden las relaciones de ?include=;
+// si no vino ninguno se usan las relaciones de Precargar y la respuesta queda completa.
+type Seleccion struct {
+	Precargar []string        // relaciones de GORM a precargar
+	campos    map[string]bool // campos propios pedidos; nil responde todos
+	proyectar bool
+}
+
+// arbol son las relaciones incluidas por su llave JSON, con las relaciones anidadas que también se incluyeron
+type arbol map[string]arbol
+
+// Seleccionar valida ?include= contra Incluir y ?fields= contra Campos
+func (def Definicion) Seleccionar(c *gin.Context) (Seleccion, *errores.Error) {
+	sel := Seleccion{Precargar: def.Precargar}
+
+	if valor, ok := c.GetQuery(ParamIncluir); ok {
+		if def.Incluir == nil {
+			return sel, errores.CampoInvalido(ParamIncluir, "excluded_with", "Este recurso no admite include")
+		}
+		sel.Precargar, sel.proyectar = nil, true
+		for _, nombre := range lista(valor) {
+			relacion, ok := def.Incluir[nombre]
+			if !ok {
+				return sel, errores.CampoInvalido(ParamIncluir, "oneof", "No se puede incluir "+nombre+"; relaciones permitidas: "+strings.Join(def.NombresInclusiones(), ", "))
+			}
+			if !slices.Contains(sel.Precargar, relacion) {
+				sel.Precargar = append(sel.Precargar, relacion)
+			}
+		}
+	}
+
+	if valor, ok := c.GetQuery(ParamCampos); ok {
+		if def.Campos == nil {
+			return sel, errores.CampoInvalido(ParamCampos, "excluded_with", "Este recurso no admite fields")
+		}
+		if !sel.proyectar {
+			sel.Precargar, sel.proyectar = nil, true
+		}
+		// El ID siempre se responde, para que el cliente pueda pedir el detalle o editar el registro
+		sel.campos = map[string]bool{"id": true}
+		for _, nombre := range lista(valor) {
+			if !slices.Contains(def.Campos, nombre) {
+				return sel, errores.CampoInvalido(ParamCampos, "oneof", "No se puede pedir el campo "+nombre+"; campos permitidos: "+strings.Join(def.Campos, ", "))
+			}
+			sel.campos[nombre] = true
+		}
+	}
+	return sel, nil
+}
+
+// Consulta agrega a q las precargas de la selección
+func (s Seleccion) Consulta(q *gorm.DB) *gorm.DB {
+	for _, relacion := range s.Precargar {
+		q = q.Preload(relacion)
+	}
+	return q
+}
+
+// Proyectar regresa el registro o la lista de registros con solo los campos pedidos y las relaciones
+// incluidas; sin ?fields= ni ?include= los regresa sin cambios
+func (s Seleccion) Proyectar(datos any) any {
+	if !s.proyectar {
+		return datos
+	}
+
+	tipo := elemento(reflect.TypeOf(datos))
+	incluidas := arbol{}
+	for _, relacion := range s.Precargar {
+		incluidas.agregar(tipo, strings.Split(relacion, "."))
+	}
+
+	crudo, err := json.Marshal(datos)
+	if err != nil {
+		return datos
+	}
+	var valor any
+	d := json.NewDecoder(bytes.NewReader(crudo))
+	d.UseNumber()
+	if err := d.Decode(&valor); err != nil {
+		return datos
+	}
+	return podar(valor, tipo, incluidas, s.campos)
+}
+
+// Proyectar aplica ?fields= e ?include= a lo que regresó Listar, que ya validó los parámetros
+func (def Definicion) Proyectar(c *gin.Context, datos any) any {
+	sel, e := def.Seleccionar(c)
+	if e != nil {
+		return datos
+	}
+	return sel.Proyectar(datos)
+}
+
+// CamposDe lista las llaves JSON de los campos propios del modelo (sin relaciones), en el orden en que
+// se declaran; sirve como lista de Campos
+func CamposDe(modelo any) []string {
+	tipo := elemento(reflect.TypeOf(modelo))
+	var campos []string
+	for i := 0; i < tipo.NumField(); i++ {
+		campo := tipo.Field(i)
+		if nombre := nombreJSON(campo); nombre != "" && !esRelacion(campo.Type) {
+			campos = append(campos, nombre)
+		}
+	}
+	return campos
+}
+
+// agregar convierte una ruta de GORM ("EstudianteTutores.Tutor") a llaves JSON y la suma al árbol
+func (a arbol) agregar(tipo reflect.Type, ruta []string) {
+	campo, ok := tipo.FieldByName(ruta[0])
+	nombre := nombreJSON(campo)
+	if !ok || nombre == "" {
+		return
+	}
+	if a[nombre] == nil {
+		a[nombre] = arbol{}
+	}
+	if len(ruta) > 1 {
+		a[nombre].agregar(elemento(campo.Type), ruta[1:])
+	}
+}
+
+// podar quita del valor decodificado los campos propios que no se pidieron y las relaciones que no se incluyeron
+func podar(valor any, tipo reflect.Type, incluidas arbol, campos map[string]bool) any {
+	if elementos, ok := valor.([]any); ok {
+		for i := range elementos {
+			elementos[i] = podar(elementos[i], tipo, incluidas, campos)
+		}
+		return elementos
+	}
+	objeto, ok := valor.(map[string]any)
+	if !ok {
+		return valor
+	}
+
+	for i := 0; i < tipo.NumField(); i++ {
+		campo := tipo.Field(i)
+		nombre := nombreJSON(campo)
+		if nombre == "" {
+			continue
+		}
+		if esRelacion(campo.Type) {
+			sub, incluida := incluidas[nombre]
+			if !incluida {
+				delete(objeto, nombre)
+				continue
+			}
+			objeto[nombre] = podar(objeto[nombre], elemento(campo.Type), sub, nil)
+			continue
+		}
+		if campos != nil && !campos[nombre] {
+			delete(objeto, nombre)
+		}
+	}
+	return objeto
+}
+
+// esRelacion indica un campo que GORM llena con Preload: una estructura o una lista de estructuras
+func esRelacion(t reflect.Type) bool {
+	t = elemento(t)
+	return t.Kind() == reflect.Struct && t != tipoFecha && !t.ConvertibleTo(tipoFecha)
+}
+
+// elemento quita punteros y listas hasta llegar al tipo del registro
+func elemento(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t
+}
+
+func nombreJSON(campo reflect.StructField) string {
+	if !campo.IsExported() {
+		return ""
+	}
+	nombre, _, _ := strings.Cut(campo.Tag.Get("json"), ",")
+	if nombre == "-" {
+		return ""
+	}
+	return nombre
+}
+
+// lista separa un parámetro con valores separados por coma, sin espacios ni vacíos
+func lista(valor string) []string {
+	var partes []string
+	for _, parte := range strings.Split(valor, ",") {
+		if parte = strings.TrimSpace(parte); parte != "" {
+			partes = append(partes, parte)
+		}
+	}
+	return partes
+}
@@ -9,9 +9,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"api-margaritai/curp"
 	"api-margaritai/database"
+	"api-margaritai/errores"
 	"api-margaritai/middleware"
 	"api-margaritai/models"
+	"api-margaritai/validadores"
 )
 
 type RegisterInput struct {
@@ -19,10 +22,10 @@ type RegisterInput struct {
 	ApellidoP string `json:"apellido_p" binding:"required"`
 	ApellidoM string `json:"apellido_m" binding:"required"`
 	Email     string `json:"email" binding:"required,email"`
-	CURP      string `json:"curp" binding:"required"`
+	CURP      string `json:"curp" binding:"required,curp"`
 	Password  string `json:"password" binding:"required,min=6"`
-	FechaNac  string `json:"fecha_nac" binding:"required"`
-	GeneroID  uint   `json:"genero_id" binding:"required"`
+	FechaNac  string `json:"fecha_nac"` // YYYY-MM-DD; si se omite se toma de la CURP
+	GeneroID  uint   `json:"genero_id"` // si se omite se toma del sexo de la CURP
 	RolID     uint   `json:"rol_id" binding:"required"`
 }
 
@@ -35,42 +38,50 @@ func Register(c *gin.Context) {
 	var input RegisterInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+	input.Email = strings.TrimSpace(input.Email)
+	input.CURP = validadores.NormalizarCURP(input.CURP)
+
+	// Completar con la CURP la fecha de nacimiento y el género si no se enviaron
+	if err := curp.Completar(c, input.CURP, curp.Faltantes{FechaNac: &input.FechaNac, GeneroID: &input.GeneroID}); err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo datos de la CURP", err))
 		return
 	}
 
 	// Verificar si el email ya existe
 	var existingUserByEmail models.User
-	if err := database.DB.Where("email = ?", input.Email).First(&existingUserByEmail).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email ya ha sido registrado", "status": http.StatusBadRequest})
+	if err := database.De(c).Where("email = ?", input.Email).First(&existingUserByEmail).Error; err == nil {
+		errores.Responder(c, errores.Conflicto(errores.EmailDuplicado, "Email ya ha sido registrado"))
 		return
 	}
 
 	// Verificar si el CURP ya existe
 	var existingUserByCURP models.User
-	if err := database.DB.Where("curp = ?", input.CURP).First(&existingUserByCURP).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "CURP ya ha sido registrado", "status": http.StatusBadRequest})
+	if err := database.De(c).Where("curp = ?", input.CURP).First(&existingUserByCURP).Error; err == nil {
+		errores.Responder(c, errores.Conflicto(errores.CURPDuplicada, "CURP ya ha sido registrado"))
 		return
 	}
 
 	// Verificar si el género existe
 	var genero models.Genero
-	if err := database.DB.Where("id = ?", input.GeneroID).First(&genero).Error; err != nil {
+	if err := database.De(c).Where("id = ?", input.GeneroID).First(&genero).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Género no encontrado", "status": http.StatusBadRequest})
+			errores.Responder(c, errores.SolicitudInvalida(errores.GeneroNoEncontrado, "Género no encontrado"))
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando género", "status": http.StatusInternalServerError})
+			errores.Responder(c, errores.Interno("Error verificando género", err))
 		}
 		return
 	}
 
 	// Verificar si el rol existe
 	var rol models.Rol
-	if err := database.DB.Where("id = ?", input.RolID).First(&rol).Error; err != nil {
+	if err := database.De(c).Where("id = ?", input.RolID).First(&rol).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Rol no encontrado", "status": http.StatusBadRequest})
+			errores.Responder(c, errores.SolicitudInvalida(errores.RolNoEncontrado, "Rol no encontrado"))
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando rol", "status": http.StatusInternalServerError})
+			errores.Responder(c, errores.Interno("Error verificando rol", err))
 		}
 		return
 	}
@@ -78,7 +89,14 @@ func Register(c *gin.Context) {
 	// Parsear la fecha de nacimiento
 	fechaNac, err := time.Parse("2006-01-02", input.FechaNac)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de fecha inválido. Use YYYY-MM-DD"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.FechaInvalida, "Formato de fecha inválido. Use YYYY-MM-DD"))
+		return
+	}
+
+	// Verificar que la fecha y el género coincidan con la CURP
+	advertencias, errCURP := curp.Verificar(c, input.CURP, curp.Capturados{FechaNac: fechaNac, GeneroID: input.GeneroID})
+	if errCURP != nil {
+		errores.Responder(c, errCURP)
 		return
 	}
 
@@ -95,28 +113,28 @@ func Register(c *gin.Context) {
 	}
 
 	if err := user.HashPassword(input.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al hashear contraseña", "status": http.StatusInternalServerError})
+		errores.Responder(c, errores.Interno("Error al hashear contraseña", err))
 		return
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando usuario", "status": http.StatusInternalServerError})
+	if err := database.De(c).Create(&user).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error creando usuario"))
 		return
 	}
 
 	// Cargar la información del género y rol
-	if err := database.DB.Preload("Genero").Preload("Rol").First(&user, user.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error cargando información del usuario", "status": http.StatusInternalServerError})
+	if err := database.De(c).Preload("Genero").Preload("Rol").First(&user, user.ID).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error cargando información del usuario", err))
 		return
 	}
 
-	token, err := middleware.GenerateToken(user.ID)
+	token, err := middleware.GenerateToken(user.ID, user.OrganizacionID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando token", "status": http.StatusInternalServerError})
+		errores.Responder(c, errores.Interno("Error generando token", err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	respuesta := gin.H{
 		"message": "Usuario registrado exitosamente",
 		"token":   token,
 		"user": gin.H{
@@ -144,49 +162,53 @@ func Register(c *gin.Context) {
 			},
 			"es_activo": user.EsActivo,
 		},
-	})
+	}
+	if len(advertencias) > 0 {
+		respuesta["advertencias"] = advertencias
+	}
+	c.JSON(http.StatusCreated, respuesta)
 }
 
 func Login(c *gin.Context) {
 	var input LoginInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
 	var user models.User
 	// Verificar si el correo existe antes de intentar cargar el usuario
 	var count int64
-	if err := database.DB.Model(&models.User{}).Where("email = ?", input.Email).Count(&count).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error de base de datos, revisar conexión", "status": 500})
+	if err := database.De(c).Model(&models.User{}).Where("email = ?", input.Email).Count(&count).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error de base de datos, revisar conexión", err))
 		return
 	}
 	if count == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "El correo electrónico no existe", "status": 401})
+		errores.Responder(c, errores.NoAutorizado(errores.CorreoNoRegistrado, "El correo electrónico no existe"))
 		return
 	}
 
 	// Preload Rol y Genero
-	if err := database.DB.Preload("Genero").Preload("Rol").Where("email = ?", input.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error de base de datos, revisar conexión", "status": 500})
+	if err := database.De(c).Preload("Genero").Preload("Rol").Where("email = ?", input.Email).First(&user).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error de base de datos, revisar conexión", err))
 		return
 	}
 
 	if err := user.CheckPassword(input.Password); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "La contraseña es incorrecta"})
+		errores.Responder(c, errores.NoAutorizado(errores.PasswordIncorrecto, "La contraseña es incorrecta"))
 		return
 	}
 
 	// Obtener los permisos del rol del usuario
 	var permisos []models.Permiso
-	err := database.DB.
+	err := database.De(c).
 		Joins("JOIN role_tiene_permisoS ON role_tiene_permisoS.permiso_id = permisos.id").
 		Where("role_tiene_permisoS.role_id = ?", user.RolID).
 		Preload("CategoriaPermiso").
 		Find(&permisos).Error
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo permisos del rol"})
+		errores.Responder(c, errores.Interno("Error obteniendo permisos del rol", err))
 		return
 	}
 
@@ -207,9 +229,9 @@ func Login(c *gin.Context) {
 		})
 	}
 
-	token, err := middleware.GenerateToken(user.ID)
+	token, err := middleware.GenerateToken(user.ID, user.OrganizacionID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando token"})
+		errores.Responder(c, errores.Interno("Error generando token", err))
 		return
 	}
 
@@ -220,11 +242,8 @@ func Login(c *gin.Context) {
 		Token:     token,
 		ExpiresAt: expiraEn,
 	}
-	if err := database.DB.Create(&session).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":  "Error creando sesión",
-			"status": 500,
-		})
+	if err := database.De(c).Create(&session).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error creando sesión", err))
 		return
 	}
 
@@ -264,7 +283,7 @@ func Login(c *gin.Context) {
 func Logout(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token no proporcionado"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.TokenRequerido, "Token no proporcionado"))
 		return
 	}
 
@@ -284,24 +303,21 @@ func Logout(c *gin.Context) {
 func ValidateToken(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token no proporcionado"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.TokenRequerido, "Token no proporcionado"))
 		return
 	}
 
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 	var session models.Session
-	if err := database.DB.Where("token = ?", tokenString).First(&session).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Por favor inicia sesión"})
+	if err := database.De(c).Where("token = ?", tokenString).First(&session).Error; err != nil {
+		errores.Responder(c, errores.NoAutorizado(errores.TokenInvalido, "Por favor inicia sesión"))
 		return
 	}
 
 	// Validar expiración
 	if session.ExpiresAt.Before(time.Now()) {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":  "La sesión ha expirado, por favor inicia sesión nuevamente.",
-			"status": http.StatusUnauthorized,
-		})
+		errores.Responder(c, errores.NoAutorizado(errores.TokenExpirado, "La sesión ha expirado, por favor inicia sesión nuevamente."))
 		return
 	}
 
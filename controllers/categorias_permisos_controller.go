@@ -3,7 +3,10 @@ package controllers
 import (
 	"net/http"
 
+	"api-margaritai/concurrencia"
+	"api-margaritai/consulta"
 	"api-margaritai/database"
+	"api-margaritai/errores"
 	"api-margaritai/models"
 
 	"github.com/gin-gonic/gin"
@@ -21,17 +24,33 @@ type UpdateCategoriaPermisoInput struct {
 	Icono       *string `json:"icono"`
 }
 
-// Obtener todas las categorías de permisos
+// ConsultaCategoriasPermisos define el orden y los filtros aceptados por GetCategoriasPermisos
+var ConsultaCategoriasPermisos = consulta.Definicion{
+	Orden: map[string]string{
+		"id":         "id",
+		"titulo":     "titulo",
+		"created_at": "created_at",
+	},
+	OrdenPorDefecto: "id",
+	Filtros: map[string]consulta.Filtro{
+		"titulo":     {Columna: "titulo", Tipo: consulta.Texto},
+		"created_at": {Columna: "created_at", Tipo: consulta.Fecha},
+	},
+	Llave: "id",
+}
+
+// Obtener las categorías de permisos paginadas
 func GetCategoriasPermisos(c *gin.Context) {
-	var categorias []models.CategoriaPermiso
-	if err := database.DB.Find(&categorias).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo categorías de permisos"})
+	categorias, paginacion, errConsulta := consulta.Listar[models.CategoriaPermiso](c, database.LecturaDe(c), ConsultaCategoriasPermisos)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Categorías de permisos obtenidas exitosamente",
 		"categorias": categorias,
+		"paginacion": paginacion,
 	})
 }
 
@@ -40,11 +59,12 @@ func GetCategoriaPermiso(c *gin.Context) {
 	var categoria models.CategoriaPermiso
 	id := c.Param("id")
 
-	if err := database.DB.First(&categoria, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Categoría de permiso no encontrada"})
+	if err := database.De(c).First(&categoria, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.CategoriaPermisoNoEncontrada, "Categoría de permiso no encontrada"))
 		return
 	}
 
+	concurrencia.EscribirETag(c, categoria.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "Categoría de permiso obtenida exitosamente",
 		"categoria": categoria,
@@ -55,7 +75,7 @@ func GetCategoriaPermiso(c *gin.Context) {
 func CreateCategoriaPermiso(c *gin.Context) {
 	var input CreateCategoriaPermisoInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
@@ -65,8 +85,8 @@ func CreateCategoriaPermiso(c *gin.Context) {
 		Icono:       input.Icono,
 	}
 
-	if err := database.DB.Create(&categoria).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando la categoría de permiso"})
+	if err := database.De(c).Create(&categoria).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error creando la categoría de permiso"))
 		return
 	}
 
@@ -81,14 +101,18 @@ func UpdateCategoriaPermiso(c *gin.Context) {
 	var categoria models.CategoriaPermiso
 	id := c.Param("id")
 
-	if err := database.DB.First(&categoria, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Categoría de permiso no encontrada"})
+	if err := database.De(c).First(&categoria, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.CategoriaPermisoNoEncontrada, "Categoría de permiso no encontrada"))
+		return
+	}
+
+	if !concurrencia.Verificar(c, categoria.Version, categoria) {
 		return
 	}
 
 	var input UpdateCategoriaPermisoInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
@@ -102,11 +126,12 @@ func UpdateCategoriaPermiso(c *gin.Context) {
 		categoria.Icono = *input.Icono
 	}
 
-	if err := database.DB.Save(&categoria).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando la categoría de permiso"})
+	if err := concurrencia.Guardar(database.De(c), &categoria); err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &categoria, "Error actualizando la categoría de permiso"))
 		return
 	}
 
+	concurrencia.EscribirETag(c, categoria.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "Categoría de permiso actualizada exitosamente",
 		"categoria": categoria,
@@ -118,13 +143,13 @@ func DeleteCategoriaPermiso(c *gin.Context) {
 	var categoria models.CategoriaPermiso
 	id := c.Param("id")
 
-	if err := database.DB.First(&categoria, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Categoría de permiso no encontrada"})
+	if err := database.De(c).First(&categoria, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.CategoriaPermisoNoEncontrada, "Categoría de permiso no encontrada"))
 		return
 	}
 
-	if err := database.DB.Delete(&categoria).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando la categoría de permiso"})
+	if err := database.De(c).Delete(&categoria).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error eliminando la categoría de permiso"))
 		return
 	}
 
@@ -0,0 +1,66 @@
+package gestioncatalogos
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/horario"
+	"api-margaritai/models"
+)
+
+// ObtenerAulasDisponibles lista las aulas activas libres en una franja de la semana (dia o fecha, desde y
+// hasta): las que no tienen una clase a esa hora en un ciclo que no está cerrado. Con fecha solo cuentan
+// los ciclos que la incluyen. Se puede acotar con plantel_id, tipo y capacidad_minima.
+func ObtenerAulasDisponibles(c *gin.Context) {
+	franja, fecha, errFranja := horario.DeConsulta(c)
+	if errFranja != nil {
+		errores.Responder(c, errFranja)
+		return
+	}
+
+	ocupadas := clasesEnFranja(database.LecturaDe(c), franja).Select("1").Where("horarios.aula_id = aulas.id")
+	if !fecha.IsZero() {
+		ocupadas = ocupadas.Where("ciclos_escolares.fecha_inicio <= ? AND ciclos_escolares.fecha_fin >= ?", fecha, fecha)
+	}
+	q := database.LecturaDe(c).Preload("Plantel").Where("activa = ?", true).Where("NOT EXISTS (?)", ocupadas)
+	if valor := c.Query("plantel_id"); valor != "" {
+		plantelID, err := strconv.ParseUint(valor, 10, 64)
+		if err != nil {
+			errores.Responder(c, errores.CampoInvalido("plantel_id", "number", "plantel_id debe ser un número"))
+			return
+		}
+		q = q.Where("plantel_id = ?", plantelID)
+	}
+	if tipo := c.Query("tipo"); tipo != "" {
+		if !slices.Contains(models.TiposAula, tipo) {
+			errores.Responder(c, errores.CampoInvalido("tipo", "oneof", "tipo debe ser salon, laboratorio o cancha"))
+			return
+		}
+		q = q.Where("tipo = ?", tipo)
+	}
+	if valor := c.Query("capacidad_minima"); valor != "" {
+		capacidad, err := strconv.Atoi(valor)
+		if err != nil || capacidad < 0 {
+			errores.Responder(c, errores.CampoInvalido("capacidad_minima", "number", "capacidad_minima debe ser un número positivo"))
+			return
+		}
+		q = q.Where("capacidad >= ?", capacidad)
+	}
+
+	var aulas []models.Aula
+	if err := q.Order("plantel_id, nombre, id").Find(&aulas).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo las aulas disponibles", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Aulas disponibles obtenidas correctamente",
+		"franja":  franja,
+		"data":    aulas,
+	})
+}
@@ -0,0 +1,259 @@
+package gestioncatalogos
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/concurrencia"
+	"api-margaritai/consulta"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+)
+
+// Dependencia es una tabla con llave foránea al catálogo; mientras tenga registros que apunten a uno
+// de sus elementos, ese elemento no se puede eliminar
+type Dependencia struct {
+	Modelo  any    // modelo de la tabla dependiente, por ejemplo &models.Materia{}
+	Columna string // columna que apunta al catálogo
+	Codigo  string // código del 409
+	Mensaje string
+}
+
+// Descripcion son los datos de un catálogo que no dependen de su tipo
+type Descripcion struct {
+	Ruta         string // segmento bajo /api/protected, por ejemplo "puestos"
+	Tag          string // sección de la documentación
+	Singular     string // en minúsculas, para los mensajes: "puesto", "estatus de empleado"
+	Plural       string
+	Femenino     bool   // concuerda los mensajes: "Aula creada", "Aulas obtenidas"
+	NoEncontrado string // código de errores para el 404
+	Consulta     consulta.Definicion
+	Cache        string // grupo de caché del listado; vacío si no se guarda en memoria
+	Dependencias []Dependencia
+}
+
+// Catalogo implementa listar, obtener, crear, editar y eliminar para el modelo T con el cuerpo E,
+// que se usa tanto en POST como en PUT. Las relaciones de Consulta.Precargar se incluyen también
+// en las respuestas de un solo registro.
+type Catalogo[T any, E any] struct {
+	Descripcion
+	Aplicar func(registro *T, entrada E) // copia la entrada al registro al crearlo y al editarlo
+	// Validar revisa, después de Aplicar, las reglas que dependen de otros registros; opcional
+	Validar func(db *gorm.DB, registro *T) *errores.Error
+}
+
+// Administrable es lo que las rutas y la documentación usan de un catálogo sin conocer su tipo
+type Administrable interface {
+	Registrar(grupo *gin.RouterGroup, middlewares ...gin.HandlerFunc)
+	Datos() Descripcion
+	Modelo() any  // valor del modelo, para derivar el esquema
+	Entrada() any // valor del cuerpo de POST y PUT
+}
+
+// Registrar agrega las cinco rutas del catálogo; middlewares (la caché del catálogo) se aplica al listado
+// y a las escrituras, como en los demás catálogos
+func (cat *Catalogo[T, E]) Registrar(grupo *gin.RouterGroup, middlewares ...gin.HandlerFunc) {
+	con := func(h gin.HandlerFunc) []gin.HandlerFunc {
+		return append(append([]gin.HandlerFunc(nil), middlewares...), h)
+	}
+	ruta := "/" + cat.Ruta
+	grupo.GET(ruta, con(cat.Listar)...)
+	grupo.GET(ruta+"/:id", cat.Obtener)
+	grupo.POST(ruta, con(cat.Crear)...)
+	grupo.PUT(ruta+"/:id", con(cat.Editar)...)
+	grupo.DELETE(ruta+"/:id", con(cat.Eliminar)...)
+}
+
+func (cat *Catalogo[T, E]) Datos() Descripcion { return cat.Descripcion }
+func (cat *Catalogo[T, E]) Modelo() any        { return *new(T) }
+func (cat *Catalogo[T, E]) Entrada() any       { return *new(E) }
+
+// Listar responde la página pedida con el orden y los filtros de Consulta
+func (cat *Catalogo[T, E]) Listar(c *gin.Context) {
+	registros, paginacion, errConsulta := consulta.Listar[T](c, database.LecturaDe(c), cat.Consulta)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    mayuscula(cat.Plural) + " obtenid" + cat.terminacion() + "s correctamente",
+		"data":       registros,
+		"paginacion": paginacion,
+	})
+}
+
+// Obtener responde un registro con su ETag, que se usa en If-Match al editarlo
+func (cat *Catalogo[T, E]) Obtener(c *gin.Context) {
+	registro, ok := cat.buscar(c, cat.conPrecargas(database.LecturaDe(c)))
+	if !ok {
+		return
+	}
+	concurrencia.EscribirETag(c, version(registro))
+	c.JSON(http.StatusOK, gin.H{
+		"message": mayuscula(cat.Singular) + " obtenid" + cat.terminacion() + " correctamente",
+		"data":    registro,
+	})
+}
+
+// Crear valida el cuerpo y guarda un registro nuevo
+func (cat *Catalogo[T, E]) Crear(c *gin.Context) {
+	var entrada E
+	if err := c.ShouldBindJSON(&entrada); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+
+	registro := new(T)
+	cat.Aplicar(registro, entrada)
+	if !cat.validar(c, registro) {
+		return
+	}
+	if err := database.De(c).Create(registro).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error al guardar "+cat.articulo()+" "+cat.Singular))
+		return
+	}
+	cat.recargar(c, registro)
+
+	concurrencia.EscribirETag(c, version(registro))
+	c.JSON(http.StatusCreated, gin.H{
+		"message": mayuscula(cat.Singular) + " cread" + cat.terminacion() + " correctamente",
+		"data":    registro,
+	})
+}
+
+// Editar reemplaza los campos del registro con el cuerpo; exige If-Match con la versión leída
+func (cat *Catalogo[T, E]) Editar(c *gin.Context) {
+	registro, ok := cat.buscar(c, database.De(c))
+	if !ok {
+		return
+	}
+	if !concurrencia.Verificar(c, version(registro), registro) {
+		return
+	}
+
+	var entrada E
+	if err := c.ShouldBindJSON(&entrada); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+	cat.Aplicar(registro, entrada)
+	if !cat.validar(c, registro) {
+		return
+	}
+
+	if err := concurrencia.Guardar(database.De(c), registro); err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, registro, "Error al actualizar "+cat.articulo()+" "+cat.Singular, cat.Consulta.Precargar...))
+		return
+	}
+	cat.recargar(c, registro)
+
+	concurrencia.EscribirETag(c, version(registro))
+	c.JSON(http.StatusOK, gin.H{
+		"message": mayuscula(cat.Singular) + " actualizad" + cat.terminacion() + " correctamente",
+		"data":    registro,
+	})
+}
+
+// Eliminar envía el registro a la papelera si ninguna dependencia lo usa
+func (cat *Catalogo[T, E]) Eliminar(c *gin.Context) {
+	registro, ok := cat.buscar(c, database.De(c))
+	if !ok {
+		return
+	}
+
+	id := reflect.ValueOf(registro).Elem().FieldByName("ID").Uint()
+	for _, dep := range cat.Dependencias {
+		var relacionados int64
+		if err := database.De(c).Model(dep.Modelo).Where(dep.Columna+" = ?", id).Count(&relacionados).Error; err != nil {
+			errores.Responder(c, errores.Interno("No se pudieron validar los registros relacionados", err))
+			return
+		}
+		if relacionados > 0 {
+			errores.Responder(c, errores.Conflicto(dep.Codigo, dep.Mensaje))
+			return
+		}
+	}
+
+	if err := database.De(c).Delete(registro).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error al eliminar "+cat.articulo()+" "+cat.Singular))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": mayuscula(cat.Singular) + " eliminad" + cat.terminacion() + " correctamente",
+	})
+}
+
+// buscar lee :id y carga el registro; si no puede, ya respondió
+func (cat *Catalogo[T, E]) buscar(c *gin.Context, db *gorm.DB) (*T, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID inválido"))
+		return nil, false
+	}
+	registro := new(T)
+	if err := db.First(registro, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, cat.NoEncontrado, mayuscula(cat.Singular)+" no encontrad"+cat.terminacion()))
+		return nil, false
+	}
+	return registro, true
+}
+
+// validar aplica Validar si el catálogo lo tiene; si el registro no es válido, ya respondió
+func (cat *Catalogo[T, E]) validar(c *gin.Context, registro *T) bool {
+	if cat.Validar == nil {
+		return true
+	}
+	if e := cat.Validar(database.De(c), registro); e != nil {
+		errores.Responder(c, e)
+		return false
+	}
+	return true
+}
+
+// recargar trae las relaciones de Consulta.Precargar para la respuesta de un guardado; si falla se
+// responde el registro sin ellas, porque el guardado ya se hizo
+func (cat *Catalogo[T, E]) recargar(c *gin.Context, registro *T) {
+	if len(cat.Consulta.Precargar) == 0 {
+		return
+	}
+	id := reflect.ValueOf(registro).Elem().FieldByName("ID").Uint()
+	cat.conPrecargas(database.De(c)).First(registro, id)
+}
+
+func (cat *Catalogo[T, E]) conPrecargas(db *gorm.DB) *gorm.DB {
+	for _, relacion := range cat.Consulta.Precargar {
+		db = db.Preload(relacion)
+	}
+	return db
+}
+
+func (d Descripcion) terminacion() string {
+	if d.Femenino {
+		return "a"
+	}
+	return "o"
+}
+
+func (d Descripcion) articulo() string {
+	if d.Femenino {
+		return "la"
+	}
+	return "el"
+}
+
+// version lee la columna Version que todos los catálogos publican como ETag
+func version(registro any) uint {
+	return uint(reflect.ValueOf(registro).Elem().FieldByName("Version").Uint())
+}
+
+func mayuscula(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
@@ -0,0 +1,197 @@
+package gestioncatalogos
+
+import (
+	"strings"
+
+	"api-margaritai/cache"
+	"api-margaritai/errores"
+	"api-margaritai/models"
+)
+
+// GradoInput es el cuerpo para crear o editar un grado
+type GradoInput struct {
+	Titulo         string `json:"titulo" binding:"required"`
+	Descripcion    string `json:"descripcion"`
+	NivelEscolarID uint   `json:"nivel_escolar_id" binding:"required"`
+}
+
+// GrupoInput es el cuerpo para crear o editar un grupo; el ciclo escolar, si se envía, debe ser del mismo nivel
+type GrupoInput struct {
+	Titulo         string `json:"titulo" binding:"required"`
+	UserID         uint   `json:"user_id" binding:"required"`
+	NivelEscolarID uint   `json:"nivel_escolar_id" binding:"required"`
+	CicloEscolarID *uint  `json:"ciclo_escolar_id" binding:"omitempty,gt=0"`
+}
+
+// GradoAcademicoInput es el cuerpo para crear o editar un grado académico
+type GradoAcademicoInput struct {
+	Titulo string `json:"titulo" binding:"required"`
+}
+
+// EstatusLaboralInput es el cuerpo para crear o editar un estatus laboral
+type EstatusLaboralInput struct {
+	Titulo string `json:"titulo" binding:"required"`
+}
+
+// EstatusEmpleadoInput es el cuerpo para crear o editar un estatus de empleado
+type EstatusEmpleadoInput struct {
+	Titulo string `json:"titulo" binding:"required"`
+}
+
+// PuestoInput es el cuerpo para crear o editar un puesto
+type PuestoInput struct {
+	Titulo  string  `json:"titulo" binding:"required"`
+	PagoXHr float64 `json:"pago_x_hr" binding:"required"`
+}
+
+// GeneroInput es el cuerpo para crear o editar un género
+type GeneroInput struct {
+	Nombre string `json:"nombre" binding:"required"`
+}
+
+// TipoContratoInput es el cuerpo para crear o editar un tipo de contrato
+type TipoContratoInput struct {
+	Titulo string `json:"titulo" binding:"required"`
+}
+
+// AulaInput es el cuerpo para crear o editar un aula; si no se envía activa, el aula queda activa
+type AulaInput struct {
+	Nombre      string `json:"nombre" binding:"required"`
+	Descripcion string `json:"descripcion"`
+	PlantelID   uint   `json:"plantel_id" binding:"required"`
+	Tipo        string `json:"tipo" binding:"required,oneof=salon laboratorio cancha"`
+	Capacidad   int    `json:"capacidad" binding:"required,gt=0,lte=10000"`
+	Activa      *bool  `json:"activa"`
+}
+
+// MateriaInput es el cuerpo para crear o editar una materia; grado_id y orden también se pueden fijar
+// para varias materias a la vez con PUT /grados/:id/materias
+type MateriaInput struct {
+	Titulo         string  `json:"titulo" binding:"required"`
+	Descripcion    string  `json:"descripcion"`
+	ClaveSEP       string  `json:"clave_sep" binding:"max=20"`
+	Creditos       float64 `json:"creditos" binding:"gte=0,lte=100"`
+	HorasTeoricas  int     `json:"horas_teoricas" binding:"gte=0,lte=60"`
+	HorasPracticas int     `json:"horas_practicas" binding:"gte=0,lte=60"`
+	GradoID        *uint   `json:"grado_id" binding:"omitempty,gt=0"`
+	Orden          int     `json:"orden" binding:"gte=0"`
+}
+
+// Catalogos son los catálogos con el CRUD genérico, en el orden en que se registran sus rutas.
+// Planteles y niveles escolares tienen sus propios controladores.
+var Catalogos = []Administrable{
+	&Catalogo[models.Grado, GradoInput]{
+		Descripcion: Descripcion{
+			Ruta: "grados", Tag: "Grados", Singular: "grado", Plural: "grados",
+			NoEncontrado: errores.GradoNoEncontrado, Consulta: ConsultaGrados, Cache: cache.GrupoGrados,
+			Dependencias: []Dependencia{
+				{Modelo: &models.Materia{}, Columna: "grado_id", Codigo: errores.GradoConMaterias, Mensaje: "No se puede eliminar el grado porque existen materias relacionadas"},
+			},
+		},
+		Aplicar: func(g *models.Grado, e GradoInput) {
+			g.Titulo, g.Descripcion, g.NivelEscolarID = e.Titulo, e.Descripcion, e.NivelEscolarID
+		},
+	},
+	&Catalogo[models.Grupo, GrupoInput]{
+		Descripcion: Descripcion{
+			Ruta: "grupos", Tag: "Grupos", Singular: "grupo", Plural: "grupos",
+			NoEncontrado: errores.GrupoNoEncontrado, Consulta: ConsultaGrupos,
+			Dependencias: []Dependencia{
+				{Modelo: &models.Estudiante{}, Columna: "grupo_id", Codigo: errores.GrupoConEstudiantes, Mensaje: "No se puede eliminar el grupo porque tiene estudiantes"},
+				{Modelo: &models.Inscripcion{}, Columna: "grupo_id", Codigo: errores.GrupoConInscripciones, Mensaje: "No se puede eliminar el grupo porque tiene inscripciones"},
+				{Modelo: &models.Horario{}, Columna: "grupo_id", Codigo: errores.GrupoConHorarios, Mensaje: "No se puede eliminar el grupo porque tiene clases en su horario"},
+			},
+		},
+		Aplicar: func(g *models.Grupo, e GrupoInput) {
+			g.Titulo, g.UserID, g.NivelEscolarID, g.CicloEscolarID = e.Titulo, e.UserID, e.NivelEscolarID, e.CicloEscolarID
+		},
+		Validar: validarCicloDeGrupo,
+	},
+	&Catalogo[models.GradoAcademico, GradoAcademicoInput]{
+		Descripcion: Descripcion{
+			Ruta: "grados_academicos", Tag: "Grados académicos", Singular: "grado académico", Plural: "grados académicos",
+			NoEncontrado: errores.GradoAcademicoNoEncontrado, Consulta: ConsultaGradosAcademicos, Cache: cache.GrupoGradosAcademicos,
+			Dependencias: []Dependencia{
+				{Modelo: &models.Personal{}, Columna: "grado_academico_id", Codigo: errores.GradoAcademicoConPersonal, Mensaje: "No se puede eliminar el grado académico porque hay personal que lo tiene asignado"},
+			},
+		},
+		Aplicar: func(g *models.GradoAcademico, e GradoAcademicoInput) { g.Titulo = e.Titulo },
+	},
+	&Catalogo[models.EstatusLaboral, EstatusLaboralInput]{
+		Descripcion: Descripcion{
+			Ruta: "estatus_laborales", Tag: "Estatus laborales", Singular: "estatus laboral", Plural: "estatus laborales",
+			NoEncontrado: errores.EstatusLaboralNoEncontrado, Consulta: ConsultaEstatusLaborales, Cache: cache.GrupoEstatusLaborales,
+			Dependencias: []Dependencia{
+				{Modelo: &models.Personal{}, Columna: "estatus_laboral_id", Codigo: errores.EstatusLaboralConPersonal, Mensaje: "No se puede eliminar el estatus laboral porque hay personal que lo tiene asignado"},
+			},
+		},
+		Aplicar: func(s *models.EstatusLaboral, e EstatusLaboralInput) { s.Titulo = e.Titulo },
+	},
+	&Catalogo[models.EstatusEmpleado, EstatusEmpleadoInput]{
+		Descripcion: Descripcion{
+			Ruta: "estatus_empleados", Tag: "Estatus empleados", Singular: "estatus de empleado", Plural: "estatus de empleados",
+			NoEncontrado: errores.EstatusEmpleadoNoEncontrado, Consulta: ConsultaEstatusEmpleados, Cache: cache.GrupoEstatusEmpleados,
+			Dependencias: []Dependencia{
+				{Modelo: &models.Personal{}, Columna: "estatus_empleado_id", Codigo: errores.EstatusEmpleadoConPersonal, Mensaje: "No se puede eliminar el estatus de empleado porque hay personal que lo tiene asignado"},
+			},
+		},
+		Aplicar: func(s *models.EstatusEmpleado, e EstatusEmpleadoInput) { s.Titulo = e.Titulo },
+	},
+	&Catalogo[models.Puesto, PuestoInput]{
+		Descripcion: Descripcion{
+			Ruta: "puestos", Tag: "Puestos", Singular: "puesto", Plural: "puestos",
+			NoEncontrado: errores.PuestoNoEncontrado, Consulta: ConsultaPuestos, Cache: cache.GrupoPuestos,
+			Dependencias: []Dependencia{
+				{Modelo: &models.Personal{}, Columna: "puesto_id", Codigo: errores.PuestoConPersonal, Mensaje: "No se puede eliminar el puesto porque hay personal que lo tiene asignado"},
+			},
+		},
+		Aplicar: func(p *models.Puesto, e PuestoInput) { p.Titulo, p.PagoXHr = e.Titulo, e.PagoXHr },
+	},
+	&Catalogo[models.Genero, GeneroInput]{
+		Descripcion: Descripcion{
+			Ruta: "generos", Tag: "Géneros", Singular: "género", Plural: "géneros",
+			NoEncontrado: errores.GeneroNoEncontrado, Consulta: ConsultaGeneros, Cache: cache.GrupoGeneros,
+			Dependencias: []Dependencia{
+				{Modelo: &models.User{}, Columna: "genero_id", Codigo: errores.GeneroConUsuarios, Mensaje: "No se puede eliminar el género porque hay usuarios que lo tienen asignado"},
+			},
+		},
+		Aplicar: func(g *models.Genero, e GeneroInput) { g.Nombre = e.Nombre },
+	},
+	&Catalogo[models.TipoContrato, TipoContratoInput]{
+		Descripcion: Descripcion{
+			Ruta: "tipos_contratos", Tag: "Tipos de contrato", Singular: "tipo de contrato", Plural: "tipos de contrato",
+			NoEncontrado: errores.TipoContratoNoEncontrado, Consulta: ConsultaTiposContratos, Cache: cache.GrupoTiposContratos,
+			Dependencias: []Dependencia{
+				{Modelo: &models.Contrato{}, Columna: "tipo_contrato_id", Codigo: errores.TipoContratoConContratos, Mensaje: "No se puede eliminar el tipo de contrato porque hay contratos de ese tipo"},
+			},
+		},
+		Aplicar: func(t *models.TipoContrato, e TipoContratoInput) { t.Titulo = e.Titulo },
+	},
+	&Catalogo[models.Aula, AulaInput]{
+		Descripcion: Descripcion{
+			Ruta: "aulas", Tag: "Aulas", Singular: "aula", Plural: "aulas", Femenino: true,
+			NoEncontrado: errores.AulaNoEncontrada, Consulta: ConsultaAulas, Cache: cache.GrupoAulas,
+			Dependencias: []Dependencia{
+				{Modelo: &models.Horario{}, Columna: "aula_id", Codigo: errores.AulaConHorarios, Mensaje: "No se puede eliminar el aula porque tiene clases en su horario"},
+			},
+		},
+		Aplicar: func(a *models.Aula, e AulaInput) {
+			a.Nombre, a.Descripcion, a.PlantelID, a.Tipo, a.Capacidad = e.Nombre, e.Descripcion, e.PlantelID, e.Tipo, e.Capacidad
+			a.Activa = e.Activa == nil || *e.Activa
+		},
+	},
+	&Catalogo[models.Materia, MateriaInput]{
+		Descripcion: Descripcion{
+			Ruta: "materias", Tag: "Materias", Singular: "materia", Plural: "materias", Femenino: true,
+			NoEncontrado: errores.MateriaNoEncontrada, Consulta: ConsultaMaterias,
+			Dependencias: []Dependencia{
+				{Modelo: &models.Horario{}, Columna: "materia_id", Codigo: errores.MateriaConHorarios, Mensaje: "No se puede eliminar la materia porque está en el horario de algún grupo"},
+			},
+		},
+		Aplicar: func(m *models.Materia, e MateriaInput) {
+			m.Titulo, m.Descripcion, m.ClaveSEP = e.Titulo, e.Descripcion, strings.ToUpper(strings.TrimSpace(e.ClaveSEP))
+			m.Creditos, m.HorasTeoricas, m.HorasPracticas = e.Creditos, e.HorasTeoricas, e.HorasPracticas
+			m.GradoID, m.Orden = e.GradoID, e.Orden
+		},
+	},
+}
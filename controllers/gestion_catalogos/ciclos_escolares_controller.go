@@ -0,0 +1,375 @@
+package gestioncatalogos
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/concurrencia"
+	"api-margaritai/consulta"
+	gestionusuarios "api-margaritai/controllers/gestion_usuarios"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/models"
+)
+
+// PeriodoEvaluacionInput es un bimestre o trimestre enviado explícitamente al crear o editar un ciclo
+type PeriodoEvaluacionInput struct {
+	Nombre      string `json:"nombre" binding:"required,max=60"`
+	FechaInicio string `json:"fecha_inicio" binding:"required,datetime=2006-01-02"`
+	FechaFin    string `json:"fecha_fin" binding:"required,datetime=2006-01-02"`
+}
+
+// CicloEscolarInput es el cuerpo para crear o editar un ciclo escolar. Si no se envían periodos, el ciclo
+// se reparte en bimestres o trimestres de la misma duración según la periodicidad.
+type CicloEscolarInput struct {
+	Nombre         string                   `json:"nombre" binding:"required,max=60"`
+	NivelEscolarID uint                     `json:"nivel_escolar_id" binding:"required"`
+	FechaInicio    string                   `json:"fecha_inicio" binding:"required,datetime=2006-01-02"`
+	FechaFin       string                   `json:"fecha_fin" binding:"required,datetime=2006-01-02"`
+	Periodicidad   string                   `json:"periodicidad" binding:"required,oneof=bimestral trimestral"`
+	Periodos       []PeriodoEvaluacionInput `json:"periodos" binding:"omitempty,max=12,dive"`
+}
+
+// aplicar copia la entrada al ciclo y arma sus periodos; las fechas ya tienen el formato validado por binding
+func (e CicloEscolarInput) aplicar(ciclo *models.CicloEscolar) ([]models.PeriodoEvaluacion, *errores.Error) {
+	inicio, _ := time.Parse("2006-01-02", e.FechaInicio)
+	fin, _ := time.Parse("2006-01-02", e.FechaFin)
+	if !fin.After(inicio) {
+		return nil, errores.CampoInvalido("fecha_fin", "gtfield", "La fecha de fin debe ser posterior a la de inicio")
+	}
+	ciclo.Nombre, ciclo.NivelEscolarID, ciclo.Periodicidad = e.Nombre, e.NivelEscolarID, e.Periodicidad
+	ciclo.FechaInicio, ciclo.FechaFin = inicio, fin
+
+	if len(e.Periodos) == 0 {
+		return repartirPeriodos(inicio, fin, e.Periodicidad), nil
+	}
+	periodos := make([]models.PeriodoEvaluacion, len(e.Periodos))
+	anterior := inicio.AddDate(0, 0, -1)
+	for i, p := range e.Periodos {
+		campo := fmt.Sprintf("periodos[%d].", i)
+		desde, _ := time.Parse("2006-01-02", p.FechaInicio)
+		hasta, _ := time.Parse("2006-01-02", p.FechaFin)
+		if !desde.After(anterior) {
+			return nil, errores.CampoInvalido(campo+"fecha_inicio", "gtfield", "Los periodos deben ir en orden, sin traslaparse y dentro del ciclo")
+		}
+		if hasta.Before(desde) || hasta.After(fin) {
+			return nil, errores.CampoInvalido(campo+"fecha_fin", "gtefield", "El periodo debe terminar después de empezar y dentro del ciclo")
+		}
+		periodos[i] = models.PeriodoEvaluacion{Numero: i + 1, Nombre: p.Nombre, FechaInicio: desde, FechaFin: hasta}
+		anterior = hasta
+	}
+	return periodos, nil
+}
+
+// repartirPeriodos divide el ciclo en tantos bimestres o trimestres completos como quepan (al menos uno),
+// todos de la misma cantidad de días salvo por redondeo. Un ciclo de fines de agosto a principios de julio
+// queda en 5 bimestres o 3 trimestres.
+func repartirPeriodos(inicio, fin time.Time, periodicidad string) []models.PeriodoEvaluacion {
+	meses, nombre := 2, "Bimestre"
+	if periodicidad == models.PeriodicidadTrimestral {
+		meses, nombre = 3, "Trimestre"
+	}
+	dias := int(fin.Sub(inicio).Hours()/24) + 1
+	n := max(1, int(float64(dias)/(30.44*float64(meses))))
+
+	periodos := make([]models.PeriodoEvaluacion, n)
+	for i := range periodos {
+		periodos[i] = models.PeriodoEvaluacion{
+			Numero:      i + 1,
+			Nombre:      fmt.Sprintf("%s %d", nombre, i+1),
+			FechaInicio: inicio.AddDate(0, 0, i*dias/n),
+			FechaFin:    inicio.AddDate(0, 0, (i+1)*dias/n-1),
+		}
+	}
+	return periodos
+}
+
+// guardarPeriodos reemplaza los periodos del ciclo
+func guardarPeriodos(tx *gorm.DB, cicloID uint, periodos []models.PeriodoEvaluacion) error {
+	if err := tx.Where("ciclo_escolar_id = ?", cicloID).Delete(&models.PeriodoEvaluacion{}).Error; err != nil {
+		return err
+	}
+	for i := range periodos {
+		periodos[i].CicloEscolarID = cicloID
+	}
+	return tx.Create(&periodos).Error
+}
+
+// buscarCiclo lee :id y carga el ciclo; si no puede, ya respondió
+func buscarCiclo(c *gin.Context, db *gorm.DB) (models.CicloEscolar, bool) {
+	var ciclo models.CicloEscolar
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID inválido"))
+		return ciclo, false
+	}
+	if err := db.First(&ciclo, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.CicloEscolarNoEncontrado, "Ciclo escolar no encontrado"))
+		return ciclo, false
+	}
+	return ciclo, true
+}
+
+// conPeriodos precarga el nivel y los periodos en orden
+func conPeriodos(db *gorm.DB) *gorm.DB {
+	return db.Preload("NivelEscolar").Preload("Periodos", func(db *gorm.DB) *gorm.DB { return db.Order("numero") })
+}
+
+// ObtenerCiclosEscolares lista los ciclos escolares (filtrables por nivel y estatus, ver ConsultaCiclosEscolares)
+func ObtenerCiclosEscolares(c *gin.Context) {
+	ciclos, paginacion, errConsulta := consulta.Listar[models.CicloEscolar](c, database.LecturaDe(c), ConsultaCiclosEscolares)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Ciclos escolares obtenidos correctamente",
+		"data":       ciclos,
+		"paginacion": paginacion,
+	})
+}
+
+// ObtenerCicloEscolar responde un ciclo con sus periodos y su ETag
+func ObtenerCicloEscolar(c *gin.Context) {
+	ciclo, ok := buscarCiclo(c, conPeriodos(database.LecturaDe(c)))
+	if !ok {
+		return
+	}
+	concurrencia.EscribirETag(c, ciclo.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Ciclo escolar obtenido correctamente",
+		"data":    ciclo,
+	})
+}
+
+// CrearCicloEscolar crea un ciclo en planeación con sus periodos de evaluación
+func CrearCicloEscolar(c *gin.Context) {
+	var input CicloEscolarInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+	ciclo := models.CicloEscolar{Estatus: models.CicloPlaneacion}
+	periodos, errEntrada := input.aplicar(&ciclo)
+	if errEntrada != nil {
+		errores.Responder(c, errEntrada)
+		return
+	}
+
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Periodos").Create(&ciclo).Error; err != nil {
+			return err
+		}
+		return guardarPeriodos(tx, ciclo.ID, periodos)
+	})
+	if err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error al guardar el ciclo escolar"))
+		return
+	}
+	conPeriodos(database.De(c)).First(&ciclo, ciclo.ID)
+
+	concurrencia.EscribirETag(c, ciclo.Version)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Ciclo escolar creado correctamente",
+		"data":    ciclo,
+	})
+}
+
+// EditarCicloEscolar reemplaza los datos y los periodos del ciclo; exige If-Match. Un ciclo cerrado ya no se
+// edita y el nivel solo se cambia mientras el ciclo está en planeación y no tiene grupos.
+func EditarCicloEscolar(c *gin.Context) {
+	ciclo, ok := buscarCiclo(c, database.De(c))
+	if !ok {
+		return
+	}
+	if !concurrencia.Verificar(c, ciclo.Version, ciclo) {
+		return
+	}
+	var input CicloEscolarInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+	if ciclo.Estatus == models.CicloCerrado {
+		errores.Responder(c, errores.Conflicto(errores.CicloCerrado, "El ciclo escolar está cerrado y ya no se puede editar"))
+		return
+	}
+	if input.NivelEscolarID != ciclo.NivelEscolarID {
+		if ciclo.Estatus != models.CicloPlaneacion {
+			errores.Responder(c, errores.Conflicto(errores.EstatusCicloInvalido, "Solo se puede cambiar el nivel de un ciclo en planeación"))
+			return
+		}
+		var grupos int64
+		if err := database.De(c).Model(&models.Grupo{}).Where("ciclo_escolar_id = ?", ciclo.ID).Count(&grupos).Error; err != nil {
+			errores.Responder(c, errores.Interno("No se pudieron validar los grupos del ciclo", err))
+			return
+		}
+		if grupos > 0 {
+			errores.Responder(c, errores.Conflicto(errores.CicloConGrupos, "No se puede cambiar el nivel porque el ciclo ya tiene grupos"))
+			return
+		}
+	}
+	periodos, errEntrada := input.aplicar(&ciclo)
+	if errEntrada != nil {
+		errores.Responder(c, errEntrada)
+		return
+	}
+
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := concurrencia.Guardar(tx, &ciclo); err != nil {
+			return err
+		}
+		return guardarPeriodos(tx, ciclo.ID, periodos)
+	})
+	if err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &ciclo, "Error al actualizar el ciclo escolar", "NivelEscolar", "Periodos"))
+		return
+	}
+	conPeriodos(database.De(c)).First(&ciclo, ciclo.ID)
+
+	concurrencia.EscribirETag(c, ciclo.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Ciclo escolar actualizado correctamente",
+		"data":    ciclo,
+	})
+}
+
+// EliminarCicloEscolar envía a la papelera un ciclo en planeación que todavía no tiene grupos
+func EliminarCicloEscolar(c *gin.Context) {
+	ciclo, ok := buscarCiclo(c, database.De(c))
+	if !ok {
+		return
+	}
+	if ciclo.Estatus != models.CicloPlaneacion {
+		errores.Responder(c, errores.Conflicto(errores.EstatusCicloInvalido, "Solo se puede eliminar un ciclo en planeación"))
+		return
+	}
+	var grupos int64
+	if err := database.De(c).Model(&models.Grupo{}).Where("ciclo_escolar_id = ?", ciclo.ID).Count(&grupos).Error; err != nil {
+		errores.Responder(c, errores.Interno("No se pudieron validar los grupos del ciclo", err))
+		return
+	}
+	if grupos > 0 {
+		errores.Responder(c, errores.Conflicto(errores.CicloConGrupos, "No se puede eliminar el ciclo porque tiene grupos"))
+		return
+	}
+
+	if err := database.De(c).Delete(&ciclo).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error al eliminar el ciclo escolar"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Ciclo escolar eliminado correctamente"})
+}
+
+// ActivarCicloEscolar pasa un ciclo de planeación a activo si su nivel no tiene otro ciclo activo. Los
+// estudiantes inscritos en el ciclo pasan a su grupo nuevo; los ciclos anteriores conservan sus inscripciones.
+func ActivarCicloEscolar(c *gin.Context) {
+	ciclo, ok := buscarCiclo(c, database.De(c))
+	if !ok {
+		return
+	}
+	if ciclo.Estatus != models.CicloPlaneacion {
+		errores.Responder(c, errores.Conflicto(errores.EstatusCicloInvalido, "Solo se puede activar un ciclo en planeación; este está "+ciclo.Estatus))
+		return
+	}
+	var activo models.CicloEscolar
+	err := database.De(c).Where("nivel_escolar_id = ? AND estatus = ?", ciclo.NivelEscolarID, models.CicloActivo).Limit(1).Find(&activo).Error
+	if err != nil {
+		errores.Responder(c, errores.Interno("No se pudo validar el ciclo activo del nivel", err))
+		return
+	}
+	if activo.ID != 0 {
+		errores.Responder(c, errores.Conflicto(errores.CicloActivoExistente,
+			fmt.Sprintf("El nivel escolar ya tiene activo el ciclo %q (ID %d); ciérrelo antes de activar otro", activo.Nombre, activo.ID)))
+		return
+	}
+
+	var movidos int
+	err = database.De(c).Transaction(func(tx *gorm.DB) error {
+		ahora := time.Now()
+		if err := concurrencia.Actualizar(tx, &ciclo, map[string]any{"estatus": models.CicloActivo, "activado_en": ahora}); err != nil {
+			return err
+		}
+		var err error
+		movidos, err = gestionusuarios.AplicarInscripciones(tx, ciclo)
+		return err
+	})
+	if err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &ciclo, "Error al activar el ciclo escolar", "NivelEscolar", "Periodos"))
+		return
+	}
+	conPeriodos(database.De(c)).First(&ciclo, ciclo.ID)
+
+	concurrencia.EscribirETag(c, ciclo.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Ciclo escolar activado correctamente",
+		"data":                ciclo,
+		"estudiantes_movidos": movidos,
+	})
+}
+
+// CerrarCicloEscolar pasa un ciclo activo a cerrado; desde entonces sus grupos e inscripciones son historial
+func CerrarCicloEscolar(c *gin.Context) {
+	ciclo, ok := buscarCiclo(c, database.De(c))
+	if !ok {
+		return
+	}
+	if ciclo.Estatus != models.CicloActivo {
+		errores.Responder(c, errores.Conflicto(errores.EstatusCicloInvalido, "Solo se puede cerrar un ciclo activo; este está "+ciclo.Estatus))
+		return
+	}
+
+	err := concurrencia.Actualizar(database.De(c), &ciclo, map[string]any{"estatus": models.CicloCerrado, "cerrado_en": time.Now()})
+	if err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &ciclo, "Error al cerrar el ciclo escolar", "NivelEscolar", "Periodos"))
+		return
+	}
+	conPeriodos(database.De(c)).First(&ciclo, ciclo.ID)
+
+	concurrencia.EscribirETag(c, ciclo.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Ciclo escolar cerrado correctamente",
+		"data":    ciclo,
+	})
+}
+
+// validarCicloDeGrupo es el Validar del catálogo de grupos: el ciclo debe ser del nivel del grupo y no estar
+// cerrado, y un grupo con clases en su horario no se cambia de ciclo
+func validarCicloDeGrupo(db *gorm.DB, g *models.Grupo) *errores.Error {
+	if g.ID != 0 {
+		otroCiclo := db.Model(&models.Horario{}).Where("grupo_id = ?", g.ID)
+		if g.CicloEscolarID != nil {
+			otroCiclo = otroCiclo.Where("ciclo_escolar_id <> ?", *g.CicloEscolarID)
+		}
+		var clases int64
+		if err := otroCiclo.Count(&clases).Error; err != nil {
+			return errores.Interno("No se pudo validar el horario del grupo", err)
+		}
+		if clases > 0 {
+			return errores.Conflicto(errores.GrupoConHorarios, "No se puede cambiar el ciclo escolar del grupo porque ya tiene clases en su horario")
+		}
+	}
+	if g.CicloEscolarID == nil {
+		return nil
+	}
+	var ciclo models.CicloEscolar
+	if err := db.First(&ciclo, *g.CicloEscolarID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errores.SolicitudInvalida(errores.CicloEscolarNoEncontrado, "Ciclo escolar no encontrado")
+		}
+		return errores.Interno("No se pudo validar el ciclo escolar", err)
+	}
+	if ciclo.NivelEscolarID != g.NivelEscolarID {
+		return errores.CampoInvalido("ciclo_escolar_id", "nivel", "El ciclo escolar es de otro nivel escolar")
+	}
+	if ciclo.Estatus == models.CicloCerrado {
+		return errores.Conflicto(errores.CicloCerrado, "El ciclo escolar está cerrado; sus grupos ya no se modifican")
+	}
+	return nil
+}
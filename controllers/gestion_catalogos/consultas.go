@@ -0,0 +1,130 @@
+package gestioncatalogos
+
+import "api-margaritai/consulta"
+
+// porTitulo es la definición común de los catálogos que solo tienen título; extra agrega filtros propios
+func porTitulo(extra map[string]consulta.Filtro, orden ...string) consulta.Definicion {
+	def := consulta.Definicion{
+		Orden: map[string]string{
+			"id":         "id",
+			"titulo":     "titulo",
+			"created_at": "created_at",
+		},
+		OrdenPorDefecto: "id",
+		Filtros: map[string]consulta.Filtro{
+			"titulo":     {Columna: "titulo", Tipo: consulta.Texto},
+			"created_at": {Columna: "created_at", Tipo: consulta.Fecha},
+		},
+		Llave: "id",
+	}
+	for nombre, filtro := range extra {
+		def.Filtros[nombre] = filtro
+	}
+	for _, columna := range orden {
+		def.Orden[columna] = columna
+	}
+	return def
+}
+
+// Definiciones de orden y filtros de cada listado de catálogos
+var (
+	ConsultaEstatusEmpleados = porTitulo(nil)
+	ConsultaEstatusLaborales = porTitulo(nil)
+	ConsultaGradosAcademicos = porTitulo(nil)
+	ConsultaPuestos          = porTitulo(nil, "pago_x_hr")
+	ConsultaTiposContratos   = porTitulo(nil)
+	ConsultaGrados           = porTitulo(map[string]consulta.Filtro{"nivel_escolar_id": {Columna: "nivel_escolar_id", Tipo: consulta.Entero}}, "nivel_escolar_id")
+	ConsultaNivelesEscolares = porTitulo(map[string]consulta.Filtro{"plantel_id": {Columna: "plantel_id", Tipo: consulta.Entero}}, "plantel_id", "mensualidad").ConPrecarga("Plantel")
+	ConsultaGrupos           = porTitulo(map[string]consulta.Filtro{
+		"nivel_escolar_id": {Columna: "nivel_escolar_id", Tipo: consulta.Entero},
+		"user_id":          {Columna: "user_id", Tipo: consulta.Entero},
+		"ciclo_escolar_id": {Columna: "ciclo_escolar_id", Tipo: consulta.Entero},
+	}, "nivel_escolar_id", "ciclo_escolar_id").ConPrecarga("User", "NivelEscolar", "CicloEscolar")
+	ConsultaMaterias = porTitulo(map[string]consulta.Filtro{
+		"grado_id":  {Columna: "grado_id", Tipo: consulta.Entero},
+		"clave_sep": {Columna: "clave_sep", Tipo: consulta.Texto},
+	}, "grado_id", "orden", "clave_sep", "creditos").ConPrecarga("Grado")
+	ConsultaCiclosEscolares = consulta.Definicion{
+		Orden: map[string]string{
+			"id":           "id",
+			"nombre":       "nombre",
+			"fecha_inicio": "fecha_inicio",
+			"created_at":   "created_at",
+		},
+		OrdenPorDefecto: "-fecha_inicio",
+		Filtros: map[string]consulta.Filtro{
+			"nombre":           {Columna: "nombre", Tipo: consulta.Texto},
+			"nivel_escolar_id": {Columna: "nivel_escolar_id", Tipo: consulta.Entero},
+			"estatus":          {Columna: "estatus", Tipo: consulta.Texto},
+			"fecha_inicio":     {Columna: "fecha_inicio", Tipo: consulta.Fecha},
+		},
+		Llave:     "id",
+		Precargar: []string{"NivelEscolar"},
+	}
+	ConsultaHorarios = consulta.Definicion{
+		Orden: map[string]string{
+			"id":         "id",
+			"dia":        "dia",
+			"desde":      "desde",
+			"created_at": "created_at",
+		},
+		OrdenPorDefecto: "dia",
+		Filtros: map[string]consulta.Filtro{
+			"ciclo_escolar_id": {Columna: "ciclo_escolar_id", Tipo: consulta.Entero},
+			"grupo_id":         {Columna: "grupo_id", Tipo: consulta.Entero},
+			"materia_id":       {Columna: "materia_id", Tipo: consulta.Entero},
+			"personal_id":      {Columna: "personal_id", Tipo: consulta.Entero},
+			"aula_id":          {Columna: "aula_id", Tipo: consulta.Entero},
+			"dia":              {Columna: "dia", Tipo: consulta.Entero},
+		},
+		Llave:     "id",
+		Precargar: precargasHorario,
+	}
+	ConsultaGeneros = consulta.Definicion{
+		Orden: map[string]string{
+			"id":     "id",
+			"nombre": "nombre",
+		},
+		OrdenPorDefecto: "id",
+		Filtros: map[string]consulta.Filtro{
+			"nombre": {Columna: "nombre", Tipo: consulta.Texto},
+		},
+		Llave: "id",
+	}
+	ConsultaAulas = consulta.Definicion{
+		Orden: map[string]string{
+			"id":         "id",
+			"nombre":     "nombre",
+			"plantel_id": "plantel_id",
+			"capacidad":  "capacidad",
+			"created_at": "created_at",
+		},
+		OrdenPorDefecto: "nombre",
+		Filtros: map[string]consulta.Filtro{
+			"nombre":      {Columna: "nombre", Tipo: consulta.Texto},
+			"descripcion": {Columna: "descripcion", Tipo: consulta.Texto},
+			"plantel_id":  {Columna: "plantel_id", Tipo: consulta.Entero},
+			"tipo":        {Columna: "tipo", Tipo: consulta.Texto},
+			"activa":      {Columna: "activa", Tipo: consulta.Booleano},
+			"created_at":  {Columna: "created_at", Tipo: consulta.Fecha},
+		},
+		Llave:     "id",
+		Precargar: []string{"Plantel"},
+	}
+	ConsultaPlanteles = consulta.Definicion{
+		Orden: map[string]string{
+			"id":         "id",
+			"nombre":     "nombre",
+			"created_at": "created_at",
+		},
+		OrdenPorDefecto: "id",
+		Filtros: map[string]consulta.Filtro{
+			"nombre":     {Columna: "nombre", Tipo: consulta.Texto},
+			"ubicacion":  {Columna: "ubicacion", Tipo: consulta.Texto},
+			"user_id":    {Columna: "user_id", Tipo: consulta.Entero},
+			"created_at": {Columna: "created_at", Tipo: consulta.Fecha},
+		},
+		Llave:     "id",
+		Precargar: []string{"User"},
+	}
+)
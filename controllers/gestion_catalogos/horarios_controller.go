@@ -0,0 +1,542 @@
+package gestioncatalogos
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"api-margaritai/concurrencia"
+	"api-margaritai/config"
+	"api-margaritai/consulta"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/exportacion"
+	"api-margaritai/horario"
+	"api-margaritai/middleware"
+	"api-margaritai/models"
+)
+
+// RutaCalendario es la ruta pública del calendario de horarios de un usuario; se abre con el enlace firmado
+// que entrega GET /protected/horarios/calendario
+const RutaCalendario = "/api/horarios/calendario.ics"
+
+// HorarioInput es el cuerpo para crear o editar una clase del horario; el ciclo escolar es el del grupo
+type HorarioInput struct {
+	GrupoID    uint   `json:"grupo_id" binding:"required"`
+	MateriaID  uint   `json:"materia_id" binding:"required"`
+	PersonalID uint   `json:"personal_id" binding:"required"`
+	AulaID     uint   `json:"aula_id" binding:"required"`
+	Dia        int    `json:"dia" binding:"required,min=1,max=7"` // 1 = lunes … 7 = domingo
+	Desde      string `json:"desde" binding:"required"`           // HH:MM
+	Hasta      string `json:"hasta" binding:"required"`           // HH:MM, sin incluir
+}
+
+// precargasHorario son las relaciones con que se responde una clase
+var precargasHorario = []string{"Grupo", "Materia", "Personal.User", "Aula"}
+
+// aplicar valida la entrada contra el grupo, la materia, el profesor y el aula, y la copia a la clase.
+// Regresa el ciclo del grupo, cuyas fechas definen contra qué clases se buscan choques.
+func (e HorarioInput) aplicar(db *gorm.DB, h *models.Horario) (models.CicloEscolar, *errores.Error) {
+	var ciclo models.CicloEscolar
+	franja := horario.Franja{Dia: e.Dia, Desde: e.Desde, Hasta: e.Hasta}
+	if err := franja.Validar(""); err != nil {
+		return ciclo, err
+	}
+
+	var grupo models.Grupo
+	if err := buscarReferencia(db.Preload("NivelEscolar"), &grupo, e.GrupoID, errores.GrupoNoEncontrado, "Grupo no encontrado"); err != nil {
+		return ciclo, err
+	}
+	if grupo.CicloEscolarID == nil {
+		return ciclo, errores.CampoInvalido("grupo_id", "ciclo", "El grupo no tiene ciclo escolar; asígnele uno antes de armar su horario")
+	}
+	if err := buscarReferencia(db, &ciclo, *grupo.CicloEscolarID, errores.CicloEscolarNoEncontrado, "Ciclo escolar no encontrado"); err != nil {
+		return ciclo, err
+	}
+	if ciclo.Estatus == models.CicloCerrado {
+		return ciclo, errores.Conflicto(errores.CicloCerrado, "El ciclo escolar del grupo está cerrado; su horario ya no se modifica")
+	}
+	if err := buscarReferencia(db, &models.Materia{}, e.MateriaID, errores.MateriaNoEncontrada, "Materia no encontrada"); err != nil {
+		return ciclo, err
+	}
+	var personal models.Personal
+	if err := buscarReferencia(db, &personal, e.PersonalID, errores.PersonalNoEncontrado, "Personal no encontrado"); err != nil {
+		return ciclo, err
+	}
+	if !personal.EsProfesor {
+		return ciclo, errores.CampoInvalido("personal_id", "profesor", "El personal indicado no es profesor")
+	}
+	var aula models.Aula
+	if err := buscarReferencia(db, &aula, e.AulaID, errores.AulaNoEncontrada, "Aula no encontrada"); err != nil {
+		return ciclo, err
+	}
+	if !aula.Activa {
+		return ciclo, errores.CampoInvalido("aula_id", "activa", "El aula está inactiva")
+	}
+	if aula.PlantelID != grupo.NivelEscolar.PlantelID {
+		return ciclo, errores.CampoInvalido("aula_id", "plantel", "El aula está en otro plantel que el grupo")
+	}
+
+	h.CicloEscolarID, h.GrupoID, h.MateriaID, h.PersonalID, h.AulaID = ciclo.ID, grupo.ID, e.MateriaID, personal.ID, aula.ID
+	h.Dia, h.Desde, h.Hasta = franja.Dia, franja.Desde, franja.Hasta
+	return ciclo, nil
+}
+
+// buscarReferencia carga el registro que la entrada indica por ID; si no existe es un 400 con el código dado
+func buscarReferencia(db *gorm.DB, destino any, id uint, codigo, mensaje string) *errores.Error {
+	if err := db.First(destino, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errores.SolicitudInvalida(codigo, mensaje)
+		}
+		return errores.Interno("No se pudieron validar los datos de la clase", err)
+	}
+	return nil
+}
+
+// clasesEnFranja son las clases de ciclos que no están cerrados que comparten algún minuto con la franja
+func clasesEnFranja(db *gorm.DB, f horario.Franja) *gorm.DB {
+	return db.Model(&models.Horario{}).
+		Joins("JOIN ciclos_escolares ON ciclos_escolares.id = horarios.ciclo_escolar_id AND ciclos_escolares.deleted_at IS NULL").
+		Where("ciclos_escolares.estatus <> ?", models.CicloCerrado).
+		Where("horarios.dia = ? AND horarios.desde < ? AND horarios.hasta > ?", f.Dia, f.Hasta, f.Desde)
+}
+
+// buscarChoques revisa que ni el grupo, ni el profesor, ni el aula tengan otra clase a la misma hora en un
+// ciclo cuyas fechas se cruzan con las del ciclo de la clase (un profesor puede dar clases en varios niveles).
+// Se llama dentro de la transacción del guardado: bloquea el grupo, el profesor y el aula para que dos
+// guardados simultáneos no pasen la revisión a la vez.
+func buscarChoques(tx *gorm.DB, h *models.Horario, ciclo models.CicloEscolar) *errores.Error {
+	bloqueos := []struct {
+		modelo any
+		id     uint
+	}{{&models.Grupo{}, h.GrupoID}, {&models.Personal{}, h.PersonalID}, {&models.Aula{}, h.AulaID}}
+	for _, b := range bloqueos {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(b.modelo, b.id).Error; err != nil {
+			return errores.Interno("No se pudo revisar el horario", err)
+		}
+	}
+
+	q := clasesEnFranja(tx, h.Franja()).
+		Where("ciclos_escolares.fecha_inicio <= ? AND ciclos_escolares.fecha_fin >= ?", ciclo.FechaFin, ciclo.FechaInicio).
+		Where("horarios.grupo_id = ? OR horarios.personal_id = ? OR horarios.aula_id = ?", h.GrupoID, h.PersonalID, h.AulaID)
+	if h.ID != 0 {
+		q = q.Where("horarios.id <> ?", h.ID)
+	}
+	for _, relacion := range precargasHorario {
+		q = q.Preload(relacion)
+	}
+	var choques []models.Horario
+	if err := q.Order("horarios.dia, horarios.desde, horarios.id").Find(&choques).Error; err != nil {
+		return errores.Interno("No se pudo revisar el horario", err)
+	}
+	if len(choques) == 0 {
+		return nil
+	}
+
+	e := errores.Conflicto(errores.HorarioEnConflicto, "La clase choca con otras del horario")
+	for _, otra := range choques {
+		clase := fmt.Sprintf("%s con %s el %s (clase %d)", otra.Materia.Titulo, otra.Grupo.Titulo, otra.Franja(), otra.ID)
+		if otra.GrupoID == h.GrupoID {
+			e.Campos = append(e.Campos, errores.CampoError{Campo: "grupo_id", Regla: "conflicto", Mensaje: "El grupo ya tiene " + clase})
+		}
+		if otra.PersonalID == h.PersonalID {
+			e.Campos = append(e.Campos, errores.CampoError{Campo: "personal_id", Regla: "conflicto", Mensaje: "El profesor ya da " + clase})
+		}
+		if otra.AulaID == h.AulaID {
+			e.Campos = append(e.Campos, errores.CampoError{Campo: "aula_id", Regla: "conflicto", Mensaje: "El aula ya está ocupada por " + clase})
+		}
+	}
+	return e
+}
+
+// guardarHorario revisa los choques y guarda la clase en una sola transacción
+func guardarHorario(c *gin.Context, h *models.Horario, ciclo models.CicloEscolar, guardar func(tx *gorm.DB) error) (*errores.Error, error) {
+	var choque *errores.Error
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if choque = buscarChoques(tx, h, ciclo); choque != nil {
+			return choque
+		}
+		return guardar(tx)
+	})
+	return choque, err
+}
+
+// buscarHorario lee :id y carga la clase; si no puede, ya respondió
+func buscarHorario(c *gin.Context, db *gorm.DB) (models.Horario, bool) {
+	var h models.Horario
+	if err := db.First(&h, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.HorarioNoEncontrado, "Clase no encontrada"))
+		return h, false
+	}
+	return h, true
+}
+
+// conClases precarga las relaciones con que se responde una clase
+func conClases(db *gorm.DB) *gorm.DB {
+	for _, relacion := range precargasHorario {
+		db = db.Preload(relacion)
+	}
+	return db
+}
+
+// ObtenerHorarios lista las clases (filtrables por ciclo, grupo, materia, profesor, aula y día, ver ConsultaHorarios)
+func ObtenerHorarios(c *gin.Context) {
+	horarios, paginacion, errConsulta := consulta.Listar[models.Horario](c, database.LecturaDe(c), ConsultaHorarios)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Horarios obtenidos correctamente",
+		"data":       horarios,
+		"paginacion": paginacion,
+	})
+}
+
+// ObtenerHorario responde una clase con su ETag
+func ObtenerHorario(c *gin.Context) {
+	h, ok := buscarHorario(c, conClases(database.LecturaDe(c)))
+	if !ok {
+		return
+	}
+	concurrencia.EscribirETag(c, h.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Clase obtenida correctamente",
+		"data":    h,
+	})
+}
+
+// CrearHorario agrega una clase al horario si no choca con otra del grupo, del profesor o del aula
+func CrearHorario(c *gin.Context) {
+	var input HorarioInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+	var h models.Horario
+	ciclo, errEntrada := input.aplicar(database.De(c), &h)
+	if errEntrada != nil {
+		errores.Responder(c, errEntrada)
+		return
+	}
+
+	choque, err := guardarHorario(c, &h, ciclo, func(tx *gorm.DB) error { return tx.Create(&h).Error })
+	if choque != nil {
+		errores.Responder(c, choque)
+		return
+	}
+	if err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error al crear la clase"))
+		return
+	}
+	conClases(database.De(c)).First(&h, h.ID)
+
+	concurrencia.EscribirETag(c, h.Version)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Clase creada correctamente",
+		"data":    h,
+	})
+}
+
+// EditarHorario reemplaza los datos de una clase; exige If-Match. Las clases de un ciclo cerrado ya no se editan.
+func EditarHorario(c *gin.Context) {
+	h, ok := buscarHorario(c, database.De(c).Preload("CicloEscolar"))
+	if !ok {
+		return
+	}
+	if !concurrencia.Verificar(c, h.Version, h) {
+		return
+	}
+	var input HorarioInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+	if h.CicloEscolar.Estatus == models.CicloCerrado {
+		errores.Responder(c, errores.Conflicto(errores.CicloCerrado, "El ciclo escolar de la clase está cerrado; su horario ya no se modifica"))
+		return
+	}
+	ciclo, errEntrada := input.aplicar(database.De(c), &h)
+	if errEntrada != nil {
+		errores.Responder(c, errEntrada)
+		return
+	}
+
+	choque, err := guardarHorario(c, &h, ciclo, func(tx *gorm.DB) error { return concurrencia.Guardar(tx, &h) })
+	if choque != nil {
+		errores.Responder(c, choque)
+		return
+	}
+	if err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &h, "Error al actualizar la clase", precargasHorario...))
+		return
+	}
+	conClases(database.De(c)).First(&h, h.ID)
+
+	concurrencia.EscribirETag(c, h.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Clase actualizada correctamente",
+		"data":    h,
+	})
+}
+
+// EliminarHorario quita una clase de un ciclo que no está cerrado; no pasa por la papelera
+func EliminarHorario(c *gin.Context) {
+	h, ok := buscarHorario(c, database.De(c).Preload("CicloEscolar"))
+	if !ok {
+		return
+	}
+	if h.CicloEscolar.Estatus == models.CicloCerrado {
+		errores.Responder(c, errores.Conflicto(errores.CicloCerrado, "El ciclo escolar de la clase está cerrado; su horario ya no se modifica"))
+		return
+	}
+	if err := database.De(c).Delete(&h).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error al eliminar la clase"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Clase eliminada correctamente"})
+}
+
+// Semana de clases de un grupo, un profesor o un aula
+var (
+	ObtenerHorarioDeGrupo    = horarioDe(&models.Grupo{}, "grupo_id", errores.GrupoNoEncontrado, "Grupo no encontrado")
+	ObtenerHorarioDeProfesor = horarioDe(&models.Personal{}, "personal_id", errores.PersonalNoEncontrado, "Personal no encontrado")
+	ObtenerHorarioDeAula     = horarioDe(&models.Aula{}, "aula_id", errores.AulaNoEncontrada, "Aula no encontrada")
+)
+
+// horarioDe responde la semana del registro de :id cuya columna en horarios es columna, ordenada por día y
+// hora. Sin ciclo_escolar_id se muestran las clases de los ciclos que no están cerrados.
+func horarioDe(modelo any, columna, codigoNoEncontrado, mensajeNoEncontrado string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := database.LecturaDe(c)
+		var registro struct{ ID uint }
+		if err := db.Model(modelo).Select("id").First(&registro, c.Param("id")).Error; err != nil {
+			errores.Responder(c, errores.DeConsulta(err, codigoNoEncontrado, mensajeNoEncontrado))
+			return
+		}
+
+		q := conClases(db).Where("horarios."+columna+" = ?", registro.ID)
+		if valor := c.Query("ciclo_escolar_id"); valor != "" {
+			cicloID, err := strconv.ParseUint(valor, 10, 64)
+			if err != nil {
+				errores.Responder(c, errores.CampoInvalido("ciclo_escolar_id", "number", "ciclo_escolar_id debe ser un número"))
+				return
+			}
+			q = q.Where("horarios.ciclo_escolar_id = ?", cicloID)
+		} else {
+			q = q.Joins("JOIN ciclos_escolares ON ciclos_escolares.id = horarios.ciclo_escolar_id AND ciclos_escolares.deleted_at IS NULL").
+				Where("ciclos_escolares.estatus <> ?", models.CicloCerrado)
+		}
+		var horarios []models.Horario
+		if err := q.Order("horarios.dia, horarios.desde, horarios.id").Find(&horarios).Error; err != nil {
+			errores.Responder(c, errores.Interno("Error obteniendo el horario", err))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Horario obtenido correctamente",
+			"data":    horarios,
+		})
+	}
+}
+
+// firmaCalendario firma la organización, el usuario y la versión del enlace del calendario con
+// CALENDARIO_SECRETO, que no se comparte con los tokens. El enlace no vence, porque las aplicaciones de
+// calendario lo consultan periódicamente; deja de servir si el usuario lo regenera (sube su versión), si se
+// desactiva o se elimina, o si cambia el secreto.
+func firmaCalendario(organizacionID, userID, version uint) (string, error) {
+	secreto, err := secretoCalendario()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, []byte(secreto))
+	fmt.Fprintf(mac, "calendario\n%d\n%d\n%d", organizacionID, userID, version)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func secretoCalendario() (string, error) {
+	secreto := config.GetEnv("CALENDARIO_SECRETO", "")
+	if secreto == "" {
+		return "", errors.New("CALENDARIO_SECRETO es necesario para firmar los calendarios")
+	}
+	return secreto, nil
+}
+
+// enlaceCalendario arma la URL firmada al calendario del usuario con su versión vigente
+func enlaceCalendario(c *gin.Context, organizacionID, userID, version uint) (string, error) {
+	firma, err := firmaCalendario(organizacionID, userID, version)
+	if err != nil {
+		return "", err
+	}
+	base := config.GetEnv("CALENDARIO_URL_BASE", "")
+	if base == "" {
+		esquema := "http"
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			esquema = "https"
+		}
+		base = esquema + "://" + c.Request.Host
+	}
+	parametros := url.Values{
+		"organizacion": {strconv.FormatUint(uint64(organizacionID), 10)},
+		"usuario":      {strconv.FormatUint(uint64(userID), 10)},
+		"version":      {strconv.FormatUint(uint64(version), 10)},
+		"firma":        {firma},
+	}
+	return strings.TrimSuffix(base, "/") + RutaCalendario + "?" + parametros.Encode(), nil
+}
+
+// ObtenerEnlaceCalendario regresa el enlace firmado al calendario de horarios del usuario de la sesión, para
+// suscribirse desde Google Calendar, Outlook o el calendario del teléfono
+func ObtenerEnlaceCalendario(c *gin.Context) {
+	var usuario models.User
+	if err := database.De(c).Select("id", "version_calendario").First(&usuario, c.GetUint("user_id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.UsuarioNoEncontrado, "Usuario no encontrado"))
+		return
+	}
+	enlace, err := enlaceCalendario(c, c.GetUint(middleware.ClaveOrganizacion), usuario.ID, usuario.VersionCalendario)
+	if err != nil {
+		errores.Responder(c, errores.Interno("El calendario no está configurado", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Enlace del calendario generado correctamente",
+		"url":     enlace,
+	})
+}
+
+// RegenerarEnlaceCalendario invalida el enlace al calendario del usuario de la sesión (por ejemplo, si lo
+// compartió por error) y regresa uno nuevo. Las aplicaciones suscritas al anterior dejan de actualizarse.
+func RegenerarEnlaceCalendario(c *gin.Context) {
+	// Sin secreto no se podría entregar el enlace nuevo; mejor no invalidar el anterior
+	if _, err := secretoCalendario(); err != nil {
+		errores.Responder(c, errores.Interno("El calendario no está configurado", err))
+		return
+	}
+	var usuario models.User
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", c.GetUint("user_id")).
+			UpdateColumn("version_calendario", gorm.Expr("version_calendario + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Select("id", "version_calendario").First(&usuario, c.GetUint("user_id")).Error
+	})
+	if err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.UsuarioNoEncontrado, "Usuario no encontrado"))
+		return
+	}
+	enlace, err := enlaceCalendario(c, c.GetUint(middleware.ClaveOrganizacion), usuario.ID, usuario.VersionCalendario)
+	if err != nil {
+		errores.Responder(c, errores.Interno("El calendario no está configurado", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Enlace del calendario regenerado; el anterior ya no funciona",
+		"url":     enlace,
+	})
+}
+
+// ObtenerCalendario entrega en iCalendar las clases de los ciclos que no están cerrados del usuario del
+// enlace: las que da, si es profesor, y las de su grupo y sus inscripciones, si es estudiante. Es pública
+// porque la firma del enlace ya es la autorización.
+func ObtenerCalendario(c *gin.Context) {
+	organizacionID, errOrg := strconv.ParseUint(c.Query("organizacion"), 10, 64)
+	userID, errUsuario := strconv.ParseUint(c.Query("usuario"), 10, 64)
+	version, errVersion := strconv.ParseUint(c.Query("version"), 10, 64)
+	firma, err := firmaCalendario(uint(organizacionID), uint(userID), uint(version))
+	if err != nil {
+		errores.Responder(c, errores.Interno("El calendario no está configurado", err))
+		return
+	}
+	enlaceInvalido := errores.Prohibido(errores.EnlaceCalendarioInvalido, "El enlace del calendario no es válido")
+	if errOrg != nil || errUsuario != nil || errVersion != nil || !hmac.Equal([]byte(c.Query("firma")), []byte(firma)) {
+		errores.Responder(c, enlaceInvalido)
+		return
+	}
+	if e := middleware.OrganizacionDeEnlace(c, uint(organizacionID)); e != nil {
+		errores.Responder(c, e)
+		return
+	}
+
+	db := database.LecturaDe(c)
+	var usuario models.User
+	if err := db.Where("es_activo = ?", true).First(&usuario, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			errores.Responder(c, enlaceInvalido)
+			return
+		}
+		errores.Responder(c, errores.Interno("Error obteniendo el usuario del calendario", err))
+		return
+	}
+	// Un enlace con una versión anterior fue regenerado
+	if usuario.VersionCalendario != uint(version) {
+		errores.Responder(c, enlaceInvalido)
+		return
+	}
+	horarios, err := clasesDeUsuario(db, usuario.ID)
+	if err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo el horario", err))
+		return
+	}
+
+	clases := make([]horario.Clase, 0, len(horarios))
+	for _, h := range horarios {
+		clases = append(clases, horario.Clase{
+			UID:         fmt.Sprintf("horario-%d-%d@margaritai", organizacionID, h.ID),
+			Resumen:     h.Materia.Titulo + " - " + h.Grupo.Titulo,
+			Lugar:       strings.TrimSuffix(h.Aula.Nombre+", "+h.Aula.Plantel.Nombre, ", "),
+			Descripcion: "Profesor: " + exportacion.NombreCompleto(h.Personal.User.Nombre, h.Personal.User.ApellidoP, h.Personal.User.ApellidoM),
+			Franja:      h.Franja(),
+			Inicio:      h.CicloEscolar.FechaInicio,
+			Fin:         h.CicloEscolar.FechaFin,
+			Modificada:  h.UpdatedAt,
+		})
+	}
+	nombre := "Horario de " + exportacion.NombreCompleto(usuario.Nombre, usuario.ApellidoP, usuario.ApellidoM)
+	c.Data(http.StatusOK, horario.TipoICalendar, horario.ICalendar(nombre, clases))
+}
+
+// clasesDeUsuario reúne las clases de los ciclos que no están cerrados que da el usuario como profesor y las
+// de los grupos en que está (o estará) como estudiante
+func clasesDeUsuario(db *gorm.DB, userID uint) ([]models.Horario, error) {
+	var condiciones []string
+	var valores []any
+
+	var personal models.Personal
+	if err := db.Select("id", "es_profesor").Where("user_id = ?", userID).Limit(1).Find(&personal).Error; err != nil {
+		return nil, err
+	}
+	if personal.ID != 0 && personal.EsProfesor {
+		condiciones, valores = append(condiciones, "horarios.personal_id = ?"), append(valores, personal.ID)
+	}
+
+	var estudiante models.Estudiante
+	if err := db.Select("id", "grupo_id").Where("user_id = ?", userID).Limit(1).Find(&estudiante).Error; err != nil {
+		return nil, err
+	}
+	if estudiante.ID != 0 {
+		inscritos := db.Model(&models.Inscripcion{}).Select("grupo_id").Where("estudiante_id = ?", estudiante.ID)
+		condiciones = append(condiciones, "horarios.grupo_id = ?", "horarios.grupo_id IN (?)")
+		valores = append(valores, estudiante.GrupoID, inscritos)
+	}
+
+	var horarios []models.Horario
+	if len(condiciones) == 0 {
+		return horarios, nil
+	}
+	err := conClases(db).Preload("Aula.Plantel").Preload("CicloEscolar").
+		Joins("JOIN ciclos_escolares ON ciclos_escolares.id = horarios.ciclo_escolar_id AND ciclos_escolares.deleted_at IS NULL").
+		Where("ciclos_escolares.estatus <> ?", models.CicloCerrado).
+		Where(strings.Join(condiciones, " OR "), valores...).
+		Order("horarios.dia, horarios.desde, horarios.id").
+		Find(&horarios).Error
+	return horarios, err
+}
@@ -0,0 +1,135 @@
+package gestioncatalogos
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/models"
+)
+
+// AsignacionMateriasInput es el cuerpo de PUT /grados/:id/materias: las materias del plan en el orden en que
+// se imparten. Las que ya estaban en el grado y no se envían quedan sin grado; las de otro grado se mueven.
+type AsignacionMateriasInput struct {
+	Materias []uint `json:"materias" binding:"required,unique,dive,gt=0"`
+}
+
+// TotalesPlan suma la carga del plan de estudios de un grado
+type TotalesPlan struct {
+	Materias       int     `json:"materias"`
+	Creditos       float64 `json:"creditos"`
+	HorasTeoricas  int     `json:"horas_teoricas"`
+	HorasPracticas int     `json:"horas_practicas"`
+	HorasSemanales int     `json:"horas_semanales"`
+}
+
+// PlanEstudios es el grado con sus materias en orden y sus totales
+type PlanEstudios struct {
+	Grado    models.Grado     `json:"grado"`
+	Materias []models.Materia `json:"materias"`
+	Totales  TotalesPlan      `json:"totales"`
+}
+
+// cargarPlan lee el grado de :id y sus materias; si no puede, ya respondió
+func cargarPlan(c *gin.Context, db *gorm.DB) (PlanEstudios, bool) {
+	var plan PlanEstudios
+	if err := db.Preload("NivelEscolar").First(&plan.Grado, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.GradoNoEncontrado, "Grado no encontrado"))
+		return plan, false
+	}
+	if err := db.Where("grado_id = ?", plan.Grado.ID).Order("orden, id").Find(&plan.Materias).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo las materias del grado", err))
+		return plan, false
+	}
+	for _, m := range plan.Materias {
+		plan.Totales.Materias++
+		plan.Totales.Creditos += m.Creditos
+		plan.Totales.HorasTeoricas += m.HorasTeoricas
+		plan.Totales.HorasPracticas += m.HorasPracticas
+	}
+	plan.Totales.HorasSemanales = plan.Totales.HorasTeoricas + plan.Totales.HorasPracticas
+	return plan, true
+}
+
+// ObtenerPlanEstudios regresa las materias de un grado en el orden en que se imparten, con créditos y horas totales
+func ObtenerPlanEstudios(c *gin.Context) {
+	plan, ok := cargarPlan(c, database.LecturaDe(c))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Plan de estudios obtenido correctamente",
+		"plan":    plan,
+	})
+}
+
+// AsignarMateriasAGrado reemplaza el plan de estudios del grado con las materias enviadas, en ese orden
+func AsignarMateriasAGrado(c *gin.Context) {
+	var input AsignacionMateriasInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+	var grado models.Grado
+	if err := database.De(c).First(&grado, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.GradoNoEncontrado, "Grado no encontrado"))
+		return
+	}
+
+	if len(input.Materias) > 0 {
+		var encontradas []uint
+		if err := database.De(c).Model(&models.Materia{}).Where("id IN ?", input.Materias).Pluck("id", &encontradas).Error; err != nil {
+			errores.Responder(c, errores.Interno("Error validando las materias", err))
+			return
+		}
+		var faltantes []string
+		for _, id := range input.Materias {
+			if !slices.Contains(encontradas, id) {
+				faltantes = append(faltantes, fmt.Sprint(id))
+			}
+		}
+		if len(faltantes) > 0 {
+			errores.Responder(c, errores.CampoInvalido("materias", "exists", "Materias no encontradas: "+strings.Join(faltantes, ", ")))
+			return
+		}
+	}
+
+	// Cada materia que cambia de grado u orden aumenta su versión, igual que al editarla
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		quitar := tx.Model(&models.Materia{}).Where("grado_id = ?", grado.ID)
+		if len(input.Materias) > 0 {
+			quitar = quitar.Where("id NOT IN ?", input.Materias)
+		}
+		if err := quitar.Updates(map[string]any{"grado_id": nil, "orden": 0, "version": gorm.Expr("version + 1")}).Error; err != nil {
+			return err
+		}
+		for i, id := range input.Materias {
+			err := tx.Model(&models.Materia{}).
+				Where("id = ? AND (grado_id IS DISTINCT FROM ? OR orden <> ?)", id, grado.ID, i+1).
+				Updates(map[string]any{"grado_id": grado.ID, "orden": i + 1, "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error asignando las materias al grado"))
+		return
+	}
+
+	plan, ok := cargarPlan(c, database.De(c))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Plan de estudios actualizado correctamente",
+		"plan":    plan,
+	})
+}
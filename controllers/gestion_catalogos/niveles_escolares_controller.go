@@ -6,55 +6,54 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"api-margaritai/concurrencia"
+	"api-margaritai/consulta"
 	"api-margaritai/database"
+	"api-margaritai/errores"
 	"api-margaritai/models"
 )
 
-// ObtenerNivelesEscolares retorna todos los niveles escolares (posiblemente filtrados por plantel_id si es pasado como query param)
-func ObtenerNivelesEscolares(c *gin.Context) {
-	db := database.DB
+// NivelEscolarInput es el cuerpo para crear un nivel escolar
+type NivelEscolarInput struct {
+	Titulo      string  `json:"titulo" binding:"required"`
+	Descripcion string  `json:"descripcion"`
+	Mensualidad float64 `json:"mensualidad" binding:"required"`
+	PlantelID   uint    `json:"plantel_id" binding:"required"`
+}
 
-	var niveles []models.NivelEscolar
+// NivelEscolarUpdateInput es el cuerpo para editar un nivel escolar; los campos nulos no se modifican
+type NivelEscolarUpdateInput struct {
+	Titulo      *string  `json:"titulo"`
+	Descripcion *string  `json:"descripcion"`
+	Mensualidad *float64 `json:"mensualidad"`
+	PlantelID   *uint    `json:"plantel_id"`
+}
 
-	plantelIDParam := c.Query("plantel_id")
-	query := db.Preload("Plantel").Order("id")
-	if plantelIDParam != "" {
-		plantelID, err := strconv.ParseUint(plantelIDParam, 10, 64)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "plantel_id inválido"})
-			return
-		}
-		query = query.Where("plantel_id = ?", plantelID)
-	}
-	if err := query.Find(&niveles).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los niveles escolares"})
+// ObtenerNivelesEscolares retorna los niveles escolares paginados (filtrables por plantel_id, ver ConsultaNivelesEscolares)
+func ObtenerNivelesEscolares(c *gin.Context) {
+	niveles, paginacion, errConsulta := consulta.Listar[models.NivelEscolar](c, database.LecturaDe(c), ConsultaNivelesEscolares)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"niveles_escolares": niveles})
+	c.JSON(http.StatusOK, gin.H{"niveles_escolares": niveles, "paginacion": paginacion})
 }
 
 // CrearNivelEscolar crea un nuevo nivel escolar
 func CrearNivelEscolar(c *gin.Context) {
-	db := database.DB
-
-	type NivelEscolarInput struct {
-		Titulo      string  `json:"titulo" binding:"required"`
-		Descripcion string  `json:"descripcion"`
-		Mensualidad float64 `json:"mensualidad" binding:"required"`
-		PlantelID   uint    `json:"plantel_id" binding:"required"`
-	}
+	db := database.De(c)
 
 	var input NivelEscolarInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos", "details": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
 	// Verifica que exista el plantel
 	var plantel models.Plantel
 	if err := db.First(&plantel, input.PlantelID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Plantel no encontrado"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.PlantelNoEncontrado, "Plantel no encontrado"))
 		return
 	}
 
@@ -66,7 +65,7 @@ func CrearNivelEscolar(c *gin.Context) {
 	}
 
 	if err := db.Create(&nivel).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear el nivel escolar", "details": err.Error()})
+		errores.Responder(c, errores.BaseDatos(err, "No se pudo crear el nivel escolar"))
 		return
 	}
 
@@ -87,30 +86,27 @@ func CrearNivelEscolar(c *gin.Context) {
 
 // EditarNivelEscolar actualiza un nivel escolar existente
 func EditarNivelEscolar(c *gin.Context) {
-	db := database.DB
+	db := database.De(c)
 
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID del nivel escolar requerido"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID del nivel escolar requerido"))
 		return
 	}
 
-	type NivelEscolarUpdateInput struct {
-		Titulo      *string  `json:"titulo"`
-		Descripcion *string  `json:"descripcion"`
-		Mensualidad *float64 `json:"mensualidad"`
-		PlantelID   *uint    `json:"plantel_id"`
-	}
-
 	var input NivelEscolarUpdateInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos", "details": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
 	var nivel models.NivelEscolar
 	if err := db.First(&nivel, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Nivel escolar no encontrado"})
+		errores.Responder(c, errores.DeConsulta(err, errores.NivelEscolarNoEncontrado, "Nivel escolar no encontrado"))
+		return
+	}
+
+	if !concurrencia.Verificar(c, nivel.Version, nivel) {
 		return
 	}
 
@@ -127,19 +123,20 @@ func EditarNivelEscolar(c *gin.Context) {
 		// Verifica que exista el plantel nuevo
 		var plantel models.Plantel
 		if err := db.First(&plantel, *input.PlantelID).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Nuevo plantel no encontrado"})
+			errores.Responder(c, errores.SolicitudInvalida(errores.PlantelNoEncontrado, "Nuevo plantel no encontrado"))
 			return
 		}
 		nivel.PlantelID = *input.PlantelID
 	}
 
-	if err := db.Save(&nivel).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar el nivel escolar", "details": err.Error()})
+	if err := concurrencia.Guardar(db, &nivel); err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &nivel, "No se pudo actualizar el nivel escolar", "Plantel"))
 		return
 	}
 
 	// Preload del plantel
 	if err := db.Preload("Plantel").First(&nivel, nivel.ID).Error; err != nil {
+		concurrencia.EscribirETag(c, nivel.Version)
 		c.JSON(http.StatusOK, gin.H{
 			"message":       "Nivel escolar editado, pero hubo un problema obteniendo la información ampliada",
 			"nivel_escolar": nivel,
@@ -147,6 +144,7 @@ func EditarNivelEscolar(c *gin.Context) {
 		return
 	}
 
+	concurrencia.EscribirETag(c, nivel.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "Nivel escolar actualizado correctamente",
 		"nivel_escolar": nivel,
@@ -155,37 +153,47 @@ func EditarNivelEscolar(c *gin.Context) {
 
 // EliminarNivelEscolar elimina un nivel escolar (solo si no existen estudiantes asociados, si aplica)
 func EliminarNivelEscolar(c *gin.Context) {
-	db := database.DB
+	db := database.De(c)
 
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID del nivel escolar requerido"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID del nivel escolar requerido"))
 		return
 	}
 
 	nivelID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID inválido"))
 		return
 	}
 
 	// Verifica que exista el nivel escolar
 	var nivel models.NivelEscolar
 	if err := db.First(&nivel, nivelID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Nivel escolar no encontrado"})
+		errores.Responder(c, errores.DeConsulta(err, errores.NivelEscolarNoEncontrado, "Nivel escolar no encontrado"))
 		return
 	}
 
 	// (Opcional): Verifica si existen estudiantes asociados a este nivel escolar
 	// Si tienes un modelo Estudiante que tiene NivelEscolarID
 	var countEstudiantes int64
-	if err := db.Table("estudiantes").Where("nivel_escolar_id = ?", nivelID).Count(&countEstudiantes).Error; err == nil && countEstudiantes > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No se puede eliminar el nivel escolar porque existen estudiantes asociados"})
+	if err := db.Model(&models.Estudiante{}).Where("nivel_escolar_id = ?", nivelID).Count(&countEstudiantes).Error; err == nil && countEstudiantes > 0 {
+		errores.Responder(c, errores.Conflicto(errores.NivelEscolarConEstudiantes, "No se puede eliminar el nivel escolar porque existen estudiantes asociados"))
+		return
+	}
+
+	var countCiclos int64
+	if err := db.Model(&models.CicloEscolar{}).Where("nivel_escolar_id = ?", nivelID).Count(&countCiclos).Error; err != nil {
+		errores.Responder(c, errores.Interno("No se pudo verificar ciclos escolares asociados", err))
+		return
+	}
+	if countCiclos > 0 {
+		errores.Responder(c, errores.Conflicto(errores.NivelEscolarConCiclos, "No se puede eliminar el nivel escolar porque tiene ciclos escolares"))
 		return
 	}
 
 	if err := db.Delete(&models.NivelEscolar{}, nivelID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar el nivel escolar", "details": err.Error()})
+		errores.Responder(c, errores.BaseDatos(err, "No se pudo eliminar el nivel escolar"))
 		return
 	}
 
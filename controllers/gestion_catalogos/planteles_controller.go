@@ -1,43 +1,74 @@
 package gestioncatalogos
 
 import (
+	"api-margaritai/concurrencia"
+	"api-margaritai/consulta"
 	"api-margaritai/database"
+	"api-margaritai/errores"
 	"api-margaritai/models"
+	"api-margaritai/parche"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// ObtenerPlanteles obtiene todos los planteles existentes
-func ObtenerPlanteles(c *gin.Context) {
-	var planteles []models.Plantel
+// PlantelInput es el cuerpo para crear un plantel
+type PlantelInput struct {
+	Nombre      string `json:"nombre" binding:"required"`
+	Descripcion string `json:"descripcion"`
+	Ubicacion   string `json:"ubicacion" binding:"required"`
+	Telefono    string `json:"telefono" binding:"required"`
+	Correo      string `json:"correo" binding:"required,email"`
+	UserID      uint   `json:"user_id" binding:"required"`
+}
+
+// PlantelUpdateInput es el cuerpo para editar un plantel; los campos nulos no se modifican
+type PlantelUpdateInput struct {
+	Nombre      *string `json:"nombre"`
+	Descripcion *string `json:"descripcion"`
+	Ubicacion   *string `json:"ubicacion"`
+	Telefono    *string `json:"telefono"`
+	Correo      *string `json:"correo"`
+	UserID      *uint   `json:"user_id"`
+}
+
+// PlantelParche es la lista blanca de PATCH /planteles/:id
+type PlantelParche struct {
+	Nombre      string `json:"nombre" binding:"required"`
+	Descripcion string `json:"descripcion"`
+	Ubicacion   string `json:"ubicacion" binding:"required"`
+	Telefono    string `json:"telefono" binding:"required"`
+	Correo      string `json:"correo" binding:"required,email"`
+	UserID      uint   `json:"user_id" binding:"required"`
+}
+
+// normalizar deja el correo sin espacios alrededor
+func (in *PlantelParche) normalizar() {
+	in.Correo = strings.TrimSpace(in.Correo)
+}
 
-	if err := database.DB.Preload("User").Find(&planteles).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los planteles", "details": err.Error()})
+// ObtenerPlanteles obtiene los planteles existentes, paginados
+func ObtenerPlanteles(c *gin.Context) {
+	planteles, paginacion, errConsulta := consulta.Listar[models.Plantel](c, database.LecturaDe(c), ConsultaPlanteles)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"planteles": planteles,
+		"planteles":  planteles,
+		"paginacion": paginacion,
 	})
 }
 
 // CrearPlantel crea un nuevo plantel
 func CrearPlantel(c *gin.Context) {
-	type PlantelInput struct {
-		Nombre      string `json:"nombre" binding:"required"`
-		Descripcion string `json:"descripcion"`
-		Ubicacion   string `json:"ubicacion" binding:"required"`
-		Telefono    string `json:"telefono" binding:"required"`
-		Correo      string `json:"correo" binding:"required,email"`
-		UserID      uint   `json:"user_id" binding:"required"`
-	}
-
 	var input PlantelInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos", "details": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
@@ -50,13 +81,13 @@ func CrearPlantel(c *gin.Context) {
 		UserID:      input.UserID,
 	}
 
-	if err := database.DB.Create(&plantel).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear el plantel", "details": err.Error()})
+	if err := database.De(c).Create(&plantel).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "No se pudo crear el plantel"))
 		return
 	}
 
 	// Preload para devolver info del usuario relacionado, si es necesario
-	if err := database.DB.Preload("User").First(&plantel, plantel.ID).Error; err != nil {
+	if err := database.De(c).Preload("User").First(&plantel, plantel.ID).Error; err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Plantel creado exitosamente, pero hubo un problema obteniendo la información ampliada",
 			"plantel": plantel,
@@ -75,29 +106,24 @@ func EditarPlantel(c *gin.Context) {
 	// El ID puede venir como parámetro de la ruta: /planteles/:id
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID del plantel requerido"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID del plantel requerido"))
 		return
 	}
 
-	type PlantelUpdateInput struct {
-		Nombre      *string `json:"nombre"`
-		Descripcion *string `json:"descripcion"`
-		Ubicacion   *string `json:"ubicacion"`
-		Telefono    *string `json:"telefono"`
-		Correo      *string `json:"correo"`
-		UserID      *uint   `json:"user_id"`
-	}
-
 	var input PlantelUpdateInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos", "details": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
 	var plantel models.Plantel
-	if err := database.DB.First(&plantel, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Plantel no encontrado"})
+	if err := database.De(c).First(&plantel, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.PlantelNoEncontrado, "Plantel no encontrado"))
+		return
+	}
+
+	if !concurrencia.Verificar(c, plantel.Version, plantel) {
 		return
 	}
 
@@ -121,13 +147,14 @@ func EditarPlantel(c *gin.Context) {
 		plantel.UserID = *input.UserID
 	}
 
-	if err := database.DB.Save(&plantel).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar el plantel", "details": err.Error()})
+	if err := concurrencia.Guardar(database.De(c), &plantel); err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &plantel, "No se pudo actualizar el plantel", "User"))
 		return
 	}
 
 	// Preload de usuario asociado actualizado
-	if err := database.DB.Preload("User").First(&plantel, plantel.ID).Error; err != nil {
+	if err := database.De(c).Preload("User").First(&plantel, plantel.ID).Error; err != nil {
+		concurrencia.EscribirETag(c, plantel.Version)
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Plantel editado, pero hubo un problema obteniendo la información ampliada",
 			"plantel": plantel,
@@ -135,6 +162,40 @@ func EditarPlantel(c *gin.Context) {
 		return
 	}
 
+	concurrencia.EscribirETag(c, plantel.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Plantel actualizado correctamente",
+		"plantel": plantel,
+	})
+}
+
+// ParchearPlantel aplica un JSON Merge Patch (o JSON Patch) a los campos de PlantelParche
+func ParchearPlantel(c *gin.Context) {
+	var plantel models.Plantel
+	if err := database.De(c).First(&plantel, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.PlantelNoEncontrado, "Plantel no encontrado"))
+		return
+	}
+	if !concurrencia.Verificar(c, plantel.Version, plantel) {
+		return
+	}
+
+	var input PlantelParche
+	p, errParche := parche.Leer(c, &input, &plantel)
+	if errParche != nil {
+		errores.Responder(c, errParche)
+		return
+	}
+	input.normalizar()
+	p.Aplicar(&plantel)
+
+	if err := concurrencia.Guardar(database.De(c), &plantel); err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &plantel, "No se pudo actualizar el plantel", "User"))
+		return
+	}
+
+	database.De(c).Preload("User").First(&plantel, plantel.ID)
+	concurrencia.EscribirETag(c, plantel.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Plantel actualizado correctamente",
 		"plantel": plantel,
@@ -145,48 +206,59 @@ func EditarPlantel(c *gin.Context) {
 func EliminarPlantel(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID del plantel requerido"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID del plantel requerido"))
 		return
 	}
 
 	plantelID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID inválido"))
 		return
 	}
 
 	// Verifica que exista el plantel
 	var plantel models.Plantel
-	if err := database.DB.First(&plantel, plantelID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Plantel no encontrado"})
+	if err := database.De(c).First(&plantel, plantelID).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.PlantelNoEncontrado, "Plantel no encontrado"))
 		return
 	}
 
 	// Verifica si existen estudiantes asociados
 	var countEstudiantes int64
-	if err := database.DB.Model(&models.Estudiante{}).Where("plantel_id = ?", plantelID).Count(&countEstudiantes).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo verificar estudiantes asociados", "details": err.Error()})
+	if err := database.De(c).Model(&models.Estudiante{}).Where("plantel_id = ?", plantelID).Count(&countEstudiantes).Error; err != nil {
+		errores.Responder(c, errores.Interno("No se pudo verificar estudiantes asociados", err))
 		return
 	}
 	if countEstudiantes > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No se puede eliminar el plantel porque existen estudiantes asociados"})
+		errores.Responder(c, errores.Conflicto(errores.PlantelConEstudiantes, "No se puede eliminar el plantel porque existen estudiantes asociados"))
 		return
 	}
 
 	// Verifica si existen niveles escolares asociados
 	var countNiveles int64
-	if err := database.DB.Model(&models.NivelEscolar{}).Where("plantel_id = ?", plantelID).Count(&countNiveles).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo verificar niveles escolares asociados", "details": err.Error()})
+	if err := database.De(c).Model(&models.NivelEscolar{}).Where("plantel_id = ?", plantelID).Count(&countNiveles).Error; err != nil {
+		errores.Responder(c, errores.Interno("No se pudo verificar niveles escolares asociados", err))
 		return
 	}
 	if countNiveles > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No se puede eliminar el plantel porque existen niveles escolares asociados"})
+		errores.Responder(c, errores.Conflicto(errores.PlantelConNiveles, "No se puede eliminar el plantel porque existen niveles escolares asociados"))
+		return
+	}
+
+	// Verifica si existen aulas en el plantel
+	var countAulas int64
+	if err := database.De(c).Model(&models.Aula{}).Where("plantel_id = ?", plantelID).Count(&countAulas).Error; err != nil {
+		errores.Responder(c, errores.Interno("No se pudo verificar aulas asociadas", err))
+		return
+	}
+	if countAulas > 0 {
+		errores.Responder(c, errores.Conflicto(errores.PlantelConAulas, "No se puede eliminar el plantel porque tiene aulas"))
 		return
 	}
 
 	// Ahora sí, eliminar el plantel
-	if err := database.DB.Delete(&models.Plantel{}, plantelID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar el plantel", "details": err.Error()})
+	if err := database.De(c).Delete(&models.Plantel{}, plantelID).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "No se pudo eliminar el plantel"))
 		return
 	}
 
@@ -0,0 +1,224 @@
+package gestionusuarios
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/consulta"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/middleware"
+	"api-margaritai/models"
+)
+
+// Tipos de persona que regresa la búsqueda
+const (
+	TipoEstudiante = "estudiante"
+	TipoPersonal   = "personal"
+	TipoTutor      = "tutor"
+)
+
+const (
+	limiteBusquedaPorDefecto = 20
+	limiteBusquedaMaximo     = 50
+)
+
+// ResultadoBusqueda es una persona encontrada; Ruta apunta al recurso de su estudiante, personal o tutor
+type ResultadoBusqueda struct {
+	Tipo          string  `json:"tipo"`
+	ID            uint    `json:"id"`
+	UserID        uint    `json:"user_id"`
+	Nombre        string  `json:"nombre"`
+	ApellidoP     string  `json:"apellido_p"`
+	ApellidoM     string  `json:"apellido_m"`
+	Email         string  `json:"email"`
+	CURP          string  `json:"curp"`
+	Identificador string  `json:"identificador"` // matrícula o número de empleado
+	Telefono      string  `json:"telefono"`
+	Puntaje       float64 `json:"puntaje"` // entre 0 y 1; 1 es una coincidencia exacta de CURP, email, matrícula o número de empleado
+	Ruta          string  `json:"ruta" gorm:"-"`
+}
+
+// busquedaPorTipo describe cómo buscar cada tipo de persona y qué permiso se necesita para verlo
+var busquedaPorTipo = map[string]struct {
+	permiso       string
+	ruta          string
+	tabla         string
+	identificador string   // columna del identificador propio (vacía si no tiene)
+	exprIdent     string   // expresión indexada de esa columna
+	telefonos     []string // columnas de teléfono
+	nombreExtra   string   // expresión indexada de un nombre adicional al del usuario
+}{
+	TipoEstudiante: {
+		permiso:       models.PermisoVerEstudiantes,
+		ruta:          "/api/protected/estudiantes/",
+		tabla:         "estudiantes",
+		identificador: "estudiantes.matricula",
+		exprIdent:     database.ExprMatricula,
+		telefonos:     []string{"estudiantes.telefono"},
+	},
+	TipoPersonal: {
+		permiso:       models.PermisoVerPersonal,
+		ruta:          "/api/protected/personal/",
+		tabla:         "personals",
+		identificador: "personals.numero_empleado",
+		exprIdent:     database.ExprNumeroEmpleado,
+		telefonos:     []string{"personals.telefono1", "personals.telefono2"},
+	},
+	TipoTutor: {
+		permiso:     models.PermisoVerTutores,
+		ruta:        "/api/protected/tutores/",
+		tabla:       "tutors",
+		telefonos:   []string{"tutors.telefono", "tutors.telefono2"},
+		nombreExtra: database.ExprNombreTutor,
+	},
+}
+
+var ordenTipos = []string{TipoEstudiante, TipoPersonal, TipoTutor}
+
+// BuscarPersonas busca estudiantes, personal y tutores por nombre parcial (sin importar acentos),
+// matrícula, número de empleado, CURP, email o teléfono. Solo incluye los tipos que el rol del usuario puede ver.
+func BuscarPersonas(c *gin.Context) {
+	termino := strings.Join(strings.Fields(strings.ToLower(c.Query("q"))), " ")
+	if len([]rune(termino)) < 2 {
+		errores.Responder(c, errores.CampoInvalido("q", "min", "El término de búsqueda debe tener al menos 2 caracteres"))
+		return
+	}
+
+	limite := limiteBusquedaPorDefecto
+	if valor := c.Query("limite"); valor != "" {
+		n, err := strconv.Atoi(valor)
+		if err != nil || n < 1 || n > limiteBusquedaMaximo {
+			errores.Responder(c, errores.CampoInvalido("limite", "max", fmt.Sprintf("limite debe estar entre 1 y %d", limiteBusquedaMaximo)))
+			return
+		}
+		limite = n
+	}
+
+	solicitados := ordenTipos
+	if valor := c.Query("tipo"); valor != "" {
+		solicitados = nil
+		for _, tipo := range strings.Split(valor, ",") {
+			tipo = strings.TrimSpace(tipo)
+			if _, ok := busquedaPorTipo[tipo]; !ok {
+				errores.Responder(c, errores.CampoInvalido("tipo", "oneof", "tipo debe ser estudiante, personal o tutor"))
+				return
+			}
+			solicitados = append(solicitados, tipo)
+		}
+	}
+
+	// Filtrar los tipos según los permisos del rol
+	titulos := make([]string, 0, len(solicitados))
+	for _, tipo := range solicitados {
+		titulos = append(titulos, busquedaPorTipo[tipo].permiso)
+	}
+	permisos, err := middleware.PermisosDelUsuario(c.GetUint("user_id"), titulos...)
+	if err != nil {
+		errores.Responder(c, errores.Interno("Error verificando permisos", err))
+		return
+	}
+	var tipos []string
+	for _, tipo := range solicitados {
+		if permisos[busquedaPorTipo[tipo].permiso] {
+			tipos = append(tipos, tipo)
+		}
+	}
+	if len(tipos) == 0 {
+		errores.Responder(c, errores.Prohibido(errores.PermisoDenegado, "No tienes permiso para buscar estudiantes, personal ni tutores"))
+		return
+	}
+
+	digitos := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, termino)
+
+	partes := make([]string, 0, len(tipos))
+	for _, tipo := range tipos {
+		partes = append(partes, consultaBusqueda(tipo, len(digitos) >= 4, len(termino) >= 4 && !strings.Contains(termino, " ")))
+	}
+	sql := strings.Join(partes, " UNION ALL ") + " ORDER BY puntaje DESC, tipo, id LIMIT @limite"
+
+	resultados := []ResultadoBusqueda{}
+	err = database.LecturaDe(c).Raw(sql, map[string]any{
+		"organizacion": c.GetUint(middleware.ClaveOrganizacion),
+		"termino":      termino,
+		"patron":       "%" + consulta.EscaparLike(termino) + "%",
+		"curp":         strings.ToUpper(consulta.EscaparLike(termino)) + "%",
+		"telefono":     "%" + digitos + "%",
+		"limite":       limite,
+	}).Scan(&resultados).Error
+	if err != nil {
+		errores.Responder(c, errores.Interno("Error buscando personas", err))
+		return
+	}
+	for i := range resultados {
+		resultados[i].Ruta = busquedaPorTipo[resultados[i].Tipo].ruta + strconv.FormatUint(uint64(resultados[i].ID), 10)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Búsqueda realizada correctamente",
+		"termino":    termino,
+		"tipos":      tipos,
+		"total":      len(resultados),
+		"resultados": resultados,
+	})
+}
+
+// consultaBusqueda arma el SELECT de un tipo de persona. Usa las expresiones de database para que
+// los filtros aprovechen los índices trigram creados por database.PrepararBusqueda.
+// Es SQL crudo, así que filtra la organización por su cuenta en lugar de depender de database.De.
+// Los prefijos de CURP y los teléfonos solo se comparan con términos de al menos 4 caracteres para no inundar los resultados.
+func consultaBusqueda(tipo string, conTelefono, conCURP bool) string {
+	b := busquedaPorTipo[tipo]
+
+	nombres := []string{database.ExprNombreCompleto}
+	if b.nombreExtra != "" {
+		nombres = append(nombres, b.nombreExtra)
+	}
+
+	var condiciones, similitudes, exactas, parciales []string
+	for _, nombre := range nombres {
+		condiciones = append(condiciones, nombre+" LIKE f_unaccent(@patron)", "f_unaccent(@termino) <% "+nombre)
+		similitudes = append(similitudes, "word_similarity(f_unaccent(@termino), "+nombre+")")
+	}
+	condiciones = append(condiciones, database.ExprEmail+" LIKE @patron")
+	exactas = append(exactas, database.ExprEmail+" = @termino", "users.curp = upper(@termino)")
+	parciales = append(parciales, database.ExprEmail+" LIKE @patron")
+	if conCURP {
+		condiciones = append(condiciones, "users.curp LIKE @curp")
+		parciales = append(parciales, "users.curp LIKE @curp")
+	}
+
+	identificador := "''"
+	if b.identificador != "" {
+		identificador = b.identificador
+		condiciones = append(condiciones, b.exprIdent+" LIKE @patron")
+		exactas = append(exactas, b.exprIdent+" = @termino")
+		parciales = append(parciales, b.exprIdent+" LIKE @patron")
+	}
+	if conTelefono {
+		for _, columna := range b.telefonos {
+			condiciones = append(condiciones, columna+" LIKE @telefono")
+			parciales = append(parciales, columna+" LIKE @telefono")
+		}
+	}
+
+	puntaje := fmt.Sprintf("GREATEST(%s, CASE WHEN %s THEN 1.0 WHEN %s THEN 0.8 ELSE 0 END)",
+		strings.Join(similitudes, ", "), strings.Join(exactas, " OR "), strings.Join(parciales, " OR "))
+
+	return fmt.Sprintf(`(SELECT '%s' AS tipo, %s.id AS id, users.id AS user_id, users.nombre, users.apellido_p, users.apellido_m,
+		users.email, users.curp, %s AS identificador, %s AS telefono, %s AS puntaje
+		FROM %s JOIN users ON users.id = %s.user_id AND users.deleted_at IS NULL
+		WHERE %s.deleted_at IS NULL AND %s.organizacion_id = @organizacion AND (%s))`,
+		tipo, b.tabla, identificador, b.telefonos[0], puntaje,
+		b.tabla, b.tabla, b.tabla, b.tabla, strings.Join(condiciones, " OR "))
+}
@@ -0,0 +1,97 @@
+package gestionusuarios
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/database"
+	"api-margaritai/database/prueba"
+	"api-margaritai/middleware"
+	"api-margaritai/models"
+)
+
+// filtroOrganizacion encuentra en el SQL ya convertido a PostgreSQL cada "<tabla>.organizacion_id = $n"
+var filtroOrganizacion = regexp.MustCompile(`(\w+)\.organizacion_id = \$(\d+)`)
+
+// La búsqueda es SQL crudo: los callbacks de organizacion no la filtran y cada SELECT debe hacerlo por su cuenta
+func TestConsultaBusquedaFiltraOrganizacion(t *testing.T) {
+	for _, tipo := range ordenTipos {
+		for _, conTelefono := range []bool{false, true} {
+			for _, conCURP := range []bool{false, true} {
+				sql := consultaBusqueda(tipo, conTelefono, conCURP)
+				tabla := busquedaPorTipo[tipo].tabla
+				if !strings.Contains(sql, tabla+".organizacion_id = @organizacion") {
+					t.Errorf("%s (telefono=%v, curp=%v): falta el filtro de organización en %s", tipo, conTelefono, conCURP, sql)
+				}
+			}
+		}
+	}
+}
+
+func TestBuscarPersonasUsaOrganizacionDePeticion(t *testing.T) {
+	db, conexion, err := prueba.Abrir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	anteriorDB, anteriorReadDB := database.DB, database.ReadDB
+	database.DB, database.ReadDB = db, db
+	t.Cleanup(func() { database.DB, database.ReadDB = anteriorDB, anteriorReadDB })
+
+	// El usuario tiene un rol con permiso para ver los tres tipos
+	conexion.Responder(func(s prueba.Sentencia) prueba.Filas {
+		switch {
+		case strings.Contains(s.SQL, `FROM "users"`):
+			return prueba.Filas{Columnas: []string{"rol_id"}, Valores: [][]any{{int64(900)}}}
+		case strings.Contains(s.SQL, "role_tiene_permisos"):
+			return prueba.Filas{Columnas: []string{"titulo"}, Valores: [][]any{
+				{models.PermisoVerEstudiantes}, {models.PermisoVerPersonal}, {models.PermisoVerTutores},
+			}}
+		}
+		return prueba.Filas{}
+	})
+
+	gin.SetMode(gin.TestMode)
+	for _, org := range []uint{3, 4} {
+		t.Run(strconv.FormatUint(uint64(org), 10), func(t *testing.T) {
+			conexion.Limpiar()
+			r := gin.New()
+			r.ContextWithFallback = true
+			r.GET("/buscar", func(c *gin.Context) {
+				c.Set("user_id", uint(1))
+				middleware.EstablecerOrganizacion(c, org)
+			}, BuscarPersonas)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/buscar?q=garcia+5512", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("estado %d: %s", w.Code, w.Body)
+			}
+
+			busquedas := conexion.Buscar("UNION ALL")
+			if len(busquedas) != 1 {
+				t.Fatalf("se esperaba una consulta de búsqueda, hubo %d", len(busquedas))
+			}
+			s := busquedas[0]
+			filtradas := map[string]bool{}
+			for _, m := range filtroOrganizacion.FindAllStringSubmatch(s.SQL, -1) {
+				n, _ := strconv.Atoi(m[2])
+				if n < 1 || n > len(s.Args) || fmt.Sprint(s.Args[n-1]) != fmt.Sprint(org) {
+					t.Errorf("%s.organizacion_id se compara con %v, se esperaba %d", m[1], s.Args[n-1], org)
+				}
+				filtradas[m[1]] = true
+			}
+			for _, tipo := range ordenTipos {
+				if tabla := busquedaPorTipo[tipo].tabla; !filtradas[tabla] {
+					t.Errorf("la búsqueda de %s no filtra %s por organización", tipo, tabla)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package gestionusuarios
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/almacenamiento"
+	"api-margaritai/config"
+	"api-margaritai/consulta"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/models"
+	"api-margaritai/organizacion"
+)
+
+// TiposDocumento son los valores aceptados en el campo tipo según el registro al que se sube el documento
+var TiposDocumento = map[string][]string{
+	models.DocumentoDeEstudiante: {"acta_nacimiento", "curp", "fotografia", "certificado_estudios", "comprobante_domicilio", "otro"},
+	models.DocumentoDePersonal:   {"titulo", "cedula_profesional", "curp", "identificacion", "fotografia", "comprobante_domicilio", "otro"},
+	models.DocumentoDeContrato:   {"contrato", "anexo", "otro"},
+}
+
+// TiposMIMEDocumento son los formatos que se aceptan, detectados del contenido, con la extensión con que se guardan
+var TiposMIMEDocumento = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+}
+
+// tipoFotografia solo acepta imágenes
+const tipoFotografia = "fotografia"
+
+// ConsultaDocumentos define el orden y los filtros aceptados por ObtenerDocumentos
+var ConsultaDocumentos = consulta.Definicion{
+	Orden: map[string]string{
+		"id":         "id",
+		"nombre":     "nombre",
+		"tamano":     "tamano",
+		"created_at": "created_at",
+	},
+	OrdenPorDefecto: "-id",
+	Filtros: map[string]consulta.Filtro{
+		"tipo":       {Columna: "tipo", Tipo: consulta.Texto},
+		"nombre":     {Columna: "nombre", Tipo: consulta.Texto},
+		"created_at": {Columna: "created_at", Tipo: consulta.Fecha},
+	},
+	Llave: "id",
+}
+
+// propietarioDocumento busca el estudiante, empleado o contrato de :id en la organización de la petición
+func propietarioDocumento(c *gin.Context, entidad string) (uint, *errores.Error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, errores.SolicitudInvalida(errores.IDInvalido, "ID inválido")
+	}
+	var modelo any
+	var codigo, mensaje string
+	switch entidad {
+	case models.DocumentoDeEstudiante:
+		modelo, codigo, mensaje = &models.Estudiante{}, errores.EstudianteNoEncontrado, "Estudiante no encontrado"
+	case models.DocumentoDePersonal:
+		modelo, codigo, mensaje = &models.Personal{}, errores.PersonalNoEncontrado, "Personal no encontrado"
+	default:
+		modelo, codigo, mensaje = &models.Contrato{}, errores.ContratoNoEncontrado, "Contrato no encontrado"
+	}
+	if err := database.De(c).Select("id").First(modelo, id).Error; err != nil {
+		return 0, errores.DeConsulta(err, codigo, mensaje)
+	}
+	return uint(id), nil
+}
+
+// margenMultipart es lo que se permite en el cuerpo además del archivo: límites, encabezados y los demás campos
+const margenMultipart = 1 << 20
+
+// leerFormulario limita el cuerpo de la petición a maximo más margenMultipart antes de procesar el multipart,
+// así un archivo enorme se corta al llegar al límite en lugar de escribirse completo a disco. que nombra al
+// archivo en el mensaje del 413 ("El documento"). Si el cuerpo no es multipart no hace nada: FormFile
+// reporta después que falta el archivo.
+func leerFormulario(c *gin.Context, maximo int64, que string) *errores.Error {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maximo+margenMultipart)
+	var demasiado *http.MaxBytesError
+	if err := c.Request.ParseMultipartForm(32 << 20); errors.As(err, &demasiado) {
+		return errores.Nuevo(http.StatusRequestEntityTooLarge, errores.ArchivoDemasiadoGrande,
+			fmt.Sprintf("%s no puede pesar más de %d MB", que, maximo>>20))
+	}
+	return nil
+}
+
+// SubirDocumento recibe un archivo (multipart, campos "archivo" y "tipo") y lo vincula al registro de :id.
+// El formato se detecta del contenido, no de la extensión; se rechaza el mismo archivo subido dos veces al mismo registro.
+func SubirDocumento(entidad string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entidadID, e := propietarioDocumento(c, entidad)
+		if e != nil {
+			errores.Responder(c, e)
+			return
+		}
+
+		maximo := int64(config.GetEnvInt("DOCUMENTOS_TAMANO_MAXIMO_MB", 10)) << 20
+		if e := leerFormulario(c, maximo, "El documento"); e != nil {
+			errores.Responder(c, e)
+			return
+		}
+
+		tipo := strings.TrimSpace(c.PostForm("tipo"))
+		if !slices.Contains(TiposDocumento[entidad], tipo) {
+			errores.Responder(c, errores.CampoInvalido("tipo", "oneof", "Tipo de documento inválido; use uno de: "+strings.Join(TiposDocumento[entidad], ", ")))
+			return
+		}
+
+		archivo, err := c.FormFile("archivo")
+		if err != nil {
+			errores.Responder(c, errores.SolicitudInvalida(errores.ArchivoRequerido, "Envíe el documento en el campo archivo (multipart/form-data)"))
+			return
+		}
+		if archivo.Size > maximo {
+			errores.Responder(c, errores.Nuevo(http.StatusRequestEntityTooLarge, errores.ArchivoDemasiadoGrande,
+				fmt.Sprintf("El documento no puede pesar más de %d MB", maximo>>20)))
+			return
+		}
+		abierto, err := archivo.Open()
+		if err != nil {
+			errores.Responder(c, errores.Interno("Error abriendo el archivo recibido", err))
+			return
+		}
+		defer abierto.Close()
+		contenido, err := io.ReadAll(io.LimitReader(abierto, maximo+1))
+		if err != nil {
+			errores.Responder(c, errores.Interno("Error leyendo el archivo recibido", err))
+			return
+		}
+		if len(contenido) == 0 {
+			errores.Responder(c, errores.SolicitudInvalida(errores.ArchivoInvalido, "El archivo está vacío"))
+			return
+		}
+
+		tipoMIME, _, _ := strings.Cut(http.DetectContentType(contenido), ";")
+		extension, permitido := TiposMIMEDocumento[tipoMIME]
+		if !permitido || (tipo == tipoFotografia && !strings.HasPrefix(tipoMIME, "image/")) {
+			errores.Responder(c, errores.Nuevo(http.StatusUnsupportedMediaType, errores.TipoArchivoNoPermitido,
+				"El contenido del archivo es "+tipoMIME+"; se aceptan PDF, JPEG, PNG y WebP (solo imágenes para fotografía)"))
+			return
+		}
+
+		suma := sha256.Sum256(contenido)
+		documento := models.Documento{
+			Entidad:   entidad,
+			EntidadID: entidadID,
+			Tipo:      tipo,
+			Nombre:    nombreDocumento(archivo.Filename, extension),
+			TipoMIME:  tipoMIME,
+			Tamano:    int64(len(contenido)),
+			SHA256:    hex.EncodeToString(suma[:]),
+			UserID:    c.GetUint("user_id"),
+		}
+
+		var existente models.Documento
+		err = database.De(c).Where("entidad = ? AND entidad_id = ? AND sha256 = ?", entidad, entidadID, documento.SHA256).First(&existente).Error
+		if err == nil {
+			errores.Responder(c, errores.Conflicto(errores.DocumentoDuplicado, fmt.Sprintf("Este archivo ya se subió como el documento %d", existente.ID)))
+			return
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			errores.Responder(c, errores.BaseDatos(err, "Error revisando los documentos existentes"))
+			return
+		}
+
+		almacen, err := almacenamiento.Actual()
+		if err != nil {
+			errores.Responder(c, errores.Interno("El almacenamiento de documentos no está configurado", err))
+			return
+		}
+		aleatorio := make([]byte, 16)
+		if _, err := rand.Read(aleatorio); err != nil {
+			errores.Responder(c, errores.Interno("Error generando el nombre del archivo", err))
+			return
+		}
+		orgID, _ := organizacion.De(c)
+		documento.Llave = fmt.Sprintf("%d/%s/%d/%s%s", orgID, entidad, entidadID, hex.EncodeToString(aleatorio), extension)
+
+		if err := almacen.Guardar(c, documento.Llave, contenido, tipoMIME); err != nil {
+			errores.Responder(c, errores.Interno("Error guardando el documento", err))
+			return
+		}
+		if err := database.De(c).Create(&documento).Error; err != nil {
+			if errBorrar := almacen.Eliminar(c, documento.Llave); errBorrar != nil {
+				log.Printf("No se pudo borrar el archivo %s de un documento que no se registró: %v", documento.Llave, errBorrar)
+			}
+			errores.Responder(c, errores.BaseDatos(err, "Error registrando el documento"))
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":   "Documento subido exitosamente",
+			"documento": documento,
+		})
+	}
+}
+
+// ObtenerDocumentos lista los documentos del registro de :id
+func ObtenerDocumentos(entidad string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entidadID, e := propietarioDocumento(c, entidad)
+		if e != nil {
+			errores.Responder(c, e)
+			return
+		}
+		base := database.LecturaDe(c).Where("entidad = ? AND entidad_id = ?", entidad, entidadID)
+		documentos, paginacion, errConsulta := consulta.Listar[models.Documento](c, base, ConsultaDocumentos)
+		if errConsulta != nil {
+			errores.Responder(c, errConsulta)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Documentos obtenidos correctamente",
+			"documentos": documentos,
+			"paginacion": paginacion,
+		})
+	}
+}
+
+// ObtenerDescargaDocumento regresa una URL firmada para descargar el documento; vence después de DOCUMENTOS_VIGENCIA_URL
+func ObtenerDescargaDocumento(c *gin.Context) {
+	var documento models.Documento
+	if err := database.De(c).First(&documento, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.DocumentoNoEncontrado, "Documento no encontrado"))
+		return
+	}
+	almacen, err := almacenamiento.Actual()
+	if err != nil {
+		errores.Responder(c, errores.Interno("El almacenamiento de documentos no está configurado", err))
+		return
+	}
+	vigencia := config.GetEnvDuration("DOCUMENTOS_VIGENCIA_URL", 5*time.Minute)
+	direccion, err := almacen.URLFirmada(c, documento.Llave, documento.Nombre, vigencia)
+	if err != nil {
+		errores.Responder(c, errores.Interno("Error generando la URL de descarga", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "URL de descarga generada correctamente",
+		"documento": documento,
+		"url":       direccion,
+		"expira":    time.Now().Add(vigencia).UTC(),
+	})
+}
+
+// EliminarDocumento borra el documento y su archivo; a diferencia de los registros, no pasa por la papelera
+func EliminarDocumento(c *gin.Context) {
+	var documento models.Documento
+	if err := database.De(c).First(&documento, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.DocumentoNoEncontrado, "Documento no encontrado"))
+		return
+	}
+	almacen, err := almacenamiento.Actual()
+	if err != nil {
+		errores.Responder(c, errores.Interno("El almacenamiento de documentos no está configurado", err))
+		return
+	}
+	// Si el archivo no se puede borrar, el registro tampoco, para no dejar archivos sin dueño
+	err = database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&documento).Error; err != nil {
+			return err
+		}
+		return almacen.Eliminar(c, documento.Llave)
+	})
+	if err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error eliminando el documento"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Documento eliminado exitosamente"})
+}
+
+// DescargarDocumentoLocal entrega un archivo del almacenamiento local a quien tenga una URL firmada vigente.
+// Es pública porque la firma ya es la autorización; con S3 las URLs apuntan directo al bucket y esta ruta responde 404.
+func DescargarDocumentoLocal(c *gin.Context) {
+	almacen, err := almacenamiento.Actual()
+	if err != nil {
+		errores.Responder(c, errores.Interno("El almacenamiento de documentos no está configurado", err))
+		return
+	}
+	local, ok := almacen.(*almacenamiento.Local)
+	if !ok {
+		errores.Responder(c, errores.NoEncontrado(errores.DocumentoNoEncontrado, almacenamiento.ErrNoEsLocal.Error()))
+		return
+	}
+	nombre := c.Query("nombre")
+	ruta, err := local.Verificar(c.Query("llave"), nombre, c.Query("expira"), c.Query("firma"))
+	switch {
+	case err == nil:
+		c.FileAttachment(ruta, nombre)
+	case errors.Is(err, almacenamiento.ErrURLExpirada):
+		errores.Responder(c, errores.Prohibido(errores.URLDescargaExpirada, "La URL de descarga expiró; solicite una nueva"))
+	case errors.Is(err, almacenamiento.ErrFirmaInvalida), errors.Is(err, almacenamiento.ErrLlaveInvalida):
+		errores.Responder(c, errores.Prohibido(errores.URLDescargaInvalida, "La URL de descarga no es válida"))
+	case errors.Is(err, almacenamiento.ErrNoEncontrado):
+		errores.Responder(c, errores.NoEncontrado(errores.DocumentoNoEncontrado, "El archivo ya no existe"))
+	default:
+		errores.Responder(c, errores.Interno("Error leyendo el documento", err))
+	}
+}
+
+// EliminarDocumentosDe borra los documentos de un registro y sus archivos; se usa al purgarlo de la papelera
+func EliminarDocumentosDe(tx *gorm.DB, entidad string, entidadID uint) error {
+	var documentos []models.Documento
+	if err := tx.Where("entidad = ? AND entidad_id = ?", entidad, entidadID).Find(&documentos).Error; err != nil {
+		return err
+	}
+	if len(documentos) == 0 {
+		return nil
+	}
+	almacen, err := almacenamiento.Actual()
+	if err != nil {
+		return err
+	}
+	if err := tx.Where("entidad = ? AND entidad_id = ?", entidad, entidadID).Delete(&models.Documento{}).Error; err != nil {
+		return err
+	}
+	for _, documento := range documentos {
+		if err := almacen.Eliminar(tx.Statement.Context, documento.Llave); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nombreDocumento limpia el nombre del archivo subido y le pone la extensión del formato detectado si no la tiene
+func nombreDocumento(original, extension string) string {
+	nombre := strings.TrimSpace(filepath.Base(strings.ReplaceAll(original, "\\", "/")))
+	if nombre == "" || nombre == "." || nombre == "/" {
+		nombre = "documento"
+	}
+	if !strings.EqualFold(filepath.Ext(nombre), extension) && !(extension == ".jpg" && strings.EqualFold(filepath.Ext(nombre), ".jpeg")) {
+		nombre += extension
+	}
+	if letras := []rune(nombre); len(letras) > 255 {
+		nombre = string(letras[len(letras)-255:])
+	}
+	return nombre
+}
@@ -0,0 +1,19 @@
+package gestionusuarios
+
+import (
+	"gorm.io/gorm"
+
+	"api-margaritai/models"
+)
+
+// eliminarConUsuario manda a la papelera el registro (estudiante, personal o tutor) junto con su usuario
+// y cierra las sesiones abiertas del usuario. Se debe llamar dentro de una transacción.
+func eliminarConUsuario(tx *gorm.DB, registro any, userID uint) error {
+	if err := tx.Delete(registro).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&models.User{}, userID).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&models.Session{}).Error
+}
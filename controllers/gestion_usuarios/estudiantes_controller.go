@@ -1,132 +1,288 @@
 package gestionusuarios
 
 import (
+	"api-margaritai/concurrencia"
+	"api-margaritai/consulta"
+	"api-margaritai/curp"
 	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/eventos"
+	"api-margaritai/exportacion"
 	"api-margaritai/models"
+	"api-margaritai/parche"
+	"api-margaritai/validadores"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// ObtenerEstudiantes obtiene todos los estudiantes con su usuario relacionado
-func ObtenerEstudiantes(c *gin.Context) {
-	var estudiantes []models.Estudiante
+// UserInput son los datos del usuario requeridos al crear un estudiante
+type UserInput struct {
+	Nombre    string `json:"nombre" binding:"required"`
+	ApellidoP string `json:"apellido_p" binding:"required"`
+	ApellidoM string `json:"apellido_m" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	CURP      string `json:"curp" binding:"required,curp"`
+	Password  string `json:"password" binding:"required"`
+	FechaNac  string `json:"fecha_nac"` // Formato YYYY-MM-DD; si se omite se toma de la CURP
+	GeneroID  uint   `json:"genero_id"` // si se omite se toma del sexo de la CURP
+	RolID     uint   `json:"rol_id" binding:"required"`
+}
+
+// EstudianteInput son los datos académicos requeridos al crear un estudiante
+type EstudianteInput struct {
+	Matricula         string `json:"matricula" binding:"required"`
+	Nacionalidad      string `json:"nacionalidad" binding:"required"`
+	FechaNacimiento   string `json:"fecha_nacimiento"` // YYYY-MM-DD; si se omite se toma de la CURP
+	EdoOrigen         string `json:"edo_origen"`       // si se omite se toma de la entidad de la CURP
+	MpioOrigen        string `json:"mpio_origen" binding:"required"`
+	EdoCivil          string `json:"edo_civil" binding:"required"`
+	Telefono          string `json:"telefono" binding:"required,telefono_mx"`
+	PlantelID         uint   `json:"plantel_id" binding:"required"`
+	NivelEscolarID    uint   `json:"nivel_escolar_id" binding:"required"`
+	GrupoID           uint   `json:"grupo_id" binding:"required"`
+	EnProcesoAdmision *bool  `json:"en_proceso_admision"`
+}
+
+// InsertarEstudianteInput es el cuerpo de POST /estudiantes: datos de usuario y estudiante en el mismo nivel
+type InsertarEstudianteInput struct {
+	UserInput
+	EstudianteInput
+}
+
+// UserUpdateInput son los datos del usuario editables de un estudiante; los campos vacíos se ignoran
+type UserUpdateInput struct {
+	Nombre    string `json:"nombre"`
+	ApellidoP string `json:"apellido_p"`
+	ApellidoM string `json:"apellido_m"`
+	Email     string `json:"email"`
+	CURP      string `json:"curp" binding:"omitempty,curp"`
+	Password  string `json:"password"`
+	FechaNac  string `json:"fecha_nac"`
+	GeneroID  *uint  `json:"genero_id"`
+	RolID     *uint  `json:"rol_id"`
+}
 
-	if err := database.DB.Preload("User").Find(&estudiantes).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener los estudiantes"})
+// EstudianteUpdateInput son los datos académicos editables de un estudiante
+type EstudianteUpdateInput struct {
+	Matricula         string `json:"matricula"`
+	Nacionalidad      string `json:"nacionalidad"`
+	FechaNacimiento   string `json:"fecha_nacimiento"` // YYYY-MM-DD
+	EdoOrigen         string `json:"edo_origen"`
+	MpioOrigen        string `json:"mpio_origen"`
+	EdoCivil          string `json:"edo_civil"`
+	Telefono          string `json:"telefono" binding:"omitempty,telefono_mx"`
+	PlantelID         *uint  `json:"plantel_id"`
+	NivelEscolarID    *uint  `json:"nivel_escolar_id"`
+	GrupoID           *uint  `json:"grupo_id"`
+	EnProcesoAdmision *bool  `json:"en_proceso_admision"`
+}
+
+// EditarEstudianteInput es el cuerpo de PUT /estudiantes/:id
+type EditarEstudianteInput struct {
+	UserUpdateInput
+	EstudianteUpdateInput
+}
+
+// normalizar deja CURP y teléfono en el formato en que se guardan
+func (in *InsertarEstudianteInput) normalizar() {
+	in.Email = strings.TrimSpace(in.Email)
+	in.CURP = validadores.NormalizarCURP(in.CURP)
+	in.Telefono = validadores.NormalizarTelefono(in.Telefono)
+}
+
+// normalizar deja CURP y teléfono en el formato en que se guardan
+func (in *EditarEstudianteInput) normalizar() {
+	in.Email = strings.TrimSpace(in.Email)
+	in.CURP = validadores.NormalizarCURP(in.CURP)
+	in.Telefono = validadores.NormalizarTelefono(in.Telefono)
+}
+
+// ConsultaEstudiantes define el orden y los filtros aceptados por ObtenerEstudiantes
+var ConsultaEstudiantes = consulta.Definicion{
+	Orden: map[string]string{
+		"id":         "estudiantes.id",
+		"matricula":  "estudiantes.matricula",
+		"nombre":     "users.nombre",
+		"apellido_p": "users.apellido_p",
+		"created_at": "estudiantes.created_at",
+		"updated_at": "estudiantes.updated_at",
+	},
+	OrdenPorDefecto: "id",
+	Filtros: map[string]consulta.Filtro{
+		"plantel_id":          {Columna: "estudiantes.plantel_id", Tipo: consulta.Entero},
+		"nivel_escolar_id":    {Columna: "estudiantes.nivel_escolar_id", Tipo: consulta.Entero},
+		"grupo_id":            {Columna: "estudiantes.grupo_id", Tipo: consulta.Entero},
+		"en_proceso_admision": {Columna: "estudiantes.en_proceso_admision", Tipo: consulta.Booleano},
+		"matricula":           {Columna: "estudiantes.matricula", Tipo: consulta.Texto},
+		"rol_id":              {Columna: "users.rol_id", Tipo: consulta.Entero},
+		"genero_id":           {Columna: "users.genero_id", Tipo: consulta.Entero},
+		"es_activo":           {Columna: "users.es_activo", Tipo: consulta.Booleano},
+		"created_at":          {Columna: "estudiantes.created_at", Tipo: consulta.Fecha},
+	},
+	Llave:     "estudiantes.id",
+	Precargar: []string{"User"},
+	Incluir: map[string]string{
+		"user":          "User",
+		"user.genero":   "User.Genero",
+		"user.rol":      "User.Rol",
+		"plantel":       "Plantel",
+		"nivel_escolar": "NivelEscolar",
+		"grupo":         "Grupo",
+		"tutores":       "EstudianteTutores.Tutor",
+	},
+	Campos: consulta.CamposDe(models.Estudiante{}),
+}
+
+// ObtenerEstudiantes obtiene los estudiantes con su usuario relacionado, paginados y filtrados según la query string
+func ObtenerEstudiantes(c *gin.Context) {
+	base := database.LecturaDe(c).Joins("JOIN users ON users.id = estudiantes.user_id AND users.deleted_at IS NULL")
+	estudiantes, paginacion, errConsulta := consulta.Listar[models.Estudiante](c, base, ConsultaEstudiantes)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":     "Estudiantes obtenidos correctamente",
-		"estudiantes": estudiantes,
+		"estudiantes": ConsultaEstudiantes.Proyectar(c, estudiantes),
+		"paginacion":  paginacion,
+	})
+}
+
+// columnasEstudiantes son las columnas de ExportarEstudiantes
+var columnasEstudiantes = []exportacion.Columna[models.Estudiante]{
+	{Titulo: "ID", Ancho: 6, Valor: func(e *models.Estudiante) string { return exportacion.Entero(e.ID) }},
+	{Titulo: "Matrícula", Ancho: 12, Valor: func(e *models.Estudiante) string { return e.Matricula }},
+	{Titulo: "Nombre", Ancho: 30, Valor: func(e *models.Estudiante) string {
+		return exportacion.NombreCompleto(e.User.Nombre, e.User.ApellidoP, e.User.ApellidoM)
+	}},
+	{Titulo: "CURP", Ancho: 20, Valor: func(e *models.Estudiante) string { return e.User.CURP }},
+	{Titulo: "Email", Ancho: 28, Valor: func(e *models.Estudiante) string { return e.User.Email }},
+	{Titulo: "Teléfono", Ancho: 12, Valor: func(e *models.Estudiante) string { return e.Telefono }},
+	{Titulo: "Fecha de nacimiento", Ancho: 12, Valor: func(e *models.Estudiante) string { return exportacion.Fecha(e.User.FechaNac) }},
+	{Titulo: "Plantel", Ancho: 18, Valor: func(e *models.Estudiante) string { return e.Plantel.Nombre }},
+	{Titulo: "Nivel escolar", Ancho: 16, Valor: func(e *models.Estudiante) string { return e.NivelEscolar.Titulo }},
+	{Titulo: "Grupo", Ancho: 10, Valor: func(e *models.Estudiante) string { return e.Grupo.Titulo }},
+	{Titulo: "En proceso de admisión", Ancho: 8, Valor: func(e *models.Estudiante) string { return exportacion.SiNo(e.EnProcesoAdmision) }},
+	{Titulo: "Activo", Ancho: 6, Valor: func(e *models.Estudiante) string { return exportacion.SiNo(e.User.EsActivo) }},
+}
+
+// ExportarEstudiantes descarga el listado de estudiantes como CSV, XLSX o PDF con los mismos filtros que ObtenerEstudiantes
+func ExportarEstudiantes(c *gin.Context) {
+	base := database.LecturaDe(c).Joins("JOIN users ON users.id = estudiantes.user_id AND users.deleted_at IS NULL")
+	def := ConsultaEstudiantes.ConPrecarga("Plantel", "NivelEscolar", "Grupo")
+	exportacion.Responder(c, base, def, "estudiantes", "Estudiantes", columnasEstudiantes)
+}
+
+// ObtenerEstudiante regresa un estudiante con las mismas relaciones que el listado, o las de ?include=.
+// El ETag de la respuesta es el valor que se envía en If-Match al editarlo.
+func ObtenerEstudiante(c *gin.Context) {
+	sel, errSel := ConsultaEstudiantes.Seleccionar(c)
+	if errSel != nil {
+		errores.Responder(c, errSel)
+		return
+	}
+	var estudiante models.Estudiante
+	if err := sel.Consulta(database.De(c)).First(&estudiante, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.EstudianteNoEncontrado, "Estudiante no encontrado"))
+		return
+	}
+
+	concurrencia.EscribirETag(c, estudiante.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Estudiante obtenido correctamente",
+		"estudiante": sel.Proyectar(estudiante),
 	})
 }
 
 // InsertarEstudiante crea un usuario y un estudiante asociado con control avanzado de errores
 func InsertarEstudiante(c *gin.Context) {
-	type UserInput struct {
-		Nombre    string `json:"nombre" binding:"required"`
-		ApellidoP string `json:"apellido_p" binding:"required"`
-		ApellidoM string `json:"apellido_m" binding:"required"`
-		Email     string `json:"email" binding:"required,email"`
-		CURP      string `json:"curp" binding:"required"`
-		Password  string `json:"password" binding:"required"`
-		FechaNac  string `json:"fecha_nac" binding:"required"` // Formato YYYY-MM-DD
-		GeneroID  uint   `json:"genero_id" binding:"required"`
-		RolID     uint   `json:"rol_id" binding:"required"`
-	}
-
-	type EstudianteInput struct {
-		Matricula         string `json:"matricula" binding:"required"`
-		Nacionalidad      string `json:"nacionalidad" binding:"required"`
-		FechaNacimiento   string `json:"fecha_nacimiento" binding:"required"` // YYYY-MM-DD
-		EdoOrigen         string `json:"edo_origen" binding:"required"`
-		MpioOrigen        string `json:"mpio_origen" binding:"required"`
-		EdoCivil          string `json:"edo_civil" binding:"required"`
-		Telefono          string `json:"telefono" binding:"required"`
-		PlantelID         uint   `json:"plantel_id" binding:"required"`
-		NivelEscolarID    uint   `json:"nivel_escolar_id" binding:"required"`
-		GrupoID           uint   `json:"grupo_id" binding:"required"`
-		EnProcesoAdmision *bool  `json:"en_proceso_admision"`
-	}
-
-	var input struct {
-		UserInput
-		EstudianteInput
-	}
+	var input InsertarEstudianteInput
 
 	// Manejo detallado de errores de bind
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Estructura de datos inválida",
-			"details": err.Error(),
-		})
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+	input.normalizar()
+
+	// Completar con la CURP los datos personales que no se enviaron
+	faltantes := curp.Faltantes{
+		FechaNac:        &input.UserInput.FechaNac,
+		GeneroID:        &input.GeneroID,
+		FechaNacimiento: &input.EstudianteInput.FechaNacimiento,
+		EdoOrigen:       &input.EdoOrigen,
+	}
+	if err := curp.Completar(c, input.CURP, faltantes); err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo datos de la CURP", err))
 		return
 	}
 
 	// Check unicidad de email y curp con error específico
 	var count int64
-	if tx := database.DB.Model(&models.User{}).
+	if tx := database.De(c).Model(&models.User{}).
 		Where("email = ?", input.Email).
 		Or("curp = ?", input.CURP).
 		Count(&count); tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error al verificar unicidad de usuario",
-			"details": tx.Error.Error(),
-		})
+		errores.Responder(c, errores.Interno("Error al verificar unicidad de usuario", tx.Error))
 		return
 	}
 	if count > 0 {
 		// Determinar duplicado exacto
 		var existingUser models.User
-		e := database.DB.Where("email = ?", input.Email).First(&existingUser)
+		e := database.De(c).Where("email = ?", input.Email).First(&existingUser)
 		if e.Error == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "El email ya existe."})
+			errores.Responder(c, errores.Conflicto(errores.EmailDuplicado, "El email ya existe."))
 			return
 		}
-		e = database.DB.Where("curp = ?", input.CURP).First(&existingUser)
+		e = database.De(c).Where("curp = ?", input.CURP).First(&existingUser)
 		if e.Error == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "La CURP ya existe."})
+			errores.Responder(c, errores.Conflicto(errores.CURPDuplicada, "La CURP ya existe."))
 			return
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": "El email o CURP ya existe."})
+		errores.Responder(c, errores.Conflicto(errores.RegistroDuplicado, "El email o CURP ya existe."))
 		return
 	}
 
 	// Check unicidad matrícula
-	if tx := database.DB.Model(&models.Estudiante{}).
+	if tx := database.De(c).Model(&models.Estudiante{}).
 		Where("matricula = ?", input.Matricula).
 		Count(&count); tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error al verificar unicidad de matrícula",
-			"details": tx.Error.Error(),
-		})
+		errores.Responder(c, errores.Interno("Error al verificar unicidad de matrícula", tx.Error))
 		return
 	}
 	if count > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "La matrícula ya existe.",
-		})
+		errores.Responder(c, errores.Conflicto(errores.MatriculaDuplicada, "La matrícula ya existe."))
 		return
 	}
 
 	// Parse fechas user y estudiante
 	fechaNacUser, err := time.Parse("2006-01-02", input.UserInput.FechaNac)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Formato de fecha_nac inválido (user): utilice YYYY-MM-DD",
-			"details": err.Error(),
-		})
+		errores.Responder(c, errores.SolicitudInvalida(errores.FechaInvalida, "Formato de fecha_nac inválido (user): utilice YYYY-MM-DD"))
 		return
 	}
 	fechaNacEstudiante, err := time.Parse("2006-01-02", input.EstudianteInput.FechaNacimiento)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Formato de fecha_nacimiento inválido (estudiante): utilice YYYY-MM-DD",
-			"details": err.Error(),
-		})
+		errores.Responder(c, errores.SolicitudInvalida(errores.FechaInvalida, "Formato de fecha_nacimiento inválido (estudiante): utilice YYYY-MM-DD"))
+		return
+	}
+
+	// Cruzar fechas, género y entidad de origen con la CURP
+	advertencias, errCURP := curp.Verificar(c, input.CURP, curp.Capturados{
+		FechaNac:        fechaNacUser,
+		GeneroID:        input.GeneroID,
+		FechaNacimiento: fechaNacEstudiante,
+		EdoOrigen:       input.EdoOrigen,
+	})
+	if errCURP != nil {
+		errores.Responder(c, errCURP)
 		return
 	}
 
@@ -143,31 +299,22 @@ func InsertarEstudiante(c *gin.Context) {
 		EsActivo:  true,
 	}
 	if err := user.HashPassword(input.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error al hashear el password.",
-			"details": err.Error(),
-		})
+		errores.Responder(c, errores.Interno("Error al hashear el password.", err))
 		return
 	}
 
 	// Transaccion para atomicidad entre usuario y estudiante
-	tx := database.DB.Begin()
+	tx := database.De(c).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Error inesperado al crear estudiante.",
-				"details": r,
-			})
+			errores.Responder(c, errores.Interno("Error inesperado al crear estudiante.", fmt.Errorf("panic: %v", r)))
 		}
 	}()
 
 	if err := tx.Create(&user).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error al guardar el usuario en base de datos.",
-			"details": err.Error(),
-		})
+		errores.Responder(c, errores.BaseDatos(err, "Error al guardar el usuario en base de datos."))
 		return
 	}
 
@@ -193,36 +340,44 @@ func InsertarEstudiante(c *gin.Context) {
 	if err := tx.Create(&est).Error; err != nil {
 		tx.Rollback()
 		// Intentar limpiar el usuario insertado
-		database.DB.Unscoped().Delete(&user)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error al guardar al estudiante en base de datos.",
-			"details": err.Error(),
-		})
+		database.De(c).Unscoped().Delete(&user)
+		errores.Responder(c, errores.BaseDatos(err, "Error al guardar al estudiante en base de datos."))
 		return
 	}
 
 	// Pre-cargar datos y responder
 	if err := tx.Preload("User").First(&est, est.ID).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error al recuperar datos del estudiante insertado.",
-			"details": err.Error(),
-		})
+		errores.Responder(c, errores.Interno("Error al recuperar datos del estudiante insertado.", err))
+		return
+	}
+
+	// El evento se guarda en la misma transacción: solo se publica si el estudiante quedó creado
+	if err := eventos.Registrar(tx, eventos.EstudianteCreado, est.ID, eventos.DeEstudiante(&est)); err != nil {
+		tx.Rollback()
+		errores.Responder(c, errores.Interno("Error al registrar el evento del estudiante.", err))
+		return
+	}
+
+	if err := inscribirEnCicloDelGrupo(tx, &est); err != nil {
+		tx.Rollback()
+		errores.Responder(c, errores.BaseDatos(err, "Error al inscribir al estudiante en el ciclo de su grupo."))
 		return
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error al finalizar la transacción.",
-			"details": err.Error(),
-		})
+		errores.Responder(c, errores.Interno("Error al finalizar la transacción.", err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	respuesta := gin.H{
 		"message":    "Estudiante creado correctamente",
 		"estudiante": est,
-	})
+	}
+	if len(advertencias) > 0 {
+		respuesta["advertencias"] = advertencias
+	}
+	c.JSON(http.StatusCreated, respuesta)
 }
 
 // EditarEstudiante edita los datos del estudiante y su usuario
@@ -230,46 +385,21 @@ func EditarEstudiante(c *gin.Context) {
 	id := c.Param("id")
 	var estudiante models.Estudiante
 
-	if err := database.DB.Preload("User").First(&estudiante, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Estudiante no encontrado"})
+	if err := database.De(c).Preload("User").First(&estudiante, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.EstudianteNoEncontrado, "Estudiante no encontrado"))
 		return
 	}
-
-	// ASUMIMOS exactamente el mismo input que al crear
-	type UserInput struct {
-		Nombre    string `json:"nombre"`
-		ApellidoP string `json:"apellido_p"`
-		ApellidoM string `json:"apellido_m"`
-		Email     string `json:"email"`
-		CURP      string `json:"curp"`
-		Password  string `json:"password"`
-		FechaNac  string `json:"fecha_nac"`
-		GeneroID  *uint  `json:"genero_id"`
-		RolID     *uint  `json:"rol_id"`
-	}
-
-	type EstudianteInput struct {
-		Matricula         string `json:"matricula"`
-		Nacionalidad      string `json:"nacionalidad"`
-		FechaNacimiento   string `json:"fecha_nacimiento"` // YYYY-MM-DD
-		EdoOrigen         string `json:"edo_origen"`
-		MpioOrigen        string `json:"mpio_origen"`
-		EdoCivil          string `json:"edo_civil"`
-		Telefono          string `json:"telefono"`
-		PlantelID         *uint  `json:"plantel_id"`
-		NivelEscolarID    *uint  `json:"nivel_escolar_id"`
-		GrupoID           *uint  `json:"grupo_id"`
-		EnProcesoAdmision *bool  `json:"en_proceso_admision"`
-	}
-
-	var input struct {
-		UserInput
-		EstudianteInput
+	if !concurrencia.Verificar(c, estudiante.Version, estudiante) {
+		return
 	}
+
+	var input EditarEstudianteInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Estructura de datos inválida", "details": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
+	input.normalizar()
+	grupoAnterior := estudiante.GrupoID
 
 	// Actualización selectiva de campos del usuario
 	user := &estudiante.User
@@ -284,18 +414,18 @@ func EditarEstudiante(c *gin.Context) {
 	}
 	if input.Email != "" && input.Email != user.Email {
 		var count int64
-		database.DB.Model(&models.User{}).Where("email = ? AND id <> ?", input.Email, user.ID).Count(&count)
+		database.De(c).Model(&models.User{}).Where("email = ? AND id <> ?", input.Email, user.ID).Count(&count)
 		if count > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "El email ya existe para otro usuario"})
+			errores.Responder(c, errores.Conflicto(errores.EmailDuplicado, "El email ya existe para otro usuario"))
 			return
 		}
 		user.Email = input.Email
 	}
 	if input.CURP != "" && input.CURP != user.CURP {
 		var count int64
-		database.DB.Model(&models.User{}).Where("curp = ? AND id <> ?", input.CURP, user.ID).Count(&count)
+		database.De(c).Model(&models.User{}).Where("curp = ? AND id <> ?", input.CURP, user.ID).Count(&count)
 		if count > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "La CURP ya existe para otro usuario"})
+			errores.Responder(c, errores.Conflicto(errores.CURPDuplicada, "La CURP ya existe para otro usuario"))
 			return
 		}
 		user.CURP = input.CURP
@@ -303,14 +433,14 @@ func EditarEstudiante(c *gin.Context) {
 	if input.FechaNac != "" {
 		fecha, err := time.Parse("2006-01-02", input.FechaNac)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de fecha_nac inválido (user)"})
+			errores.Responder(c, errores.SolicitudInvalida(errores.FechaInvalida, "Formato de fecha_nac inválido (user)"))
 			return
 		}
 		user.FechaNac = fecha
 	}
 	if input.Password != "" {
 		if err := user.HashPassword(input.Password); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al hashear password"})
+			errores.Responder(c, errores.Interno("Error al hashear password", err))
 			return
 		}
 	}
@@ -324,9 +454,9 @@ func EditarEstudiante(c *gin.Context) {
 	// Actualización selectiva del estudiante
 	if input.Matricula != "" && input.Matricula != estudiante.Matricula {
 		var count int64
-		database.DB.Model(&models.Estudiante{}).Where("matricula = ? AND id <> ?", input.Matricula, estudiante.ID).Count(&count)
+		database.De(c).Model(&models.Estudiante{}).Where("matricula = ? AND id <> ?", input.Matricula, estudiante.ID).Count(&count)
 		if count > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "La matrícula ya existe para otro estudiante"})
+			errores.Responder(c, errores.Conflicto(errores.MatriculaDuplicada, "La matrícula ya existe para otro estudiante"))
 			return
 		}
 		estudiante.Matricula = input.Matricula
@@ -337,7 +467,7 @@ func EditarEstudiante(c *gin.Context) {
 	if input.FechaNacimiento != "" {
 		fecha, err := time.Parse("2006-01-02", input.FechaNacimiento)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de fecha_nacimiento inválido (estudiante)"})
+			errores.Responder(c, errores.SolicitudInvalida(errores.FechaInvalida, "Formato de fecha_nacimiento inválido (estudiante)"))
 			return
 		}
 		estudiante.FechaNacimiento = fecha
@@ -367,48 +497,148 @@ func EditarEstudiante(c *gin.Context) {
 		estudiante.EnProcesoAdmision = *input.EnProcesoAdmision
 	}
 
-	// Guardar usuario y luego estudiante
-	if err := database.DB.Save(user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar el usuario"})
-		return
+	// Si se tocó algún dato derivable de la CURP, cruzar el resultado final contra ella
+	var advertencias []curp.Discrepancia
+	if input.CURP != "" || input.FechaNac != "" || input.GeneroID != nil || input.FechaNacimiento != "" || input.EdoOrigen != "" {
+		var errCURP *errores.Error
+		advertencias, errCURP = curp.Verificar(c, user.CURP, curp.Capturados{
+			FechaNac:        user.FechaNac,
+			GeneroID:        user.GeneroID,
+			FechaNacimiento: estudiante.FechaNacimiento,
+			EdoOrigen:       estudiante.EdoOrigen,
+		})
+		if errCURP != nil {
+			errores.Responder(c, errCURP)
+			return
+		}
 	}
-	if err := database.DB.Save(&estudiante).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar el estudiante"})
+
+	// Guardar estudiante y usuario juntos; si alguien más editó el estudiante no se guarda nada
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := concurrencia.Guardar(tx, &estudiante); err != nil {
+			return err
+		}
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		if estudiante.GrupoID == grupoAnterior {
+			return nil
+		}
+		if err := inscribirEnCicloDelGrupo(tx, &estudiante); err != nil {
+			return err
+		}
+		return eventos.Registrar(tx, eventos.EstudianteGrupoCambiado, estudiante.ID, eventos.CambioDeGrupo{
+			Estudiante:      eventos.DeEstudiante(&estudiante),
+			GrupoAnteriorID: grupoAnterior,
+		})
+	})
+	if err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &estudiante, "Error al actualizar el estudiante", "User"))
 		return
 	}
 
 	// Responder con el estudiante actualizado
-	database.DB.Preload("User").First(&estudiante, estudiante.ID)
-	c.JSON(http.StatusOK, gin.H{
+	database.De(c).Preload("User").First(&estudiante, estudiante.ID)
+	concurrencia.EscribirETag(c, estudiante.Version)
+	respuesta := gin.H{
 		"message":    "Estudiante actualizado correctamente",
 		"estudiante": estudiante,
-	})
+	}
+	if len(advertencias) > 0 {
+		respuesta["advertencias"] = advertencias
+	}
+	c.JSON(http.StatusOK, respuesta)
 }
 
-// EliminarEstudiante elimina el estudiante y el usuario asociado
-func EliminarEstudiante(c *gin.Context) {
-	id := c.Param("id")
+// ParchearEstudiante aplica un JSON Merge Patch (o JSON Patch) a los campos de EstudianteParche y a su usuario
+func ParchearEstudiante(c *gin.Context) {
 	var estudiante models.Estudiante
+	if err := database.De(c).Preload("User").First(&estudiante, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.EstudianteNoEncontrado, "Estudiante no encontrado"))
+		return
+	}
+	if !concurrencia.Verificar(c, estudiante.Version, estudiante) {
+		return
+	}
+
+	var input EstudianteParche
+	p, errParche := parche.Leer(c, &input, &estudiante)
+	if errParche != nil {
+		errores.Responder(c, errParche)
+		return
+	}
+	input.normalizar()
+	grupoAnterior := estudiante.GrupoID
+	p.Aplicar(&estudiante)
+
+	advertencias, errCURP := verificarCURPParche(c, p, &estudiante.User, curp.Capturados{
+		FechaNacimiento: estudiante.FechaNacimiento,
+		EdoOrigen:       estudiante.EdoOrigen,
+	})
+	if errCURP != nil {
+		errores.Responder(c, errCURP)
+		return
+	}
 
-	// Primero, obtener el estudiante con su UserID
-	if err := database.DB.First(&estudiante, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Estudiante no encontrado"})
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := concurrencia.Guardar(tx, &estudiante); err != nil {
+			return err
+		}
+		if p.Toca("user") {
+			if err := tx.Omit(clause.Associations).Save(&estudiante.User).Error; err != nil {
+				return err
+			}
+		}
+		if estudiante.GrupoID == grupoAnterior {
+			return nil
+		}
+		if err := inscribirEnCicloDelGrupo(tx, &estudiante); err != nil {
+			return err
+		}
+		return eventos.Registrar(tx, eventos.EstudianteGrupoCambiado, estudiante.ID, eventos.CambioDeGrupo{
+			Estudiante:      eventos.DeEstudiante(&estudiante),
+			GrupoAnteriorID: grupoAnterior,
+		})
+	})
+	if err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &estudiante, "Error al actualizar el estudiante", "User"))
 		return
 	}
 
-	userID := estudiante.UserID
+	database.De(c).Preload("User").First(&estudiante, estudiante.ID)
+	concurrencia.EscribirETag(c, estudiante.Version)
+	respuesta := gin.H{
+		"message":    "Estudiante actualizado correctamente",
+		"estudiante": estudiante,
+	}
+	if len(advertencias) > 0 {
+		respuesta["advertencias"] = advertencias
+	}
+	c.JSON(http.StatusOK, respuesta)
+}
+
+// EliminarEstudiante manda a la papelera el estudiante y el usuario asociado
+func EliminarEstudiante(c *gin.Context) {
+	id := c.Param("id")
+	var estudiante models.Estudiante
 
-	// Eliminar el estudiante
-	if err := database.DB.Delete(&estudiante).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al eliminar estudiante"})
+	// Primero, obtener el estudiante con su usuario (el evento lleva sus datos)
+	if err := database.De(c).Preload("User").First(&estudiante, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.EstudianteNoEncontrado, "Estudiante no encontrado"))
 		return
 	}
 
-	// Eliminar el usuario asociado
-	if err := database.DB.Delete(&models.User{}, userID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Estudiante eliminado, pero error al eliminar usuario"})
+	// El estudiante y su usuario van juntos a la papelera; se pueden restaurar desde /papelera
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := eventos.Registrar(tx, eventos.EstudianteEliminado, estudiante.ID, eventos.DeEstudiante(&estudiante)); err != nil {
+			return err
+		}
+		return eliminarConUsuario(tx, &estudiante, estudiante.UserID)
+	})
+	if err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error al eliminar estudiante"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Estudiante y usuario asociados eliminados correctamente"})
+	c.JSON(http.StatusOK, gin.H{"message": "Estudiante y usuario asociados enviados a la papelera"})
 }
@@ -0,0 +1,909 @@
+package gestionusuarios
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+
+	"api-margaritai/config"
+	"api-margaritai/curp"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/eventos"
+	"api-margaritai/models"
+	"api-margaritai/organizacion"
+	"api-margaritai/tablas"
+	"api-margaritai/validadores"
+)
+
+// TipoImportacionEstudiantes es el valor de Importacion.Tipo para las cargas de estudiantes
+const TipoImportacionEstudiantes = "estudiantes"
+
+// Columnas del archivo de importación; son los mismos campos que recibe POST /estudiantes.
+// Las columnas tutor_* son opcionales y vinculan al estudiante con un tutor existente (por tutor_curp) o nuevo.
+var (
+	ColumnasImportacionEstudiantes = []string{
+		"nombre", "apellido_p", "apellido_m", "email", "curp", "password", "fecha_nac", "genero_id", "rol_id",
+		"matricula", "nacionalidad", "fecha_nacimiento", "edo_origen", "mpio_origen", "edo_civil", "telefono",
+		"plantel_id", "nivel_escolar_id", "grupo_id", "en_proceso_admision",
+	}
+	ColumnasImportacionTutores = []string{
+		"tutor_nombre", "tutor_apellido_p", "tutor_apellido_m", "tutor_email", "tutor_curp", "tutor_password",
+		"tutor_fecha_nac", "tutor_genero_id", "tutor_rol_id", "tutor_telefono", "tutor_telefono2",
+	}
+	columnasImportacionObligatorias = []string{
+		"nombre", "apellido_p", "apellido_m", "email", "curp", "password", "rol_id",
+		"matricula", "nacionalidad", "mpio_origen", "edo_civil", "telefono", "plantel_id", "nivel_escolar_id", "grupo_id",
+	}
+)
+
+// TutorImportacionInput son las columnas tutor_* de una fila. Con solo tutor_curp se vincula un tutor existente;
+// para crear uno nuevo también se requieren nombre, apellido, email, password y rol.
+type TutorImportacionInput struct {
+	Nombre    string `json:"tutor_nombre"`
+	ApellidoP string `json:"tutor_apellido_p"`
+	ApellidoM string `json:"tutor_apellido_m"`
+	Email     string `json:"tutor_email" binding:"omitempty,email"`
+	CURP      string `json:"tutor_curp" binding:"required,curp"`
+	Password  string `json:"tutor_password"`
+	FechaNac  string `json:"tutor_fecha_nac"`
+	GeneroID  uint   `json:"tutor_genero_id"`
+	RolID     uint   `json:"tutor_rol_id"`
+	Telefono  string `json:"tutor_telefono" binding:"omitempty,telefono_mx"`
+	Telefono2 string `json:"tutor_telefono2" binding:"omitempty,telefono_mx"`
+}
+
+// filaImportacion es una fila del archivo convertida a los datos de POST /estudiantes
+type filaImportacion struct {
+	numero          int
+	valores         map[string]string
+	estudiante      InsertarEstudianteInput
+	tutor           *TutorImportacionInput
+	fechaNac        time.Time
+	fechaNacimiento time.Time
+	tutorFechaNac   time.Time
+	problemas       []models.ProblemaFila
+	advertencias    []models.ProblemaFila
+}
+
+func (f *filaImportacion) problema(campo, codigo, mensaje string) {
+	f.problemas = append(f.problemas, models.ProblemaFila{Fila: f.numero, Campo: campo, Codigo: codigo, Mensaje: mensaje})
+}
+
+// ImportarEstudiantes da de alta estudiantes, y opcionalmente a sus tutores, desde un archivo CSV o XLSX
+// (multipart, campo "archivo"). Con dry_run=true solo valida y regresa el reporte por fila. Sin dry_run guarda
+// las filas válidas en una sola transacción; si el archivo tiene más de IMPORTACION_FILAS_SINCRONAS filas
+// responde 202 y lo procesa en segundo plano, con el avance en GET /importaciones/:id.
+func ImportarEstudiantes(c *gin.Context) {
+	simulacion, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		errores.Responder(c, errores.CampoInvalido("dry_run", "boolean", "Debe ser true o false"))
+		return
+	}
+
+	maximo := int64(config.GetEnvInt("IMPORTACION_TAMANO_MAXIMO_MB", 10)) << 20
+	if e := leerFormulario(c, maximo, "El archivo"); e != nil {
+		errores.Responder(c, e)
+		return
+	}
+	archivo, err := c.FormFile("archivo")
+	if err != nil {
+		errores.Responder(c, errores.SolicitudInvalida(errores.ArchivoRequerido, "Envíe el archivo CSV o XLSX en el campo archivo (multipart/form-data)"))
+		return
+	}
+	if archivo.Size > maximo {
+		errores.Responder(c, errores.Nuevo(http.StatusRequestEntityTooLarge, errores.ArchivoDemasiadoGrande,
+			fmt.Sprintf("El archivo no puede pesar más de %d MB", maximo>>20)))
+		return
+	}
+	abierto, err := archivo.Open()
+	if err != nil {
+		errores.Responder(c, errores.Interno("Error abriendo el archivo recibido", err))
+		return
+	}
+	defer abierto.Close()
+	contenido, err := io.ReadAll(abierto)
+	if err != nil {
+		errores.Responder(c, errores.Interno("Error leyendo el archivo recibido", err))
+		return
+	}
+
+	// Se deja de leer al pasar del máximo (más el encabezado) para no cargar completa una hoja enorme
+	maxFilas := config.GetEnvInt("IMPORTACION_MAX_FILAS", 2000)
+	hoja, err := tablas.Leer(archivo.Filename, contenido, maxFilas+1)
+	if errors.Is(err, tablas.ErrDemasiadasFilas) {
+		errores.Responder(c, errores.SolicitudInvalida(errores.ArchivoInvalido, fmt.Sprintf("El archivo tiene más de %d filas; el máximo por importación es %d", maxFilas, maxFilas)))
+		return
+	}
+	if err != nil {
+		errores.Responder(c, errores.SolicitudInvalida(errores.ArchivoInvalido, "No se pudo leer el archivo: "+err.Error()))
+		return
+	}
+	filas, errFilas := leerFilasImportacion(hoja, maxFilas)
+	if errFilas != nil {
+		errores.Responder(c, errFilas)
+		return
+	}
+
+	importacion := models.Importacion{
+		UserID:     c.GetUint("user_id"),
+		Tipo:       TipoImportacionEstudiantes,
+		Archivo:    archivo.Filename,
+		Simulacion: simulacion,
+		Estado:     models.ImportacionPendiente,
+		TotalFilas: len(filas),
+	}
+	if err := database.De(c).Create(&importacion).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error registrando la importación"))
+		return
+	}
+
+	// La validación es rápida; lo lento es cifrar las contraseñas, así que solo las cargas grandes van a segundo plano
+	if simulacion || len(filas) <= config.GetEnvInt("IMPORTACION_FILAS_SINCRONAS", 10) {
+		procesarImportacion(c, &importacion, filas)
+		mensaje := "Importación completada"
+		if simulacion {
+			mensaje = "Validación completada; no se guardó ningún registro"
+		}
+		c.JSON(http.StatusOK, gin.H{"message": mensaje, "importacion": importacion})
+		return
+	}
+
+	respuesta := importacion
+	orgID, _ := organizacion.De(c)
+	encolarImportacion(orgID, &importacion, filas)
+	c.Header("Location", "/api/protected/importaciones/"+strconv.FormatUint(uint64(respuesta.ID), 10))
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "La importación se está procesando; consulte su avance en la URL del header Location",
+		"importacion": respuesta,
+	})
+}
+
+// ObtenerImportacion regresa el estado, el avance y el reporte por fila de una importación
+func ObtenerImportacion(c *gin.Context) {
+	var importacion models.Importacion
+	if err := database.De(c).First(&importacion, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.ImportacionNoEncontrada, "Importación no encontrada"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Importación obtenida correctamente",
+		"importacion": importacion,
+	})
+}
+
+// leerFilasImportacion valida el encabezado y convierte cada fila no vacía en un mapa columna -> valor; rechaza
+// el archivo si tiene más de maxFilas filas de datos
+func leerFilasImportacion(hoja [][]string, maxFilas int) ([]*filaImportacion, *errores.Error) {
+	if len(hoja) == 0 {
+		return nil, errores.SolicitudInvalida(errores.ArchivoInvalido, "El archivo está vacío")
+	}
+
+	conocidas := map[string]bool{}
+	for _, columna := range append(append([]string{}, ColumnasImportacionEstudiantes...), ColumnasImportacionTutores...) {
+		conocidas[columna] = true
+	}
+	encabezado := make([]string, len(hoja[0]))
+	presentes := map[string]bool{}
+	var campos []errores.CampoError
+	for i, titulo := range hoja[0] {
+		columna := normalizarColumna(titulo)
+		if columna == "" {
+			continue
+		}
+		if !conocidas[columna] {
+			campos = append(campos, errores.CampoError{Campo: titulo, Regla: "columna", Mensaje: "Columna desconocida"})
+			continue
+		}
+		if presentes[columna] {
+			campos = append(campos, errores.CampoError{Campo: titulo, Regla: "columna", Mensaje: "Columna repetida"})
+			continue
+		}
+		encabezado[i] = columna
+		presentes[columna] = true
+	}
+	for _, columna := range columnasImportacionObligatorias {
+		if !presentes[columna] {
+			campos = append(campos, errores.CampoError{Campo: columna, Regla: "required", Mensaje: "Falta la columna obligatoria"})
+		}
+	}
+	if len(campos) > 0 {
+		e := errores.SolicitudInvalida(errores.ColumnasInvalidas, "El encabezado del archivo no coincide con las columnas de importación")
+		e.Campos = campos
+		return nil, e
+	}
+
+	var filas []*filaImportacion
+	for i, celdas := range hoja[1:] {
+		valores := map[string]string{}
+		for j, valor := range celdas {
+			if j < len(encabezado) && encabezado[j] != "" {
+				if valor = strings.TrimSpace(valor); valor != "" {
+					valores[encabezado[j]] = valor
+				}
+			}
+		}
+		if len(valores) > 0 {
+			filas = append(filas, &filaImportacion{numero: i + 2, valores: valores})
+		}
+	}
+	if len(filas) == 0 {
+		return nil, errores.SolicitudInvalida(errores.ArchivoInvalido, "El archivo no tiene filas de datos")
+	}
+	if len(filas) > maxFilas {
+		return nil, errores.SolicitudInvalida(errores.ArchivoInvalido, fmt.Sprintf("El archivo tiene %d filas; el máximo por importación es %d", len(filas), maxFilas))
+	}
+	return filas, nil
+}
+
+var sinAcentos = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// normalizarColumna permite encabezados como "Apellido P" o "Matrícula"
+func normalizarColumna(titulo string) string {
+	titulo = sinAcentos.Replace(strings.ToLower(strings.TrimSpace(titulo)))
+	return strings.NewReplacer(" ", "_", "-", "_", ".", "").Replace(titulo)
+}
+
+// procesarImportacion valida todas las filas y, si no es simulación, guarda las válidas. Actualiza el registro
+// de la importación al avanzar; nunca entra en pánico porque puede correr fuera de una petición.
+func procesarImportacion(ctx context.Context, importacion *models.Importacion, filas []*filaImportacion) {
+	defer func() {
+		if r := recover(); r != nil {
+			terminarImportacion(ctx, importacion, models.ImportacionFallida, fmt.Sprintf("Error inesperado: %v", r))
+		}
+	}()
+
+	ahora := time.Now()
+	importacion.Estado = models.ImportacionProcesando
+	importacion.IniciadaEn = &ahora
+	database.De(ctx).Model(importacion).Updates(map[string]any{"estado": importacion.Estado, "iniciada_en": ahora})
+
+	validas, err := validarFilasImportacion(ctx, filas)
+	if err != nil {
+		terminarImportacion(ctx, importacion, models.ImportacionFallida, "Error validando las filas contra la base de datos")
+		log.Printf("Importación %d: %v", importacion.ID, err)
+		return
+	}
+	importacion.FilasValidas = len(validas)
+
+	if !importacion.Simulacion && len(validas) > 0 {
+		importadas, err := guardarFilasImportacion(ctx, importacion, validas)
+		if err != nil {
+			reunirProblemas(importacion, filas)
+			terminarImportacion(ctx, importacion, models.ImportacionFallida, "No se pudo confirmar la transacción; no se guardó ninguna fila")
+			log.Printf("Importación %d: %v", importacion.ID, err)
+			return
+		}
+		importacion.FilasImportadas = importadas
+	}
+	importacion.FilasProcesadas = len(filas)
+	reunirProblemas(importacion, filas)
+	terminarImportacion(ctx, importacion, models.ImportacionCompletada, "")
+}
+
+func reunirProblemas(importacion *models.Importacion, filas []*filaImportacion) {
+	importacion.Errores = []models.ProblemaFila{}
+	importacion.Advertencias = []models.ProblemaFila{}
+	for _, f := range filas {
+		importacion.Errores = append(importacion.Errores, f.problemas...)
+		importacion.Advertencias = append(importacion.Advertencias, f.advertencias...)
+	}
+}
+
+func terminarImportacion(ctx context.Context, importacion *models.Importacion, estado, mensaje string) {
+	ahora := time.Now()
+	importacion.Estado = estado
+	importacion.Mensaje = mensaje
+	importacion.TerminadaEn = &ahora
+	if err := database.De(ctx).Select("*").Omit("created_at").Updates(importacion).Error; err != nil {
+		log.Printf("Error guardando el resultado de la importación %d: %v", importacion.ID, err)
+	}
+}
+
+// validarFilasImportacion revisa cada fila con las mismas reglas que POST /estudiantes, los duplicados dentro
+// del archivo y contra la base, y que existan plantel, nivel, grupo, rol y género. Regresa las filas sin errores.
+func validarFilasImportacion(ctx context.Context, filas []*filaImportacion) ([]*filaImportacion, error) {
+	for _, f := range filas {
+		if err := convertirFilaImportacion(ctx, f); err != nil {
+			return nil, err
+		}
+	}
+	marcarDuplicadosEnArchivo(filas)
+	if err := validarContraBase(ctx, filas); err != nil {
+		return nil, err
+	}
+
+	var validas []*filaImportacion
+	for _, f := range filas {
+		if len(f.problemas) == 0 {
+			validas = append(validas, f)
+		}
+	}
+	return validas, nil
+}
+
+// convertirFilaImportacion llena los inputs con los valores de la fila, completa con la CURP y aplica el binding
+func convertirFilaImportacion(ctx context.Context, f *filaImportacion) error {
+	in := &f.estudiante
+	in.Nombre = f.valores["nombre"]
+	in.ApellidoP = f.valores["apellido_p"]
+	in.ApellidoM = f.valores["apellido_m"]
+	in.Email = f.valores["email"]
+	in.CURP = f.valores["curp"]
+	in.Password = f.valores["password"]
+	in.FechaNac = f.fecha("fecha_nac")
+	in.GeneroID = f.entero("genero_id")
+	in.RolID = f.entero("rol_id")
+	in.Matricula = f.valores["matricula"]
+	in.Nacionalidad = f.valores["nacionalidad"]
+	in.FechaNacimiento = f.fecha("fecha_nacimiento")
+	in.EdoOrigen = f.valores["edo_origen"]
+	in.MpioOrigen = f.valores["mpio_origen"]
+	in.EdoCivil = f.valores["edo_civil"]
+	in.Telefono = f.valores["telefono"]
+	in.PlantelID = f.entero("plantel_id")
+	in.NivelEscolarID = f.entero("nivel_escolar_id")
+	in.GrupoID = f.entero("grupo_id")
+	in.EnProcesoAdmision = f.booleano("en_proceso_admision")
+	in.normalizar()
+
+	faltantes := curp.Faltantes{FechaNac: &in.FechaNac, GeneroID: &in.GeneroID, FechaNacimiento: &in.FechaNacimiento, EdoOrigen: &in.EdoOrigen}
+	if err := curp.Completar(ctx, in.CURP, faltantes); err != nil {
+		return err
+	}
+	f.validar(in)
+	f.fechaNac, _ = time.Parse("2006-01-02", in.FechaNac)
+	f.fechaNacimiento, _ = time.Parse("2006-01-02", in.FechaNacimiento)
+
+	if !f.tieneTutor() {
+		return nil
+	}
+	t := &TutorImportacionInput{
+		Nombre:    f.valores["tutor_nombre"],
+		ApellidoP: f.valores["tutor_apellido_p"],
+		ApellidoM: f.valores["tutor_apellido_m"],
+		Email:     strings.TrimSpace(f.valores["tutor_email"]),
+		CURP:      validadores.NormalizarCURP(f.valores["tutor_curp"]),
+		Password:  f.valores["tutor_password"],
+		FechaNac:  f.fecha("tutor_fecha_nac"),
+		GeneroID:  f.entero("tutor_genero_id"),
+		RolID:     f.entero("tutor_rol_id"),
+		Telefono:  validadores.NormalizarTelefono(f.valores["tutor_telefono"]),
+		Telefono2: validadores.NormalizarTelefono(f.valores["tutor_telefono2"]),
+	}
+	if err := curp.Completar(ctx, t.CURP, curp.Faltantes{FechaNac: &t.FechaNac, GeneroID: &t.GeneroID}); err != nil {
+		return err
+	}
+	f.validar(t)
+	f.tutorFechaNac, _ = time.Parse("2006-01-02", t.FechaNac)
+	f.tutor = t
+	return nil
+}
+
+func (f *filaImportacion) tieneTutor() bool {
+	for _, columna := range ColumnasImportacionTutores {
+		if f.valores[columna] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// validar aplica las reglas binding del input; cada campo inválido es un problema de la fila
+func (f *filaImportacion) validar(input any) {
+	err := binding.Validator.ValidateStruct(input)
+	if err == nil {
+		return
+	}
+	for _, campo := range errores.Validacion(err).Campos {
+		// Las columnas son planas: "UserInput.email" se reporta como "email"
+		nombre := campo.Campo[strings.LastIndex(campo.Campo, ".")+1:]
+		if _, yaReportado := f.buscarProblema(nombre); !yaReportado {
+			f.problema(nombre, errores.ValidacionFallida, campo.Mensaje)
+		}
+	}
+}
+
+func (f *filaImportacion) buscarProblema(campo string) (models.ProblemaFila, bool) {
+	for _, p := range f.problemas {
+		if p.Campo == campo {
+			return p, true
+		}
+	}
+	return models.ProblemaFila{}, false
+}
+
+// fecha acepta YYYY-MM-DD, DD/MM/YYYY y el número de serie con que Excel guarda las fechas
+func (f *filaImportacion) fecha(columna string) string {
+	valor := f.valores[columna]
+	if valor == "" {
+		return ""
+	}
+	for _, formato := range []string{"2006-01-02", "2/1/2006", "2-1-2006"} {
+		if t, err := time.Parse(formato, valor); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	if t, ok := tablas.FechaDeSerie(valor); ok {
+		return t.Format("2006-01-02")
+	}
+	f.problema(columna, errores.FechaInvalida, "Fecha inválida: utilice YYYY-MM-DD o DD/MM/YYYY")
+	return ""
+}
+
+func (f *filaImportacion) entero(columna string) uint {
+	valor := f.valores[columna]
+	if valor == "" {
+		return 0
+	}
+	n, err := strconv.ParseUint(strings.TrimSuffix(valor, ".0"), 10, 32) // Excel puede guardar 3 como 3.0
+	if err != nil {
+		f.problema(columna, errores.ValidacionFallida, "Debe ser un número entero positivo")
+		return 0
+	}
+	return uint(n)
+}
+
+func (f *filaImportacion) booleano(columna string) *bool {
+	valor := sinAcentos.Replace(strings.ToLower(f.valores[columna]))
+	switch valor {
+	case "":
+		return nil
+	case "1", "true", "si", "verdadero":
+		v := true
+		return &v
+	case "0", "false", "no", "falso":
+		v := false
+		return &v
+	}
+	f.problema(columna, errores.ValidacionFallida, "Debe ser sí o no")
+	return nil
+}
+
+// marcarDuplicadosEnArchivo reporta emails, CURPs y matrículas repetidos; la primera aparición se conserva
+func marcarDuplicadosEnArchivo(filas []*filaImportacion) {
+	vistos := map[string]int{}
+	revisar := func(f *filaImportacion, campo, valor, codigo string) {
+		if valor == "" {
+			return
+		}
+		llave := campo + ":" + strings.ToLower(valor)
+		if primera, ok := vistos[llave]; ok {
+			f.problema(campo, codigo, fmt.Sprintf("Repetido en el archivo; ya aparece en la fila %d", primera))
+			return
+		}
+		vistos[llave] = f.numero
+	}
+	for _, f := range filas {
+		revisar(f, "email", f.estudiante.Email, errores.EmailDuplicado)
+		revisar(f, "curp", f.estudiante.CURP, errores.CURPDuplicada)
+		revisar(f, "matricula", f.estudiante.Matricula, errores.MatriculaDuplicada)
+	}
+	// Un mismo tutor puede aparecer en varias filas (hermanos), pero su CURP no puede ser la de un estudiante del archivo
+	for _, f := range filas {
+		if f.tutor != nil {
+			if primera, ok := vistos["curp:"+strings.ToLower(f.tutor.CURP)]; ok {
+				f.problema("tutor_curp", errores.CURPDuplicada, fmt.Sprintf("Es la CURP del estudiante de la fila %d", primera))
+			}
+		}
+	}
+}
+
+// usuarioExistente es un usuario que ya ocupa un email o CURP, incluso si está en la papelera
+type usuarioExistente struct {
+	ID        uint
+	Email     string
+	CURP      string
+	DeletedAt gorm.DeletedAt
+}
+
+func (u usuarioExistente) sufijo() string {
+	if u.DeletedAt.Valid {
+		return " (el registro está en la papelera)"
+	}
+	return ""
+}
+
+// validarContraBase consulta en lote los registros que las filas referencian o podrían duplicar
+func validarContraBase(ctx context.Context, filas []*filaImportacion) error {
+	var emails, curps, matriculas []string
+	ids := map[string]map[uint]bool{"planteles": {}, "niveles_escolares": {}, "grupos": {}, "roles": {}, "generos": {}}
+	for _, f := range filas {
+		in := f.estudiante
+		emails = append(emails, in.Email)
+		curps = append(curps, in.CURP)
+		matriculas = append(matriculas, in.Matricula)
+		ids["planteles"][in.PlantelID] = true
+		ids["niveles_escolares"][in.NivelEscolarID] = true
+		ids["grupos"][in.GrupoID] = true
+		ids["roles"][in.RolID] = true
+		ids["generos"][in.GeneroID] = true
+		if f.tutor != nil {
+			emails = append(emails, f.tutor.Email)
+			curps = append(curps, f.tutor.CURP)
+			ids["roles"][f.tutor.RolID] = true
+			ids["generos"][f.tutor.GeneroID] = true
+		}
+	}
+
+	// Los índices únicos no cubren los registros en la papelera: un email, CURP o matrícula borrado se puede reutilizar
+	var usuarios []usuarioExistente
+	if err := database.De(ctx).Model(&models.User{}).
+		Where("email IN ? OR curp IN ?", emails, curps).
+		Find(&usuarios).Error; err != nil {
+		return err
+	}
+	porEmail := map[string]usuarioExistente{}
+	porCURP := map[string]usuarioExistente{}
+	for _, u := range usuarios {
+		porEmail[strings.ToLower(u.Email)] = u
+		porCURP[u.CURP] = u
+	}
+	var matriculasOcupadas []string
+	if err := database.De(ctx).Model(&models.Estudiante{}).Where("matricula IN ?", matriculas).Pluck("matricula", &matriculasOcupadas).Error; err != nil {
+		return err
+	}
+	ocupadas := map[string]bool{}
+	for _, m := range matriculasOcupadas {
+		ocupadas[m] = true
+	}
+
+	// Tutores existentes de los usuarios encontrados por CURP
+	tutoresPorUsuario := map[uint]uint{}
+	var tutores []models.Tutor
+	if err := database.De(ctx).Select("id", "user_id").Where("user_id IN ?", idsDeUsuarios(usuarios)).Find(&tutores).Error; err != nil {
+		return err
+	}
+	for _, t := range tutores {
+		tutoresPorUsuario[t.UserID] = t.ID
+	}
+
+	var niveles []models.NivelEscolar
+	var grupos []models.Grupo
+	var planteles, roles, generos []uint
+	consultas := []error{
+		database.De(ctx).Model(&models.Plantel{}).Where("id IN ?", llaves(ids["planteles"])).Pluck("id", &planteles).Error,
+		database.De(ctx).Select("id", "plantel_id").Where("id IN ?", llaves(ids["niveles_escolares"])).Find(&niveles).Error,
+		database.De(ctx).Select("id", "nivel_escolar_id").Where("id IN ?", llaves(ids["grupos"])).Find(&grupos).Error,
+		database.De(ctx).Model(&models.Rol{}).Where("id IN ?", llaves(ids["roles"])).Pluck("id", &roles).Error,
+		database.De(ctx).Model(&models.Genero{}).Where("id IN ?", llaves(ids["generos"])).Pluck("id", &generos).Error,
+	}
+	for _, err := range consultas {
+		if err != nil {
+			return err
+		}
+	}
+	existePlantel, existeRol, existeGenero := conjunto(planteles), conjunto(roles), conjunto(generos)
+	plantelDeNivel := map[uint]uint{}
+	for _, n := range niveles {
+		plantelDeNivel[n.ID] = n.PlantelID
+	}
+	nivelDeGrupo := map[uint]uint{}
+	for _, g := range grupos {
+		nivelDeGrupo[g.ID] = g.NivelEscolarID
+	}
+
+	for _, f := range filas {
+		in := f.estudiante
+		if u, ok := porEmail[strings.ToLower(in.Email)]; ok && in.Email != "" {
+			f.problema("email", errores.EmailDuplicado, "El email ya está registrado"+u.sufijo())
+		}
+		if u, ok := porCURP[in.CURP]; ok && in.CURP != "" {
+			f.problema("curp", errores.CURPDuplicada, "La CURP ya está registrada"+u.sufijo())
+		}
+		if ocupadas[in.Matricula] {
+			f.problema("matricula", errores.MatriculaDuplicada, "La matrícula ya existe")
+		}
+		if in.PlantelID != 0 && !existePlantel[in.PlantelID] {
+			f.problema("plantel_id", errores.PlantelNoEncontrado, "El plantel no existe")
+		}
+		if plantel, ok := plantelDeNivel[in.NivelEscolarID]; in.NivelEscolarID != 0 && !ok {
+			f.problema("nivel_escolar_id", errores.NivelEscolarNoEncontrado, "El nivel escolar no existe")
+		} else if ok && in.PlantelID != 0 && plantel != in.PlantelID {
+			f.problema("nivel_escolar_id", errores.ReferenciaInvalida, "El nivel escolar no pertenece al plantel indicado")
+		}
+		if nivel, ok := nivelDeGrupo[in.GrupoID]; in.GrupoID != 0 && !ok {
+			f.problema("grupo_id", errores.GrupoNoEncontrado, "El grupo no existe")
+		} else if ok && in.NivelEscolarID != 0 && nivel != in.NivelEscolarID {
+			f.problema("grupo_id", errores.ReferenciaInvalida, "El grupo no pertenece al nivel escolar indicado")
+		}
+		if in.RolID != 0 && !existeRol[in.RolID] {
+			f.problema("rol_id", errores.RolNoEncontrado, "El rol no existe")
+		}
+		if in.GeneroID != 0 && !existeGenero[in.GeneroID] {
+			f.problema("genero_id", errores.GeneroNoEncontrado, "El género no existe")
+		}
+		if err := compararConCURP(ctx, f, "", in.CURP, curp.Capturados{
+			FechaNac: f.fechaNac, GeneroID: in.GeneroID, FechaNacimiento: f.fechaNacimiento, EdoOrigen: in.EdoOrigen,
+		}); err != nil {
+			return err
+		}
+
+		if f.tutor == nil || f.tutor.CURP == "" {
+			continue
+		}
+		t := f.tutor
+		if u, ok := porCURP[t.CURP]; ok {
+			// Tutor existente: solo se vincula, los demás datos tutor_* se ignoran
+			if _, esTutor := tutoresPorUsuario[u.ID]; !esTutor || u.DeletedAt.Valid {
+				f.problema("tutor_curp", errores.TutorNoEncontrado, "La CURP pertenece a un usuario que no es un tutor activo")
+			}
+			continue
+		}
+		validarTutorNuevo(f, porEmail, existeRol, existeGenero)
+		if err := compararConCURP(ctx, f, "tutor_", t.CURP, curp.Capturados{FechaNac: f.tutorFechaNac, GeneroID: t.GeneroID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validarTutorNuevo revisa los datos necesarios para crear un tutor que todavía no existe
+func validarTutorNuevo(f *filaImportacion, porEmail map[string]usuarioExistente, existeRol, existeGenero map[uint]bool) {
+	t := f.tutor
+	requeridos := map[string]string{
+		"tutor_nombre": t.Nombre, "tutor_apellido_p": t.ApellidoP, "tutor_email": t.Email, "tutor_password": t.Password,
+	}
+	for _, columna := range []string{"tutor_nombre", "tutor_apellido_p", "tutor_email", "tutor_password"} {
+		if requeridos[columna] == "" {
+			f.problema(columna, errores.CampoRequerido, "Obligatorio cuando el tutor no está registrado")
+		}
+	}
+	if t.RolID == 0 {
+		f.problema("tutor_rol_id", errores.CampoRequerido, "Obligatorio cuando el tutor no está registrado")
+	} else if !existeRol[t.RolID] {
+		f.problema("tutor_rol_id", errores.RolNoEncontrado, "El rol no existe")
+	}
+	if t.GeneroID != 0 && !existeGenero[t.GeneroID] {
+		f.problema("tutor_genero_id", errores.GeneroNoEncontrado, "El género no existe")
+	}
+	if u, ok := porEmail[strings.ToLower(t.Email)]; ok && t.Email != "" {
+		f.problema("tutor_email", errores.EmailDuplicado, "El email ya está registrado"+u.sufijo())
+	}
+}
+
+// compararConCURP aplica CURP_DISCREPANCIAS: en modo error la discrepancia invalida la fila, si no es advertencia
+func compararConCURP(ctx context.Context, f *filaImportacion, prefijo, valor string, capturados curp.Capturados) error {
+	datos, err := curp.Parsear(valor)
+	if err != nil {
+		return nil // la estructura de la CURP ya se reportó en la validación
+	}
+	discrepancias, err := datos.Comparar(ctx, capturados)
+	if err != nil {
+		return err
+	}
+	for _, d := range discrepancias {
+		p := models.ProblemaFila{
+			Fila:    f.numero,
+			Campo:   prefijo + d.Campo,
+			Codigo:  errores.CURPInconsistente,
+			Mensaje: "No coincide con la CURP (se esperaba " + d.Esperado + ", se capturó " + d.Actual + ")",
+		}
+		if curp.Modo() == curp.ModoError {
+			f.problemas = append(f.problemas, p)
+		} else {
+			f.advertencias = append(f.advertencias, p)
+		}
+	}
+	return nil
+}
+
+// registrosImportacion son los modelos listos para insertar; las contraseñas se cifran antes de abrir la transacción
+type registrosImportacion struct {
+	fila       *filaImportacion
+	usuario    models.User
+	estudiante models.Estudiante
+}
+
+type tutorImportacion struct {
+	usuario models.User
+	tutor   models.Tutor
+}
+
+// guardarFilasImportacion inserta las filas válidas en una transacción; cada fila va en un savepoint para que
+// una restricción violada (por ejemplo un email registrado mientras corría la importación) solo descarte esa fila
+func guardarFilasImportacion(ctx context.Context, importacion *models.Importacion, validas []*filaImportacion) (int, error) {
+	registros := make([]registrosImportacion, 0, len(validas))
+	tutoresNuevos := map[string]*tutorImportacion{}
+	for i, f := range validas {
+		r, err := prepararRegistros(f, tutoresNuevos)
+		if err != nil {
+			f.problema("password", errores.ErrorInterno, "No se pudo cifrar la contraseña")
+			continue
+		}
+		registros = append(registros, r)
+		if (i+1)%10 == 0 {
+			database.De(ctx).Model(importacion).Update("filas_procesadas", i+1)
+		}
+	}
+
+	// Tutores ya registrados, por CURP
+	tutoresPorCURP := map[string]uint{}
+	var curpsTutores []string
+	for _, f := range validas {
+		if f.tutor != nil && tutoresNuevos[f.tutor.CURP] == nil {
+			curpsTutores = append(curpsTutores, f.tutor.CURP)
+		}
+	}
+	if len(curpsTutores) > 0 {
+		var existentes []struct {
+			ID   uint
+			CURP string
+		}
+		if err := database.De(ctx).Model(&models.Tutor{}).Select("tutors.id", "users.curp").
+			Joins("JOIN users ON users.id = tutors.user_id AND users.deleted_at IS NULL").
+			Where("users.curp IN ?", curpsTutores).Scan(&existentes).Error; err != nil {
+			return 0, err
+		}
+		for _, t := range existentes {
+			tutoresPorCURP[t.CURP] = t.ID
+		}
+	}
+
+	importadas := 0
+	err := database.De(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, r := range registros {
+			creados := map[string]uint{}
+			err := tx.Transaction(func(fila *gorm.DB) error {
+				return insertarRegistros(fila, r, tutoresNuevos, tutoresPorCURP, creados)
+			})
+			if err != nil {
+				e := errores.BaseDatos(err, "Error guardando la fila")
+				r.fila.problema("", e.Codigo, e.Mensaje)
+				continue
+			}
+			for curpTutor, id := range creados {
+				tutoresPorCURP[curpTutor] = id
+			}
+			importadas++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return importadas, nil
+}
+
+func prepararRegistros(f *filaImportacion, tutoresNuevos map[string]*tutorImportacion) (registrosImportacion, error) {
+	in := f.estudiante
+	usuario := models.User{
+		Nombre:    in.Nombre,
+		ApellidoP: in.ApellidoP,
+		ApellidoM: in.ApellidoM,
+		Email:     in.Email,
+		CURP:      in.CURP,
+		FechaNac:  f.fechaNac,
+		GeneroID:  in.GeneroID,
+		RolID:     in.RolID,
+		EsActivo:  true,
+	}
+	if err := usuario.HashPassword(in.Password); err != nil {
+		return registrosImportacion{}, err
+	}
+	enProceso := true
+	if in.EnProcesoAdmision != nil {
+		enProceso = *in.EnProcesoAdmision
+	}
+	estudiante := models.Estudiante{
+		Matricula:         in.Matricula,
+		Nacionalidad:      in.Nacionalidad,
+		FechaNacimiento:   f.fechaNacimiento,
+		EdoOrigen:         in.EdoOrigen,
+		MpioOrigen:        in.MpioOrigen,
+		EdoCivil:          in.EdoCivil,
+		Telefono:          in.Telefono,
+		PlantelID:         in.PlantelID,
+		NivelEscolarID:    in.NivelEscolarID,
+		GrupoID:           in.GrupoID,
+		EnProcesoAdmision: enProceso,
+	}
+
+	// Un tutor nuevo que aparece en varias filas se prepara con los datos de la primera
+	if t := f.tutor; t != nil && t.Password != "" && tutoresNuevos[t.CURP] == nil {
+		nuevo := &tutorImportacion{
+			usuario: models.User{
+				Nombre:    t.Nombre,
+				ApellidoP: t.ApellidoP,
+				ApellidoM: t.ApellidoM,
+				Email:     t.Email,
+				CURP:      t.CURP,
+				FechaNac:  f.tutorFechaNac,
+				GeneroID:  t.GeneroID,
+				RolID:     t.RolID,
+				EsActivo:  true,
+			},
+			tutor: models.Tutor{
+				Nombre:    strings.TrimSpace(t.Nombre + " " + t.ApellidoP + " " + t.ApellidoM),
+				Telefono:  t.Telefono,
+				Telefono2: t.Telefono2,
+			},
+		}
+		if err := nuevo.usuario.HashPassword(t.Password); err != nil {
+			return registrosImportacion{}, err
+		}
+		tutoresNuevos[t.CURP] = nuevo
+	}
+	return registrosImportacion{fila: f, usuario: usuario, estudiante: estudiante}, nil
+}
+
+// insertarRegistros crea usuario y estudiante y, si la fila lo indica, crea o vincula al tutor
+func insertarRegistros(tx *gorm.DB, r registrosImportacion, tutoresNuevos map[string]*tutorImportacion, tutoresPorCURP, creados map[string]uint) error {
+	usuario, estudiante := r.usuario, r.estudiante
+	if err := tx.Create(&usuario).Error; err != nil {
+		return err
+	}
+	estudiante.UserID = usuario.ID
+	if err := tx.Create(&estudiante).Error; err != nil {
+		return err
+	}
+	estudiante.User = usuario
+	if err := eventos.Registrar(tx, eventos.EstudianteCreado, estudiante.ID, eventos.DeEstudiante(&estudiante)); err != nil {
+		return err
+	}
+	if err := inscribirEnCicloDelGrupo(tx, &estudiante); err != nil {
+		return err
+	}
+	if r.fila.tutor == nil {
+		return nil
+	}
+
+	curpTutor := r.fila.tutor.CURP
+	tutorID, ok := tutoresPorCURP[curpTutor]
+	if !ok {
+		// Copias: si la fila se descarta, la siguiente fila del mismo tutor vuelve a intentar crearlo
+		nuevo := tutoresNuevos[curpTutor]
+		if nuevo == nil {
+			return fmt.Errorf("tutor %s no preparado", curpTutor)
+		}
+		usuarioTutor, tutor := nuevo.usuario, nuevo.tutor
+		if err := tx.Create(&usuarioTutor).Error; err != nil {
+			return err
+		}
+		tutor.UserID = usuarioTutor.ID
+		if err := tx.Create(&tutor).Error; err != nil {
+			return err
+		}
+		tutorID = tutor.ID
+		creados[curpTutor] = tutorID
+	}
+	return tx.Create(&models.EstudianteTutor{EstudianteID: estudiante.ID, TutorID: tutorID}).Error
+}
+
+func idsDeUsuarios(usuarios []usuarioExistente) []uint {
+	ids := make([]uint, 0, len(usuarios))
+	for _, u := range usuarios {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func llaves(m map[uint]bool) []uint {
+	ids := make([]uint, 0, len(m))
+	for id := range m {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func conjunto(ids []uint) map[uint]bool {
+	m := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		m[id] = true
+	}
+	return m
+}
@@ -0,0 +1,99 @@
+package gestionusuarios
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"api-margaritai/config"
+	"api-margaritai/database"
+	"api-margaritai/models"
+	"api-margaritai/organizacion"
+)
+
+// latidoImportacion es cada cuánto una importación en segundo plano actualiza su updated_at; si deja de
+// hacerlo por varios latidos es que su instancia se detuvo
+const latidoImportacion = time.Minute
+
+var (
+	importacionesEnCurso sync.WaitGroup
+	turnosOnce           sync.Once
+	turnosImportacion    chan struct{}
+)
+
+// turnos limita las importaciones que se procesan a la vez en esta instancia a IMPORTACION_EN_PARALELO;
+// las demás esperan como pendientes
+func turnos() chan struct{} {
+	turnosOnce.Do(func() {
+		turnosImportacion = make(chan struct{}, max(config.GetEnvInt("IMPORTACION_EN_PARALELO", 2), 1))
+	})
+	return turnosImportacion
+}
+
+// encolarImportacion procesa la importación fuera de la petición. Queda registrada para que EsperarImportaciones
+// la deje terminar al apagar el servidor, y mientras espera turno o se procesa reporta su latido.
+func encolarImportacion(orgID uint, importacion *models.Importacion, filas []*filaImportacion) {
+	// La petición termina antes que la importación: se conserva solo la organización, no su cancelación
+	ctx := organizacion.Con(context.Background(), orgID)
+	importacionesEnCurso.Add(1)
+	go func() {
+		defer importacionesEnCurso.Done()
+		listo := make(chan struct{})
+		defer close(listo)
+		go latir(ctx, importacion.ID, listo)
+
+		turnos() <- struct{}{}
+		defer func() { <-turnosImportacion }()
+		procesarImportacion(ctx, importacion, filas)
+	}()
+}
+
+// latir actualiza updated_at de la importación hasta que se cierre listo
+func latir(ctx context.Context, id uint, listo <-chan struct{}) {
+	t := time.NewTicker(latidoImportacion)
+	defer t.Stop()
+	for {
+		select {
+		case <-listo:
+			return
+		case <-t.C:
+			if err := database.De(ctx).Model(&models.Importacion{}).
+				Where("id = ? AND estado IN ?", id, []string{models.ImportacionPendiente, models.ImportacionProcesando}).
+				Update("updated_at", time.Now()).Error; err != nil {
+				log.Printf("Importación %d: error registrando el avance: %v", id, err)
+			}
+		}
+	}
+}
+
+// EsperarImportaciones espera a que terminen las importaciones en segundo plano de esta instancia, o a que
+// venza ctx. Se llama al apagar el servidor; las que no alcancen a terminar las marca después
+// MarcarImportacionesInterrumpidas.
+func EsperarImportaciones(ctx context.Context) error {
+	terminadas := make(chan struct{})
+	go func() {
+		importacionesEnCurso.Wait()
+		close(terminadas)
+	}()
+	select {
+	case <-terminadas:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// MarcarImportacionesInterrumpidas marca las importaciones pendientes o en proceso que llevan varios latidos
+// sin avance: su instancia se detuvo, ya no van a terminar y su transacción no se confirmó. La ejecuta la
+// tarea marcar_importaciones_interrumpidas; ctx debe ver todas las organizaciones.
+func MarcarImportacionesInterrumpidas(ctx context.Context) (int64, error) {
+	resultado := database.De(ctx).Model(&models.Importacion{}).
+		Where("estado IN ? AND updated_at < ?", []string{models.ImportacionPendiente, models.ImportacionProcesando}, time.Now().Add(-5*latidoImportacion)).
+		Updates(map[string]any{
+			"estado":       models.ImportacionInterrumpida,
+			"mensaje":      "El servidor se detuvo antes de terminar; no se guardó ninguna fila. Vuelva a subir el archivo",
+			"terminada_en": time.Now(),
+		})
+	return resultado.RowsAffected, resultado.Error
+}
@@ -0,0 +1,255 @@
+package gestionusuarios
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"api-margaritai/consulta"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/eventos"
+	"api-margaritai/models"
+)
+
+// InscripcionInput es el cuerpo de POST /ciclos_escolares/:id/inscripciones. Si el estudiante ya estaba
+// inscrito en el ciclo, se cambia de grupo.
+type InscripcionInput struct {
+	EstudianteID uint `json:"estudiante_id" binding:"required"`
+	GrupoID      uint `json:"grupo_id" binding:"required"`
+}
+
+// ConsultaInscripciones define el orden y los filtros de los listados de inscripciones
+var ConsultaInscripciones = consulta.Definicion{
+	Orden: map[string]string{
+		"id":         "id",
+		"grupo_id":   "grupo_id",
+		"created_at": "created_at",
+	},
+	OrdenPorDefecto: "id",
+	Filtros: map[string]consulta.Filtro{
+		"grupo_id":         {Columna: "grupo_id", Tipo: consulta.Entero},
+		"estudiante_id":    {Columna: "estudiante_id", Tipo: consulta.Entero},
+		"ciclo_escolar_id": {Columna: "ciclo_escolar_id", Tipo: consulta.Entero},
+	},
+	Llave:     "id",
+	Precargar: []string{"Estudiante.User", "Grupo", "CicloEscolar"},
+}
+
+// guardarInscripcion crea la inscripción del estudiante en el ciclo o, si ya existía, le cambia el grupo
+func guardarInscripcion(tx *gorm.DB, inscripcion *models.Inscripcion) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "ciclo_escolar_id"}, {Name: "estudiante_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"grupo_id", "updated_at"}),
+	}).Create(inscripcion).Error
+}
+
+// inscribirEnCicloDelGrupo mantiene la inscripción al día cuando se asigna un grupo al crear o editar un
+// estudiante: si el grupo pertenece a un ciclo que no está cerrado, el estudiante queda inscrito en él
+func inscribirEnCicloDelGrupo(tx *gorm.DB, estudiante *models.Estudiante) error {
+	var grupo models.Grupo
+	if err := tx.Select("id", "ciclo_escolar_id").First(&grupo, estudiante.GrupoID).Error; err != nil {
+		return err
+	}
+	if grupo.CicloEscolarID == nil {
+		return nil
+	}
+	var ciclo models.CicloEscolar
+	if err := tx.Select("id", "estatus").First(&ciclo, *grupo.CicloEscolarID).Error; err != nil {
+		return err
+	}
+	if ciclo.Estatus == models.CicloCerrado {
+		return nil
+	}
+	return guardarInscripcion(tx, &models.Inscripcion{CicloEscolarID: ciclo.ID, EstudianteID: estudiante.ID, GrupoID: grupo.ID})
+}
+
+// moverEstudiante deja al estudiante en el grupo, nivel y plantel de su inscripción en el ciclo activo y
+// publica estudiante.grupo_cambiado si cambió de grupo. Regresa si hubo cambio.
+func moverEstudiante(tx *gorm.DB, estudiante *models.Estudiante, grupoID uint, ciclo models.CicloEscolar) (bool, error) {
+	if estudiante.GrupoID == grupoID && estudiante.NivelEscolarID == ciclo.NivelEscolarID && estudiante.PlantelID == ciclo.NivelEscolar.PlantelID {
+		return false, nil
+	}
+	grupoAnterior := estudiante.GrupoID
+	err := tx.Model(estudiante).Updates(map[string]any{
+		"grupo_id":         grupoID,
+		"nivel_escolar_id": ciclo.NivelEscolarID,
+		"plantel_id":       ciclo.NivelEscolar.PlantelID,
+		"version":          gorm.Expr("version + 1"),
+	}).Error
+	if err != nil {
+		return false, err
+	}
+	estudiante.GrupoID, estudiante.NivelEscolarID, estudiante.PlantelID = grupoID, ciclo.NivelEscolarID, ciclo.NivelEscolar.PlantelID
+	if grupoAnterior == grupoID {
+		return true, nil
+	}
+	return true, eventos.Registrar(tx, eventos.EstudianteGrupoCambiado, estudiante.ID, eventos.CambioDeGrupo{
+		Estudiante:      eventos.DeEstudiante(estudiante),
+		GrupoAnteriorID: grupoAnterior,
+	})
+}
+
+// AplicarInscripciones mueve a cada estudiante inscrito en el ciclo, que se acaba de activar, al grupo de su
+// inscripción. Se llama dentro de la transacción de la activación; regresa cuántos estudiantes cambiaron.
+func AplicarInscripciones(tx *gorm.DB, ciclo models.CicloEscolar) (int, error) {
+	if err := tx.Select("id", "plantel_id").First(&ciclo.NivelEscolar, ciclo.NivelEscolarID).Error; err != nil {
+		return 0, err
+	}
+	var inscripciones []models.Inscripcion
+	if err := tx.Preload("Estudiante.User").Where("ciclo_escolar_id = ?", ciclo.ID).Find(&inscripciones).Error; err != nil {
+		return 0, err
+	}
+	movidos := 0
+	for i := range inscripciones {
+		movido, err := moverEstudiante(tx, &inscripciones[i].Estudiante, inscripciones[i].GrupoID, ciclo)
+		if err != nil {
+			return movidos, err
+		}
+		if movido {
+			movidos++
+		}
+	}
+	return movidos, nil
+}
+
+// cicloDeRuta lee el ciclo de :id; si no puede, ya respondió
+func cicloDeRuta(c *gin.Context) (models.CicloEscolar, bool) {
+	var ciclo models.CicloEscolar
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID inválido"))
+		return ciclo, false
+	}
+	if err := database.De(c).Preload("NivelEscolar").First(&ciclo, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.CicloEscolarNoEncontrado, "Ciclo escolar no encontrado"))
+		return ciclo, false
+	}
+	return ciclo, true
+}
+
+// ObtenerInscripcionesDeCiclo lista los estudiantes inscritos en el ciclo de :id con su grupo
+func ObtenerInscripcionesDeCiclo(c *gin.Context) {
+	ciclo, ok := cicloDeRuta(c)
+	if !ok {
+		return
+	}
+	base := database.LecturaDe(c).Where("ciclo_escolar_id = ?", ciclo.ID)
+	inscripciones, paginacion, errConsulta := consulta.Listar[models.Inscripcion](c, base, ConsultaInscripciones)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Inscripciones obtenidas correctamente",
+		"data":       inscripciones,
+		"paginacion": paginacion,
+	})
+}
+
+// ObtenerInscripcionesDeEstudiante es el historial del estudiante de :id: un renglón por ciclo en que estuvo inscrito
+func ObtenerInscripcionesDeEstudiante(c *gin.Context) {
+	var estudiante models.Estudiante
+	if err := database.LecturaDe(c).Select("id").First(&estudiante, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.EstudianteNoEncontrado, "Estudiante no encontrado"))
+		return
+	}
+	base := database.LecturaDe(c).Where("estudiante_id = ?", estudiante.ID)
+	inscripciones, paginacion, errConsulta := consulta.Listar[models.Inscripcion](c, base, ConsultaInscripciones)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Inscripciones obtenidas correctamente",
+		"data":       inscripciones,
+		"paginacion": paginacion,
+	})
+}
+
+// InscribirEstudiante inscribe al estudiante en un grupo del ciclo de :id, o lo cambia de grupo si ya estaba
+// inscrito. Si el ciclo está activo, el estudiante pasa de inmediato a ese grupo.
+func InscribirEstudiante(c *gin.Context) {
+	ciclo, ok := cicloDeRuta(c)
+	if !ok {
+		return
+	}
+	var input InscripcionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+	if ciclo.Estatus == models.CicloCerrado {
+		errores.Responder(c, errores.Conflicto(errores.CicloCerrado, "El ciclo escolar está cerrado; sus inscripciones ya no se modifican"))
+		return
+	}
+
+	var grupo models.Grupo
+	if err := database.De(c).First(&grupo, input.GrupoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			errores.Responder(c, errores.SolicitudInvalida(errores.GrupoNoEncontrado, "Grupo no encontrado"))
+			return
+		}
+		errores.Responder(c, errores.Interno("No se pudo validar el grupo", err))
+		return
+	}
+	if grupo.CicloEscolarID == nil || *grupo.CicloEscolarID != ciclo.ID {
+		errores.Responder(c, errores.CampoInvalido("grupo_id", "ciclo", "El grupo no pertenece a este ciclo escolar"))
+		return
+	}
+	var estudiante models.Estudiante
+	if err := database.De(c).Preload("User").First(&estudiante, input.EstudianteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			errores.Responder(c, errores.SolicitudInvalida(errores.EstudianteNoEncontrado, "Estudiante no encontrado"))
+			return
+		}
+		errores.Responder(c, errores.Interno("No se pudo validar el estudiante", err))
+		return
+	}
+
+	inscripcion := models.Inscripcion{CicloEscolarID: ciclo.ID, EstudianteID: estudiante.ID, GrupoID: grupo.ID}
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := guardarInscripcion(tx, &inscripcion); err != nil {
+			return err
+		}
+		if ciclo.Estatus != models.CicloActivo {
+			return nil
+		}
+		_, err := moverEstudiante(tx, &estudiante, grupo.ID, ciclo)
+		return err
+	})
+	if err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error al inscribir al estudiante"))
+		return
+	}
+	database.De(c).Preload("Estudiante.User").Preload("Grupo").
+		Where("ciclo_escolar_id = ? AND estudiante_id = ?", ciclo.ID, estudiante.ID).First(&inscripcion)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Estudiante inscrito correctamente",
+		"data":    inscripcion,
+	})
+}
+
+// EliminarInscripcion quita una inscripción de un ciclo que no está cerrado. El grupo actual del estudiante
+// no cambia; para moverlo se inscribe en otro grupo.
+func EliminarInscripcion(c *gin.Context) {
+	var inscripcion models.Inscripcion
+	if err := database.De(c).Preload("CicloEscolar").First(&inscripcion, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.InscripcionNoEncontrada, "Inscripción no encontrada"))
+		return
+	}
+	if inscripcion.CicloEscolar.Estatus == models.CicloCerrado {
+		errores.Responder(c, errores.Conflicto(errores.CicloCerrado, "El ciclo escolar está cerrado; sus inscripciones ya no se modifican"))
+		return
+	}
+	if err := database.De(c).Delete(&inscripcion).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error al eliminar la inscripción"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Inscripción eliminada correctamente"})
+}
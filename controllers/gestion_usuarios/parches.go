@@ -0,0 +1,103 @@
+package gestionusuarios
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/curp"
+	"api-margaritai/errores"
+	"api-margaritai/models"
+	"api-margaritai/parche"
+	"api-margaritai/validadores"
+)
+
+// UsuarioParche son los campos del usuario que se pueden cambiar con PATCH. La contraseña no está: se
+// cambia con PUT o con el flujo de recuperación.
+type UsuarioParche struct {
+	Nombre    string `json:"nombre" binding:"required"`
+	ApellidoP string `json:"apellido_p" binding:"required"`
+	ApellidoM string `json:"apellido_m" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	CURP      string `json:"curp" binding:"required,curp"`
+	FechaNac  string `json:"fecha_nac" binding:"required,datetime=2006-01-02"`
+	GeneroID  uint   `json:"genero_id" binding:"required"`
+	RolID     uint   `json:"rol_id" binding:"required"`
+	EsActivo  bool   `json:"es_activo"`
+}
+
+// EstudianteParche es la lista blanca de PATCH /estudiantes/:id
+type EstudianteParche struct {
+	Matricula         string        `json:"matricula" binding:"required"`
+	Nacionalidad      string        `json:"nacionalidad" binding:"required"`
+	FechaNacimiento   string        `json:"fecha_nacimiento" binding:"required,datetime=2006-01-02"`
+	EdoOrigen         string        `json:"edo_origen" binding:"required"`
+	MpioOrigen        string        `json:"mpio_origen" binding:"required"`
+	EdoCivil          string        `json:"edo_civil" binding:"required"`
+	Telefono          string        `json:"telefono" binding:"required,telefono_mx"`
+	PlantelID         uint          `json:"plantel_id" binding:"required"`
+	NivelEscolarID    uint          `json:"nivel_escolar_id" binding:"required"`
+	GrupoID           uint          `json:"grupo_id" binding:"required"`
+	EnProcesoAdmision bool          `json:"en_proceso_admision"`
+	User              UsuarioParche `json:"user"`
+}
+
+// PersonalParche es la lista blanca de PATCH /personal/:id
+type PersonalParche struct {
+	RFC               string        `json:"rfc" binding:"omitempty,rfc"`
+	NumeroEmpleado    string        `json:"numero_empleado"`
+	Telefono1         string        `json:"telefono_1" binding:"omitempty,telefono_mx"`
+	Telefono2         string        `json:"telefono_2" binding:"omitempty,telefono_mx"`
+	Carrera           string        `json:"carrera"`
+	EsProfesor        bool          `json:"es_profesor"`
+	GradoAcademicoID  uint          `json:"grado_academico_id" binding:"required"`
+	EstatusLaboralID  uint          `json:"estatus_laboral_id" binding:"required"`
+	PuestoID          uint          `json:"puesto_id" binding:"required"`
+	EstatusEmpleadoID uint          `json:"estatus_empleado_id" binding:"required"`
+	User              UsuarioParche `json:"user"`
+}
+
+// TutorParche es la lista blanca de PATCH /tutores/:id
+type TutorParche struct {
+	Nombre    string        `json:"nombre" binding:"required"`
+	Telefono  string        `json:"telefono" binding:"omitempty,telefono_mx"`
+	Telefono2 string        `json:"telefono2" binding:"omitempty,telefono_mx"`
+	User      UsuarioParche `json:"user"`
+}
+
+// normalizar deja email y CURP en el formato en que se guardan
+func (in *UsuarioParche) normalizar() {
+	in.Email = strings.TrimSpace(in.Email)
+	in.CURP = validadores.NormalizarCURP(in.CURP)
+}
+
+// normalizar deja CURP y teléfono en el formato en que se guardan
+func (in *EstudianteParche) normalizar() {
+	in.User.normalizar()
+	in.Telefono = validadores.NormalizarTelefono(in.Telefono)
+}
+
+// normalizar deja CURP, RFC y teléfonos en el formato en que se guardan
+func (in *PersonalParche) normalizar() {
+	in.User.normalizar()
+	in.RFC = validadores.NormalizarRFC(in.RFC)
+	in.Telefono1 = validadores.NormalizarTelefono(in.Telefono1)
+	in.Telefono2 = validadores.NormalizarTelefono(in.Telefono2)
+}
+
+// normalizar deja CURP y teléfonos en el formato en que se guardan
+func (in *TutorParche) normalizar() {
+	in.User.normalizar()
+	in.Telefono = validadores.NormalizarTelefono(in.Telefono)
+	in.Telefono2 = validadores.NormalizarTelefono(in.Telefono2)
+}
+
+// verificarCURPParche cruza el usuario (y los datos del estudiante, si se pasan) contra la CURP cuando el
+// parche tocó alguno de los datos que se derivan de ella
+func verificarCURPParche(c *gin.Context, p *parche.Parche, user *models.User, capturados curp.Capturados) ([]curp.Discrepancia, *errores.Error) {
+	if !p.Toca("user.curp", "user.fecha_nac", "user.genero_id", "fecha_nacimiento", "edo_origen") {
+		return nil, nil
+	}
+	capturados.FechaNac, capturados.GeneroID = user.FechaNac, user.GeneroID
+	return curp.Verificar(c, user.CURP, capturados)
+}
@@ -2,62 +2,213 @@ package gestionusuarios
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"api-margaritai/concurrencia"
+	"api-margaritai/consulta"
+	"api-margaritai/curp"
 	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/eventos"
+	"api-margaritai/exportacion"
 	"api-margaritai/models"
+	"api-margaritai/parche"
+	"api-margaritai/validadores"
 )
 
-// ObtenerPersonal: devuelve la lista de personal con su usuario asociado.
+// InsertarPersonalInput describe el cuerpo de POST /personal
+type InsertarPersonalInput struct {
+	User              PersonalUserInput `json:"user" binding:"required"`
+	RFC               string            `json:"rfc" binding:"omitempty,rfc"`
+	NumeroEmpleado    string            `json:"numero_empleado"`
+	Telefono1         string            `json:"telefono_1" binding:"omitempty,telefono_mx"`
+	Telefono2         string            `json:"telefono_2" binding:"omitempty,telefono_mx"`
+	Carrera           string            `json:"carrera"`
+	EsProfesor        bool              `json:"es_profesor"`
+	GradoAcademicoID  uint              `json:"grado_academico_id"`
+	EstatusLaboralID  uint              `json:"estatus_laboral_id"`
+	PuestoID          uint              `json:"puesto_id"`
+	EstatusEmpleadoID uint              `json:"estatus_empleado_id"`
+}
+
+// PersonalUserInput son los datos del usuario asociado al crear personal
+type PersonalUserInput struct {
+	Nombre    string `json:"nombre"`
+	ApellidoP string `json:"apellido_p"`
+	ApellidoM string `json:"apellido_m"`
+	Email     string `json:"email"`
+	CURP      string `json:"curp" binding:"omitempty,curp"`
+	Password  string `json:"password" binding:"required"`
+	FechaNac  string `json:"fecha_nac"` // RFC3339
+	GeneroID  uint   `json:"genero_id"`
+	RolID     uint   `json:"rol_id"`
+	EsActivo  bool   `json:"es_activo"`
+}
+
+// EditarPersonalInput es el cuerpo de PUT /personal/:id
+type EditarPersonalInput struct {
+	User              *models.User `json:"user"`
+	RFC               string       `json:"rfc" binding:"omitempty,rfc"`
+	NumeroEmpleado    string       `json:"numero_empleado"`
+	Telefono1         string       `json:"telefono_1" binding:"omitempty,telefono_mx"`
+	Telefono2         string       `json:"telefono_2" binding:"omitempty,telefono_mx"`
+	Carrera           string       `json:"carrera"`
+	EsProfesor        bool         `json:"es_profesor"`
+	GradoAcademicoID  uint         `json:"grado_academico_id"`
+	EstatusLaboralID  uint         `json:"estatus_laboral_id"`
+	PuestoID          uint         `json:"puesto_id"`
+	EstatusEmpleadoID uint         `json:"estatus_empleado_id"`
+}
+
+// ConsultaPersonal define el orden y los filtros aceptados por ObtenerPersonal
+var ConsultaPersonal = consulta.Definicion{
+	Orden: map[string]string{
+		"id":              "personals.id",
+		"numero_empleado": "personals.numero_empleado",
+		"nombre":          "users.nombre",
+		"apellido_p":      "users.apellido_p",
+		"created_at":      "personals.created_at",
+		"updated_at":      "personals.updated_at",
+	},
+	OrdenPorDefecto: "id",
+	Filtros: map[string]consulta.Filtro{
+		"es_profesor":         {Columna: "personals.es_profesor", Tipo: consulta.Booleano},
+		"grado_academico_id":  {Columna: "personals.grado_academico_id", Tipo: consulta.Entero},
+		"estatus_laboral_id":  {Columna: "personals.estatus_laboral_id", Tipo: consulta.Entero},
+		"puesto_id":           {Columna: "personals.puesto_id", Tipo: consulta.Entero},
+		"estatus_empleado_id": {Columna: "personals.estatus_empleado_id", Tipo: consulta.Entero},
+		"numero_empleado":     {Columna: "personals.numero_empleado", Tipo: consulta.Texto},
+		"rfc":                 {Columna: "personals.rfc", Tipo: consulta.Texto},
+		"rol_id":              {Columna: "users.rol_id", Tipo: consulta.Entero},
+		"es_activo":           {Columna: "users.es_activo", Tipo: consulta.Booleano},
+		"created_at":          {Columna: "personals.created_at", Tipo: consulta.Fecha},
+	},
+	Llave:     "personals.id",
+	Precargar: []string{"User", "GradoAcademico", "EstatusLaboral", "Puesto", "EstatusEmpleado"},
+	Incluir: map[string]string{
+		"user":             "User",
+		"user.genero":      "User.Genero",
+		"user.rol":         "User.Rol",
+		"grado_academico":  "GradoAcademico",
+		"estatus_laboral":  "EstatusLaboral",
+		"puesto":           "Puesto",
+		"estatus_empleado": "EstatusEmpleado",
+	},
+	Campos: consulta.CamposDe(models.Personal{}),
+}
+
+// ObtenerPersonal: devuelve la lista paginada de personal con su usuario asociado.
 func ObtenerPersonal(c *gin.Context) {
-	var personal []models.Personal
-	result := database.DB.Preload("User").Preload("GradoAcademico").
-		Preload("EstatusLaboral").Preload("Puesto").Preload("EstatusEmpleado").
-		Find(&personal)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al consultar personal", "details": result.Error.Error()})
+	base := database.LecturaDe(c).Joins("JOIN users ON users.id = personals.user_id AND users.deleted_at IS NULL")
+	personal, paginacion, errConsulta := consulta.Listar[models.Personal](c, base, ConsultaPersonal)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Personal obtenido correctamente",
+		"personal":   ConsultaPersonal.Proyectar(c, personal),
+		"paginacion": paginacion,
+	})
+}
+
+// columnasPersonal son las columnas de ExportarPersonal
+var columnasPersonal = []exportacion.Columna[models.Personal]{
+	{Titulo: "ID", Ancho: 6, Valor: func(p *models.Personal) string { return exportacion.Entero(p.ID) }},
+	{Titulo: "Número de empleado", Ancho: 10, Valor: func(p *models.Personal) string { return p.NumeroEmpleado }},
+	{Titulo: "Nombre", Ancho: 30, Valor: func(p *models.Personal) string {
+		return exportacion.NombreCompleto(p.User.Nombre, p.User.ApellidoP, p.User.ApellidoM)
+	}},
+	{Titulo: "RFC", Ancho: 14, Valor: func(p *models.Personal) string { return p.RFC }},
+	{Titulo: "CURP", Ancho: 20, Valor: func(p *models.Personal) string { return p.User.CURP }},
+	{Titulo: "Email", Ancho: 28, Valor: func(p *models.Personal) string { return p.User.Email }},
+	{Titulo: "Teléfono", Ancho: 12, Valor: func(p *models.Personal) string { return p.Telefono1 }},
+	{Titulo: "Puesto", Ancho: 18, Valor: func(p *models.Personal) string { return p.Puesto.Titulo }},
+	{Titulo: "Grado académico", Ancho: 16, Valor: func(p *models.Personal) string { return p.GradoAcademico.Titulo }},
+	{Titulo: "Carrera", Ancho: 20, Valor: func(p *models.Personal) string { return p.Carrera }},
+	{Titulo: "Estatus laboral", Ancho: 14, Valor: func(p *models.Personal) string { return p.EstatusLaboral.Titulo }},
+	{Titulo: "Estatus de empleado", Ancho: 14, Valor: func(p *models.Personal) string { return p.EstatusEmpleado.Titulo }},
+	{Titulo: "Profesor", Ancho: 8, Valor: func(p *models.Personal) string { return exportacion.SiNo(p.EsProfesor) }},
+	{Titulo: "Activo", Ancho: 6, Valor: func(p *models.Personal) string { return exportacion.SiNo(p.User.EsActivo) }},
+}
+
+// ExportarPersonal descarga el listado de personal como CSV, XLSX o PDF con los mismos filtros que ObtenerPersonal
+func ExportarPersonal(c *gin.Context) {
+	base := database.LecturaDe(c).Joins("JOIN users ON users.id = personals.user_id AND users.deleted_at IS NULL")
+	exportacion.Responder(c, base, ConsultaPersonal, "personal", "Personal", columnasPersonal)
+}
+
+// ObtenerPersonalPorID regresa un registro de personal con las mismas relaciones que el listado, o las de ?include=.
+// El ETag de la respuesta es el valor que se envía en If-Match al editarlo.
+func ObtenerPersonalPorID(c *gin.Context) {
+	sel, errSel := ConsultaPersonal.Seleccionar(c)
+	if errSel != nil {
+		errores.Responder(c, errSel)
+		return
+	}
+	var personal models.Personal
+	if err := sel.Consulta(database.De(c)).First(&personal, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.PersonalNoEncontrado, "Personal no encontrado"))
 		return
 	}
-	c.JSON(http.StatusOK, personal)
+
+	concurrencia.EscribirETag(c, personal.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Personal obtenido correctamente",
+		"personal": sel.Proyectar(personal),
+	})
 }
 
 // InsertarPersonal: crea personal y usuario asociado.
 func InsertarPersonal(c *gin.Context) {
+	// El cuerpo sigue la forma de InsertarPersonalInput; se bindea primero para validar formatos (CURP, RFC, teléfonos)
+	var input InsertarPersonalInput
+	if err := c.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+
 	// Utilizar map[string]interface{} para bindear, debido a la ambigüedad con los campos no-exportados (Password) y el binding de json anidados
 	var payload map[string]interface{}
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos", "details": err.Error()})
+	if err := c.ShouldBindBodyWith(&payload, binding.JSON); err != nil {
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
 	// Validar que el usuario esté presente y sea un map[string]interface{}
 	userMap, ok := payload["user"].(map[string]interface{})
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "El campo user es obligatorio"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.UsuarioRequerido, "El campo user es obligatorio"))
 		return
 	}
 
 	// Parsear password manualmente para evitar problemas de binding
 	password, passOk := userMap["password"].(string)
 	if !passOk || password == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere contraseña"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.PasswordRequerido, "Se requiere contraseña"))
 		return
 	}
 
 	// Checar email y curp únicos
 	email, _ := userMap["email"].(string)
-	curp, _ := userMap["curp"].(string)
+	valorCURP, _ := userMap["curp"].(string)
+	email = strings.TrimSpace(email)
+	valorCURP = validadores.NormalizarCURP(valorCURP)
 	var count int64
-	database.DB.Model(&models.User{}).Where("email = ?", email).Count(&count)
+	database.De(c).Model(&models.User{}).Where("email = ?", email).Count(&count)
 	if count > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "El correo electrónico ya está registrado"})
+		errores.Responder(c, errores.Conflicto(errores.EmailDuplicado, "El correo electrónico ya está registrado"))
 		return
 	}
-	database.DB.Model(&models.User{}).Where("curp = ?", curp).Count(&count)
+	database.De(c).Model(&models.User{}).Where("curp = ?", valorCURP).Count(&count)
 	if count > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "La CURP ya está registrada"})
+		errores.Responder(c, errores.Conflicto(errores.CURPDuplicada, "La CURP ya está registrada"))
 		return
 	}
 
@@ -76,8 +227,8 @@ func InsertarPersonal(c *gin.Context) {
 	if email != "" {
 		usr.Email = email
 	}
-	if curp != "" {
-		usr.CURP = curp
+	if valorCURP != "" {
+		usr.CURP = valorCURP
 	}
 	if fechaNacStr, ok := userMap["fecha_nac"].(string); ok && fechaNacStr != "" {
 		// Parse fecha_nac, asume formato RFC3339
@@ -95,35 +246,38 @@ func InsertarPersonal(c *gin.Context) {
 	if esActivo, ok := userMap["es_activo"].(bool); ok {
 		usr.EsActivo = esActivo
 	}
+	// Completar con la CURP la fecha de nacimiento y el género que no se enviaron, y cruzar los que sí.
+	// La respuesta es el registro creado, así que las advertencias solo viajan en el header Warning
+	if err := curp.CompletarUsuario(c, &usr); err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo datos de la CURP", err))
+		return
+	}
+	if _, errCURP := curp.Verificar(c, usr.CURP, curp.Capturados{FechaNac: usr.FechaNac, GeneroID: usr.GeneroID}); errCURP != nil {
+		errores.Responder(c, errCURP)
+		return
+	}
 	// Hash de password
 	if err := usr.HashPassword(password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo procesar la contraseña", "details": err.Error()})
+		errores.Responder(c, errores.Interno("No se pudo procesar la contraseña", err))
 		return
 	}
 	usr.CreatedAt = time.Now()
 	usr.UpdatedAt = usr.CreatedAt
 
-	if err := database.DB.Create(&usr).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear usuario", "details": err.Error()})
-		return
-	}
-
-	personal := models.Personal{
-		UserID: usr.ID,
-		// Los siguientes campos se obtienen del payload root, convertir cada uno adecuado
-	}
+	// Los campos se obtienen del payload root; UserID se asigna al crear el usuario
+	personal := models.Personal{}
 
 	if rfc, ok := payload["rfc"].(string); ok {
-		personal.RFC = rfc
+		personal.RFC = validadores.NormalizarRFC(rfc)
 	}
 	if numEmp, ok := payload["numero_empleado"].(string); ok {
 		personal.NumeroEmpleado = numEmp
 	}
 	if tel1, ok := payload["telefono_1"].(string); ok {
-		personal.Telefono1 = tel1
+		personal.Telefono1 = validadores.NormalizarTelefono(tel1)
 	}
 	if tel2, ok := payload["telefono_2"].(string); ok {
-		personal.Telefono2 = tel2
+		personal.Telefono2 = validadores.NormalizarTelefono(tel2)
 	}
 	if carrera, ok := payload["carrera"].(string); ok {
 		personal.Carrera = carrera
@@ -146,15 +300,26 @@ func InsertarPersonal(c *gin.Context) {
 	personal.CreatedAt = time.Now()
 	personal.UpdatedAt = time.Now()
 
-	if err := database.DB.Create(&personal).Error; err != nil {
-		// Rollback usuario si no se creó el personal
-		database.DB.Delete(&models.User{}, usr.ID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear personal", "details": err.Error()})
+	// Usuario y personal se crean juntos: si falla el personal no queda un usuario suelto
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&usr).Error; err != nil {
+			return err
+		}
+		personal.UserID = usr.ID
+		if err := tx.Create(&personal).Error; err != nil {
+			return err
+		}
+		evento := personal
+		evento.User = usr
+		return eventos.Registrar(tx, eventos.PersonalCreado, personal.ID, eventos.DePersonal(&evento))
+	})
+	if err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error al crear el usuario y su personal"))
 		return
 	}
 
 	var personalCreado models.Personal
-	database.DB.Preload("User").First(&personalCreado, personal.ID)
+	database.De(c).Preload("User").First(&personalCreado, personal.ID)
 	c.JSON(http.StatusCreated, personalCreado)
 }
 
@@ -162,30 +327,30 @@ func InsertarPersonal(c *gin.Context) {
 func EditarPersonal(c *gin.Context) {
 	var personal models.Personal
 	id := c.Param("id")
-	if err := database.DB.Preload("User").First(&personal, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Personal no encontrado"})
+	if err := database.De(c).Preload("User").First(&personal, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.PersonalNoEncontrado, "Personal no encontrado"))
 		return
 	}
-
-	var input struct {
-		User              *models.User `json:"user"`
-		RFC               string       `json:"rfc"`
-		NumeroEmpleado    string       `json:"numero_empleado"`
-		Telefono1         string       `json:"telefono_1"`
-		Telefono2         string       `json:"telefono_2"`
-		Carrera           string       `json:"carrera"`
-		EsProfesor        bool         `json:"es_profesor"`
-		GradoAcademicoID  uint         `json:"grado_academico_id"`
-		EstatusLaboralID  uint         `json:"estatus_laboral_id"`
-		PuestoID          uint         `json:"puesto_id"`
-		EstatusEmpleadoID uint         `json:"estatus_empleado_id"`
+	if !concurrencia.Verificar(c, personal.Version, personal) {
+		return
 	}
+
+	var input EditarPersonalInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos", "details": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
+	// El usuario llega como models.User (sin reglas de binding), así que la CURP se revisa aquí
+	if input.User != nil && input.User.CURP != "" {
+		input.User.CURP = validadores.NormalizarCURP(input.User.CURP)
+		if !validadores.EsCURPValida(input.User.CURP) {
+			errores.Responder(c, errores.CampoInvalido("user.curp", validadores.ReglaCURP, "Debe ser una CURP válida de 18 caracteres"))
+			return
+		}
+	}
 
-	// Edita datos del usuario
+	// Edita datos del usuario; se guarda junto con el personal
+	var usuarioEditado *models.User
 	if input.User != nil {
 		user := personal.User
 
@@ -200,18 +365,18 @@ func EditarPersonal(c *gin.Context) {
 		}
 		if input.User.Email != "" && input.User.Email != user.Email {
 			var count int64
-			database.DB.Model(&models.User{}).Where("email = ? AND id <> ?", input.User.Email, user.ID).Count(&count)
+			database.De(c).Model(&models.User{}).Where("email = ? AND id <> ?", input.User.Email, user.ID).Count(&count)
 			if count > 0 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "El correo electrónico ya está registrado por otro usuario"})
+				errores.Responder(c, errores.Conflicto(errores.EmailDuplicado, "El correo electrónico ya está registrado por otro usuario"))
 				return
 			}
 			user.Email = input.User.Email
 		}
 		if input.User.CURP != "" && input.User.CURP != user.CURP {
 			var count int64
-			database.DB.Model(&models.User{}).Where("curp = ? AND id <> ?", input.User.CURP, user.ID).Count(&count)
+			database.De(c).Model(&models.User{}).Where("curp = ? AND id <> ?", input.User.CURP, user.ID).Count(&count)
 			if count > 0 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "La CURP ya está registrada por otro usuario"})
+				errores.Responder(c, errores.Conflicto(errores.CURPDuplicada, "La CURP ya está registrada por otro usuario"))
 				return
 			}
 			user.CURP = input.User.CURP
@@ -231,30 +396,34 @@ func EditarPersonal(c *gin.Context) {
 		// Si hay nueva contraseña
 		if input.User.Password != "" {
 			if err := user.HashPassword(input.User.Password); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar la contraseña"})
+				errores.Responder(c, errores.BaseDatos(err, "No se pudo actualizar la contraseña"))
 				return
 			}
 		}
-		user.UpdatedAt = time.Now()
-		if err := database.DB.Save(&user).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando User", "details": err.Error()})
-			return
+		// Si se tocó la CURP, la fecha de nacimiento o el género, cruzar el resultado contra la CURP
+		if input.User.CURP != "" || !input.User.FechaNac.IsZero() || input.User.GeneroID != 0 {
+			if _, errCURP := curp.Verificar(c, user.CURP, curp.Capturados{FechaNac: user.FechaNac, GeneroID: user.GeneroID}); errCURP != nil {
+				errores.Responder(c, errCURP)
+				return
+			}
 		}
+		user.UpdatedAt = time.Now()
+		usuarioEditado = &user
 	}
 
 	// Edita datos de Personal
 	toUpdate := map[string]interface{}{}
 	if input.RFC != "" {
-		toUpdate["rfc"] = input.RFC
+		toUpdate["rfc"] = validadores.NormalizarRFC(input.RFC)
 	}
 	if input.NumeroEmpleado != "" {
 		toUpdate["numero_empleado"] = input.NumeroEmpleado
 	}
 	if input.Telefono1 != "" {
-		toUpdate["telefono1"] = input.Telefono1
+		toUpdate["telefono1"] = validadores.NormalizarTelefono(input.Telefono1)
 	}
 	if input.Telefono2 != "" {
-		toUpdate["telefono2"] = input.Telefono2
+		toUpdate["telefono2"] = validadores.NormalizarTelefono(input.Telefono2)
 	}
 	if input.Carrera != "" {
 		toUpdate["carrera"] = input.Carrera
@@ -273,36 +442,113 @@ func EditarPersonal(c *gin.Context) {
 		toUpdate["estatus_empleado_id"] = input.EstatusEmpleadoID
 	}
 	toUpdate["updated_at"] = time.Now()
-	if err := database.DB.Model(&personal).Updates(toUpdate).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando Personal", "details": err.Error()})
+	// Si alguien más editó el personal no se guarda nada
+	var actualizado models.Personal
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := concurrencia.Actualizar(tx, &personal, toUpdate); err != nil {
+			return err
+		}
+		if usuarioEditado != nil {
+			if err := tx.Save(usuarioEditado).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Preload("User").First(&actualizado, personal.ID).Error; err != nil {
+			return err
+		}
+		return eventos.Registrar(tx, eventos.PersonalActualizado, actualizado.ID, eventos.DePersonal(&actualizado))
+	})
+	if err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &personal, "Error actualizando Personal", "User"))
+		return
+	}
+
+	concurrencia.EscribirETag(c, actualizado.Version)
+	c.JSON(http.StatusOK, actualizado)
+}
+
+// ParchearPersonal aplica un JSON Merge Patch (o JSON Patch) a los campos de PersonalParche y a su usuario
+func ParchearPersonal(c *gin.Context) {
+	var personal models.Personal
+	if err := database.De(c).Preload("User").First(&personal, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.PersonalNoEncontrado, "Personal no encontrado"))
+		return
+	}
+	if !concurrencia.Verificar(c, personal.Version, personal) {
+		return
+	}
+
+	var input PersonalParche
+	p, errParche := parche.Leer(c, &input, &personal)
+	if errParche != nil {
+		errores.Responder(c, errParche)
+		return
+	}
+	input.normalizar()
+	p.Aplicar(&personal)
+
+	if _, errCURP := verificarCURPParche(c, p, &personal.User, curp.Capturados{}); errCURP != nil {
+		errores.Responder(c, errCURP)
 		return
 	}
 
 	var actualizado models.Personal
-	database.DB.Preload("User").First(&actualizado, personal.ID)
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := concurrencia.Guardar(tx, &personal); err != nil {
+			return err
+		}
+		if p.Toca("user") {
+			if err := tx.Omit(clause.Associations).Save(&personal.User).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Preload("User").First(&actualizado, personal.ID).Error; err != nil {
+			return err
+		}
+		return eventos.Registrar(tx, eventos.PersonalActualizado, actualizado.ID, eventos.DePersonal(&actualizado))
+	})
+	if err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &personal, "Error actualizando Personal", "User"))
+		return
+	}
+
+	concurrencia.EscribirETag(c, actualizado.Version)
 	c.JSON(http.StatusOK, actualizado)
 }
 
-// EliminarPersonal: elimina personal y su usuario
+// EliminarPersonal: manda a la papelera el personal y su usuario
 func EliminarPersonal(c *gin.Context) {
 	id := c.Param("id")
 	var personal models.Personal
-	if err := database.DB.First(&personal, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Personal no encontrado"})
+	if err := database.De(c).Preload("User").First(&personal, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.PersonalNoEncontrado, "Personal no encontrado"))
 		return
 	}
-
-	// Eliminar el Personal primero para evitar errores de restricción de clave foránea
-	if err := database.DB.Delete(&models.Personal{}, personal.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar el registro personal"})
+	// Las clases de ciclos cerrados son historial; las demás hay que reasignarlas a otro profesor antes
+	var clases int64
+	err := database.De(c).Model(&models.Horario{}).
+		Joins("JOIN ciclos_escolares ON ciclos_escolares.id = horarios.ciclo_escolar_id").
+		Where("horarios.personal_id = ? AND ciclos_escolares.estatus <> ?", personal.ID, models.CicloCerrado).
+		Count(&clases).Error
+	if err != nil {
+		errores.Responder(c, errores.Interno("No se pudo validar el horario del profesor", err))
 		return
 	}
-	// Ahora eliminar el usuario asociado
-	if err := database.DB.Delete(&models.User{}, personal.UserID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "No se pudo eliminar el usuario asociado",
-		})
+	if clases > 0 {
+		errores.Responder(c, errores.Conflicto(errores.PersonalConHorarios, "No se puede eliminar al profesor porque tiene clases en el horario de un ciclo que no está cerrado"))
+		return
+	}
+
+	// El personal y su usuario van juntos a la papelera; se pueden restaurar desde /papelera
+	err = database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := eventos.Registrar(tx, eventos.PersonalEliminado, personal.ID, eventos.DePersonal(&personal)); err != nil {
+			return err
+		}
+		return eliminarConUsuario(tx, &personal, personal.UserID)
+	})
+	if err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "No se pudo eliminar el registro personal"))
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"mensaje": "Personal y usuario asociado eliminados exitosamente"})
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Personal y usuario asociado enviados a la papelera"})
 }
@@ -0,0 +1,96 @@
+package gestionusuarios
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/curp"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/models"
+)
+
+// InconsistenciaCURP es un renglón del reporte: un usuario cuyos datos guardados contradicen su CURP
+type InconsistenciaCURP struct {
+	UserID        uint                `json:"user_id"`
+	Nombre        string              `json:"nombre"`
+	Email         string              `json:"email"`
+	CURP          string              `json:"curp"`
+	EstudianteID  *uint               `json:"estudiante_id,omitempty"`
+	CURPInvalida  bool                `json:"curp_invalida"`
+	Discrepancias []curp.Discrepancia `json:"discrepancias"`
+}
+
+// ReporteInconsistenciasCURP lista los usuarios cuya fecha de nacimiento, género o entidad de origen
+// (en el caso de estudiantes) no coinciden con lo que codifica su CURP, o cuya CURP es inválida
+func ReporteInconsistenciasCURP(c *gin.Context) {
+	reporte, err := BuscarInconsistenciasCURP(database.LecturaDe(c))
+	if err != nil {
+		errores.Responder(c, errores.Interno("Error generando el reporte de inconsistencias de CURP", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Reporte de inconsistencias de CURP generado correctamente",
+		"modo":     curp.Modo(),
+		"total":    len(reporte),
+		"usuarios": reporte,
+	})
+}
+
+// BuscarInconsistenciasCURP recorre todos los usuarios en lotes; también la usa el reporte nocturno
+func BuscarInconsistenciasCURP(db *gorm.DB) ([]InconsistenciaCURP, error) {
+	reporte := []InconsistenciaCURP{}
+
+	var usuarios []models.User
+	err := db.Order("id").FindInBatches(&usuarios, 500, func(tx *gorm.DB, lote int) error {
+		ids := make([]uint, len(usuarios))
+		for i, u := range usuarios {
+			ids[i] = u.ID
+		}
+		var estudiantes []models.Estudiante
+		if err := db.Where("user_id IN ?", ids).Find(&estudiantes).Error; err != nil {
+			return err
+		}
+		estudiantePorUsuario := make(map[uint]models.Estudiante, len(estudiantes))
+		for _, e := range estudiantes {
+			estudiantePorUsuario[e.UserID] = e
+		}
+
+		for _, u := range usuarios {
+			renglon := InconsistenciaCURP{
+				UserID: u.ID,
+				Nombre: u.Nombre + " " + u.ApellidoP + " " + u.ApellidoM,
+				Email:  u.Email,
+				CURP:   u.CURP,
+			}
+			capturados := curp.Capturados{FechaNac: u.FechaNac, GeneroID: u.GeneroID}
+			if e, ok := estudiantePorUsuario[u.ID]; ok {
+				id := e.ID
+				renglon.EstudianteID = &id
+				capturados.FechaNacimiento = e.FechaNacimiento
+				capturados.EdoOrigen = e.EdoOrigen
+			}
+
+			datos, err := curp.Parsear(u.CURP)
+			if err != nil {
+				renglon.CURPInvalida = true
+				renglon.Discrepancias = []curp.Discrepancia{}
+				reporte = append(reporte, renglon)
+				continue
+			}
+			discrepancias, err := datos.Comparar(db.Statement.Context, capturados)
+			if err != nil {
+				return err
+			}
+			if len(discrepancias) > 0 {
+				renglon.Discrepancias = discrepancias
+				reporte = append(reporte, renglon)
+			}
+		}
+		return nil
+	}).Error
+	return reporte, err
+}
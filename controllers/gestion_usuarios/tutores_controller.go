@@ -6,58 +6,191 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"api-margaritai/concurrencia"
+	"api-margaritai/consulta"
+	"api-margaritai/curp"
 	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/exportacion"
 	"api-margaritai/models"
+	"api-margaritai/parche"
+	"api-margaritai/validadores"
 )
 
-// obtenerTutores: devuelve la lista de tutores con su usuario asociado.
+// InsertarTutorInput describe el cuerpo de POST /tutores
+type InsertarTutorInput struct {
+	Nombre    string         `json:"nombre"`
+	Telefono  string         `json:"telefono" binding:"omitempty,telefono_mx"`
+	Telefono2 string         `json:"telefono2" binding:"omitempty,telefono_mx"`
+	User      TutorUserInput `json:"user" binding:"required"`
+}
+
+// TutorUserInput son los datos del usuario asociado al crear un tutor
+type TutorUserInput struct {
+	Nombre    string `json:"nombre"`
+	ApellidoP string `json:"apellido_p"`
+	ApellidoM string `json:"apellido_m"`
+	Email     string `json:"email"`
+	CURP      string `json:"curp" binding:"omitempty,curp"`
+	Password  string `json:"password" binding:"required"`
+	FechaNac  string `json:"fecha_nac"` // YYYY-MM-DD
+	GeneroID  uint   `json:"genero_id"`
+	RolID     uint   `json:"rol_id"`
+}
+
+// TutorUserUpdateInput son los datos editables del usuario de un tutor
+type TutorUserUpdateInput struct {
+	Nombre    string `json:"nombre"`
+	ApellidoP string `json:"apellido_p"`
+	ApellidoM string `json:"apellido_m"`
+	Email     string `json:"email"`
+	CURP      string `json:"curp" binding:"omitempty,curp"`
+	Password  string `json:"password"`
+	FechaNac  string `json:"fecha_nac"`
+	GeneroID  uint   `json:"genero_id"`
+	RolID     uint   `json:"rol_id"`
+	EsActivo  *bool  `json:"es_activo"`
+}
+
+// EditarTutorInput es el cuerpo de PUT /tutores/:id
+type EditarTutorInput struct {
+	Nombre    string               `json:"nombre"`
+	Telefono  string               `json:"telefono" binding:"omitempty,telefono_mx"`
+	Telefono2 string               `json:"telefono2" binding:"omitempty,telefono_mx"`
+	User      TutorUserUpdateInput `json:"user"`
+}
+
+// ConsultaTutores define el orden y los filtros aceptados por ObtenerTutores
+var ConsultaTutores = consulta.Definicion{
+	Orden: map[string]string{
+		"id":         "tutors.id",
+		"nombre":     "tutors.nombre",
+		"created_at": "tutors.created_at",
+		"updated_at": "tutors.updated_at",
+	},
+	OrdenPorDefecto: "id",
+	Filtros: map[string]consulta.Filtro{
+		"nombre":     {Columna: "tutors.nombre", Tipo: consulta.Texto},
+		"rol_id":     {Columna: "users.rol_id", Tipo: consulta.Entero},
+		"es_activo":  {Columna: "users.es_activo", Tipo: consulta.Booleano},
+		"created_at": {Columna: "tutors.created_at", Tipo: consulta.Fecha},
+	},
+	Llave:     "tutors.id",
+	Precargar: []string{"User"},
+	Incluir: map[string]string{
+		"user":        "User",
+		"user.genero": "User.Genero",
+		"user.rol":    "User.Rol",
+		"estudiantes": "EstudianteTutores.Estudiante",
+	},
+	Campos: consulta.CamposDe(models.Tutor{}),
+}
+
+// obtenerTutores: devuelve la lista paginada de tutores con su usuario asociado.
 func ObtenerTutores(c *gin.Context) {
-	var tutores []models.Tutor
-	result := database.DB.Preload("User").Find(&tutores)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al consultar tutores", "details": result.Error.Error()})
+	base := database.LecturaDe(c).Joins("JOIN users ON users.id = tutors.user_id AND users.deleted_at IS NULL")
+	tutores, paginacion, errConsulta := consulta.Listar[models.Tutor](c, base, ConsultaTutores)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
 		return
 	}
-	c.JSON(http.StatusOK, tutores)
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Tutores obtenidos correctamente",
+		"tutores":    ConsultaTutores.Proyectar(c, tutores),
+		"paginacion": paginacion,
+	})
+}
+
+// columnasTutores son las columnas de ExportarTutores
+var columnasTutores = []exportacion.Columna[models.Tutor]{
+	{Titulo: "ID", Ancho: 6, Valor: func(t *models.Tutor) string { return exportacion.Entero(t.ID) }},
+	{Titulo: "Nombre", Ancho: 30, Valor: func(t *models.Tutor) string {
+		return exportacion.NombreCompleto(t.User.Nombre, t.User.ApellidoP, t.User.ApellidoM)
+	}},
+	{Titulo: "CURP", Ancho: 20, Valor: func(t *models.Tutor) string { return t.User.CURP }},
+	{Titulo: "Email", Ancho: 28, Valor: func(t *models.Tutor) string { return t.User.Email }},
+	{Titulo: "Teléfono", Ancho: 12, Valor: func(t *models.Tutor) string { return t.Telefono }},
+	{Titulo: "Teléfono 2", Ancho: 12, Valor: func(t *models.Tutor) string { return t.Telefono2 }},
+	{Titulo: "Activo", Ancho: 6, Valor: func(t *models.Tutor) string { return exportacion.SiNo(t.User.EsActivo) }},
+}
+
+// ExportarTutores descarga el listado de tutores como CSV, XLSX o PDF con los mismos filtros que ObtenerTutores
+func ExportarTutores(c *gin.Context) {
+	base := database.LecturaDe(c).Joins("JOIN users ON users.id = tutors.user_id AND users.deleted_at IS NULL")
+	exportacion.Responder(c, base, ConsultaTutores, "tutores", "Tutores", columnasTutores)
+}
+
+// ObtenerTutor regresa un tutor con las mismas relaciones que el listado, o las de ?include=.
+// El ETag de la respuesta es el valor que se envía en If-Match al editarlo.
+func ObtenerTutor(c *gin.Context) {
+	sel, errSel := ConsultaTutores.Seleccionar(c)
+	if errSel != nil {
+		errores.Responder(c, errSel)
+		return
+	}
+	var tutor models.Tutor
+	if err := sel.Consulta(database.De(c)).First(&tutor, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.TutorNoEncontrado, "Tutor no encontrado"))
+		return
+	}
+
+	concurrencia.EscribirETag(c, tutor.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tutor obtenido correctamente",
+		"tutor":   sel.Proyectar(tutor),
+	})
 }
 
 // insertarTutor: crea un tutor con su usuario asociado.
 func InsertarTutor(c *gin.Context) {
+	// El cuerpo sigue la forma de InsertarTutorInput; se bindea primero para validar formatos (CURP, teléfonos)
+	var input InsertarTutorInput
+	if err := c.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+
+	// Después se lee como mapa para tolerar tipos mixtos
 	var payload map[string]interface{}
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos", "details": err.Error()})
+	if err := c.ShouldBindBodyWith(&payload, binding.JSON); err != nil {
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
 	// Validar usuario presente y correcto
 	userMap, ok := payload["user"].(map[string]interface{})
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "El campo user es obligatorio"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.UsuarioRequerido, "El campo user es obligatorio"))
 		return
 	}
 
 	password, passOk := userMap["password"].(string)
 	if !passOk || password == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere contraseña para el usuario del tutor"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.PasswordRequerido, "Se requiere contraseña para el usuario del tutor"))
 		return
 	}
 
 	email, _ := userMap["email"].(string)
-	curp, _ := userMap["curp"].(string)
+	valorCURP, _ := userMap["curp"].(string)
+	email = strings.TrimSpace(email)
+	valorCURP = validadores.NormalizarCURP(valorCURP)
 	if email != "" {
 		var count int64
-		database.DB.Model(&models.User{}).Where("email = ?", email).Count(&count)
+		database.De(c).Model(&models.User{}).Where("email = ?", email).Count(&count)
 		if count > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "El email ya está registrado"})
+			errores.Responder(c, errores.Conflicto(errores.EmailDuplicado, "El email ya está registrado"))
 			return
 		}
 	}
-	if curp != "" {
+	if valorCURP != "" {
 		var count int64
-		database.DB.Model(&models.User{}).Where("curp = ?", curp).Count(&count)
+		database.De(c).Model(&models.User{}).Where("curp = ?", valorCURP).Count(&count)
 		if count > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "La CURP ya está registrada"})
+			errores.Responder(c, errores.Conflicto(errores.CURPDuplicada, "La CURP ya está registrada"))
 			return
 		}
 	}
@@ -66,8 +199,8 @@ func InsertarTutor(c *gin.Context) {
 	user.Nombre, _ = userMap["nombre"].(string)
 	user.ApellidoP, _ = userMap["apellido_p"].(string)
 	user.ApellidoM, _ = userMap["apellido_m"].(string)
-	user.Email, _ = userMap["email"].(string)
-	user.CURP, _ = userMap["curp"].(string)
+	user.Email = email
+	user.CURP = valorCURP
 	user.EsActivo = true
 	// Parse FechaNac
 	if fn, exists := userMap["fecha_nac"].(string); exists && fn != "" {
@@ -82,46 +215,50 @@ func InsertarTutor(c *gin.Context) {
 	if rolID, ok := userMap["rol_id"].(float64); ok {
 		user.RolID = uint(rolID)
 	}
-	// Password - hash
-	if err := user.HashPassword(password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la contraseña"})
+	// Completar con la CURP la fecha de nacimiento y el género que no se enviaron, y cruzar los que sí.
+	// La respuesta es el registro creado, así que las advertencias solo viajan en el header Warning
+	if err := curp.CompletarUsuario(c, &user); err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo datos de la CURP", err))
 		return
 	}
-
-	// Guardar usuario primero
-	if err := database.DB.Create(&user).Error; err != nil {
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "violates foreign key constraint") && strings.Contains(errMsg, "fk_users_rol") {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "No se pudo crear el usuario",
-				"details": "El rol especificado no es válido o no existe en la base de datos.",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear el usuario", "details": errMsg})
+	if _, errCURP := curp.Verificar(c, user.CURP, curp.Capturados{FechaNac: user.FechaNac, GeneroID: user.GeneroID}); errCURP != nil {
+		errores.Responder(c, errCURP)
+		return
+	}
+	// Password - hash
+	if err := user.HashPassword(password); err != nil {
+		errores.Responder(c, errores.Interno("Error al procesar la contraseña", err))
 		return
 	}
 
-	// Crear Tutor con referencias
+	// Tutor con referencias; UserID se asigna al crear el usuario
 	var tutor models.Tutor
-	tutor.UserID = user.ID
 
 	if nombre, ok := payload["nombre"].(string); ok {
 		tutor.Nombre = nombre
 	}
 	if telefono, ok := payload["telefono"].(string); ok {
-		tutor.Telefono = telefono
+		tutor.Telefono = validadores.NormalizarTelefono(telefono)
 	}
 	if telefono2, ok := payload["telefono2"].(string); ok {
-		tutor.Telefono2 = telefono2
+		tutor.Telefono2 = validadores.NormalizarTelefono(telefono2)
 	}
 
-	if err := database.DB.Create(&tutor).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear el tutor", "details": err.Error()})
+	// Usuario y tutor se crean juntos: si falla el tutor no queda un usuario suelto
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		tutor.UserID = user.ID
+		return tx.Create(&tutor).Error
+	})
+	if err != nil {
+		// Un rol o género inexistente llega como violación de llave foránea y se reporta como REFERENCIA_INVALIDA
+		errores.Responder(c, errores.BaseDatos(err, "No se pudo crear el usuario y su tutor"))
 		return
 	}
 
-	database.DB.Preload("User").First(&tutor, tutor.ID)
+	database.De(c).Preload("User").First(&tutor, tutor.ID)
 	c.JSON(http.StatusCreated, tutor)
 }
 
@@ -129,77 +266,58 @@ func InsertarTutor(c *gin.Context) {
 func EditarTutor(c *gin.Context) {
 	id := c.Param("id")
 
-	// Estructura para recibir datos anidados
-	type UserInput struct {
-		Nombre    string `json:"nombre"`
-		ApellidoP string `json:"apellido_p"`
-		ApellidoM string `json:"apellido_m"`
-		Email     string `json:"email"`
-		CURP      string `json:"curp"`
-		Password  string `json:"password"`
-		FechaNac  string `json:"fecha_nac"`
-		GeneroID  uint   `json:"genero_id"`
-		RolID     uint   `json:"rol_id"`
-		EsActivo  *bool  `json:"es_activo"`
-	}
-	type TutorInput struct {
-		Nombre    string    `json:"nombre"`
-		Telefono  string    `json:"telefono"`
-		Telefono2 string    `json:"telefono2"`
-		User      UserInput `json:"user"`
-	}
-
-	var input TutorInput
+	var input EditarTutorInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos", "details": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
+	input.User.Email = strings.TrimSpace(input.User.Email)
+	input.User.CURP = validadores.NormalizarCURP(input.User.CURP)
 
 	var tutor models.Tutor
-	if err := database.DB.First(&tutor, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Tutor no encontrado"})
+	if err := database.De(c).First(&tutor, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.TutorNoEncontrado, "Tutor no encontrado"))
+		return
+	}
+	if !concurrencia.Verificar(c, tutor.Version, tutor) {
 		return
 	}
 
-	// Actualizar datos de tutor
+	// Datos del tutor; se guardan al final junto con el usuario
 	tutorMap := map[string]interface{}{}
 	if input.Nombre != "" {
 		tutorMap["nombre"] = input.Nombre
 	}
 	if input.Telefono != "" {
-		tutorMap["telefono"] = input.Telefono
+		tutorMap["telefono"] = validadores.NormalizarTelefono(input.Telefono)
 	}
 	if input.Telefono2 != "" {
-		tutorMap["telefono2"] = input.Telefono2
+		tutorMap["telefono2"] = validadores.NormalizarTelefono(input.Telefono2)
 	}
 	tutorMap["updated_at"] = time.Now()
-	if err := database.DB.Model(&tutor).Updates(tutorMap).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando tutor", "details": err.Error()})
-		return
-	}
 
 	// Actualizar usuario asociado
 	var user models.User
-	if err := database.DB.First(&user, tutor.UserID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario asociado no encontrado"})
+	if err := database.De(c).First(&user, tutor.UserID).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.UsuarioNoEncontrado, "Usuario asociado no encontrado"))
 		return
 	}
 
 	// Checar email/curp únicos SOLO si cambian
 	if input.User.Email != "" && input.User.Email != user.Email {
 		var count int64
-		database.DB.Model(&models.User{}).Where("email = ? AND id <> ?", input.User.Email, user.ID).Count(&count)
+		database.De(c).Model(&models.User{}).Where("email = ? AND id <> ?", input.User.Email, user.ID).Count(&count)
 		if count > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "El email ya está registrado por otro usuario"})
+			errores.Responder(c, errores.Conflicto(errores.EmailDuplicado, "El email ya está registrado por otro usuario"))
 			return
 		}
 		user.Email = input.User.Email
 	}
 	if input.User.CURP != "" && input.User.CURP != user.CURP {
 		var count int64
-		database.DB.Model(&models.User{}).Where("curp = ? AND id <> ?", input.User.CURP, user.ID).Count(&count)
+		database.De(c).Model(&models.User{}).Where("curp = ? AND id <> ?", input.User.CURP, user.ID).Count(&count)
 		if count > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "La CURP ya está registrada por otro usuario"})
+			errores.Responder(c, errores.Conflicto(errores.CURPDuplicada, "La CURP ya está registrada por otro usuario"))
 			return
 		}
 		user.CURP = input.User.CURP
@@ -230,40 +348,98 @@ func EditarTutor(c *gin.Context) {
 	// Password (si manda uno nuevo)
 	if input.User.Password != "" {
 		if err := user.HashPassword(input.User.Password); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar la contraseña"})
+			errores.Responder(c, errores.BaseDatos(err, "No se pudo actualizar la contraseña"))
+			return
+		}
+	}
+	// Si se tocó la CURP, la fecha de nacimiento o el género, cruzar el resultado contra la CURP
+	if input.User.CURP != "" || input.User.FechaNac != "" || input.User.GeneroID != 0 {
+		if _, errCURP := curp.Verificar(c, user.CURP, curp.Capturados{FechaNac: user.FechaNac, GeneroID: user.GeneroID}); errCURP != nil {
+			errores.Responder(c, errCURP)
 			return
 		}
 	}
 	user.UpdatedAt = time.Now()
-	if err := database.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario asociado", "details": err.Error()})
+	// Si alguien más editó el tutor no se guarda nada
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := concurrencia.Actualizar(tx, &tutor, tutorMap); err != nil {
+			return err
+		}
+		return tx.Save(&user).Error
+	})
+	if err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &tutor, "Error actualizando tutor", "User"))
 		return
 	}
 
 	// Responder tutor actualizado con User
 	var actualizado models.Tutor
-	database.DB.Preload("User").First(&actualizado, tutor.ID)
+	database.De(c).Preload("User").First(&actualizado, tutor.ID)
+	concurrencia.EscribirETag(c, actualizado.Version)
 	c.JSON(http.StatusOK, actualizado)
 }
 
-// eliminarTutor: elimina un tutor y su usuario asociado
-func EliminarTutor(c *gin.Context) {
-	id := c.Param("id")
+// ParchearTutor aplica un JSON Merge Patch (o JSON Patch) a los campos de TutorParche y a su usuario
+func ParchearTutor(c *gin.Context) {
 	var tutor models.Tutor
-	if err := database.DB.First(&tutor, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Tutor no encontrado"})
+	if err := database.De(c).Preload("User").First(&tutor, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.TutorNoEncontrado, "Tutor no encontrado"))
+		return
+	}
+	if !concurrencia.Verificar(c, tutor.Version, tutor) {
+		return
+	}
+
+	var input TutorParche
+	p, errParche := parche.Leer(c, &input, &tutor)
+	if errParche != nil {
+		errores.Responder(c, errParche)
 		return
 	}
+	input.normalizar()
+	p.Aplicar(&tutor)
 
-	// Eliminar tutor primero (para no violar FK)
-	if err := database.DB.Delete(&models.Tutor{}, tutor.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar el registro de tutor"})
+	if _, errCURP := verificarCURPParche(c, p, &tutor.User, curp.Capturados{}); errCURP != nil {
+		errores.Responder(c, errCURP)
 		return
 	}
-	// Eliminar usuario asociado
-	if err := database.DB.Delete(&models.User{}, tutor.UserID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar el usuario asociado"})
+
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		if err := concurrencia.Guardar(tx, &tutor); err != nil {
+			return err
+		}
+		if !p.Toca("user") {
+			return nil
+		}
+		return tx.Omit(clause.Associations).Save(&tutor.User).Error
+	})
+	if err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &tutor, "Error actualizando tutor", "User"))
+		return
+	}
+
+	var actualizado models.Tutor
+	database.De(c).Preload("User").First(&actualizado, tutor.ID)
+	concurrencia.EscribirETag(c, actualizado.Version)
+	c.JSON(http.StatusOK, actualizado)
+}
+
+// eliminarTutor: manda a la papelera un tutor y su usuario asociado
+func EliminarTutor(c *gin.Context) {
+	id := c.Param("id")
+	var tutor models.Tutor
+	if err := database.De(c).First(&tutor, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.TutorNoEncontrado, "Tutor no encontrado"))
+		return
+	}
+
+	// El tutor y su usuario van juntos a la papelera; se pueden restaurar desde /papelera
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		return eliminarConUsuario(tx, &tutor, tutor.UserID)
+	})
+	if err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "No se pudo eliminar el registro de tutor"))
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"mensaje": "Tutor y usuario asociado eliminados exitosamente"})
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Tutor y usuario asociado enviados a la papelera"})
 }
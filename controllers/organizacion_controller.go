@@ -0,0 +1,26 @@
+// controllers/organizacion_controller.go
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/middleware"
+	"api-margaritai/models"
+)
+
+// ObtenerOrganizacion regresa la organización del usuario autenticado
+func ObtenerOrganizacion(c *gin.Context) {
+	var org models.Organizacion
+	if err := database.LecturaDe(c).First(&org, c.GetUint(middleware.ClaveOrganizacion)).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.OrganizacionNoEncontrada, "Organización no encontrada"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Organización obtenida correctamente",
+		"organizacion": org,
+	})
+}
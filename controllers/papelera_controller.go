@@ -0,0 +1,335 @@
+// controllers/papelera_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/cache"
+	"api-margaritai/consulta"
+	gestionusuarios "api-margaritai/controllers/gestion_usuarios"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/eventos"
+	"api-margaritai/middleware"
+	"api-margaritai/models"
+	"api-margaritai/organizacion"
+)
+
+// ElementoPapelera es un registro eliminado (soft delete) que todavía se puede restaurar o purgar
+type ElementoPapelera struct {
+	ID          uint      `json:"id"`
+	EliminadoEn time.Time `json:"eliminado_en"`
+	Registro    any       `json:"registro"`
+}
+
+// tipoPapelera describe cómo listar, restaurar y purgar un tipo de registro
+type tipoPapelera struct {
+	codigo     string // código de error cuando el registro no está en la papelera
+	conUsuario bool   // estudiante, personal y tutor se eliminan y restauran junto con su usuario
+	nuevo      func() any
+	listar     func(c *gin.Context) ([]ElementoPapelera, consulta.Paginacion, *errores.Error)
+	limpiar    func(tx *gorm.DB, id uint) error      // borra las filas dependientes antes de purgar; opcional
+	cache      []string                              // grupos de caché que cambian al restaurar o purgar
+	restaurado func(tx *gorm.DB, registro any) error // publica el evento de restauración; opcional
+	compartido bool                                  // catálogo común a todas las organizaciones; solo la principal lo cambia
+}
+
+// ConsultaPapelera define el orden y los filtros aceptados por ObtenerPapelera
+var ConsultaPapelera = consulta.Definicion{
+	Orden: map[string]string{
+		"id":         "id",
+		"deleted_at": "deleted_at",
+	},
+	OrdenPorDefecto: "-deleted_at",
+	Filtros: map[string]consulta.Filtro{
+		"deleted_at": {Columna: "deleted_at", Tipo: consulta.Fecha},
+	},
+	Llave: "id",
+}
+
+// enPapelera arma el tipoPapelera de un modelo con ID y DeletedAt
+func enPapelera[T any](codigo string, conUsuario bool, precargar ...string) tipoPapelera {
+	def := ConsultaPapelera.ConPrecarga(precargar...)
+	return tipoPapelera{
+		codigo:     codigo,
+		conUsuario: conUsuario,
+		nuevo:      func() any { return new(T) },
+		listar: func(c *gin.Context) ([]ElementoPapelera, consulta.Paginacion, *errores.Error) {
+			base := database.LecturaDe(c).Unscoped().Where("deleted_at IS NOT NULL")
+			registros, paginacion, e := consulta.Listar[T](c, base, def)
+			if e != nil {
+				return nil, paginacion, e
+			}
+			elementos := make([]ElementoPapelera, 0, len(registros))
+			for i := range registros {
+				v := reflect.ValueOf(&registros[i]).Elem()
+				elementos = append(elementos, ElementoPapelera{
+					ID:          uint(v.FieldByName("ID").Uint()),
+					EliminadoEn: v.FieldByName("DeletedAt").Interface().(gorm.DeletedAt).Time,
+					Registro:    registros[i],
+				})
+			}
+			return elementos, paginacion, nil
+		},
+	}
+}
+
+// conAsignaciones hace que al purgar se quiten primero las asignaciones rol-permiso que usan columna.
+// Un permiso está asignado en todas las organizaciones, así que se borran sin filtrar por la de la petición.
+func (t tipoPapelera) conAsignaciones(columna string) tipoPapelera {
+	t.limpiar = func(tx *gorm.DB, id uint) error {
+		return tx.WithContext(organizacion.Global(tx.Statement.Context)).Where(columna+" = ?", id).Delete(&models.RoleTienePermiso{}).Error
+	}
+	return t
+}
+
+// conDocumentos hace que al purgar se borren también los documentos del registro y sus archivos
+func (t tipoPapelera) conDocumentos(entidad string) tipoPapelera {
+	t.limpiar = func(tx *gorm.DB, id uint) error {
+		return gestionusuarios.EliminarDocumentosDe(tx, entidad, id)
+	}
+	return t
+}
+
+// conDependientes hace que al purgar se borren antes las filas de modelo que apuntan al registro por columna;
+// se encadena con la limpieza que ya tuviera el tipo
+func (t tipoPapelera) conDependientes(modelo any, columna string) tipoPapelera {
+	previa := t.limpiar
+	t.limpiar = func(tx *gorm.DB, id uint) error {
+		if previa != nil {
+			if err := previa(tx, id); err != nil {
+				return err
+			}
+		}
+		return tx.Where(columna+" = ?", id).Delete(modelo).Error
+	}
+	return t
+}
+
+// deTodas marca los tipos que no pertenecen a una organización
+func (t tipoPapelera) deTodas() tipoPapelera {
+	t.compartido = true
+	return t
+}
+
+// invalida indica los grupos de caché que muestran este tipo de registro
+func (t tipoPapelera) invalida(grupos ...string) tipoPapelera {
+	t.cache = grupos
+	return t
+}
+
+// alRestaurar indica qué hacer, dentro de la transacción, después de restaurar un registro de este tipo
+func (t tipoPapelera) alRestaurar(f func(tx *gorm.DB, registro any) error) tipoPapelera {
+	t.restaurado = f
+	return t
+}
+
+// estudianteRestaurado publica estudiante.restaurado; se recarga con su usuario, que ya está restaurado
+func estudianteRestaurado(tx *gorm.DB, registro any) error {
+	e := registro.(*models.Estudiante)
+	if err := tx.Preload("User").First(e, e.ID).Error; err != nil {
+		return err
+	}
+	return eventos.Registrar(tx, eventos.EstudianteRestaurado, e.ID, eventos.DeEstudiante(e))
+}
+
+// personalRestaurado publica personal.restaurado; se recarga con su usuario, que ya está restaurado
+func personalRestaurado(tx *gorm.DB, registro any) error {
+	p := registro.(*models.Personal)
+	if err := tx.Preload("User").First(p, p.ID).Error; err != nil {
+		return err
+	}
+	return eventos.Registrar(tx, eventos.PersonalRestaurado, p.ID, eventos.DePersonal(p))
+}
+
+// tiposPapelera son los valores aceptados en /papelera/:tipo
+var tiposPapelera = map[string]tipoPapelera{
+	"estudiantes":         enPapelera[models.Estudiante](errores.EstudianteNoEncontrado, true, "User").alRestaurar(estudianteRestaurado).conDocumentos(models.DocumentoDeEstudiante).conDependientes(&models.Inscripcion{}, "estudiante_id"),
+	"personal":            enPapelera[models.Personal](errores.PersonalNoEncontrado, true, "User").alRestaurar(personalRestaurado).conDocumentos(models.DocumentoDePersonal),
+	"tutores":             enPapelera[models.Tutor](errores.TutorNoEncontrado, true, "User"),
+	"planteles":           enPapelera[models.Plantel](errores.PlantelNoEncontrado, false).invalida(cache.GrupoNivelesEscolares, cache.GrupoAulas),
+	"niveles_escolares":   enPapelera[models.NivelEscolar](errores.NivelEscolarNoEncontrado, false).invalida(cache.GrupoNivelesEscolares),
+	"grados":              enPapelera[models.Grado](errores.GradoNoEncontrado, false).invalida(cache.GrupoGrados),
+	"grupos":              enPapelera[models.Grupo](errores.GrupoNoEncontrado, false),
+	"ciclos_escolares":    enPapelera[models.CicloEscolar](errores.CicloEscolarNoEncontrado, false, "NivelEscolar").conDependientes(&models.PeriodoEvaluacion{}, "ciclo_escolar_id"),
+	"grados_academicos":   enPapelera[models.GradoAcademico](errores.GradoAcademicoNoEncontrado, false).invalida(cache.GrupoGradosAcademicos),
+	"estatus_laborales":   enPapelera[models.EstatusLaboral](errores.EstatusLaboralNoEncontrado, false).invalida(cache.GrupoEstatusLaborales),
+	"estatus_empleados":   enPapelera[models.EstatusEmpleado](errores.EstatusEmpleadoNoEncontrado, false).invalida(cache.GrupoEstatusEmpleados),
+	"puestos":             enPapelera[models.Puesto](errores.PuestoNoEncontrado, false).invalida(cache.GrupoPuestos),
+	"generos":             enPapelera[models.Genero](errores.GeneroNoEncontrado, false).invalida(cache.GrupoGeneros),
+	"tipos_contratos":     enPapelera[models.TipoContrato](errores.TipoContratoNoEncontrado, false).invalida(cache.GrupoTiposContratos),
+	"aulas":               enPapelera[models.Aula](errores.AulaNoEncontrada, false).invalida(cache.GrupoAulas),
+	"materias":            enPapelera[models.Materia](errores.MateriaNoEncontrada, false),
+	"roles":               enPapelera[models.Rol](errores.RolNoEncontrado, false).conAsignaciones("role_id").invalida(cache.GrupoPermisos),
+	"permisos":            enPapelera[models.Permiso](errores.PermisoNoEncontrado, false).conAsignaciones("permiso_id").invalida(cache.GrupoPermisos).deTodas(),
+	"categorias_permisos": enPapelera[models.CategoriaPermiso](errores.CategoriaPermisoNoEncontrada, false).invalida(cache.GrupoPermisos).deTodas(),
+}
+
+// TiposPapelera regresa los tipos aceptados por las rutas de papelera, en orden alfabético
+func TiposPapelera() []string {
+	tipos := make([]string, 0, len(tiposPapelera))
+	for tipo := range tiposPapelera {
+		tipos = append(tipos, tipo)
+	}
+	sort.Strings(tipos)
+	return tipos
+}
+
+// tipoDePapelera lee :tipo de la ruta
+func tipoDePapelera(c *gin.Context) (tipoPapelera, bool) {
+	t, ok := tiposPapelera[c.Param("tipo")]
+	if !ok {
+		errores.Responder(c, errores.SolicitudInvalida(errores.TipoInvalido, "Tipo de papelera inválido; use uno de: "+strings.Join(TiposPapelera(), ", ")))
+	}
+	return t, ok
+}
+
+// registroDePapelera lee :tipo e :id de la ruta para restaurar o purgar
+func registroDePapelera(c *gin.Context) (tipoPapelera, uint, bool) {
+	t, ok := tipoDePapelera(c)
+	if !ok {
+		return t, 0, false
+	}
+	if t.compartido {
+		if err := middleware.SoloOrganizacionPrincipal(c); err != nil {
+			errores.Responder(c, err)
+			return t, 0, false
+		}
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID inválido"))
+		return t, 0, false
+	}
+	return t, uint(id), true
+}
+
+// ObtenerPapelera lista los registros eliminados de un tipo, del más reciente al más antiguo
+func ObtenerPapelera(c *gin.Context) {
+	t, ok := tipoDePapelera(c)
+	if !ok {
+		return
+	}
+	elementos, paginacion, e := t.listar(c)
+	if e != nil {
+		errores.Responder(c, e)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Registros en la papelera obtenidos exitosamente",
+		"tipo":       c.Param("tipo"),
+		"registros":  elementos,
+		"paginacion": paginacion,
+	})
+}
+
+// buscarEnPapelera carga un registro eliminado; regresa gorm.ErrRecordNotFound si no existe o no está eliminado
+func buscarEnPapelera(tx *gorm.DB, t tipoPapelera, id uint) (any, error) {
+	registro := t.nuevo()
+	err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(registro).Error
+	return registro, err
+}
+
+// userIDDe lee el UserID de estudiante, personal o tutor
+func userIDDe(registro any) uint {
+	return uint(reflect.ValueOf(registro).Elem().FieldByName("UserID").Uint())
+}
+
+// RestaurarDePapelera quita la marca de eliminado de un registro (y de su usuario, si tiene)
+func RestaurarDePapelera(c *gin.Context) {
+	t, id, ok := registroDePapelera(c)
+	if !ok {
+		return
+	}
+
+	var registro any
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if registro, err = buscarEnPapelera(tx, t, id); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(registro).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if t.conUsuario {
+			if err := tx.Unscoped().Model(&models.User{}).Where("id = ?", userIDDe(registro)).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+		}
+		if t.restaurado != nil {
+			return t.restaurado(tx, registro)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			errores.Responder(c, errores.NoEncontrado(t.codigo, "El registro no está en la papelera"))
+		} else {
+			errores.Responder(c, errores.BaseDatos(err, "Error restaurando el registro"))
+		}
+		return
+	}
+	cache.Invalidar(t.cache...)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Registro restaurado exitosamente",
+		"tipo":     c.Param("tipo"),
+		"registro": registro,
+	})
+}
+
+// PurgarDePapelera borra definitivamente un registro que ya está en la papelera.
+// Para estudiante, personal y tutor también borra su usuario, sus sesiones y sus direcciones.
+func PurgarDePapelera(c *gin.Context) {
+	t, id, ok := registroDePapelera(c)
+	if !ok {
+		return
+	}
+
+	err := database.De(c).Transaction(func(tx *gorm.DB) error {
+		registro, err := buscarEnPapelera(tx, t, id)
+		if err != nil {
+			return err
+		}
+		if t.limpiar != nil {
+			if err := t.limpiar(tx, id); err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Delete(registro).Error; err != nil {
+			return err
+		}
+		if !t.conUsuario {
+			return nil
+		}
+		userID := userIDDe(registro)
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Direccion{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.User{}, userID).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			errores.Responder(c, errores.NoEncontrado(t.codigo, "El registro no está en la papelera"))
+		} else {
+			// Un 23503 llega como REGISTRO_EN_USO: otro registro todavía lo referencia
+			errores.Responder(c, errores.BaseDatos(err, "Error eliminando definitivamente el registro"))
+		}
+		return
+	}
+	cache.Invalidar(t.cache...)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Registro eliminado definitivamente"})
+}
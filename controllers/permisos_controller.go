@@ -4,7 +4,10 @@ import (
 	"net/http"
 	"strconv"
 
+	"api-margaritai/concurrencia"
+	"api-margaritai/consulta"
 	"api-margaritai/database"
+	"api-margaritai/errores"
 	"api-margaritai/models"
 
 	"github.com/gin-gonic/gin"
@@ -26,7 +29,7 @@ type UpdatePermisoInput struct {
 func CreatePermiso(c *gin.Context) {
 	var input CreatePermisoInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
@@ -36,8 +39,8 @@ func CreatePermiso(c *gin.Context) {
 		CategoriaPermisoID: input.CategoriaPermisoID,
 	}
 
-	if err := database.DB.Create(&permiso).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando permiso"})
+	if err := database.De(c).Create(&permiso).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error creando permiso"))
 		return
 	}
 
@@ -47,17 +50,36 @@ func CreatePermiso(c *gin.Context) {
 	})
 }
 
-// Obtener todos los permisos
+// ConsultaPermisos define el orden y los filtros aceptados por GetPermisos
+var ConsultaPermisos = consulta.Definicion{
+	Orden: map[string]string{
+		"id":                   "id",
+		"titulo":               "titulo",
+		"categoria_permiso_id": "categoria_permiso_id",
+		"created_at":           "created_at",
+	},
+	OrdenPorDefecto: "id",
+	Filtros: map[string]consulta.Filtro{
+		"titulo":               {Columna: "titulo", Tipo: consulta.Texto},
+		"categoria_permiso_id": {Columna: "categoria_permiso_id", Tipo: consulta.Entero},
+		"created_at":           {Columna: "created_at", Tipo: consulta.Fecha},
+	},
+	Llave:     "id",
+	Precargar: []string{"CategoriaPermiso"},
+}
+
+// Obtener los permisos paginados
 func GetPermisos(c *gin.Context) {
-	var permisos []models.Permiso
-	if err := database.DB.Preload("CategoriaPermiso").Find(&permisos).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo permisos"})
+	permisos, paginacion, errConsulta := consulta.Listar[models.Permiso](c, database.LecturaDe(c), ConsultaPermisos)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Permisos obtenidos exitosamente",
-		"permisos": permisos,
+		"message":    "Permisos obtenidos exitosamente",
+		"permisos":   permisos,
+		"paginacion": paginacion,
 	})
 }
 
@@ -66,11 +88,12 @@ func GetPermiso(c *gin.Context) {
 	var permiso models.Permiso
 	id := c.Param("id")
 
-	if err := database.DB.Preload("CategoriaPermiso").First(&permiso, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Permiso no encontrado"})
+	if err := database.De(c).Preload("CategoriaPermiso").First(&permiso, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.PermisoNoEncontrado, "Permiso no encontrado"))
 		return
 	}
 
+	concurrencia.EscribirETag(c, permiso.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Permiso obtenido exitosamente",
 		"permiso": permiso,
@@ -82,14 +105,18 @@ func UpdatePermiso(c *gin.Context) {
 	var permiso models.Permiso
 	id := c.Param("id")
 
-	if err := database.DB.First(&permiso, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Permiso no encontrado"})
+	if err := database.De(c).First(&permiso, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.PermisoNoEncontrado, "Permiso no encontrado"))
+		return
+	}
+
+	if !concurrencia.Verificar(c, permiso.Version, permiso) {
 		return
 	}
 
 	var input UpdatePermisoInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
@@ -103,11 +130,12 @@ func UpdatePermiso(c *gin.Context) {
 		permiso.CategoriaPermisoID = *input.CategoriaPermisoID
 	}
 
-	if err := database.DB.Save(&permiso).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando permiso"})
+	if err := concurrencia.Guardar(database.De(c), &permiso); err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &permiso, "Error actualizando permiso"))
 		return
 	}
 
+	concurrencia.EscribirETag(c, permiso.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Permiso actualizado exitosamente",
 		"permiso": permiso,
@@ -119,13 +147,13 @@ func DeletePermiso(c *gin.Context) {
 	var permiso models.Permiso
 	id := c.Param("id")
 
-	if err := database.DB.First(&permiso, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Permiso no encontrado"})
+	if err := database.De(c).First(&permiso, id).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.PermisoNoEncontrado, "Permiso no encontrado"))
 		return
 	}
 
-	if err := database.DB.Delete(&permiso).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando permiso"})
+	if err := database.De(c).Delete(&permiso).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error eliminando permiso"))
 		return
 	}
 
@@ -144,54 +172,54 @@ type PermisoConAsignacion struct {
 
 // Estructura para representar una categoría con sus permisos
 type CategoriaConPermisos struct {
-	ID          uint                 `json:"id"`
-	Titulo      string               `json:"titulo"`
-	Descripcion string               `json:"descripcion"`
-	Icono       string               `json:"icono"`
+	ID          uint                   `json:"id"`
+	Titulo      string                 `json:"titulo"`
+	Descripcion string                 `json:"descripcion"`
+	Icono       string                 `json:"icono"`
 	Permisos    []PermisoConAsignacion `json:"permisos"`
 }
 
 // GetPermisosConEstadoAsignacion obtiene todos los permisos del sistema agrupados por categoría y verifica cuáles están asignados a un rol específico
 func GetPermisosConEstadoAsignacion(c *gin.Context) {
 	roleID := c.Param("role_id")
-	
+
 	// Convertir roleID a uint para comparaciones
 	_, err := strconv.ParseUint(roleID, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de rol inválido"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID de rol inválido"))
 		return
 	}
-	
+
 	// Obtener todas las categorías de permisos
 	var categorias []models.CategoriaPermiso
-	if err := database.DB.Find(&categorias).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo categorías de permisos"})
+	if err := database.De(c).Find(&categorias).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo categorías de permisos", err))
 		return
 	}
-	
+
 	// Obtener todos los permisos del sistema con sus categorías
 	var permisos []models.Permiso
-	if err := database.DB.Preload("CategoriaPermiso").Find(&permisos).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo permisos"})
+	if err := database.De(c).Preload("CategoriaPermiso").Find(&permisos).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo permisos", err))
 		return
 	}
-	
+
 	// Obtener los permisos asignados al rol
 	var permisosDelRol []models.RoleTienePermiso
-	if err := database.DB.Where("role_id = ?", roleID).Find(&permisosDelRol).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo permisos del rol"})
+	if err := database.De(c).Where("role_id = ?", roleID).Find(&permisosDelRol).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo permisos del rol", err))
 		return
 	}
-	
+
 	// Crear un mapa para verificar rápidamente si un permiso está asignado al rol
 	permisosAsignados := make(map[uint]bool)
 	for _, rolPermiso := range permisosDelRol {
 		permisosAsignados[rolPermiso.PermisoID] = true
 	}
-	
+
 	// Crear un mapa para agrupar permisos por categoría
 	permisosPorCategoria := make(map[uint][]PermisoConAsignacion)
-	
+
 	// Agrupar permisos por categoría
 	for _, permiso := range permisos {
 		permisoConAsignacion := PermisoConAsignacion{
@@ -200,13 +228,13 @@ func GetPermisosConEstadoAsignacion(c *gin.Context) {
 			Descripcion: permiso.Descripcion,
 			Asignado:    permisosAsignados[permiso.ID],
 		}
-		
+
 		permisosPorCategoria[permiso.CategoriaPermisoID] = append(permisosPorCategoria[permiso.CategoriaPermisoID], permisoConAsignacion)
 	}
-	
+
 	// Crear la lista de categorías con sus permisos
 	var categoriasConPermisos []CategoriaConPermisos
-	
+
 	for _, categoria := range categorias {
 		categoriasConPermisos = append(categoriasConPermisos, CategoriaConPermisos{
 			ID:          categoria.ID,
@@ -216,7 +244,7 @@ func GetPermisosConEstadoAsignacion(c *gin.Context) {
 			Permisos:    permisosPorCategoria[categoria.ID],
 		})
 	}
-	
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Permisos agrupados por categoría con estado de asignación obtenidos exitosamente",
 		"categorias": categoriasConPermisos,
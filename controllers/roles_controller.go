@@ -7,8 +7,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"api-margaritai/concurrencia"
+	"api-margaritai/consulta"
 	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/exportacion"
 	"api-margaritai/models"
+	"api-margaritai/parche"
 )
 
 type CreateRoleInput struct {
@@ -24,17 +29,14 @@ func CreateRole(c *gin.Context) {
 	var input CreateRoleInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
 	// Verificar si el rol ya existe
 	var existingRole models.Rol
-	if err := database.DB.Where("nombre = ?", input.Nombre).First(&existingRole).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":  "El rol ya existe. Por favor, elija un nombre diferente o verifique los roles existentes antes de intentar crear uno nuevo.",
-			"status": http.StatusBadRequest,
-		})
+	if err := database.De(c).Where("nombre = ?", input.Nombre).First(&existingRole).Error; err == nil {
+		errores.Responder(c, errores.Conflicto(errores.RolDuplicado, "El rol ya existe. Por favor, elija un nombre diferente o verifique los roles existentes antes de intentar crear uno nuevo."))
 		return
 	}
 
@@ -48,8 +50,8 @@ func CreateRole(c *gin.Context) {
 		ParaTutor:      input.ParaTutor,
 	}
 
-	if err := database.DB.Create(&rol).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando rol", "status": http.StatusInternalServerError})
+	if err := database.De(c).Create(&rol).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error creando rol"))
 		return
 	}
 
@@ -69,16 +71,33 @@ func CreateRole(c *gin.Context) {
 	})
 }
 
-// GetRoles obtiene todos los roles
-func GetRoles(c *gin.Context) {
-	var roles []models.Rol
+// ConsultaRoles define el orden y los filtros aceptados por los listados de roles
+var ConsultaRoles = consulta.Definicion{
+	Orden: map[string]string{
+		"id":         "id",
+		"nombre":     "nombre",
+		"created_at": "created_at",
+	},
+	OrdenPorDefecto: "-id",
+	Filtros: map[string]consulta.Filtro{
+		"nombre":          {Columna: "nombre", Tipo: consulta.Texto},
+		"para_estudiante": {Columna: "para_estudiante", Tipo: consulta.Booleano},
+		"para_personal":   {Columna: "para_personal", Tipo: consulta.Booleano},
+		"para_tutor":      {Columna: "para_tutor", Tipo: consulta.Booleano},
+		"created_at":      {Columna: "created_at", Tipo: consulta.Fecha},
+	},
+	Llave: "id",
+}
 
-	if err := database.DB.Order("id desc").Find(&roles).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo roles", "status": http.StatusInternalServerError})
+// GetRoles obtiene los roles paginados
+func GetRoles(c *gin.Context) {
+	roles, paginacion, errConsulta := consulta.Listar[models.Rol](c, database.LecturaDe(c), ConsultaRoles)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
 		return
 	}
 
-	var rolesResponse []gin.H
+	rolesResponse := make([]gin.H, 0, len(roles))
 	for _, rol := range roles {
 		tipo := ""
 		if rol.ParaEstudiante {
@@ -106,47 +125,67 @@ func GetRoles(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Roles obtenidos exitosamente",
-		"roles":   rolesResponse,
+		"message":    "Roles obtenidos exitosamente",
+		"roles":      rolesResponse,
+		"paginacion": paginacion,
 	})
 }
 
+// columnasRoles son las columnas de ExportarRoles
+var columnasRoles = []exportacion.Columna[models.Rol]{
+	{Titulo: "ID", Ancho: 6, Valor: func(r *models.Rol) string { return exportacion.Entero(r.ID) }},
+	{Titulo: "Nombre", Ancho: 20, Valor: func(r *models.Rol) string { return r.Nombre }},
+	{Titulo: "Descripción", Ancho: 40, Valor: func(r *models.Rol) string { return r.Descripcion }},
+	{Titulo: "Para estudiante", Ancho: 8, Valor: func(r *models.Rol) string { return exportacion.SiNo(r.ParaEstudiante) }},
+	{Titulo: "Para personal", Ancho: 8, Valor: func(r *models.Rol) string { return exportacion.SiNo(r.ParaPersonal) }},
+	{Titulo: "Para tutor", Ancho: 8, Valor: func(r *models.Rol) string { return exportacion.SiNo(r.ParaTutor) }},
+	{Titulo: "Creado", Ancho: 16, Valor: func(r *models.Rol) string { return exportacion.FechaHora(r.CreatedAt) }},
+}
+
+// ExportarRoles descarga el listado de roles como CSV, XLSX o PDF con los mismos filtros que GetRoles
+func ExportarRoles(c *gin.Context) {
+	exportacion.Responder(c, database.LecturaDe(c), ConsultaRoles, "roles", "Roles", columnasRoles)
+}
+
 // obtenerRolesEstudiante obtiene solo los roles donde ParaEstudiante es true
 func ObtenerRolesEstudiante(c *gin.Context) {
-	var roles []models.Rol
-	if err := database.DB.Where("para_estudiante = ?", true).Order("id desc").Find(&roles).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo roles de estudiantes"})
+	roles, paginacion, errConsulta := consulta.Listar[models.Rol](c, database.LecturaDe(c).Where("para_estudiante = ?", true), ConsultaRoles)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Roles para estudiante obtenidos exitosamente",
-		"roles":   roles,
+		"message":    "Roles para estudiante obtenidos exitosamente",
+		"roles":      roles,
+		"paginacion": paginacion,
 	})
 }
 
 // obtenerRolesPersonal obtiene solo los roles donde ParaPersonal es true
 func ObtenerRolesPersonal(c *gin.Context) {
-	var roles []models.Rol
-	if err := database.DB.Where("para_personal = ?", true).Order("id desc").Find(&roles).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo roles de personal"})
+	roles, paginacion, errConsulta := consulta.Listar[models.Rol](c, database.LecturaDe(c).Where("para_personal = ?", true), ConsultaRoles)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Roles para personal obtenidos exitosamente",
-		"roles":   roles,
+		"message":    "Roles para personal obtenidos exitosamente",
+		"roles":      roles,
+		"paginacion": paginacion,
 	})
 }
 
 // obtenerRolesTutor obtiene solo los roles donde ParaTutor es true
 func ObtenerRolesTutor(c *gin.Context) {
-	var roles []models.Rol
-	if err := database.DB.Where("para_tutor = ?", true).Order("id desc").Find(&roles).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo roles de tutor"})
+	roles, paginacion, errConsulta := consulta.Listar[models.Rol](c, database.LecturaDe(c).Where("para_tutor = ?", true), ConsultaRoles)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Roles para tutor obtenidos exitosamente",
-		"roles":   roles,
+		"message":    "Roles para tutor obtenidos exitosamente",
+		"roles":      roles,
+		"paginacion": paginacion,
 	})
 }
 
@@ -155,8 +194,8 @@ func GetRole(c *gin.Context) {
 	var rol models.Rol
 	rolID := c.Param("id")
 
-	if err := database.DB.Preload("Permisos.CategoriaPermiso").First(&rol, rolID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Rol no encontrado", "status": http.StatusNotFound})
+	if err := database.De(c).Preload("Permisos.CategoriaPermiso").First(&rol, rolID).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.RolNoEncontrado, "Rol no encontrado"))
 		return
 	}
 
@@ -166,6 +205,7 @@ func GetRole(c *gin.Context) {
 		permisosAgrupados[categoria] = append(permisosAgrupados[categoria], permiso)
 	}
 
+	concurrencia.EscribirETag(c, rol.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message":            "Rol obtenido exitosamente",
 		"rol":                rol,
@@ -182,32 +222,42 @@ type UpdateRoleInput struct {
 	ParaTutor      *bool   `json:"para_tutor"`
 }
 
+// PatchRoleInput es la lista blanca de PATCH /roles/:id
+type PatchRoleInput struct {
+	Nombre         string `json:"nombre" binding:"required"`
+	Descripcion    string `json:"descripcion"`
+	Icono          string `json:"icono"`
+	ParaEstudiante bool   `json:"para_estudiante"`
+	ParaPersonal   bool   `json:"para_personal"`
+	ParaTutor      bool   `json:"para_tutor"`
+}
+
 // UpdateRole actualiza un rol existente
 func UpdateRole(c *gin.Context) {
 	var rol models.Rol
 	rolID := c.Param("id")
 
 	// Verificar si el rol existe
-	if err := database.DB.First(&rol, rolID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Rol no encontrado", "status": http.StatusNotFound})
+	if err := database.De(c).First(&rol, rolID).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.RolNoEncontrado, "Rol no encontrado"))
+		return
+	}
+
+	if !concurrencia.Verificar(c, rol.Version, rol) {
 		return
 	}
 
 	var input UpdateRoleInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
 	// Verificar si el nuevo nombre ya existe (si se está actualizando)
 	if input.Nombre != nil && *input.Nombre != rol.Nombre {
 		var existingRole models.Rol
-		if err := database.DB.Where("nombre = ? AND id != ?", *input.Nombre, rolID).First(&existingRole).Error; err == nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "Ya existe un rol con ese nombre. Por favor, elija un nombre diferente para el rol. No se permiten duplicados para mantener la integridad de los datos.",
-				"status":  http.StatusBadRequest,
-				"detalle": "El nombre proporcionado ya está siendo utilizado por otro rol en el sistema. Si necesita actualizar el nombre, asegúrese de que sea único.",
-			})
+		if err := database.De(c).Where("nombre = ? AND id != ?", *input.Nombre, rolID).First(&existingRole).Error; err == nil {
+			errores.Responder(c, errores.Conflicto(errores.RolDuplicado, "Ya existe un rol con ese nombre. Por favor, elija un nombre diferente para el rol."))
 			return
 		}
 	}
@@ -232,11 +282,52 @@ func UpdateRole(c *gin.Context) {
 		rol.ParaTutor = *input.ParaTutor
 	}
 
-	if err := database.DB.Save(&rol).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando rol", "status": http.StatusInternalServerError})
+	if err := concurrencia.Guardar(database.De(c), &rol); err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &rol, "Error actualizando rol"))
+		return
+	}
+
+	concurrencia.EscribirETag(c, rol.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rol actualizado exitosamente",
+		"rol":     rol,
+	})
+}
+
+// PatchRole aplica un JSON Merge Patch (o JSON Patch) a los campos de PatchRoleInput
+func PatchRole(c *gin.Context) {
+	var rol models.Rol
+	if err := database.De(c).First(&rol, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.RolNoEncontrado, "Rol no encontrado"))
+		return
+	}
+	if !concurrencia.Verificar(c, rol.Version, rol) {
+		return
+	}
+
+	var input PatchRoleInput
+	p, errParche := parche.Leer(c, &input, &rol)
+	if errParche != nil {
+		errores.Responder(c, errParche)
+		return
+	}
+
+	// El nombre no tiene índice único, así que el duplicado se revisa aquí como en UpdateRole
+	if p.Toca("nombre") && input.Nombre != rol.Nombre {
+		var existingRole models.Rol
+		if err := database.De(c).Where("nombre = ? AND id != ?", input.Nombre, rol.ID).First(&existingRole).Error; err == nil {
+			errores.Responder(c, errores.Conflicto(errores.RolDuplicado, "Ya existe un rol con ese nombre. Por favor, elija un nombre diferente para el rol."))
+			return
+		}
+	}
+	p.Aplicar(&rol)
+
+	if err := concurrencia.Guardar(database.De(c), &rol); err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &rol, "Error actualizando rol"))
 		return
 	}
 
+	concurrencia.EscribirETag(c, rol.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Rol actualizado exitosamente",
 		"rol":     rol,
@@ -249,14 +340,14 @@ func DeleteRole(c *gin.Context) {
 	rolID := c.Param("id")
 
 	// Verificar si el rol existe
-	if err := database.DB.First(&rol, rolID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Rol no encontrado", "status": http.StatusNotFound})
+	if err := database.De(c).First(&rol, rolID).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.RolNoEncontrado, "Rol no encontrado"))
 		return
 	}
 
 	// Realizar soft delete
-	if err := database.DB.Delete(&rol).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando rol", "status": http.StatusInternalServerError})
+	if err := database.De(c).Delete(&rol).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error eliminando rol"))
 		return
 	}
 
@@ -271,8 +362,8 @@ func GetPermisosByRolId(c *gin.Context) {
 	rolID := c.Param("id")
 
 	// Verificar si el rol existe y precargar sus permisos y categorías
-	if err := database.DB.Preload("Permisos.CategoriaPermiso").First(&rol, rolID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Rol no encontrado", "status": http.StatusNotFound})
+	if err := database.De(c).Preload("Permisos.CategoriaPermiso").First(&rol, rolID).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.RolNoEncontrado, "Rol no encontrado"))
 		return
 	}
 
@@ -4,23 +4,41 @@ import (
 	"net/http"
 	"strconv"
 
+	"api-margaritai/consulta"
 	"api-margaritai/database"
+	"api-margaritai/errores"
 	"api-margaritai/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Obtener todas las relaciones rol-permiso
+// ConsultaRolesTienenPermisos define el orden y los filtros aceptados por GetRolesTienenPermisos.
+// La tabla no tiene ID propio, así que solo se pagina por page/page_size.
+var ConsultaRolesTienenPermisos = consulta.Definicion{
+	Orden: map[string]string{
+		"role_id":    "role_id",
+		"permiso_id": "permiso_id",
+	},
+	OrdenPorDefecto: "role_id,permiso_id",
+	Filtros: map[string]consulta.Filtro{
+		"role_id":    {Columna: "role_id", Tipo: consulta.Entero},
+		"permiso_id": {Columna: "permiso_id", Tipo: consulta.Entero},
+	},
+	Precargar: []string{"Rol", "Permiso"},
+}
+
+// Obtener las relaciones rol-permiso paginadas
 func GetRolesTienenPermisos(c *gin.Context) {
-	var relaciones []models.RoleTienePermiso
-	if err := database.DB.Preload("Rol").Preload("Permiso").Find(&relaciones).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo las relaciones rol-permiso"})
+	relaciones, paginacion, errConsulta := consulta.Listar[models.RoleTienePermiso](c, database.LecturaDe(c), ConsultaRolesTienenPermisos)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Relaciones rol-permiso obtenidas exitosamente",
 		"relaciones": relaciones,
+		"paginacion": paginacion,
 	})
 }
 
@@ -30,10 +48,10 @@ func GetRoleTienePermiso(c *gin.Context) {
 	permisoID := c.Param("permiso_id")
 
 	var relacion models.RoleTienePermiso
-	if err := database.DB.Preload("Rol").Preload("Permiso").
+	if err := database.De(c).Preload("Rol").Preload("Permiso").
 		Where("role_id = ? AND permiso_id = ?", roleID, permisoID).
 		First(&relacion).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Relación rol-permiso no encontrada"})
+		errores.Responder(c, errores.DeConsulta(err, errores.RelacionRolPermisoNoEncontrada, "Relación rol-permiso no encontrada"))
 		return
 	}
 
@@ -52,21 +70,21 @@ type CreateRoleTienePermisoInput struct {
 func CreateRoleTienePermiso(c *gin.Context) {
 	var input CreateRoleTienePermisoInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
 	// Verificar si el RoleID existe
 	var role models.Rol
-	if err := database.DB.First(&role, input.RoleID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Role no encontrado"})
+	if err := database.De(c).First(&role, input.RoleID).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.RolNoEncontrado, "Role no encontrado"))
 		return
 	}
 
 	// Verificar si el PermisoID existe
 	var permiso models.Permiso
-	if err := database.DB.First(&permiso, input.PermisoID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Permiso no encontrado"})
+	if err := database.De(c).First(&permiso, input.PermisoID).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.PermisoNoEncontrado, "Permiso no encontrado"))
 		return
 	}
 
@@ -75,8 +93,8 @@ func CreateRoleTienePermiso(c *gin.Context) {
 		PermisoID: input.PermisoID,
 	}
 
-	if err := database.DB.Create(&relacion).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando la relación rol-permiso"})
+	if err := database.De(c).Create(&relacion).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error creando la relación rol-permiso"))
 		return
 	}
 
@@ -92,13 +110,13 @@ func DeleteRoleTienePermiso(c *gin.Context) {
 	permisoID := c.Param("permiso_id")
 
 	var relacion models.RoleTienePermiso
-	if err := database.DB.Where("role_id = ? AND permiso_id = ?", roleID, permisoID).First(&relacion).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Relación rol-permiso no encontrada"})
+	if err := database.De(c).Where("role_id = ? AND permiso_id = ?", roleID, permisoID).First(&relacion).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.RelacionRolPermisoNoEncontrada, "Relación rol-permiso no encontrada"))
 		return
 	}
 
-	if err := database.DB.Delete(&relacion).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando la relación rol-permiso"})
+	if err := database.De(c).Delete(&relacion).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error eliminando la relación rol-permiso"))
 		return
 	}
 
@@ -112,13 +130,13 @@ func GetPermisosDeRol(c *gin.Context) {
 	roleIDStr := c.Param("id")
 	roleID, err := strconv.ParseUint(roleIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de rol inválido"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID de rol inválido"))
 		return
 	}
 
 	var relaciones []models.RoleTienePermiso
-	if err := database.DB.Preload("Permiso.CategoriaPermiso").Where("role_id = ?", roleID).Find(&relaciones).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo los permisos del rol"})
+	if err := database.De(c).Preload("Permiso.CategoriaPermiso").Where("role_id = ?", roleID).Find(&relaciones).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo los permisos del rol", err))
 		return
 	}
 
@@ -158,13 +176,13 @@ func GetRolesDePermiso(c *gin.Context) {
 	permisoIDStr := c.Param("id")
 	permisoID, err := strconv.ParseUint(permisoIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de permiso inválido"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID de permiso inválido"))
 		return
 	}
 
 	var relaciones []models.RoleTienePermiso
-	if err := database.DB.Preload("Rol").Where("permiso_id = ?", permisoID).Find(&relaciones).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo los roles del permiso"})
+	if err := database.De(c).Preload("Rol").Where("permiso_id = ?", permisoID).Find(&relaciones).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo los roles del permiso", err))
 		return
 	}
 
@@ -189,33 +207,33 @@ type AsignarPermisosARolInput struct {
 func AsignarPermisosARol(c *gin.Context) {
 	var input AsignarPermisosARolInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
 	// Verificar si el RoleID existe
 	var role models.Rol
-	if err := database.DB.First(&role, input.RoleID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Role no encontrado"})
+	if err := database.De(c).First(&role, input.RoleID).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.RolNoEncontrado, "Role no encontrado"))
 		return
 	}
 
 	// Verificar que todos los permisos existan
 	for _, permisoID := range input.PermisosID {
 		var permiso models.Permiso
-		if err := database.DB.First(&permiso, permisoID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Permiso con ID " + strconv.FormatUint(uint64(permisoID), 10) + " no encontrado"})
+		if err := database.De(c).First(&permiso, permisoID).Error; err != nil {
+			errores.Responder(c, errores.DeConsulta(err, errores.PermisoNoEncontrado, "Permiso con ID "+strconv.FormatUint(uint64(permisoID), 10)+" no encontrado"))
 			return
 		}
 	}
 
 	// Iniciar una transacción para asegurar que todas las asignaciones se realicen correctamente
-	tx := database.DB.Begin()
+	tx := database.De(c).Begin()
 
 	// Eliminar todos los permisos existentes para el rol
 	if err := tx.Where("role_id = ?", input.RoleID).Unscoped().Delete(&models.RoleTienePermiso{}).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al eliminar permisos existentes del rol"})
+		errores.Responder(c, errores.BaseDatos(err, "Error al eliminar permisos existentes del rol"))
 		return
 	}
 
@@ -231,7 +249,7 @@ func AsignarPermisosARol(c *gin.Context) {
 
 		if err := tx.Create(&relacion).Error; err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error asignando permisos al rol"})
+			errores.Responder(c, errores.BaseDatos(err, "Error asignando permisos al rol"))
 			return
 		}
 
@@ -240,7 +258,7 @@ func AsignarPermisosARol(c *gin.Context) {
 
 	// Confirmar la transacción
 	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al confirmar la transacción"})
+		errores.Responder(c, errores.Interno("Error al confirmar la transacción", err))
 		return
 	}
 
@@ -261,19 +279,19 @@ type DesasignarPermisosARolInput struct {
 func DesasignarPermisosARol(c *gin.Context) {
 	var input DesasignarPermisosARolInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		errores.Responder(c, errores.Validacion(err))
 		return
 	}
 
 	// Verificar si el RoleID existe
 	var role models.Rol
-	if err := database.DB.First(&role, input.RoleID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Role no encontrado"})
+	if err := database.De(c).First(&role, input.RoleID).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.RolNoEncontrado, "Role no encontrado"))
 		return
 	}
 
 	// Iniciar una transacción para asegurar que todas las desasignaciones se realicen correctamente
-	tx := database.DB.Begin()
+	tx := database.De(c).Begin()
 
 	permisosDesasignados := []uint{}
 	permisosNoAsignados := []uint{}
@@ -288,7 +306,7 @@ func DesasignarPermisosARol(c *gin.Context) {
 
 		if err := tx.Delete(&relacion).Error; err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error desasignando permisos del rol"})
+			errores.Responder(c, errores.BaseDatos(err, "Error desasignando permisos del rol"))
 			return
 		}
 		permisosDesasignados = append(permisosDesasignados, permisoID)
@@ -308,28 +326,28 @@ func GetRolePermisosConEstadoAsignacion(c *gin.Context) {
 	roleIDStr := c.Param("id")
 	roleID, err := strconv.ParseUint(roleIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de rol inválido"})
+		errores.Responder(c, errores.SolicitudInvalida(errores.IDInvalido, "ID de rol inválido"))
 		return
 	}
 
 	// Verificar si el rol existe
 	var role models.Rol
-	if err := database.DB.First(&role, roleID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Rol no encontrado"})
+	if err := database.De(c).First(&role, roleID).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.RolNoEncontrado, "Rol no encontrado"))
 		return
 	}
 
 	// Obtener todos los permisos
 	var permisos []models.Permiso
-	if err := database.DB.Preload("CategoriaPermiso").Find(&permisos).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo los permisos"})
+	if err := database.De(c).Preload("CategoriaPermiso").Find(&permisos).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo los permisos", err))
 		return
 	}
 
 	// Obtener los permisos asignados al rol
 	var relaciones []models.RoleTienePermiso
-	if err := database.DB.Where("role_id = ?", roleID).Find(&relaciones).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo las relaciones rol-permiso"})
+	if err := database.De(c).Where("role_id = ?", roleID).Find(&relaciones).Error; err != nil {
+		errores.Responder(c, errores.Interno("Error obteniendo las relaciones rol-permiso", err))
 		return
 	}
 
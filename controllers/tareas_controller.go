@@ -0,0 +1,92 @@
+// controllers/tareas_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/consulta"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/models"
+	"api-margaritai/tareas"
+)
+
+// ConsultaEjecucionesTareas define el orden y los filtros aceptados por ObtenerEjecucionesTareas
+var ConsultaEjecucionesTareas = consulta.Definicion{
+	Orden: map[string]string{
+		"id":          "id",
+		"iniciada_en": "iniciada_en",
+		"duracion_ms": "duracion_ms",
+	},
+	OrdenPorDefecto: "-id",
+	Filtros: map[string]consulta.Filtro{
+		"tarea":       {Columna: "tarea", Tipo: consulta.Texto},
+		"estado":      {Columna: "estado", Tipo: consulta.Texto},
+		"manual":      {Columna: "manual", Tipo: consulta.Booleano},
+		"iniciada_en": {Columna: "iniciada_en", Tipo: consulta.Fecha},
+	},
+	Llave: "id",
+}
+
+// ObtenerTareas lista las tareas programadas con su horario, su siguiente ejecución y la última que corrió
+func ObtenerTareas(c *gin.Context) {
+	estados, err := tareas.Estados()
+	if err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error obteniendo las tareas"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tareas obtenidas correctamente",
+		"tareas":  estados,
+	})
+}
+
+// ObtenerEjecucionesTareas lista el historial de ejecuciones, de la más reciente a la más antigua
+func ObtenerEjecucionesTareas(c *gin.Context) {
+	ejecuciones, paginacion, errConsulta := consulta.Listar[models.EjecucionTarea](c, database.LecturaDe(c), ConsultaEjecucionesTareas)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Ejecuciones obtenidas correctamente",
+		"ejecuciones": ejecuciones,
+		"paginacion":  paginacion,
+	})
+}
+
+// ObtenerEjecucionTarea regresa una ejecución; sirve para consultar el avance de una lanzada a mano
+func ObtenerEjecucionTarea(c *gin.Context) {
+	var ejecucion models.EjecucionTarea
+	if err := database.De(c).First(&ejecucion, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.EjecucionTareaNoEncontrada, "Ejecución no encontrada"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Ejecución obtenida correctamente",
+		"ejecucion": ejecucion,
+	})
+}
+
+// EjecutarTarea lanza una tarea fuera de su horario; responde 202 con la ejecución en curso
+func EjecutarTarea(c *gin.Context) {
+	ejecucion, err := tareas.EjecutarAhora(c.Param("nombre"), c.MustGet("user_id").(uint))
+	switch {
+	case errors.Is(err, tareas.ErrDesconocida):
+		errores.Responder(c, errores.NoEncontrado(errores.TareaNoEncontrada, "Tarea no encontrada"))
+		return
+	case errors.Is(err, tareas.ErrEnCurso):
+		errores.Responder(c, errores.Conflicto(errores.TareaEnCurso, "La tarea ya se está ejecutando"))
+		return
+	case err != nil:
+		errores.Responder(c, errores.BaseDatos(err, "Error iniciando la tarea"))
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":   "Tarea iniciada",
+		"ejecucion": ejecucion,
+	})
+}
@@ -0,0 +1,323 @@
+// controllers/webhooks_controller.go
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/concurrencia"
+	"api-margaritai/consulta"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/eventos"
+	"api-margaritai/models"
+)
+
+// SuscripcionWebhookInput es el cuerpo para registrar una suscripción
+type SuscripcionWebhookInput struct {
+	URL         string   `json:"url" binding:"required,url,max=500"`
+	Descripcion string   `json:"descripcion"`
+	Eventos     []string `json:"eventos" binding:"required,min=1"` // tipos de evento, o "*" para todos
+	Activa      *bool    `json:"activa"`                           // true por defecto
+}
+
+// SuscripcionWebhookUpdateInput es el cuerpo para editar una suscripción; los campos omitidos no cambian
+type SuscripcionWebhookUpdateInput struct {
+	URL         *string  `json:"url" binding:"omitempty,url,max=500"`
+	Descripcion *string  `json:"descripcion"`
+	Eventos     []string `json:"eventos" binding:"omitempty,min=1"`
+	Activa      *bool    `json:"activa"`
+}
+
+// ConsultaSuscripcionesWebhook define el orden y los filtros aceptados por ObtenerSuscripcionesWebhook
+var ConsultaSuscripcionesWebhook = consulta.Definicion{
+	Orden: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+	},
+	OrdenPorDefecto: "id",
+	Filtros: map[string]consulta.Filtro{
+		"activa":     {Columna: "activa", Tipo: consulta.Booleano},
+		"url":        {Columna: "url", Tipo: consulta.Texto},
+		"created_at": {Columna: "created_at", Tipo: consulta.Fecha},
+	},
+	Llave: "id",
+}
+
+// ConsultaEntregasWebhook define el orden y los filtros aceptados por ObtenerEntregasWebhook
+var ConsultaEntregasWebhook = consulta.Definicion{
+	Orden: map[string]string{
+		"id":                "id",
+		"created_at":        "created_at",
+		"siguiente_intento": "siguiente_intento",
+	},
+	OrdenPorDefecto: "-id",
+	Filtros: map[string]consulta.Filtro{
+		"suscripcion_id": {Columna: "suscripcion_id", Tipo: consulta.Entero},
+		"evento_id":      {Columna: "evento_id", Tipo: consulta.Entero},
+		"estado":         {Columna: "estado", Tipo: consulta.Texto},
+		"created_at":     {Columna: "created_at", Tipo: consulta.Fecha},
+	},
+	Llave:     "id",
+	Precargar: []string{"Evento"},
+}
+
+// validarSuscripcion revisa que la URL sea http(s), que no apunte a la red interna y que los eventos existan
+func validarSuscripcion(direccion string, tipos []string) *errores.Error {
+	u, err := url.Parse(direccion)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errores.CampoInvalido("url", "url", "La URL debe ser http o https")
+	}
+	if u.User != nil {
+		return errores.CampoInvalido("url", "url", "La URL no debe llevar usuario ni contraseña")
+	}
+	if eventos.RevisarDestino(u.Hostname()) != nil {
+		return errores.CampoInvalido("url", "url", "La URL no puede apuntar a localhost ni a una red privada")
+	}
+	for _, tipo := range tipos {
+		if tipo != eventos.Todos && !slices.Contains(eventos.Tipos, tipo) {
+			return errores.CampoInvalido("eventos", "oneof", "Evento desconocido: "+tipo+"; use "+eventos.Todos+" o alguno de: "+strings.Join(eventos.Tipos, ", "))
+		}
+	}
+	return nil
+}
+
+// generarSecreto crea el secreto con que se firman los envíos de una suscripción
+func generarSecreto() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// ObtenerTiposEventos lista los eventos a los que se puede suscribir
+func ObtenerTiposEventos(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tipos de eventos obtenidos correctamente",
+		"tipos":   eventos.Tipos,
+	})
+}
+
+// ObtenerSuscripcionesWebhook lista las suscripciones paginadas
+func ObtenerSuscripcionesWebhook(c *gin.Context) {
+	suscripciones, paginacion, errConsulta := consulta.Listar[models.SuscripcionWebhook](c, database.LecturaDe(c), ConsultaSuscripcionesWebhook)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Suscripciones obtenidas correctamente",
+		"suscripciones": suscripciones,
+		"paginacion":    paginacion,
+	})
+}
+
+// ObtenerSuscripcionWebhook regresa una suscripción; su ETag se usa en If-Match al editarla
+func ObtenerSuscripcionWebhook(c *gin.Context) {
+	var suscripcion models.SuscripcionWebhook
+	if err := database.De(c).First(&suscripcion, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.SuscripcionWebhookNoEncontrada, "Suscripción no encontrada"))
+		return
+	}
+	concurrencia.EscribirETag(c, suscripcion.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Suscripción obtenida correctamente",
+		"suscripcion": suscripcion,
+	})
+}
+
+// CrearSuscripcionWebhook registra una suscripción. El secreto para verificar las firmas solo se muestra en esta respuesta.
+func CrearSuscripcionWebhook(c *gin.Context) {
+	var input SuscripcionWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+	if e := validarSuscripcion(input.URL, input.Eventos); e != nil {
+		errores.Responder(c, e)
+		return
+	}
+	secreto, err := generarSecreto()
+	if err != nil {
+		errores.Responder(c, errores.Interno("Error generando el secreto", err))
+		return
+	}
+
+	suscripcion := models.SuscripcionWebhook{
+		URL:         input.URL,
+		Descripcion: input.Descripcion,
+		Eventos:     input.Eventos,
+		Secreto:     secreto,
+		Activa:      input.Activa == nil || *input.Activa,
+		UserID:      c.MustGet("user_id").(uint),
+	}
+	if err := database.De(c).Create(&suscripcion).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error creando la suscripción"))
+		return
+	}
+
+	concurrencia.EscribirETag(c, suscripcion.Version)
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Suscripción creada exitosamente; guarde el secreto, no se volverá a mostrar",
+		"suscripcion": suscripcion,
+		"secreto":     secreto,
+	})
+}
+
+// EditarSuscripcionWebhook cambia la URL, los eventos, la descripción o si está activa
+func EditarSuscripcionWebhook(c *gin.Context) {
+	var suscripcion models.SuscripcionWebhook
+	if err := database.De(c).First(&suscripcion, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.SuscripcionWebhookNoEncontrada, "Suscripción no encontrada"))
+		return
+	}
+	if !concurrencia.Verificar(c, suscripcion.Version, suscripcion) {
+		return
+	}
+
+	var input SuscripcionWebhookUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errores.Responder(c, errores.Validacion(err))
+		return
+	}
+	if input.URL != nil {
+		suscripcion.URL = *input.URL
+	}
+	if input.Descripcion != nil {
+		suscripcion.Descripcion = *input.Descripcion
+	}
+	if input.Eventos != nil {
+		suscripcion.Eventos = input.Eventos
+	}
+	if input.Activa != nil {
+		suscripcion.Activa = *input.Activa
+	}
+	if e := validarSuscripcion(suscripcion.URL, suscripcion.Eventos); e != nil {
+		errores.Responder(c, e)
+		return
+	}
+
+	if err := concurrencia.Guardar(database.De(c), &suscripcion); err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &suscripcion, "Error actualizando la suscripción"))
+		return
+	}
+	concurrencia.EscribirETag(c, suscripcion.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Suscripción actualizada exitosamente",
+		"suscripcion": suscripcion,
+	})
+}
+
+// EliminarSuscripcionWebhook deja de enviar eventos a la suscripción; sus entregas pendientes quedan como fallidas
+func EliminarSuscripcionWebhook(c *gin.Context) {
+	var suscripcion models.SuscripcionWebhook
+	if err := database.De(c).First(&suscripcion, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.SuscripcionWebhookNoEncontrada, "Suscripción no encontrada"))
+		return
+	}
+	if err := database.De(c).Delete(&suscripcion).Error; err != nil {
+		errores.Responder(c, errores.BaseDatos(err, "Error eliminando la suscripción"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Suscripción eliminada exitosamente"})
+}
+
+// RotarSecretoWebhook reemplaza el secreto de la suscripción; los envíos siguientes se firman con el nuevo
+func RotarSecretoWebhook(c *gin.Context) {
+	var suscripcion models.SuscripcionWebhook
+	if err := database.De(c).First(&suscripcion, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.SuscripcionWebhookNoEncontrada, "Suscripción no encontrada"))
+		return
+	}
+	secreto, err := generarSecreto()
+	if err != nil {
+		errores.Responder(c, errores.Interno("Error generando el secreto", err))
+		return
+	}
+	if err := concurrencia.Actualizar(database.De(c), &suscripcion, map[string]any{"secreto": secreto}); err != nil {
+		errores.Responder(c, concurrencia.ErrorAlGuardar(c, err, &suscripcion, "Error rotando el secreto"))
+		return
+	}
+	concurrencia.EscribirETag(c, suscripcion.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Secreto rotado exitosamente; guarde el nuevo secreto, no se volverá a mostrar",
+		"suscripcion": suscripcion,
+		"secreto":     secreto,
+	})
+}
+
+// ReenviarFallidasWebhook vuelve a programar todas las entregas fallidas de una suscripción
+func ReenviarFallidasWebhook(c *gin.Context) {
+	var suscripcion models.SuscripcionWebhook
+	if err := database.De(c).First(&suscripcion, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.SuscripcionWebhookNoEncontrada, "Suscripción no encontrada"))
+		return
+	}
+	resultado := database.De(c).Model(&models.EntregaWebhook{}).
+		Where("suscripcion_id = ? AND estado = ?", suscripcion.ID, models.EntregaFallida).
+		Updates(reprogramacion())
+	if resultado.Error != nil {
+		errores.Responder(c, errores.BaseDatos(resultado.Error, "Error reprogramando las entregas"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Entregas fallidas reprogramadas",
+		"reenviadas": resultado.RowsAffected,
+	})
+}
+
+// ObtenerEntregasWebhook lista las entregas con su evento, de la más reciente a la más antigua
+func ObtenerEntregasWebhook(c *gin.Context) {
+	entregas, paginacion, errConsulta := consulta.Listar[models.EntregaWebhook](c, database.LecturaDe(c), ConsultaEntregasWebhook)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Entregas obtenidas correctamente",
+		"entregas":   entregas,
+		"paginacion": paginacion,
+	})
+}
+
+// ReenviarEntregaWebhook vuelve a programar una entrega fallida o ya entregada, con los intentos desde cero
+func ReenviarEntregaWebhook(c *gin.Context) {
+	var entrega models.EntregaWebhook
+	if err := database.De(c).First(&entrega, c.Param("id")).Error; err != nil {
+		errores.Responder(c, errores.DeConsulta(err, errores.EntregaWebhookNoEncontrada, "Entrega no encontrada"))
+		return
+	}
+	// La condición sobre el estado evita reprogramar una entrega que el despachador está enviando
+	resultado := database.De(c).Model(&entrega).Where("estado <> ?", models.EntregaPendiente).Updates(reprogramacion())
+	if resultado.Error != nil {
+		errores.Responder(c, errores.BaseDatos(resultado.Error, "Error reprogramando la entrega"))
+		return
+	}
+	if resultado.RowsAffected == 0 {
+		errores.Responder(c, errores.Conflicto(errores.EntregaWebhookPendiente, "La entrega todavía está pendiente; se reintentará automáticamente"))
+		return
+	}
+	database.De(c).Preload("Evento").First(&entrega, entrega.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Entrega reprogramada",
+		"entrega": entrega,
+	})
+}
+
+// reprogramacion son los cambios que dejan una entrega lista para que el despachador la envíe en su siguiente pasada
+func reprogramacion() map[string]any {
+	return map[string]any{
+		"estado":            models.EntregaPendiente,
+		"intentos":          0,
+		"siguiente_intento": time.Now(),
+		"ultimo_error":      "",
+	}
+}
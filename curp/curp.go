@@ -0,0 +1,117 @@
+// Package curp extrae la fecha de nacimiento, el sexo y la entidad de nacimiento codificados en una CURP
+package curp
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"api-margaritai/validadores"
+)
+
+// ErrCURPInvalida indica que la CURP no tiene estructura o dígito verificador válidos
+var ErrCURPInvalida = errors.New("CURP inválida")
+
+// Sexos codificados en la posición 11 de la CURP
+const (
+	SexoHombre      = "H"
+	SexoMujer       = "M"
+	SexoNoBinario   = "X"
+	ClaveExtranjero = "NE"
+)
+
+// Datos son los datos personales que se pueden derivar de una CURP
+type Datos struct {
+	FechaNacimiento time.Time `json:"fecha_nacimiento"`
+	Sexo            string    `json:"sexo"`
+	ClaveEstado     string    `json:"clave_estado"`
+	Estado          string    `json:"estado"`
+}
+
+// estados relaciona la clave de entidad de la CURP con su nombre y otras formas comunes de escribirlo
+var estados = map[string][]string{
+	"AS": {"Aguascalientes"},
+	"BC": {"Baja California"},
+	"BS": {"Baja California Sur"},
+	"CC": {"Campeche"},
+	"CL": {"Coahuila", "Coahuila de Zaragoza"},
+	"CM": {"Colima"},
+	"CS": {"Chiapas"},
+	"CH": {"Chihuahua"},
+	"DF": {"Ciudad de México", "CDMX", "Distrito Federal", "DF"},
+	"DG": {"Durango"},
+	"GT": {"Guanajuato"},
+	"GR": {"Guerrero"},
+	"HG": {"Hidalgo"},
+	"JC": {"Jalisco"},
+	"MC": {"Estado de México", "México", "Edomex"},
+	"MN": {"Michoacán", "Michoacán de Ocampo"},
+	"MS": {"Morelos"},
+	"NT": {"Nayarit"},
+	"NL": {"Nuevo León"},
+	"OC": {"Oaxaca"},
+	"PL": {"Puebla"},
+	"QT": {"Querétaro", "Querétaro de Arteaga"},
+	"QR": {"Quintana Roo"},
+	"SP": {"San Luis Potosí"},
+	"SL": {"Sinaloa"},
+	"SR": {"Sonora"},
+	"TC": {"Tabasco"},
+	"TS": {"Tamaulipas"},
+	"TL": {"Tlaxcala"},
+	"VZ": {"Veracruz", "Veracruz de Ignacio de la Llave"},
+	"YN": {"Yucatán"},
+	"ZS": {"Zacatecas"},
+	"NE": {"Nacido en el extranjero", "Extranjero"},
+}
+
+// Parsear valida la CURP y regresa los datos que codifica
+func Parsear(c string) (Datos, error) {
+	c = validadores.NormalizarCURP(c)
+	if !validadores.EsCURPValida(c) {
+		return Datos{}, ErrCURPInvalida
+	}
+
+	// Posición 17: dígito para nacidos en el siglo XX, letra para nacidos a partir del 2000
+	siglo := "19"
+	if c[16] >= 'A' && c[16] <= 'Z' {
+		siglo = "20"
+	}
+	fecha, err := time.Parse("20060102", siglo+c[4:10])
+	if err != nil {
+		return Datos{}, ErrCURPInvalida
+	}
+
+	clave := c[11:13]
+	return Datos{
+		FechaNacimiento: fecha,
+		Sexo:            c[10:11],
+		ClaveEstado:     clave,
+		Estado:          estados[clave][0],
+	}, nil
+}
+
+// MismoEstado indica si el texto capturado corresponde a la entidad de la CURP (acepta nombre, alias o clave)
+func (d Datos) MismoEstado(capturado string) bool {
+	capturado = simplificar(capturado)
+	if capturado == strings.ToLower(d.ClaveEstado) {
+		return true
+	}
+	for _, nombre := range estados[d.ClaveEstado] {
+		if simplificar(nombre) == capturado {
+			return true
+		}
+	}
+	return false
+}
+
+// MismaFecha compara solo año, mes y día
+func (d Datos) MismaFecha(t time.Time) bool {
+	return d.FechaNacimiento.Format("2006-01-02") == t.Format("2006-01-02")
+}
+
+// simplificar quita acentos, espacios sobrantes y mayúsculas para comparar nombres de estados
+func simplificar(s string) string {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	return strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", ".", "").Replace(s)
+}
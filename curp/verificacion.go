@@ -0,0 +1,191 @@
+package curp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/cache"
+	"api-margaritai/config"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/models"
+	"api-margaritai/organizacion"
+)
+
+// Modos de CURP_DISCREPANCIAS: con "warning" se guarda y se avisa, con "error" se rechaza la petición
+const (
+	ModoAdvertencia = "warning"
+	ModoError       = "error"
+)
+
+// nombresGenero relaciona el sexo de la CURP con el nombre sembrado en la tabla generos
+var nombresGenero = map[string]string{
+	SexoHombre:    "Masculino",
+	SexoMujer:     "Femenino",
+	SexoNoBinario: "No especificado",
+}
+
+// Discrepancia describe un dato capturado que contradice la CURP
+type Discrepancia struct {
+	Campo    string `json:"field"`
+	Esperado string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Capturados son los datos tal como se van a guardar; los valores vacíos no se comparan
+type Capturados struct {
+	FechaNac        time.Time // users.fecha_nac
+	GeneroID        uint      // users.genero_id
+	FechaNacimiento time.Time // estudiantes.fecha_nacimiento
+	EdoOrigen       string    // estudiantes.edo_origen
+}
+
+// Modo regresa el modo configurado; cualquier valor distinto de "error" se trata como advertencia
+func Modo() string {
+	if config.GetEnv("CURP_DISCREPANCIAS", ModoAdvertencia) == ModoError {
+		return ModoError
+	}
+	return ModoAdvertencia
+}
+
+// GeneroID busca en la organización del contexto el género que corresponde al sexo de la CURP;
+// regresa 0 si no está sembrado. Los IDs encontrados se guardan en el grupo de caché de géneros, que se
+// invalida al editar el catálogo.
+func GeneroID(ctx context.Context, sexo string) (uint, error) {
+	orgID, _ := organizacion.De(ctx)
+	llave := "sexo:" + strconv.FormatUint(uint64(orgID), 10) + ":" + sexo
+	if id, _, ok := cache.Obtener(cache.GrupoGeneros, llave); ok {
+		return id.(uint), nil
+	}
+	generacion := cache.Generacion(cache.GrupoGeneros)
+	var genero models.Genero
+	if err := database.De(ctx).Where("nombre = ?", nombresGenero[sexo]).Limit(1).Find(&genero).Error; err != nil {
+		return 0, err
+	}
+	if genero.ID != 0 {
+		cache.Guardar(cache.GrupoGeneros, llave, genero.ID, generacion)
+	}
+	return genero.ID, nil
+}
+
+// Comparar regresa los campos capturados que no coinciden con lo que codifica la CURP
+func (d Datos) Comparar(ctx context.Context, c Capturados) ([]Discrepancia, error) {
+	var discrepancias []Discrepancia
+	esperada := d.FechaNacimiento.Format("2006-01-02")
+
+	if !c.FechaNac.IsZero() && !d.MismaFecha(c.FechaNac) {
+		discrepancias = append(discrepancias, Discrepancia{Campo: "fecha_nac", Esperado: esperada, Actual: c.FechaNac.Format("2006-01-02")})
+	}
+	if !c.FechaNacimiento.IsZero() && !d.MismaFecha(c.FechaNacimiento) {
+		discrepancias = append(discrepancias, Discrepancia{Campo: "fecha_nacimiento", Esperado: esperada, Actual: c.FechaNacimiento.Format("2006-01-02")})
+	}
+	if c.EdoOrigen != "" && !d.MismoEstado(c.EdoOrigen) {
+		discrepancias = append(discrepancias, Discrepancia{Campo: "edo_origen", Esperado: d.Estado, Actual: c.EdoOrigen})
+	}
+	if c.GeneroID != 0 {
+		generoID, err := GeneroID(ctx, d.Sexo)
+		if err != nil {
+			return nil, err
+		}
+		if generoID != 0 && generoID != c.GeneroID {
+			discrepancias = append(discrepancias, Discrepancia{
+				Campo:    "genero_id",
+				Esperado: strconv.FormatUint(uint64(generoID), 10) + " (" + nombresGenero[d.Sexo] + ")",
+				Actual:   strconv.FormatUint(uint64(c.GeneroID), 10),
+			})
+		}
+	}
+	return discrepancias, nil
+}
+
+// Verificar compara los datos capturados con la CURP y aplica el modo configurado.
+// En modo advertencia regresa las discrepancias (y las agrega al header Warning) para incluirlas en la respuesta;
+// en modo error regresa un 422 con el detalle por campo.
+func Verificar(c *gin.Context, valor string, capturados Capturados) ([]Discrepancia, *errores.Error) {
+	datos, err := Parsear(valor)
+	if err != nil {
+		// La estructura ya se valida en el binding; una CURP inválida aquí no tiene datos que comparar
+		return nil, nil
+	}
+	discrepancias, err := datos.Comparar(c, capturados)
+	if err != nil {
+		return nil, errores.Interno("Error verificando los datos contra la CURP", err)
+	}
+	if len(discrepancias) == 0 {
+		return nil, nil
+	}
+
+	if Modo() == ModoError {
+		e := errores.Nuevo(http.StatusUnprocessableEntity, errores.CURPInconsistente, "Los datos capturados no coinciden con la CURP")
+		for _, d := range discrepancias {
+			e.Campos = append(e.Campos, errores.CampoError{
+				Campo:   d.Campo,
+				Regla:   "curp",
+				Mensaje: "No coincide con la CURP (se esperaba " + d.Esperado + ")",
+			})
+		}
+		return nil, e
+	}
+
+	for _, d := range discrepancias {
+		c.Writer.Header().Add("Warning", `299 - "`+d.Campo+` no coincide con la CURP"`)
+	}
+	return discrepancias, nil
+}
+
+// Faltantes apunta a los campos de entrada que se pueden llenar con la CURP; los nil se ignoran
+type Faltantes struct {
+	FechaNac        *string // YYYY-MM-DD
+	GeneroID        *uint
+	FechaNacimiento *string // YYYY-MM-DD
+	EdoOrigen       *string
+}
+
+// Completar llena con los datos de la CURP los campos que vienen vacíos
+func Completar(ctx context.Context, valor string, f Faltantes) error {
+	datos, err := Parsear(valor)
+	if err != nil {
+		return nil
+	}
+	fecha := datos.FechaNacimiento.Format("2006-01-02")
+	if f.FechaNac != nil && *f.FechaNac == "" {
+		*f.FechaNac = fecha
+	}
+	if f.FechaNacimiento != nil && *f.FechaNacimiento == "" {
+		*f.FechaNacimiento = fecha
+	}
+	if f.EdoOrigen != nil && *f.EdoOrigen == "" {
+		*f.EdoOrigen = datos.Estado
+	}
+	if f.GeneroID != nil && *f.GeneroID == 0 {
+		generoID, err := GeneroID(ctx, datos.Sexo)
+		if err != nil {
+			return err
+		}
+		*f.GeneroID = generoID
+	}
+	return nil
+}
+
+// CompletarUsuario llena la fecha de nacimiento y el género vacíos de un usuario a partir de su CURP
+func CompletarUsuario(ctx context.Context, u *models.User) error {
+	datos, err := Parsear(u.CURP)
+	if err != nil {
+		return nil
+	}
+	if u.FechaNac.IsZero() {
+		u.FechaNac = datos.FechaNacimiento
+	}
+	if u.GeneroID == 0 {
+		generoID, err := GeneroID(ctx, datos.Sexo)
+		if err != nil {
+			return err
+		}
+		u.GeneroID = generoID
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import "fmt"
+
+// Expresiones indexadas para la búsqueda de personas. Las consultas deben usar exactamente
+// estas mismas expresiones para que Postgres aproveche los índices trigram.
+const (
+	ExprNombreCompleto = "f_unaccent(lower(users.nombre || ' ' || users.apellido_p || ' ' || users.apellido_m))"
+	ExprEmail          = "lower(users.email)"
+	ExprMatricula      = "lower(estudiantes.matricula)"
+	ExprNumeroEmpleado = "lower(personals.numero_empleado)"
+	ExprNombreTutor    = "f_unaccent(lower(tutors.nombre))"
+)
+
+// sentenciasBusqueda habilita pg_trgm y unaccent y crea los índices usados por la búsqueda de personas.
+// unaccent() no es IMMUTABLE, por eso se envuelve en f_unaccent para poder indexarla.
+var sentenciasBusqueda = []string{
+	"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+	"CREATE EXTENSION IF NOT EXISTS unaccent",
+	`CREATE OR REPLACE FUNCTION f_unaccent(text) RETURNS text
+		AS $$ SELECT public.unaccent('public.unaccent', $1) $$
+		LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT`,
+	indiceTrigram("idx_users_nombre_completo_trgm", "users", ExprNombreCompleto),
+	indiceTrigram("idx_users_email_trgm", "users", ExprEmail),
+	indiceTrigram("idx_users_curp_trgm", "users", "curp"),
+	indiceTrigram("idx_estudiantes_matricula_trgm", "estudiantes", ExprMatricula),
+	indiceTrigram("idx_estudiantes_telefono_trgm", "estudiantes", "telefono"),
+	indiceTrigram("idx_personals_numero_empleado_trgm", "personals", ExprNumeroEmpleado),
+	indiceTrigram("idx_personals_telefono1_trgm", "personals", "telefono1"),
+	indiceTrigram("idx_personals_telefono2_trgm", "personals", "telefono2"),
+	indiceTrigram("idx_tutors_nombre_trgm", "tutors", ExprNombreTutor),
+	indiceTrigram("idx_tutors_telefono_trgm", "tutors", "telefono"),
+	indiceTrigram("idx_tutors_telefono2_trgm", "tutors", "telefono2"),
+}
+
+func indiceTrigram(nombre, tabla, expresion string) string {
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING gin ((%s) gin_trgm_ops)", nombre, tabla, expresion)
+}
+
+// PrepararBusqueda crea (si no existen) las extensiones, la función y los índices de búsqueda.
+// Requiere que las tablas ya existan y que el usuario de la base pueda crear extensiones.
+func PrepararBusqueda() error {
+	for _, sentencia := range sentenciasBusqueda {
+		if err := DB.Exec(sentencia).Error; err != nil {
+			return fmt.Errorf("%s: %w", sentencia, err)
+		}
+	}
+	return nil
+}
@@ -1,31 +1,139 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+
+	"api-margaritai/config"
+	"api-margaritai/organizacion"
 )
 
 var DB *gorm.DB
 
+// ReadDB apunta a la réplica de lectura si DB_REPLICA_HOST está configurado; si no, es la misma conexión que DB.
+// Se usa en los endpoints de listado que toleran un pequeño retraso de replicación.
+var ReadDB *gorm.DB
+
+// De regresa la conexión principal limitada a la organización del contexto (la petición, con
+// organizacion.Con o con organizacion.Global). Sin organización, las consultas a sus modelos fallan.
+func De(ctx context.Context) *gorm.DB {
+	return DB.WithContext(ctx)
+}
+
+// LecturaDe es como De pero sobre la réplica de lectura
+func LecturaDe(ctx context.Context) *gorm.DB {
+	return ReadDB.WithContext(ctx)
+}
+
 func ConnectDB() {
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
+	db, err := openWithRetry("principal", buildDSN(
 		os.Getenv("DB_HOST"),
 		os.Getenv("DB_USER"),
 		os.Getenv("DB_PASSWORD"),
 		os.Getenv("DB_NAME"),
 		os.Getenv("DB_PORT"),
-	)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	))
 	if err != nil {
 		log.Fatal("Error connecting to database: ", err)
 	}
 
 	DB = db
+	ReadDB = db
 	fmt.Println("Database connected successfully")
-}
\ No newline at end of file
+
+	if replicaHost := os.Getenv("DB_REPLICA_HOST"); replicaHost != "" {
+		replica, err := openWithRetry("réplica", buildDSN(
+			replicaHost,
+			config.GetEnv("DB_REPLICA_USER", os.Getenv("DB_USER")),
+			config.GetEnv("DB_REPLICA_PASSWORD", os.Getenv("DB_PASSWORD")),
+			config.GetEnv("DB_REPLICA_NAME", os.Getenv("DB_NAME")),
+			config.GetEnv("DB_REPLICA_PORT", os.Getenv("DB_PORT")),
+		))
+		if err != nil {
+			// La réplica es opcional: si no responde, las lecturas siguen en la base principal
+			log.Printf("No se pudo conectar a la réplica de lectura, usando la base principal: %v", err)
+			return
+		}
+		ReadDB = replica
+		fmt.Println("Read replica connected successfully")
+	}
+}
+
+// buildDSN arma la cadena de conexión con sslmode y statement_timeout configurables
+func buildDSN(host, user, password, dbname, port string) string {
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=UTC",
+		host,
+		user,
+		password,
+		dbname,
+		port,
+		config.GetEnv("DB_SSLMODE", "disable"),
+	)
+	if rootCert := os.Getenv("DB_SSLROOTCERT"); rootCert != "" {
+		dsn += " sslrootcert=" + rootCert
+	}
+	// pgx envía los parámetros desconocidos como parámetros de sesión de Postgres
+	if timeout := config.GetEnvDuration("DB_STATEMENT_TIMEOUT", 30*time.Second); timeout > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", timeout.Milliseconds())
+	}
+	return dsn
+}
+
+// openWithRetry abre la conexión reintentando con backoff exponencial mientras Postgres termina de arrancar
+func openWithRetry(nombre, dsn string) (*gorm.DB, error) {
+	maxIntentos := config.GetEnvInt("DB_CONNECT_RETRIES", 10)
+	if maxIntentos < 1 {
+		maxIntentos = 1
+	}
+	espera := config.GetEnvDuration("DB_RETRY_INITIAL_BACKOFF", 1*time.Second)
+	esperaMaxima := config.GetEnvDuration("DB_RETRY_MAX_BACKOFF", 30*time.Second)
+
+	var err error
+	for intento := 1; intento <= maxIntentos; intento++ {
+		var db *gorm.DB
+		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		if err == nil {
+			if err = configurePool(db); err == nil {
+				if err = organizacion.Registrar(db); err == nil {
+					return db, nil
+				}
+			}
+			if sqlDB, e := db.DB(); e == nil {
+				sqlDB.Close()
+			}
+		}
+
+		if intento == maxIntentos {
+			break
+		}
+		log.Printf("Base de datos %s no disponible (intento %d/%d): %v. Reintentando en %s", nombre, intento, maxIntentos, err, espera)
+		time.Sleep(espera)
+		espera *= 2
+		if espera > esperaMaxima {
+			espera = esperaMaxima
+		}
+	}
+	return nil, fmt.Errorf("no se pudo conectar después de %d intentos: %w", maxIntentos, err)
+}
+
+// configurePool aplica los límites del pool de conexiones y verifica que la conexión responda
+func configurePool(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	sqlDB.SetMaxOpenConns(config.GetEnvInt("DB_MAX_OPEN_CONNS", 25))
+	sqlDB.SetMaxIdleConns(config.GetEnvInt("DB_MAX_IDLE_CONNS", 10))
+	sqlDB.SetConnMaxLifetime(config.GetEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute))
+	sqlDB.SetConnMaxIdleTime(config.GetEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute))
+
+	return sqlDB.Ping()
+}
@@ -0,0 +1,168 @@
+// Package prueba abre una conexión de GORM sobre un driver falso que anota cada sentencia con sus argumentos
+// y responde con las filas que indique la prueba. Sirve para revisar sin PostgreSQL el SQL que arma la API,
+// por ejemplo que los filtros de organización lleguen a la consulta. Solo lo usan los archivos _test.go.
+package prueba
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Sentencia es una consulta o ejecución que recibió el driver, con el SQL ya en la sintaxis de PostgreSQL ($1, $2...)
+type Sentencia struct {
+	SQL  string
+	Args []any
+}
+
+// Filas es la respuesta a una consulta
+type Filas struct {
+	Columnas []string
+	Valores  [][]any
+}
+
+// Respondedor decide qué filas regresa cada consulta; sin respondedor todas regresan vacías
+type Respondedor func(s Sentencia) Filas
+
+// Conexion guarda lo que recibió el driver de una base abierta con Abrir
+type Conexion struct {
+	mu          sync.Mutex
+	sentencias  []Sentencia
+	respondedor Respondedor
+}
+
+// Responder cambia las filas que regresan las consultas siguientes
+func (c *Conexion) Responder(r Respondedor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.respondedor = r
+}
+
+// Sentencias regresa lo recibido desde la última llamada a Limpiar
+func (c *Conexion) Sentencias() []Sentencia {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Sentencia(nil), c.sentencias...)
+}
+
+// Limpiar olvida las sentencias recibidas
+func (c *Conexion) Limpiar() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sentencias = nil
+}
+
+// Buscar regresa las sentencias cuyo SQL contiene el texto
+func (c *Conexion) Buscar(texto string) []Sentencia {
+	var encontradas []Sentencia
+	for _, s := range c.Sentencias() {
+		if strings.Contains(s.SQL, texto) {
+			encontradas = append(encontradas, s)
+		}
+	}
+	return encontradas
+}
+
+func (c *Conexion) anotar(consulta string, args []driver.NamedValue) Sentencia {
+	s := Sentencia{SQL: consulta, Args: make([]any, len(args))}
+	for i, a := range args {
+		s.Args[i] = a.Value
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sentencias = append(c.sentencias, s)
+	return s
+}
+
+func (c *Conexion) filas(s Sentencia) Filas {
+	c.mu.Lock()
+	r := c.respondedor
+	c.mu.Unlock()
+	if r == nil {
+		return Filas{}
+	}
+	return r(s)
+}
+
+var (
+	registroOnce sync.Once
+	conexiones   sync.Map // DSN -> *Conexion
+	contador     atomic.Int64
+)
+
+// Abrir regresa una base de GORM con el dialecto de PostgreSQL cuyo driver solo anota lo que recibe
+func Abrir() (*gorm.DB, *Conexion, error) {
+	registroOnce.Do(func() { sql.Register("prueba", controlador{}) })
+	dsn := fmt.Sprintf("prueba-%d", contador.Add(1))
+	conexion := &Conexion{}
+	conexiones.Store(dsn, conexion)
+	db, err := gorm.Open(postgres.New(postgres.Config{DriverName: "prueba", DSN: dsn}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	return db, conexion, err
+}
+
+type controlador struct{}
+
+func (controlador) Open(dsn string) (driver.Conn, error) {
+	c, ok := conexiones.Load(dsn)
+	if !ok {
+		return nil, fmt.Errorf("prueba: conexión desconocida %q", dsn)
+	}
+	return &conn{c.(*Conexion)}, nil
+}
+
+type conn struct{ *Conexion }
+
+func (c *conn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prueba: Prepare no está soportado")
+}
+func (c *conn) Close() error              { return nil }
+func (c *conn) Begin() (driver.Tx, error) { return transaccion{}, nil }
+
+// CheckNamedValue acepta cualquier argumento tal cual, para que la prueba vea el mismo valor que pasó el código
+func (c *conn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *conn) QueryContext(_ context.Context, consulta string, args []driver.NamedValue) (driver.Rows, error) {
+	f := c.filas(c.anotar(consulta, args))
+	return &filas{Filas: f}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, consulta string, args []driver.NamedValue) (driver.Result, error) {
+	c.anotar(consulta, args)
+	return driver.RowsAffected(1), nil
+}
+
+type transaccion struct{}
+
+func (transaccion) Commit() error   { return nil }
+func (transaccion) Rollback() error { return nil }
+
+type filas struct {
+	Filas
+	siguiente int
+}
+
+func (f *filas) Columns() []string { return f.Columnas }
+func (f *filas) Close() error      { return nil }
+
+func (f *filas) Next(dest []driver.Value) error {
+	if f.siguiente >= len(f.Valores) {
+		return io.EOF
+	}
+	for i, v := range f.Valores[f.siguiente] {
+		dest[i] = v
+	}
+	f.siguiente++
+	return nil
+}
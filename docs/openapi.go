@@ -0,0 +1,330 @@
+// Package docs genera la especificación OpenAPI 3 de la API a partir de los modelos y las estructuras de entrada
+package docs
+
+import (
+	_ "embed"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/parche"
+	"api-margaritai/tablas"
+)
+
+// Parametro describe un parámetro de query string de una operación
+type Parametro struct {
+	Nombre      string
+	Tipo        string // integer, string, boolean
+	Descripcion string
+}
+
+// Operacion documenta una ruta registrada en routes.SetupRouter
+type Operacion struct {
+	Metodo  string
+	Ruta    string // misma ruta que en gin, por ejemplo /api/protected/roles/:id
+	Resumen string
+	Tag     string
+	Entrada any // valor cuyo tipo describe el cuerpo JSON; nil si no recibe cuerpo
+	// Formulario describe un cuerpo multipart/form-data, para las rutas que reciben archivos
+	Formulario Schema
+	Respuesta  any // valor, de(valor) o Schema con la respuesta exitosa; nil si no tiene cuerpo
+	Estado     int // código de la respuesta exitosa; 200 por defecto
+	Publica    bool
+	Query      []Parametro
+	// Versionado indica que la respuesta lleva ETag; en PUT y PATCH además se exige If-Match (412 y 428)
+	Versionado bool
+	// EnCache indica un GET que el servidor guarda en memoria; responde ETag y Last-Modified y admite
+	// If-None-Match / If-Modified-Since (304)
+	EnCache bool
+	// Asincrona indica que además puede responder 202 con Location cuando el trabajo sigue en segundo plano
+	Asincrona bool
+	// Descarga indica que la respuesta es un archivo en uno de los formatos de tablas.Formatos en lugar de JSON
+	Descarga bool
+	// Parche indica un PATCH: Entrada es la lista blanca y el cuerpo se acepta como JSON Merge Patch o JSON Patch
+	Parche bool
+}
+
+//go:embed swagger.html
+var swaggerHTML []byte
+
+var (
+	especificacion     gin.H
+	especificacionOnce sync.Once
+)
+
+// Especificacion construye (una sola vez) el documento OpenAPI a partir de las operaciones registradas
+func Especificacion() gin.H {
+	especificacionOnce.Do(func() {
+		especificacion = construir(operaciones)
+	})
+	return especificacion
+}
+
+// ServirEspecificacion responde el documento en /openapi.json
+func ServirEspecificacion(c *gin.Context) {
+	c.JSON(http.StatusOK, Especificacion())
+}
+
+// ServirSwaggerUI responde la interfaz interactiva que consume /openapi.json
+func ServirSwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", swaggerHTML)
+}
+
+// RutasSinDocumentar regresa las rutas registradas en gin que no tienen operación en la especificación
+func RutasSinDocumentar(rutas gin.RoutesInfo) []string {
+	documentadas := make(map[string]bool, len(operaciones))
+	for _, op := range operaciones {
+		documentadas[op.Metodo+" "+op.Ruta] = true
+	}
+
+	var faltantes []string
+	for _, ruta := range rutas {
+		clave := ruta.Method + " " + ruta.Path
+		if !documentadas[clave] {
+			faltantes = append(faltantes, clave)
+		}
+	}
+	sort.Strings(faltantes)
+	return faltantes
+}
+
+func construir(ops []Operacion) gin.H {
+	g := nuevoGenerador()
+	paths := map[string]Schema{}
+
+	for _, op := range ops {
+		ruta, parametros := convertirRuta(op.Ruta)
+		for _, q := range op.Query {
+			parametros = append(parametros, Schema{
+				"name":        q.Nombre,
+				"in":          "query",
+				"description": q.Descripcion,
+				"schema":      Schema{"type": q.Tipo},
+			})
+		}
+
+		operacion := Schema{
+			"summary":     op.Resumen,
+			"tags":        []string{op.Tag},
+			"operationId": strings.ToLower(op.Metodo) + strings.NewReplacer("/", "_", ":", "", "{", "", "}", "").Replace(op.Ruta),
+			"responses":   respuestas(g, op),
+		}
+		if op.Versionado && op.Metodo != http.MethodGet {
+			parametros = append(parametros, Schema{
+				"name":        "If-Match",
+				"in":          "header",
+				"required":    true,
+				"description": "ETag obtenido al leer el registro; si otro usuario lo modificó después se responde 412",
+				"schema":      Schema{"type": "string"},
+			})
+		}
+		if op.EnCache {
+			parametros = append(parametros, Schema{
+				"name":        "If-None-Match",
+				"in":          "header",
+				"description": "ETag de la respuesta anterior; si no ha cambiado se responde 304 sin cuerpo",
+				"schema":      Schema{"type": "string"},
+			}, Schema{
+				"name":        "If-Modified-Since",
+				"in":          "header",
+				"description": "Last-Modified de la respuesta anterior; se ignora si viene If-None-Match",
+				"schema":      Schema{"type": "string"},
+			})
+		}
+		if strings.HasPrefix(op.Ruta, rutaAPI+"/") {
+			parametros = append(parametros, Schema{
+				"name":        "X-Organizacion",
+				"in":          "header",
+				"description": "Slug de la organización; si se omite se toma del subdominio o se usa la principal. En las rutas protegidas debe coincidir con la del token",
+				"schema":      Schema{"type": "string"},
+			})
+		}
+		if idempotente(op) {
+			parametros = append(parametros, Schema{
+				"name":        "Idempotency-Key",
+				"in":          "header",
+				"description": "Identificador único de la operación (por ejemplo un UUID). Los reintentos con la misma clave y el mismo cuerpo reciben la respuesta original con Idempotent-Replayed: true",
+				"schema":      Schema{"type": "string", "maxLength": 255},
+			})
+		}
+		if len(parametros) > 0 {
+			operacion["parameters"] = parametros
+		}
+		if op.Entrada != nil {
+			operacion["requestBody"] = Schema{
+				"required": true,
+				"content": Schema{
+					"application/json": Schema{"schema": g.resolver(op.Entrada)},
+				},
+			}
+		}
+		if op.Parche {
+			operacion["requestBody"] = Schema{
+				"required":    true,
+				"description": "Solo los campos enviados cambian; null vacía el campo. Con JSON Patch se aceptan add, replace, remove y test",
+				"content": Schema{
+					parche.TipoMergePatch: Schema{"schema": g.resolver(op.Entrada)},
+					parche.TipoJSONPatch:  Schema{"schema": operacionesJSONPatch},
+				},
+			}
+		}
+		if op.Formulario != nil {
+			operacion["requestBody"] = Schema{
+				"required": true,
+				"content": Schema{
+					"multipart/form-data": Schema{"schema": op.Formulario},
+				},
+			}
+		}
+		if !op.Publica {
+			operacion["security"] = []any{Schema{"bearerAuth": []string{}}}
+		}
+
+		if paths[ruta] == nil {
+			paths[ruta] = Schema{}
+		}
+		paths[ruta][strings.ToLower(op.Metodo)] = operacion
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":       "Margarita API",
+			"version":     "1.0.0",
+			"description": "API de gestión escolar: usuarios, roles, permisos y catálogos.",
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": g.componentes,
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+// idempotente indica si la operación acepta Idempotency-Key: los POST protegidos (login y registro no,
+// porque su respuesta lleva un token)
+func idempotente(op Operacion) bool {
+	return op.Metodo == http.MethodPost && !op.Publica
+}
+
+func respuestas(g *generador, op Operacion) Schema {
+	estado := op.Estado
+	if estado == 0 {
+		estado = http.StatusOK
+	}
+
+	exito := Schema{"description": http.StatusText(estado)}
+	if op.Respuesta != nil {
+		exito["content"] = Schema{"application/json": Schema{"schema": g.resolver(op.Respuesta)}}
+	}
+	if op.Descarga {
+		contenido := Schema{}
+		for _, formato := range tablas.Formatos {
+			tipo, _, _ := strings.Cut(tablas.TipoContenido(formato), ";")
+			contenido[tipo] = Schema{"schema": Schema{"type": "string", "format": "binary"}}
+		}
+		exito["content"] = contenido
+		exito["headers"] = Schema{"Content-Disposition": Schema{"description": "attachment con el nombre sugerido del archivo", "schema": Schema{"type": "string"}}}
+	}
+	if op.Versionado {
+		exito["headers"] = Schema{"ETag": Schema{"description": "Versión del registro, para enviarla en If-Match", "schema": Schema{"type": "string"}}}
+	}
+	if op.EnCache {
+		exito["headers"] = Schema{
+			"ETag":          Schema{"description": "Identifica el contenido de la respuesta, para enviarlo en If-None-Match", "schema": Schema{"type": "string"}},
+			"Last-Modified": Schema{"description": "Momento en que se generó la respuesta, para enviarlo en If-Modified-Since", "schema": Schema{"type": "string"}},
+			"Cache-Control": Schema{"schema": Schema{"type": "string"}},
+		}
+	}
+
+	errorSchema := Schema{"application/json": Schema{"schema": g.resolver(respuestaError)}}
+	r := Schema{
+		strconv.Itoa(estado): exito,
+		"400":                Schema{"description": "Solicitud inválida", "content": errorSchema},
+		"500":                Schema{"description": "Error interno", "content": errorSchema},
+	}
+	if op.Asincrona {
+		aceptado := Schema{
+			"description": "Aceptado; se procesa en segundo plano y Location indica dónde consultar el avance",
+			"headers":     Schema{"Location": Schema{"schema": Schema{"type": "string"}}},
+		}
+		if op.Respuesta != nil {
+			aceptado["content"] = exito["content"]
+		}
+		r["202"] = aceptado
+	}
+	if op.EnCache {
+		r["304"] = Schema{"description": "La respuesta no ha cambiado desde la versión que tiene el cliente"}
+	}
+	if strings.Contains(op.Ruta, ":") {
+		r["404"] = Schema{"description": "Recurso no encontrado", "content": errorSchema}
+	}
+	if op.Metodo != http.MethodGet {
+		r["409"] = Schema{"description": "Registro duplicado o en uso", "content": errorSchema}
+	}
+	if idempotente(op) {
+		r["409"] = Schema{"description": "Registro duplicado o en uso, o la petición con la misma Idempotency-Key sigue en proceso", "content": errorSchema}
+		r["422"] = Schema{"description": "La Idempotency-Key ya se usó con un cuerpo distinto", "content": errorSchema}
+	}
+	if op.Parche {
+		r["409"] = Schema{"description": "Registro duplicado o en uso, o falló una operación test del JSON Patch", "content": errorSchema}
+		r["415"] = Schema{"description": "Content-Type distinto de " + parche.TipoMergePatch + " o " + parche.TipoJSONPatch, "content": errorSchema}
+	}
+	if op.Versionado && op.Metodo != http.MethodGet {
+		r["412"] = Schema{"description": "El registro cambió desde que se leyó; current trae la versión vigente", "content": errorSchema}
+		r["428"] = Schema{"description": "Falta el encabezado If-Match", "content": errorSchema}
+	}
+	if strings.HasPrefix(op.Ruta, rutaAPI+"/") {
+		r["403"] = Schema{"description": "Sin permiso, la organización está desactivada o no coincide con la del token", "content": errorSchema}
+		r["404"] = Schema{"description": "Recurso u organización no encontrados", "content": errorSchema}
+		r["429"] = Schema{"description": "Demasiadas peticiones; Retry-After indica cuántos segundos esperar", "content": errorSchema}
+	}
+	if !op.Publica {
+		r["401"] = Schema{"description": "Token ausente, inválido o expirado", "content": errorSchema}
+	}
+	return r
+}
+
+// operacionesJSONPatch describe el cuerpo JSON Patch (RFC 6902) con las operaciones que acepta el paquete parche
+var operacionesJSONPatch = Schema{
+	"type": "array",
+	"items": Schema{
+		"type":     "object",
+		"required": []string{"op", "path"},
+		"properties": Schema{
+			"op":    Schema{"type": "string", "enum": []string{"add", "replace", "remove", "test"}},
+			"path":  Schema{"type": "string", "description": "JSON Pointer al campo, por ejemplo /user/nombre"},
+			"value": Schema{"description": "Valor nuevo, o el esperado en test"},
+		},
+	},
+}
+
+// convertirRuta cambia /roles/:id por /roles/{id} y genera los parámetros de ruta
+func convertirRuta(ruta string) (string, []any) {
+	var parametros []any
+	segmentos := strings.Split(ruta, "/")
+	for i, seg := range segmentos {
+		if !strings.HasPrefix(seg, ":") && !strings.HasPrefix(seg, "*") {
+			continue
+		}
+		nombre := seg[1:]
+		tipo := "string"
+		if nombre == "id" || strings.HasSuffix(nombre, "_id") {
+			tipo = "integer"
+		}
+		parametros = append(parametros, Schema{
+			"name":     nombre,
+			"in":       "path",
+			"required": true,
+			"schema":   Schema{"type": tipo},
+		})
+		segmentos[i] = "{" + nombre + "}"
+	}
+	return strings.Join(segmentos, "/"), parametros
+}
@@ -0,0 +1,493 @@
+package docs
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"api-margaritai/consulta"
+	"api-margaritai/controllers"
+	gestioncatalogos "api-margaritai/controllers/gestion_catalogos"
+	gestionusuarios "api-margaritai/controllers/gestion_usuarios"
+	"api-margaritai/errores"
+	"api-margaritai/exportacion"
+	"api-margaritai/horario"
+	"api-margaritai/models"
+	"api-margaritai/tablas"
+	"api-margaritai/tareas"
+)
+
+var (
+	texto  = Schema{"type": "string"}
+	entero = Schema{"type": "integer"}
+)
+
+func objeto(propiedades Schema) Schema {
+	return Schema{"type": "object", "properties": propiedades}
+}
+
+func arreglo(items any) Schema {
+	return Schema{"type": "array", "items": items}
+}
+
+// paginado describe los listados de la forma {"message": "...", clave: [...], "paginacion": {...}}
+func paginado(clave string, item any) Schema {
+	return objeto(Schema{"message": texto, clave: arreglo(item), "paginacion": paginacion})
+}
+
+// listado documenta los parámetros de paginación, orden y filtros que acepta una consulta
+func listado(def consulta.Definicion) []Parametro {
+	parametros := []Parametro{
+		{Nombre: consulta.ParamPagina, Tipo: "integer", Descripcion: "Página a obtener, desde 1"},
+		{Nombre: consulta.ParamTamano, Tipo: "integer", Descripcion: fmt.Sprintf("Registros por página (máximo %d, %d por defecto)", consulta.TamanoMaximo, consulta.TamanoPorDefecto)},
+		{Nombre: consulta.ParamOrden, Tipo: "string", Descripcion: "Campos separados por coma; prefijo - para descendente. Permitidos: " + strings.Join(def.CamposOrden(), ", ")},
+	}
+	if def.Llave != "" {
+		parametros = append(parametros, Parametro{Nombre: consulta.ParamCursor, Tipo: "string", Descripcion: "Pagina por ID en lugar de page; vacío para la primera página y después el valor de paginacion.siguiente_cursor"})
+	}
+	parametros = append(parametros, seleccion(def)...)
+	return append(parametros, filtros(def)...)
+}
+
+// seleccion documenta ?include= y ?fields= de los recursos que los admiten, en el listado y en el detalle
+func seleccion(def consulta.Definicion) []Parametro {
+	var parametros []Parametro
+	if def.Incluir != nil {
+		parametros = append(parametros, Parametro{Nombre: consulta.ParamIncluir, Tipo: "string", Descripcion: "Relaciones a incluir, separadas por coma; con include o fields solo se responden las relaciones pedidas. Permitidas: " + strings.Join(def.NombresInclusiones(), ", ")})
+	}
+	if def.Campos != nil {
+		parametros = append(parametros, Parametro{Nombre: consulta.ParamCampos, Tipo: "string", Descripcion: "Campos propios a responder, separados por coma; id siempre se incluye. Permitidos: " + strings.Join(def.Campos, ", ")})
+	}
+	return parametros
+}
+
+// exportable documenta los parámetros de una descarga: el formato, el orden y los mismos filtros que el listado
+func exportable(def consulta.Definicion) []Parametro {
+	return append([]Parametro{
+		{Nombre: exportacion.ParamFormato, Tipo: "string", Descripcion: strings.Join(tablas.Formatos, ", ") + "; " + tablas.FormatoCSV + " por defecto"},
+		{Nombre: consulta.ParamOrden, Tipo: "string", Descripcion: "Campos separados por coma; prefijo - para descendente. Permitidos: " + strings.Join(def.CamposOrden(), ", ")},
+	}, filtros(def)...)
+}
+
+func filtros(def consulta.Definicion) []Parametro {
+	var parametros []Parametro
+	for _, nombre := range def.NombresFiltros() {
+		switch def.Filtros[nombre].Tipo {
+		case consulta.Entero:
+			parametros = append(parametros, Parametro{Nombre: nombre, Tipo: "string", Descripcion: "Igual a uno o varios IDs separados por coma"})
+		case consulta.Booleano:
+			parametros = append(parametros, Parametro{Nombre: nombre, Tipo: "boolean", Descripcion: "Filtra por " + nombre})
+		case consulta.Texto:
+			parametros = append(parametros, Parametro{Nombre: nombre, Tipo: "string", Descripcion: "Contiene el texto, sin distinguir mayúsculas"})
+		case consulta.Fecha:
+			parametros = append(parametros,
+				Parametro{Nombre: nombre + consulta.SufijoDesde, Tipo: "string", Descripcion: "Desde la fecha (YYYY-MM-DD o RFC 3339), inclusive"},
+				Parametro{Nombre: nombre + consulta.SufijoHasta, Tipo: "string", Descripcion: "Hasta la fecha (YYYY-MM-DD o RFC 3339), inclusive"})
+		}
+	}
+	return parametros
+}
+
+// conMensaje describe las respuestas de la forma {"message": "...", clave: valor}
+func conMensaje(clave string, valor any) Schema {
+	return objeto(Schema{"message": texto, clave: valor})
+}
+
+var (
+	soloMensaje    = objeto(Schema{"message": texto})
+	respuestaError = de(errores.Respuesta{})
+	paginacion     = de(consulta.Paginacion{})
+
+	permisosPorCategoria = arreglo(objeto(Schema{
+		"categoria": de(models.CategoriaPermiso{}),
+		"permisos":  arreglo(de(models.Permiso{})),
+	}))
+	permisosAgrupadosPorTitulo = Schema{"type": "object", "additionalProperties": arreglo(de(models.Permiso{}))}
+
+	horarioSemanal = []Parametro{
+		{Nombre: "ciclo_escolar_id", Tipo: "integer", Descripcion: "Clases de ese ciclo; sin él, las de los ciclos que no están cerrados"},
+	}
+)
+
+const (
+	rutaAPI       = "/api"
+	rutaProtegida = "/api/protected"
+)
+
+// operaciones contiene una entrada por cada ruta de routes.SetupRouter.
+// Al agregar una ruta nueva se debe documentar aquí; routes/rutas_test.go falla si alguna queda sin documentar.
+var operaciones = []Operacion{
+	// ---------- Sistema --------------
+	{Metodo: http.MethodGet, Ruta: "/health", Resumen: "Verifica que la API esté en línea", Tag: "Sistema", Publica: true},
+	{Metodo: http.MethodGet, Ruta: "/openapi.json", Resumen: "Especificación OpenAPI 3 de la API", Tag: "Sistema", Publica: true, Respuesta: Schema{"type": "object"}},
+	{Metodo: http.MethodGet, Ruta: "/docs", Resumen: "Documentación interactiva (Swagger UI)", Tag: "Sistema", Publica: true},
+
+	// ---------- Autenticación --------------
+	{Metodo: http.MethodPost, Ruta: rutaAPI + "/register", Resumen: "Registra un usuario y regresa su token", Tag: "Autenticación", Publica: true,
+		Entrada: controllers.RegisterInput{}, Estado: http.StatusCreated,
+		Respuesta: objeto(Schema{"message": texto, "token": texto, "user": de(models.User{})})},
+	{Metodo: http.MethodPost, Ruta: rutaAPI + "/login", Resumen: "Inicia sesión y regresa el token con los permisos del rol", Tag: "Autenticación", Publica: true,
+		Entrada:   controllers.LoginInput{},
+		Respuesta: objeto(Schema{"message": texto, "token": texto, "expires_at": texto, "user": de(models.User{})})},
+	{Metodo: http.MethodGet, Ruta: rutaAPI + "/validate-token", Resumen: "Valida el token enviado en Authorization", Tag: "Autenticación", Publica: true,
+		Respuesta: objeto(Schema{"message": texto, "status": entero, "expires_at": texto})},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/profile", Resumen: "Regresa el usuario autenticado", Tag: "Autenticación",
+		Respuesta: objeto(Schema{"message": texto, "user_id": entero})},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/logout", Resumen: "Invalida el token actual", Tag: "Autenticación",
+		Respuesta: objeto(Schema{"message": texto, "status": entero})},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/organizacion", Resumen: "Organización del usuario autenticado", Tag: "Autenticación",
+		Respuesta: conMensaje("organizacion", de(models.Organizacion{}))},
+
+	// ---------- Roles --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/roles/para_estudiante", Resumen: "Roles disponibles para estudiantes", Tag: "Roles", EnCache: true,
+		Query: listado(controllers.ConsultaRoles), Respuesta: paginado("roles", de(models.Rol{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/roles/para_personal", Resumen: "Roles disponibles para personal", Tag: "Roles", EnCache: true,
+		Query: listado(controllers.ConsultaRoles), Respuesta: paginado("roles", de(models.Rol{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/roles/para_tutor", Resumen: "Roles disponibles para tutores", Tag: "Roles", EnCache: true,
+		Query: listado(controllers.ConsultaRoles), Respuesta: paginado("roles", de(models.Rol{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/roles", Resumen: "Lista los roles", Tag: "Roles", EnCache: true,
+		Query: listado(controllers.ConsultaRoles), Respuesta: paginado("roles", de(models.Rol{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/roles", Resumen: "Crea un rol", Tag: "Roles",
+		Entrada: controllers.CreateRoleInput{}, Estado: http.StatusCreated, Respuesta: conMensaje("rol", de(models.Rol{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/roles/:id/permisos", Resumen: "Permisos de un rol agrupados por categoría", Tag: "Roles", EnCache: true,
+		Respuesta: conMensaje("permisos_agrupados", permisosPorCategoria)},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/roles/:id/permisos_agrupados", Resumen: "Permisos de un rol agrupados por título de categoría", Tag: "Roles", EnCache: true,
+		Respuesta: objeto(Schema{"message": texto, "rol_id": entero, "rol_nombre": texto, "permisos_agrupados": permisosAgrupadosPorTitulo})},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/roles/:id/permisos_con_asignacion", Resumen: "Todos los permisos indicando si están asignados al rol", Tag: "Roles", EnCache: true,
+		Respuesta: conMensaje("permisos_agrupados", arreglo(objeto(Schema{
+			"categoria": de(models.CategoriaPermiso{}),
+			"permisos": arreglo(objeto(Schema{
+				"id": entero, "nombre": texto, "descripcion": texto, "categoria_id": entero,
+				"categoria_permiso": de(models.CategoriaPermiso{}), "asignado": Schema{"type": "boolean"},
+			})),
+		})))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/roles/:id", Resumen: "Obtiene un rol con sus permisos", Tag: "Roles", Versionado: true,
+		Respuesta: objeto(Schema{"message": texto, "rol": de(models.Rol{}), "permisos_agrupados": permisosAgrupadosPorTitulo})},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/roles/:id", Resumen: "Actualiza un rol", Tag: "Roles", Versionado: true,
+		Entrada: controllers.UpdateRoleInput{}, Respuesta: conMensaje("rol", de(models.Rol{}))},
+	{Metodo: http.MethodPatch, Ruta: rutaProtegida + "/roles/:id", Resumen: "Modifica solo los campos enviados de un rol", Tag: "Roles", Versionado: true,
+		Entrada: controllers.PatchRoleInput{}, Parche: true, Respuesta: conMensaje("rol", de(models.Rol{}))},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/roles/:id", Resumen: "Envía un rol a la papelera", Tag: "Roles", Respuesta: soloMensaje},
+
+	// ---------- Permisos --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/permisos", Resumen: "Lista los permisos", Tag: "Permisos", EnCache: true,
+		Query: listado(controllers.ConsultaPermisos), Respuesta: paginado("permisos", de(models.Permiso{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/permisos", Resumen: "Crea un permiso; los permisos son comunes a todas las organizaciones y solo la principal los modifica", Tag: "Permisos",
+		Entrada: controllers.CreatePermisoInput{}, Estado: http.StatusCreated, Respuesta: conMensaje("permiso", de(models.Permiso{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/permisos/:id/roles", Resumen: "Roles que tienen asignado el permiso", Tag: "Permisos", EnCache: true,
+		Respuesta: conMensaje("roles", arreglo(de(models.Rol{})))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/permisos/:id", Resumen: "Obtiene un permiso", Tag: "Permisos", Versionado: true,
+		Respuesta: conMensaje("permiso", de(models.Permiso{}))},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/permisos/:id", Resumen: "Actualiza un permiso (solo la organización principal)", Tag: "Permisos", Versionado: true,
+		Entrada: controllers.UpdatePermisoInput{}, Respuesta: conMensaje("permiso", de(models.Permiso{}))},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/permisos/:id", Resumen: "Envía un permiso a la papelera (solo la organización principal)", Tag: "Permisos", Respuesta: soloMensaje},
+
+	// ---------- Categorías de permisos --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/categorias_permisos", Resumen: "Lista las categorías de permisos", Tag: "Categorías de permisos", EnCache: true,
+		Query: listado(controllers.ConsultaCategoriasPermisos), Respuesta: paginado("categorias", de(models.CategoriaPermiso{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/categorias_permisos/:id", Resumen: "Obtiene una categoría de permisos", Tag: "Categorías de permisos", Versionado: true,
+		Respuesta: conMensaje("categoria", de(models.CategoriaPermiso{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/categorias_permisos", Resumen: "Crea una categoría de permisos (solo la organización principal)", Tag: "Categorías de permisos",
+		Entrada: controllers.CreateCategoriaPermisoInput{}, Estado: http.StatusCreated, Respuesta: conMensaje("categoria", de(models.CategoriaPermiso{}))},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/categorias_permisos/:id", Resumen: "Actualiza una categoría de permisos (solo la organización principal)", Tag: "Categorías de permisos", Versionado: true,
+		Entrada: controllers.UpdateCategoriaPermisoInput{}, Respuesta: conMensaje("categoria", de(models.CategoriaPermiso{}))},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/categorias_permisos/:id", Resumen: "Envía una categoría de permisos a la papelera (solo la organización principal)", Tag: "Categorías de permisos", Respuesta: soloMensaje},
+
+	// ---------- Roles tienen permisos --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/roles_tienen_permisos", Resumen: "Lista las relaciones rol-permiso", Tag: "Roles y permisos", EnCache: true,
+		Query: listado(controllers.ConsultaRolesTienenPermisos), Respuesta: paginado("relaciones", de(models.RoleTienePermiso{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/roles_tienen_permisos/:role_id/:permiso_id", Resumen: "Obtiene una relación rol-permiso", Tag: "Roles y permisos",
+		Respuesta: conMensaje("relacion", de(models.RoleTienePermiso{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/roles_tienen_permisos", Resumen: "Asigna un permiso a un rol", Tag: "Roles y permisos",
+		Entrada: controllers.CreateRoleTienePermisoInput{}, Estado: http.StatusCreated, Respuesta: conMensaje("relacion", de(models.RoleTienePermiso{}))},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/roles_tienen_permisos/:role_id/:permiso_id", Resumen: "Quita un permiso de un rol", Tag: "Roles y permisos", Respuesta: soloMensaje},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/roles/asignar_permisos", Resumen: "Reemplaza los permisos de un rol", Tag: "Roles y permisos",
+		Entrada:   controllers.AsignarPermisosARolInput{},
+		Respuesta: objeto(Schema{"message": texto, "permisos_asignados": arreglo(de(models.RoleTienePermiso{})), "permisos_ya_asignados": arreglo(entero)})},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/roles/desasignar_permisos", Resumen: "Quita varios permisos de un rol", Tag: "Roles y permisos",
+		Entrada:   controllers.DesasignarPermisosARolInput{},
+		Respuesta: objeto(Schema{"message": texto, "permisos_desasignados": arreglo(entero), "permisos_no_asignados": arreglo(entero)})},
+
+	// ---------- Catálogos: Planteles --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/planteles", Resumen: "Lista los planteles", Tag: "Planteles",
+		Query: listado(gestioncatalogos.ConsultaPlanteles), Respuesta: objeto(Schema{"planteles": arreglo(de(models.Plantel{})), "paginacion": paginacion})},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/planteles", Resumen: "Crea un plantel", Tag: "Planteles",
+		Entrada: gestioncatalogos.PlantelInput{}, Estado: http.StatusCreated, Respuesta: conMensaje("plantel", de(models.Plantel{}))},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/planteles/:id", Resumen: "Edita un plantel", Tag: "Planteles", Versionado: true,
+		Entrada: gestioncatalogos.PlantelUpdateInput{}, Respuesta: conMensaje("plantel", de(models.Plantel{}))},
+	{Metodo: http.MethodPatch, Ruta: rutaProtegida + "/planteles/:id", Resumen: "Modifica solo los campos enviados de un plantel", Tag: "Planteles", Versionado: true,
+		Entrada: gestioncatalogos.PlantelParche{}, Parche: true, Respuesta: conMensaje("plantel", de(models.Plantel{}))},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/planteles/:id", Resumen: "Envía a la papelera un plantel sin estudiantes, niveles ni aulas", Tag: "Planteles",
+		Respuesta: conMensaje("plantel", de(models.Plantel{}))},
+
+	// ---------- Catálogos: Niveles escolares --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/niveles_escolares", Resumen: "Lista los niveles escolares", Tag: "Niveles escolares", EnCache: true,
+		Query:     listado(gestioncatalogos.ConsultaNivelesEscolares),
+		Respuesta: objeto(Schema{"niveles_escolares": arreglo(de(models.NivelEscolar{})), "paginacion": paginacion})},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/niveles_escolares", Resumen: "Crea un nivel escolar", Tag: "Niveles escolares",
+		Entrada: gestioncatalogos.NivelEscolarInput{}, Estado: http.StatusCreated, Respuesta: conMensaje("nivel_escolar", de(models.NivelEscolar{}))},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/niveles_escolares/:id", Resumen: "Edita un nivel escolar", Tag: "Niveles escolares", Versionado: true,
+		Entrada: gestioncatalogos.NivelEscolarUpdateInput{}, Respuesta: conMensaje("nivel_escolar", de(models.NivelEscolar{}))},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/niveles_escolares/:id", Resumen: "Envía a la papelera un nivel escolar sin estudiantes ni ciclos escolares", Tag: "Niveles escolares", Respuesta: soloMensaje},
+
+	// ---------- Catálogos: grados, grupos, grados académicos, estatus, puestos, géneros, tipos de contrato y aulas --------------
+	// Se documentan desde gestioncatalogos.Catalogos en operacionesDeCatalogo
+
+	// ---------- Usuarios: Estudiantes --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/estudiantes", Resumen: "Lista los estudiantes con su usuario", Tag: "Estudiantes",
+		Query: listado(gestionusuarios.ConsultaEstudiantes), Respuesta: paginado("estudiantes", de(models.Estudiante{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/estudiantes/:id", Resumen: "Obtiene un estudiante con su usuario", Tag: "Estudiantes", Versionado: true,
+		Query: seleccion(gestionusuarios.ConsultaEstudiantes),
+		Respuesta: conMensaje("estudiante", de(models.Estudiante{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/estudiantes", Resumen: "Crea un usuario y su estudiante", Tag: "Estudiantes",
+		Entrada: gestionusuarios.InsertarEstudianteInput{}, Estado: http.StatusCreated, Respuesta: conMensaje("estudiante", de(models.Estudiante{}))},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/estudiantes/:id", Resumen: "Edita un estudiante y su usuario", Tag: "Estudiantes", Versionado: true,
+		Entrada: gestionusuarios.EditarEstudianteInput{}, Respuesta: conMensaje("estudiante", de(models.Estudiante{}))},
+	{Metodo: http.MethodPatch, Ruta: rutaProtegida + "/estudiantes/:id", Resumen: "Modifica solo los campos enviados de un estudiante y su usuario", Tag: "Estudiantes", Versionado: true,
+		Entrada: gestionusuarios.EstudianteParche{}, Parche: true, Respuesta: conMensaje("estudiante", de(models.Estudiante{}))},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/estudiantes/:id", Resumen: "Envía a la papelera un estudiante y su usuario, y cierra sus sesiones", Tag: "Estudiantes", Respuesta: soloMensaje},
+
+	// ---------- Usuarios: Personal --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/personal", Resumen: "Lista el personal con su usuario", Tag: "Personal",
+		Query: listado(gestionusuarios.ConsultaPersonal), Respuesta: paginado("personal", de(models.Personal{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/personal/:id", Resumen: "Obtiene un registro de personal con su usuario", Tag: "Personal", Versionado: true,
+		Query: seleccion(gestionusuarios.ConsultaPersonal),
+		Respuesta: conMensaje("personal", de(models.Personal{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/personal", Resumen: "Crea un usuario y su registro de personal", Tag: "Personal",
+		Entrada: gestionusuarios.InsertarPersonalInput{}, Estado: http.StatusCreated, Respuesta: de(models.Personal{})},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/personal/:id", Resumen: "Edita un registro de personal y su usuario", Tag: "Personal", Versionado: true,
+		Entrada: gestionusuarios.EditarPersonalInput{}, Respuesta: de(models.Personal{})},
+	{Metodo: http.MethodPatch, Ruta: rutaProtegida + "/personal/:id", Resumen: "Modifica solo los campos enviados de un registro de personal y su usuario", Tag: "Personal", Versionado: true,
+		Entrada: gestionusuarios.PersonalParche{}, Parche: true, Respuesta: de(models.Personal{})},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/personal/:id", Resumen: "Envía a la papelera un registro de personal y su usuario, y cierra sus sesiones; un profesor con clases en un ciclo que no está cerrado no se elimina", Tag: "Personal",
+		Respuesta: objeto(Schema{"mensaje": texto})},
+
+	// ---------- Usuarios: Tutores --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/tutores", Resumen: "Lista los tutores con su usuario", Tag: "Tutores",
+		Query: listado(gestionusuarios.ConsultaTutores), Respuesta: paginado("tutores", de(models.Tutor{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/tutores/:id", Resumen: "Obtiene un tutor con su usuario", Tag: "Tutores", Versionado: true,
+		Query: seleccion(gestionusuarios.ConsultaTutores),
+		Respuesta: conMensaje("tutor", de(models.Tutor{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/tutores", Resumen: "Crea un usuario y su tutor", Tag: "Tutores",
+		Entrada: gestionusuarios.InsertarTutorInput{}, Estado: http.StatusCreated, Respuesta: de(models.Tutor{})},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/tutores/:id", Resumen: "Edita un tutor y su usuario", Tag: "Tutores", Versionado: true,
+		Entrada: gestionusuarios.EditarTutorInput{}, Respuesta: de(models.Tutor{})},
+	{Metodo: http.MethodPatch, Ruta: rutaProtegida + "/tutores/:id", Resumen: "Modifica solo los campos enviados de un tutor y su usuario", Tag: "Tutores", Versionado: true,
+		Entrada: gestionusuarios.TutorParche{}, Parche: true, Respuesta: de(models.Tutor{})},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/tutores/:id", Resumen: "Envía a la papelera un tutor y su usuario, y cierra sus sesiones", Tag: "Tutores",
+		Respuesta: objeto(Schema{"mensaje": texto})},
+
+	// ---------- Importación de estudiantes --------------
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/estudiantes/importar", Resumen: "Da de alta estudiantes, y opcionalmente sus tutores, desde un CSV o XLSX; con dry_run=true solo valida. Los archivos grandes se procesan en segundo plano (requiere \"" + models.PermisoImportarEstudiantes + "\")", Tag: "Estudiantes",
+		Asincrona: true,
+		Query:     []Parametro{{Nombre: "dry_run", Tipo: "boolean", Descripcion: "Solo valida y regresa el reporte por fila, sin guardar"}},
+		Formulario: objeto(Schema{"archivo": Schema{
+			"type":        "string",
+			"format":      "binary",
+			"description": "CSV (coma o punto y coma) o XLSX con encabezado. Columnas: " + strings.Join(gestionusuarios.ColumnasImportacionEstudiantes, ", ") + ". Opcionales para vincular o crear al tutor: " + strings.Join(gestionusuarios.ColumnasImportacionTutores, ", "),
+		}}),
+		Respuesta: conMensaje("importacion", de(models.Importacion{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/importaciones/:id", Resumen: "Estado, avance y errores por fila de una importación (requiere \"" + models.PermisoImportarEstudiantes + "\")", Tag: "Estudiantes",
+		Respuesta: conMensaje("importacion", de(models.Importacion{}))},
+
+	// ---------- Planes de estudio --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/grados/:id/materias", Resumen: "Plan de estudios del grado: sus materias en el orden en que se imparten, con créditos y horas totales", Tag: "Materias",
+		Respuesta: conMensaje("plan", de(gestioncatalogos.PlanEstudios{}))},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/grados/:id/materias", Resumen: "Reemplaza el plan de estudios del grado con las materias enviadas, en ese orden; las que no se envían quedan sin grado y las de otro grado se mueven", Tag: "Materias",
+		Entrada: gestioncatalogos.AsignacionMateriasInput{}, Respuesta: conMensaje("plan", de(gestioncatalogos.PlanEstudios{}))},
+
+	// ---------- Ciclos escolares --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/ciclos_escolares", Resumen: "Lista los ciclos escolares", Tag: "Ciclos escolares",
+		Query: listado(gestioncatalogos.ConsultaCiclosEscolares), Respuesta: paginado("data", de(models.CicloEscolar{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/ciclos_escolares/:id", Resumen: "Obtiene un ciclo escolar con sus periodos de evaluación", Tag: "Ciclos escolares", Versionado: true,
+		Respuesta: conMensaje("data", de(models.CicloEscolar{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/ciclos_escolares", Resumen: "Crea un ciclo escolar en planeación; sin periodos, se reparte en bimestres o trimestres iguales", Tag: "Ciclos escolares",
+		Entrada: gestioncatalogos.CicloEscolarInput{}, Estado: http.StatusCreated, Respuesta: conMensaje("data", de(models.CicloEscolar{}))},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/ciclos_escolares/:id", Resumen: "Edita un ciclo escolar que no está cerrado y reemplaza sus periodos; el nivel solo cambia en planeación y sin grupos", Tag: "Ciclos escolares", Versionado: true,
+		Entrada: gestioncatalogos.CicloEscolarInput{}, Respuesta: conMensaje("data", de(models.CicloEscolar{}))},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/ciclos_escolares/:id", Resumen: "Envía a la papelera un ciclo escolar en planeación sin grupos", Tag: "Ciclos escolares", Respuesta: soloMensaje},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/ciclos_escolares/:id/activar", Resumen: "Activa un ciclo en planeación si su nivel no tiene otro activo; los estudiantes inscritos pasan al grupo de su inscripción", Tag: "Ciclos escolares",
+		Respuesta: objeto(Schema{"message": texto, "data": de(models.CicloEscolar{}), "estudiantes_movidos": entero})},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/ciclos_escolares/:id/cerrar", Resumen: "Cierra un ciclo activo; sus grupos e inscripciones quedan como historial", Tag: "Ciclos escolares",
+		Respuesta: conMensaje("data", de(models.CicloEscolar{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/ciclos_escolares/:id/inscripciones", Resumen: "Estudiantes inscritos en el ciclo con su grupo", Tag: "Ciclos escolares",
+		Query: listado(gestionusuarios.ConsultaInscripciones), Respuesta: paginado("data", de(models.Inscripcion{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/ciclos_escolares/:id/inscripciones", Resumen: "Inscribe a un estudiante en un grupo del ciclo, o lo cambia de grupo; si el ciclo está activo el estudiante pasa de inmediato a ese grupo", Tag: "Ciclos escolares",
+		Entrada: gestionusuarios.InscripcionInput{}, Respuesta: conMensaje("data", de(models.Inscripcion{}))},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/inscripciones/:id", Resumen: "Quita una inscripción de un ciclo que no está cerrado; el grupo actual del estudiante no cambia", Tag: "Ciclos escolares", Respuesta: soloMensaje},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/estudiantes/:id/inscripciones", Resumen: "Historial del estudiante: un renglón por ciclo en que estuvo inscrito", Tag: "Estudiantes",
+		Query: listado(gestionusuarios.ConsultaInscripciones), Respuesta: paginado("data", de(models.Inscripcion{}))},
+
+	// ---------- Aulas --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/aulas/disponibles", Resumen: "Aulas activas sin clase en un día de la semana y rango de horas (en los ciclos que no están cerrados)", Tag: "Aulas",
+		Query: []Parametro{
+			{Nombre: "dia", Tipo: "integer", Descripcion: "Día de la semana: 1 = lunes … 7 = domingo"},
+			{Nombre: "fecha", Tipo: "string", Descripcion: "En lugar de dia, una fecha (YYYY-MM-DD) de la que se toma el día de la semana; solo cuentan las clases de los ciclos que incluyen esa fecha"},
+			{Nombre: "desde", Tipo: "string", Descripcion: "Hora inicial (HH:MM)"},
+			{Nombre: "hasta", Tipo: "string", Descripcion: "Hora final (HH:MM), no incluida"},
+			{Nombre: "plantel_id", Tipo: "integer", Descripcion: "Solo las aulas del plantel"},
+			{Nombre: "tipo", Tipo: "string", Descripcion: "salon, laboratorio o cancha"},
+			{Nombre: "capacidad_minima", Tipo: "integer", Descripcion: "Solo las aulas con al menos esa capacidad"},
+		},
+		Respuesta: objeto(Schema{"message": texto, "franja": de(horario.Franja{}), "data": arreglo(de(models.Aula{}))})},
+
+	// ---------- Horarios --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/horarios", Resumen: "Lista las clases de los horarios", Tag: "Horarios",
+		Query: listado(gestioncatalogos.ConsultaHorarios), Respuesta: paginado("data", de(models.Horario{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/horarios/:id", Resumen: "Obtiene una clase del horario", Tag: "Horarios", Versionado: true,
+		Respuesta: conMensaje("data", de(models.Horario{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/horarios", Resumen: "Agrega una clase al horario del grupo; se rechaza (409) si el grupo, el profesor o el aula ya tienen clase a esa hora", Tag: "Horarios",
+		Entrada: gestioncatalogos.HorarioInput{}, Estado: http.StatusCreated, Respuesta: conMensaje("data", de(models.Horario{}))},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/horarios/:id", Resumen: "Edita una clase de un ciclo que no está cerrado, con la misma revisión de choques", Tag: "Horarios", Versionado: true,
+		Entrada: gestioncatalogos.HorarioInput{}, Respuesta: conMensaje("data", de(models.Horario{}))},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/horarios/:id", Resumen: "Quita una clase de un ciclo que no está cerrado; no pasa por la papelera", Tag: "Horarios", Respuesta: soloMensaje},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/grupos/:id/horario", Resumen: "Semana de clases del grupo, por día y hora", Tag: "Horarios",
+		Query: horarioSemanal, Respuesta: conMensaje("data", arreglo(de(models.Horario{})))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/personal/:id/horario", Resumen: "Semana de clases del profesor, por día y hora", Tag: "Horarios",
+		Query: horarioSemanal, Respuesta: conMensaje("data", arreglo(de(models.Horario{})))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/aulas/:id/horario", Resumen: "Semana de clases del aula, por día y hora", Tag: "Horarios",
+		Query: horarioSemanal, Respuesta: conMensaje("data", arreglo(de(models.Horario{})))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/horarios/calendario", Resumen: "Enlace firmado al calendario (iCalendar) del usuario de la sesión, para suscribirse desde una aplicación de calendario; no vence hasta que se regenera", Tag: "Horarios",
+		Respuesta: objeto(Schema{"message": texto, "url": texto})},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/horarios/calendario/regenerar", Resumen: "Invalida el enlace al calendario del usuario de la sesión y regresa uno nuevo; las suscripciones al anterior dejan de actualizarse", Tag: "Horarios",
+		Respuesta: objeto(Schema{"message": texto, "url": texto})},
+	{Metodo: http.MethodGet, Ruta: rutaAPI + "/horarios/calendario.ics", Resumen: "Calendario iCalendar con las clases que da o toma el usuario del enlace, repetidas cada semana durante su ciclo; la firma hace de autorización", Tag: "Horarios", Publica: true,
+		Query: []Parametro{
+			{Nombre: "organizacion", Tipo: "integer", Descripcion: "ID de la organización"},
+			{Nombre: "usuario", Tipo: "integer", Descripcion: "ID del usuario"},
+			{Nombre: "version", Tipo: "integer", Descripcion: "Versión del enlace; cambia al regenerarlo"},
+			{Nombre: "firma", Tipo: "string", Descripcion: "HMAC-SHA256 de la organización, el usuario y la versión"},
+		}},
+
+	// ---------- Documentos --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/documentos/:id/descarga", Resumen: "Genera una URL firmada para descargar el documento; vence después de DOCUMENTOS_VIGENCIA_URL (5 minutos por defecto) (requiere \"" + models.PermisoVerDocumentos + "\")", Tag: "Documentos",
+		Respuesta: objeto(Schema{"message": texto, "documento": de(models.Documento{}), "url": texto, "expira": texto})},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/documentos/:id", Resumen: "Elimina un documento y su archivo; no pasa por la papelera (requiere \"" + models.PermisoSubirDocumentos + "\")", Tag: "Documentos", Respuesta: soloMensaje},
+	{Metodo: http.MethodGet, Ruta: rutaAPI + "/documentos/descargar", Resumen: "Entrega el archivo de una URL firmada del almacenamiento local; la firma hace de autorización. Con S3 las URLs apuntan directo al bucket", Tag: "Documentos", Publica: true,
+		Query: []Parametro{
+			{Nombre: "llave", Tipo: "string", Descripcion: "Ubicación del archivo"},
+			{Nombre: "nombre", Tipo: "string", Descripcion: "Nombre con que se descarga"},
+			{Nombre: "expira", Tipo: "integer", Descripcion: "Momento de expiración (segundos Unix)"},
+			{Nombre: "firma", Tipo: "string", Descripcion: "HMAC-SHA256 de los parámetros anteriores"},
+		}},
+
+	// ---------- Exportación --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/estudiantes/exportar", Resumen: "Descarga los estudiantes que cumplen los filtros como CSV, XLSX o PDF (requiere \"" + models.PermisoExportarListados + "\")", Tag: "Estudiantes",
+		Descarga: true, Query: exportable(gestionusuarios.ConsultaEstudiantes)},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/personal/exportar", Resumen: "Descarga el personal que cumple los filtros como CSV, XLSX o PDF (requiere \"" + models.PermisoExportarListados + "\")", Tag: "Personal",
+		Descarga: true, Query: exportable(gestionusuarios.ConsultaPersonal)},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/tutores/exportar", Resumen: "Descarga los tutores que cumplen los filtros como CSV, XLSX o PDF (requiere \"" + models.PermisoExportarListados + "\")", Tag: "Tutores",
+		Descarga: true, Query: exportable(gestionusuarios.ConsultaTutores)},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/grupos/exportar", Resumen: "Descarga los grupos que cumplen los filtros como CSV, XLSX o PDF (requiere \"" + models.PermisoExportarListados + "\")", Tag: "Grupos",
+		Descarga: true, Query: exportable(gestioncatalogos.ConsultaGrupos)},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/roles/exportar", Resumen: "Descarga los roles que cumplen los filtros como CSV, XLSX o PDF (requiere \"" + models.PermisoExportarListados + "\")", Tag: "Roles",
+		Descarga: true, Query: exportable(controllers.ConsultaRoles)},
+
+	// ---------- Búsqueda --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/busqueda", Resumen: "Busca estudiantes, personal y tutores por nombre, matrícula, CURP, email o teléfono; solo incluye los tipos que el rol puede ver", Tag: "Búsqueda",
+		Query: []Parametro{
+			{Nombre: "q", Tipo: "string", Descripcion: "Término a buscar (mínimo 2 caracteres); no distingue acentos ni mayúsculas"},
+			{Nombre: "tipo", Tipo: "string", Descripcion: "estudiante, personal o tutor, separados por coma; por defecto todos los permitidos"},
+			{Nombre: "limite", Tipo: "integer", Descripcion: "Máximo de resultados (1 a 50, 20 por defecto)"},
+		},
+		Respuesta: objeto(Schema{"message": texto, "termino": texto, "tipos": arreglo(texto), "total": entero, "resultados": arreglo(de(gestionusuarios.ResultadoBusqueda{}))})},
+
+	// ---------- Papelera --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/papelera/:tipo", Resumen: "Lista los registros eliminados de un tipo (" + strings.Join(controllers.TiposPapelera(), ", ") + "); requiere \"" + models.PermisoRestaurarRegistros + "\"", Tag: "Papelera",
+		Query: listado(controllers.ConsultaPapelera), Respuesta: objeto(Schema{"message": texto, "tipo": texto, "registros": arreglo(de(controllers.ElementoPapelera{})), "paginacion": paginacion})},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/papelera/:tipo/:id/restaurar", Resumen: "Restaura un registro eliminado (y su usuario, si tiene); permisos y categorías solo desde la organización principal; requiere \"" + models.PermisoRestaurarRegistros + "\"", Tag: "Papelera",
+		Respuesta: objeto(Schema{"message": texto, "tipo": texto, "registro": Schema{"type": "object"}})},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/papelera/:tipo/:id", Resumen: "Elimina definitivamente un registro que está en la papelera; permisos y categorías solo desde la organización principal; requiere \"" + models.PermisoPurgarRegistros + "\"", Tag: "Papelera", Respuesta: soloMensaje},
+
+	// ---------- Reportes --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/reportes/curp_inconsistencias", Resumen: "Usuarios cuya fecha de nacimiento, género o entidad no coinciden con su CURP (requiere \"Ver reportes\")", Tag: "Reportes",
+		Respuesta: objeto(Schema{
+			"message":  texto,
+			"modo":     texto,
+			"total":    entero,
+			"usuarios": arreglo(de(gestionusuarios.InconsistenciaCURP{})),
+		})},
+
+	// ---------- Webhooks --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/webhooks/tipos_eventos", Resumen: "Tipos de evento a los que se puede suscribir; \"*\" recibe todos (requiere \"" + models.PermisoAdministrarWebhooks + "\")", Tag: "Webhooks",
+		Respuesta: objeto(Schema{"message": texto, "tipos": arreglo(texto)})},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/webhooks/suscripciones", Resumen: "Lista las suscripciones de webhooks (requiere \"" + models.PermisoAdministrarWebhooks + "\")", Tag: "Webhooks",
+		Query: listado(controllers.ConsultaSuscripcionesWebhook), Respuesta: paginado("suscripciones", de(models.SuscripcionWebhook{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/webhooks/suscripciones/:id", Resumen: "Obtiene una suscripción de webhooks (requiere \"" + models.PermisoAdministrarWebhooks + "\")", Tag: "Webhooks", Versionado: true,
+		Respuesta: conMensaje("suscripcion", de(models.SuscripcionWebhook{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/webhooks/suscripciones", Resumen: "Registra una URL que recibirá por POST los eventos indicados, firmados con HMAC-SHA256 en X-Webhook-Signature. La URL no puede apuntar a localhost ni a una red privada y las redirecciones cuentan como fallo; el secreto solo se muestra en esta respuesta (requiere \"" + models.PermisoAdministrarWebhooks + "\")", Tag: "Webhooks",
+		Entrada: controllers.SuscripcionWebhookInput{}, Estado: http.StatusCreated,
+		Respuesta: objeto(Schema{"message": texto, "suscripcion": de(models.SuscripcionWebhook{}), "secreto": texto})},
+	{Metodo: http.MethodPut, Ruta: rutaProtegida + "/webhooks/suscripciones/:id", Resumen: "Edita la URL, los eventos, la descripción o si la suscripción está activa (requiere \"" + models.PermisoAdministrarWebhooks + "\")", Tag: "Webhooks", Versionado: true,
+		Entrada: controllers.SuscripcionWebhookUpdateInput{}, Respuesta: conMensaje("suscripcion", de(models.SuscripcionWebhook{}))},
+	{Metodo: http.MethodDelete, Ruta: rutaProtegida + "/webhooks/suscripciones/:id", Resumen: "Elimina una suscripción; sus entregas pendientes quedan como fallidas (requiere \"" + models.PermisoAdministrarWebhooks + "\")", Tag: "Webhooks", Respuesta: soloMensaje},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/webhooks/suscripciones/:id/rotar_secreto", Resumen: "Genera un secreto nuevo para la suscripción; solo se muestra en esta respuesta (requiere \"" + models.PermisoAdministrarWebhooks + "\")", Tag: "Webhooks",
+		Respuesta: objeto(Schema{"message": texto, "suscripcion": de(models.SuscripcionWebhook{}), "secreto": texto})},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/webhooks/suscripciones/:id/reenviar_fallidas", Resumen: "Vuelve a programar todas las entregas fallidas de la suscripción (requiere \"" + models.PermisoAdministrarWebhooks + "\")", Tag: "Webhooks",
+		Respuesta: objeto(Schema{"message": texto, "reenviadas": entero})},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/webhooks/entregas", Resumen: "Historial de entregas con su evento, código de respuesta y último error (requiere \"" + models.PermisoAdministrarWebhooks + "\")", Tag: "Webhooks",
+		Query: listado(controllers.ConsultaEntregasWebhook), Respuesta: paginado("entregas", de(models.EntregaWebhook{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/webhooks/entregas/:id/reenviar", Resumen: "Vuelve a programar una entrega fallida o ya entregada (requiere \"" + models.PermisoAdministrarWebhooks + "\")", Tag: "Webhooks",
+		Respuesta: conMensaje("entrega", de(models.EntregaWebhook{}))},
+
+	// ---------- Tareas programadas --------------
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/tareas", Resumen: "Tareas programadas (" + strings.Join(tareas.Nombres(), ", ") + ") con su horario cron, siguiente ejecución, candado y última ejecución (requiere \"" + models.PermisoAdministrarTareas + "\" en la organización principal)", Tag: "Tareas",
+		Respuesta: objeto(Schema{"message": texto, "tareas": arreglo(de(tareas.Estado{}))})},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/tareas/ejecuciones", Resumen: "Historial de ejecuciones de las tareas (requiere \"" + models.PermisoAdministrarTareas + "\" en la organización principal)", Tag: "Tareas",
+		Query: listado(controllers.ConsultaEjecucionesTareas), Respuesta: paginado("ejecuciones", de(models.EjecucionTarea{}))},
+	{Metodo: http.MethodGet, Ruta: rutaProtegida + "/tareas/ejecuciones/:id", Resumen: "Obtiene una ejecución con su estado y resultado (requiere \"" + models.PermisoAdministrarTareas + "\" en la organización principal)", Tag: "Tareas",
+		Respuesta: conMensaje("ejecucion", de(models.EjecucionTarea{}))},
+	{Metodo: http.MethodPost, Ruta: rutaProtegida + "/tareas/:nombre/ejecutar", Resumen: "Ejecuta una tarea fuera de su horario sin esperar a que termine; 409 si ya se está ejecutando (requiere \"" + models.PermisoAdministrarTareas + "\" en la organización principal)", Tag: "Tareas",
+		Estado: http.StatusAccepted, Respuesta: conMensaje("ejecucion", de(models.EjecucionTarea{}))},
+}
+
+func init() {
+	for _, catalogo := range gestioncatalogos.Catalogos {
+		operaciones = append(operaciones, operacionesDeCatalogo(catalogo)...)
+	}
+	operaciones = append(operaciones, operacionesDeDocumentos(models.DocumentoDeEstudiante, "un estudiante", "Estudiantes")...)
+	operaciones = append(operaciones, operacionesDeDocumentos(models.DocumentoDePersonal, "un empleado", "Personal")...)
+	operaciones = append(operaciones, operacionesDeDocumentos(models.DocumentoDeContrato, "un contrato", "Documentos")...)
+}
+
+// operacionesDeDocumentos documenta el listado y la subida de documentos de un tipo de registro
+func operacionesDeDocumentos(entidad, registro, tag string) []Operacion {
+	ruta := rutaProtegida + "/" + entidad + "/:id/documentos"
+	return []Operacion{
+		{Metodo: http.MethodGet, Ruta: ruta, Resumen: "Lista los documentos de " + registro + " (requiere \"" + models.PermisoVerDocumentos + "\")", Tag: tag,
+			Query: listado(gestionusuarios.ConsultaDocumentos), Respuesta: paginado("documentos", de(models.Documento{}))},
+		{Metodo: http.MethodPost, Ruta: ruta, Resumen: "Sube un documento de " + registro + "; el formato se detecta del contenido y se rechaza el mismo archivo dos veces (requiere \"" + models.PermisoSubirDocumentos + "\")", Tag: tag,
+			Estado: http.StatusCreated,
+			Formulario: objeto(Schema{
+				"archivo": Schema{"type": "string", "format": "binary", "description": "PDF, JPEG, PNG o WebP; máximo DOCUMENTOS_TAMANO_MAXIMO_MB (10 MB por defecto)"},
+				"tipo":    Schema{"type": "string", "enum": gestionusuarios.TiposDocumento[entidad]},
+			}),
+			Respuesta: conMensaje("documento", de(models.Documento{}))},
+	}
+}
+
+// operacionesDeCatalogo documenta las cinco rutas que registra un catálogo del CRUD genérico
+func operacionesDeCatalogo(catalogo gestioncatalogos.Administrable) []Operacion {
+	d := catalogo.Datos()
+	ruta := rutaProtegida + "/" + d.Ruta
+	modelo := de(catalogo.Modelo())
+	un, los := "un", "los"
+	if d.Femenino {
+		un, los = "una", "las"
+	}
+	eliminar := "Envía " + un + " " + d.Singular + " a la papelera"
+	if len(d.Dependencias) > 0 {
+		eliminar += "; responde 409 si otros registros lo usan"
+	}
+	return []Operacion{
+		{Metodo: http.MethodGet, Ruta: ruta, Resumen: "Lista " + los + " " + d.Plural, Tag: d.Tag, EnCache: d.Cache != "",
+			Query: listado(d.Consulta), Respuesta: paginado("data", modelo)},
+		{Metodo: http.MethodGet, Ruta: ruta + "/:id", Resumen: "Obtiene " + un + " " + d.Singular, Tag: d.Tag, Versionado: true,
+			Respuesta: conMensaje("data", modelo)},
+		{Metodo: http.MethodPost, Ruta: ruta, Resumen: "Crea " + un + " " + d.Singular, Tag: d.Tag,
+			Entrada: catalogo.Entrada(), Estado: http.StatusCreated, Respuesta: conMensaje("data", modelo)},
+		{Metodo: http.MethodPut, Ruta: ruta + "/:id", Resumen: "Edita " + un + " " + d.Singular, Tag: d.Tag, Versionado: true,
+			Entrada: catalogo.Entrada(), Respuesta: conMensaje("data", modelo)},
+		{Metodo: http.MethodDelete, Ruta: ruta + "/:id", Resumen: eliminar, Tag: d.Tag, Respuesta: soloMensaje},
+	}
+}
@@ -0,0 +1,190 @@
+package docs
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema es un objeto de esquema de OpenAPI 3 representado como mapa para serializarlo directamente
+type Schema = map[string]any
+
+// modelo marca un valor de Go cuyo tipo debe convertirse a esquema al construir la especificación
+type modelo struct {
+	valor any
+}
+
+// de indica que el esquema se deriva por reflexión del tipo del valor recibido
+func de(v any) modelo {
+	return modelo{valor: v}
+}
+
+var tipoTime = reflect.TypeOf(time.Time{})
+
+// generador convierte tipos de Go en esquemas y acumula los componentes reutilizables
+type generador struct {
+	componentes map[string]Schema
+	tipos       map[string]reflect.Type
+}
+
+func nuevoGenerador() *generador {
+	return &generador{
+		componentes: map[string]Schema{},
+		tipos:       map[string]reflect.Type{},
+	}
+}
+
+// resolver recorre un esquema armado a mano y reemplaza los marcadores de modelo por su esquema real
+func (g *generador) resolver(v any) any {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case modelo:
+		return g.schemaDe(reflect.TypeOf(x.valor))
+	case Schema:
+		out := Schema{}
+		for k, val := range x {
+			out[k] = g.resolver(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i, val := range x {
+			out[i] = g.resolver(val)
+		}
+		return out
+	case []string:
+		return x
+	case string, bool, int, float64:
+		return x
+	default:
+		return g.schemaDe(reflect.TypeOf(v))
+	}
+}
+
+// schemaDe genera el esquema de un tipo; las estructuras con nombre se registran como componentes
+func (g *generador) schemaDe(t reflect.Type) Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var s Schema
+	switch {
+	case t == tipoTime:
+		s = Schema{"type": "string", "format": "date-time"}
+	case t.Name() == "DeletedAt":
+		s = Schema{"type": "string", "format": "date-time", "nullable": true}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		s = Schema{"$ref": "#/components/schemas/" + g.registrar(t)}
+	case t.Kind() == reflect.Struct:
+		s = g.objeto(t)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		s = Schema{"type": "string", "format": "byte"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s = Schema{"type": "array", "items": g.schemaDe(t.Elem())}
+	case t.Kind() == reflect.Map:
+		s = Schema{"type": "object", "additionalProperties": g.schemaDe(t.Elem())}
+	case t.Kind() == reflect.Bool:
+		s = Schema{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		s = Schema{"type": "integer"}
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr:
+		s = Schema{"type": "integer", "minimum": 0}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s = Schema{"type": "number"}
+	case t.Kind() == reflect.String:
+		s = Schema{"type": "string"}
+	default:
+		s = Schema{}
+	}
+
+	if nullable {
+		if _, esRef := s["$ref"]; esRef {
+			return Schema{"allOf": []any{s}, "nullable": true}
+		}
+		s["nullable"] = true
+	}
+	return s
+}
+
+// registrar agrega la estructura a los componentes (una sola vez) y regresa su nombre
+func (g *generador) registrar(t reflect.Type) string {
+	nombre := t.Name()
+	if existente, ok := g.tipos[nombre]; ok && existente != t {
+		// Dos paquetes con el mismo nombre de tipo: se antepone el paquete
+		partes := strings.Split(t.PkgPath(), "/")
+		nombre = partes[len(partes)-1] + "." + nombre
+	}
+	if _, ok := g.tipos[nombre]; ok {
+		return nombre
+	}
+
+	// Registrar antes de recorrer los campos para soportar relaciones cíclicas (User -> Genero -> Users)
+	g.tipos[nombre] = t
+	g.componentes[nombre] = g.objeto(t)
+	return nombre
+}
+
+// objeto describe los campos exportados de una estructura según sus etiquetas json y binding
+func (g *generador) objeto(t reflect.Type) Schema {
+	propiedades := Schema{}
+	var requeridos []string
+	g.agregarCampos(t, propiedades, &requeridos)
+
+	s := Schema{"type": "object", "properties": propiedades}
+	if len(requeridos) > 0 {
+		s["required"] = requeridos
+	}
+	return s
+}
+
+func (g *generador) agregarCampos(t reflect.Type, propiedades Schema, requeridos *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		campo := t.Field(i)
+		if !campo.IsExported() {
+			continue
+		}
+
+		etiqueta := campo.Tag.Get("json")
+		if etiqueta == "-" {
+			continue
+		}
+		nombre := strings.Split(etiqueta, ",")[0]
+
+		// Las estructuras embebidas sin nombre json se aplanan, igual que lo hace encoding/json
+		if campo.Anonymous && nombre == "" {
+			embebido := campo.Type
+			if embebido.Kind() == reflect.Ptr {
+				embebido = embebido.Elem()
+			}
+			if embebido.Kind() == reflect.Struct {
+				g.agregarCampos(embebido, propiedades, requeridos)
+				continue
+			}
+		}
+		if nombre == "" {
+			nombre = campo.Name
+		}
+
+		s := g.schemaDe(campo.Type)
+		binding := campo.Tag.Get("binding")
+		for _, regla := range strings.Split(binding, ",") {
+			switch {
+			case regla == "required":
+				*requeridos = append(*requeridos, nombre)
+			case regla == "email":
+				s["format"] = "email"
+			case regla == "curp" || regla == "rfc" || regla == "telefono_mx" || regla == "cp_mx":
+				s["format"] = regla
+			case strings.HasPrefix(regla, "min=") && s["type"] == "string":
+				if n, err := strconv.Atoi(strings.TrimPrefix(regla, "min=")); err == nil {
+					s["minLength"] = n
+				}
+			}
+		}
+		propiedades[nombre] = s
+	}
+}
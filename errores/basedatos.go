@@ -0,0 +1,77 @@
+package errores
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"api-margaritai/organizacion"
+)
+
+// Códigos SQLSTATE de Postgres que se traducen a errores de dominio
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgNotNullViolation    = "23502"
+	pgCheckViolation      = "23514"
+)
+
+// restriccionUnica relaciona una columna con índice único con el código y mensaje que ve el cliente
+type restriccionUnica struct {
+	columna string
+	codigo  string
+	mensaje string
+}
+
+var restriccionesUnicas = []restriccionUnica{
+	{columna: "email", codigo: EmailDuplicado, mensaje: "El email ya está registrado"},
+	{columna: "curp", codigo: CURPDuplicada, mensaje: "La CURP ya está registrada"},
+	{columna: "matricula", codigo: MatriculaDuplicada, mensaje: "La matrícula ya existe"},
+	{columna: "idx_ciclos_escolares_activo", codigo: CicloActivoExistente, mensaje: "El nivel escolar ya tiene un ciclo activo"},
+}
+
+// BaseDatos traduce violaciones de restricciones de Postgres a errores de dominio sin filtrar el SQL al cliente.
+// Cualquier otro error se reporta como interno con el mensaje indicado.
+func BaseDatos(err error, mensajeInterno string) *Error {
+	// Una llave foránea hacia otra organización se reporta igual que una que no existe
+	if errors.Is(err, organizacion.ErrReferenciaAjena) {
+		columna := err.Error()[strings.LastIndex(err.Error(), ": ")+2:]
+		return SolicitudInvalida(ReferenciaInvalida, "Un registro relacionado no existe ("+columna+")").ConCausa(err)
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return Interno(mensajeInterno, err)
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		restriccion := strings.ToLower(pgErr.ConstraintName + " " + pgErr.Detail)
+		for _, r := range restriccionesUnicas {
+			if strings.Contains(restriccion, r.columna) {
+				return Conflicto(r.codigo, r.mensaje).ConCausa(err)
+			}
+		}
+		return Conflicto(RegistroDuplicado, "Ya existe un registro con esos datos").ConCausa(err)
+	case pgForeignKeyViolation:
+		if strings.Contains(pgErr.Detail, "still referenced") {
+			return Conflicto(RegistroEnUso, "El registro está en uso por otros registros y no puede eliminarse").ConCausa(err)
+		}
+		return SolicitudInvalida(ReferenciaInvalida, "Un registro relacionado no existe"+referencia(pgErr.ConstraintName)).ConCausa(err)
+	case pgNotNullViolation:
+		return SolicitudInvalida(CampoRequerido, "Falta el campo obligatorio "+pgErr.ColumnName).ConCausa(err)
+	case pgCheckViolation:
+		return SolicitudInvalida(ValidacionFallida, "Los datos no cumplen las reglas del registro").ConCausa(err)
+	}
+	return Interno(mensajeInterno, err)
+}
+
+// referencia extrae la relación del nombre de la llave foránea que genera GORM (fk_users_rol -> " (rol)")
+func referencia(constraint string) string {
+	partes := strings.Split(constraint, "_")
+	if len(partes) < 3 || partes[0] != "fk" {
+		return ""
+	}
+	return " (" + strings.Join(partes[2:], "_") + ")"
+}
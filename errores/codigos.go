@@ -0,0 +1,139 @@
+package errores
+
+// Códigos estables que el frontend puede usar para decidir qué mostrar.
+// Los valores no deben cambiar una vez publicados; para casos nuevos se agregan códigos nuevos.
+const (
+	// Generales
+	ErrorInterno          = "ERROR_INTERNO"
+	DatosInvalidos        = "DATOS_INVALIDOS"
+	ValidacionFallida     = "VALIDACION_FALLIDA"
+	IDInvalido            = "ID_INVALIDO"
+	FechaInvalida         = "FECHA_INVALIDA"
+	RegistroDuplicado     = "REGISTRO_DUPLICADO"
+	ReferenciaInvalida    = "REFERENCIA_INVALIDA"
+	RegistroEnUso         = "REGISTRO_EN_USO"
+	CampoRequerido        = "CAMPO_REQUERIDO"
+	CURPInconsistente     = "CURP_INCONSISTENTE"
+	PermisoDenegado       = "PERMISO_DENEGADO"
+	TipoInvalido          = "TIPO_INVALIDO"
+	LimiteExcedido        = "LIMITE_EXCEDIDO"
+	CuerpoDemasiadoGrande = "CUERPO_DEMASIADO_GRANDE"
+
+	// Importaciones
+	ArchivoRequerido        = "ARCHIVO_REQUERIDO"
+	ArchivoInvalido         = "ARCHIVO_INVALIDO"
+	ArchivoDemasiadoGrande  = "ARCHIVO_DEMASIADO_GRANDE"
+	ColumnasInvalidas       = "COLUMNAS_INVALIDAS"
+	ImportacionNoEncontrada = "IMPORTACION_NO_ENCONTRADA"
+
+	// Documentos
+	TipoArchivoNoPermitido = "TIPO_ARCHIVO_NO_PERMITIDO"
+	DocumentoDuplicado     = "DOCUMENTO_DUPLICADO"
+	DocumentoNoEncontrado  = "DOCUMENTO_NO_ENCONTRADO"
+	URLDescargaInvalida    = "URL_DESCARGA_INVALIDA"
+	URLDescargaExpirada    = "URL_DESCARGA_EXPIRADA"
+
+	// Ciclos escolares
+	CicloActivoExistente = "CICLO_ACTIVO_EXISTENTE"
+	EstatusCicloInvalido = "ESTATUS_CICLO_INVALIDO"
+	CicloCerrado         = "CICLO_CERRADO"
+
+	// Horarios
+	HorarioEnConflicto       = "HORARIO_EN_CONFLICTO"
+	EnlaceCalendarioInvalido = "ENLACE_CALENDARIO_INVALIDO"
+
+	// Webhooks
+	SuscripcionWebhookNoEncontrada = "SUSCRIPCION_WEBHOOK_NO_ENCONTRADA"
+	EntregaWebhookNoEncontrada     = "ENTREGA_WEBHOOK_NO_ENCONTRADA"
+	EntregaWebhookPendiente        = "ENTREGA_WEBHOOK_PENDIENTE"
+
+	// Tareas programadas
+	TareaNoEncontrada          = "TAREA_NO_ENCONTRADA"
+	TareaEnCurso               = "TAREA_EN_CURSO"
+	EjecucionTareaNoEncontrada = "EJECUCION_TAREA_NO_ENCONTRADA"
+
+	// Organizaciones
+	OrganizacionNoEncontrada  = "ORGANIZACION_NO_ENCONTRADA"
+	OrganizacionInactiva      = "ORGANIZACION_INACTIVA"
+	OrganizacionNoCoincide    = "ORGANIZACION_NO_COINCIDE"
+	SoloOrganizacionPrincipal = "SOLO_ORGANIZACION_PRINCIPAL"
+
+	// Concurrencia optimista
+	IfMatchRequerido  = "IF_MATCH_REQUERIDO"
+	VersionModificada = "VERSION_MODIFICADA"
+
+	// Ediciones parciales (PATCH)
+	CampoNoModificable    = "CAMPO_NO_MODIFICABLE"
+	CampoNoAnulable       = "CAMPO_NO_ANULABLE"
+	TipoParcheNoSoportado = "TIPO_PARCHE_NO_SOPORTADO"
+	OperacionNoSoportada  = "OPERACION_NO_SOPORTADA"
+	PruebaParcheFallida   = "PRUEBA_PARCHE_FALLIDA"
+
+	// Idempotencia
+	ClaveIdempotenciaInvalida    = "CLAVE_IDEMPOTENCIA_INVALIDA"
+	ClaveIdempotenciaEnProceso   = "CLAVE_IDEMPOTENCIA_EN_PROCESO"
+	ClaveIdempotenciaReutilizada = "CLAVE_IDEMPOTENCIA_REUTILIZADA"
+
+	// Autenticación
+	TokenRequerido     = "TOKEN_REQUERIDO"
+	TokenInvalido      = "TOKEN_INVALIDO"
+	TokenExpirado      = "TOKEN_EXPIRADO"
+	TokenInvalidado    = "TOKEN_INVALIDADO"
+	CorreoNoRegistrado = "CORREO_NO_REGISTRADO"
+	PasswordIncorrecto = "PASSWORD_INCORRECTO"
+	PasswordRequerido  = "PASSWORD_REQUERIDO"
+	UsuarioRequerido   = "USUARIO_REQUERIDO"
+
+	// Duplicados
+	EmailDuplicado     = "EMAIL_DUPLICADO"
+	CURPDuplicada      = "CURP_DUPLICADA"
+	MatriculaDuplicada = "MATRICULA_DUPLICADA"
+	RolDuplicado       = "ROL_DUPLICADO"
+
+	// Registros no encontrados
+	UsuarioNoEncontrado            = "USUARIO_NO_ENCONTRADO"
+	GeneroNoEncontrado             = "GENERO_NO_ENCONTRADO"
+	RolNoEncontrado                = "ROL_NO_ENCONTRADO"
+	PermisoNoEncontrado            = "PERMISO_NO_ENCONTRADO"
+	CategoriaPermisoNoEncontrada   = "CATEGORIA_PERMISO_NO_ENCONTRADA"
+	RelacionRolPermisoNoEncontrada = "RELACION_ROL_PERMISO_NO_ENCONTRADA"
+	PlantelNoEncontrado            = "PLANTEL_NO_ENCONTRADO"
+	NivelEscolarNoEncontrado       = "NIVEL_ESCOLAR_NO_ENCONTRADO"
+	GradoNoEncontrado              = "GRADO_NO_ENCONTRADO"
+	GrupoNoEncontrado              = "GRUPO_NO_ENCONTRADO"
+	GradoAcademicoNoEncontrado     = "GRADO_ACADEMICO_NO_ENCONTRADO"
+	EstatusLaboralNoEncontrado     = "ESTATUS_LABORAL_NO_ENCONTRADO"
+	EstatusEmpleadoNoEncontrado    = "ESTATUS_EMPLEADO_NO_ENCONTRADO"
+	PuestoNoEncontrado             = "PUESTO_NO_ENCONTRADO"
+	TipoContratoNoEncontrado       = "TIPO_CONTRATO_NO_ENCONTRADO"
+	AulaNoEncontrada               = "AULA_NO_ENCONTRADA"
+	MateriaNoEncontrada            = "MATERIA_NO_ENCONTRADA"
+	EstudianteNoEncontrado         = "ESTUDIANTE_NO_ENCONTRADO"
+	PersonalNoEncontrado           = "PERSONAL_NO_ENCONTRADO"
+	TutorNoEncontrado              = "TUTOR_NO_ENCONTRADO"
+	ContratoNoEncontrado           = "CONTRATO_NO_ENCONTRADO"
+	CicloEscolarNoEncontrado       = "CICLO_ESCOLAR_NO_ENCONTRADO"
+	InscripcionNoEncontrada        = "INSCRIPCION_NO_ENCONTRADA"
+	HorarioNoEncontrado            = "HORARIO_NO_ENCONTRADO"
+
+	// Reglas de eliminación
+	PlantelConEstudiantes      = "PLANTEL_CON_ESTUDIANTES"
+	PlantelConNiveles          = "PLANTEL_CON_NIVELES"
+	PlantelConAulas            = "PLANTEL_CON_AULAS"
+	NivelEscolarConEstudiantes = "NIVEL_ESCOLAR_CON_ESTUDIANTES"
+	GradoConMaterias           = "GRADO_CON_MATERIAS"
+	GrupoConEstudiantes        = "GRUPO_CON_ESTUDIANTES"
+	GrupoConInscripciones      = "GRUPO_CON_INSCRIPCIONES"
+	GradoAcademicoConPersonal  = "GRADO_ACADEMICO_CON_PERSONAL"
+	EstatusLaboralConPersonal  = "ESTATUS_LABORAL_CON_PERSONAL"
+	EstatusEmpleadoConPersonal = "ESTATUS_EMPLEADO_CON_PERSONAL"
+	PuestoConPersonal          = "PUESTO_CON_PERSONAL"
+	GeneroConUsuarios          = "GENERO_CON_USUARIOS"
+	TipoContratoConContratos   = "TIPO_CONTRATO_CON_CONTRATOS"
+	CicloConGrupos             = "CICLO_CON_GRUPOS"
+	NivelEscolarConCiclos      = "NIVEL_ESCOLAR_CON_CICLOS"
+	GrupoConHorarios           = "GRUPO_CON_HORARIOS"
+	MateriaConHorarios         = "MATERIA_CON_HORARIOS"
+	AulaConHorarios            = "AULA_CON_HORARIOS"
+	PersonalConHorarios        = "PERSONAL_CON_HORARIOS"
+)
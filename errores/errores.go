@@ -0,0 +1,157 @@
+// Package errores define el formato único de error que regresa la API y los códigos estables que lo acompañan
+package errores
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CampoError describe un problema de validación en un campo específico del cuerpo
+type CampoError struct {
+	Campo   string `json:"field"`
+	Regla   string `json:"rule"`
+	Mensaje string `json:"message"`
+}
+
+// Error es el error de dominio que los controladores regresan; el middleware ManejarErrores lo serializa
+type Error struct {
+	Status  int          `json:"-"`
+	Codigo  string       `json:"code"`
+	Mensaje string       `json:"message"`
+	Campos  []CampoError `json:"fields,omitempty"`
+	Actual  any          `json:"-"` // representación vigente del registro, para los conflictos de versión
+	causa   error
+}
+
+func (e *Error) Error() string {
+	if e.causa != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Codigo, e.Mensaje, e.causa)
+	}
+	return e.Codigo + ": " + e.Mensaje
+}
+
+func (e *Error) Unwrap() error {
+	return e.causa
+}
+
+// ConCausa conserva el error original para el log sin exponerlo al cliente
+func (e *Error) ConCausa(err error) *Error {
+	e.causa = err
+	return e
+}
+
+// ConActual adjunta la representación vigente del registro a la respuesta
+func (e *Error) ConActual(actual any) *Error {
+	e.Actual = actual
+	return e
+}
+
+// Respuesta es el cuerpo JSON de cualquier error.
+// "error" repite el mensaje para los clientes que todavía leen el formato anterior.
+type Respuesta struct {
+	Codigo    string       `json:"code"`
+	Mensaje   string       `json:"message"`
+	Campos    []CampoError `json:"fields,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Actual    any          `json:"current,omitempty"`
+	Error     string       `json:"error"`
+}
+
+// Nuevo crea un error con estado HTTP, código estable y mensaje para el usuario
+func Nuevo(status int, codigo, mensaje string) *Error {
+	return &Error{Status: status, Codigo: codigo, Mensaje: mensaje}
+}
+
+// SolicitudInvalida regresa un error 400
+func SolicitudInvalida(codigo, mensaje string) *Error {
+	return Nuevo(http.StatusBadRequest, codigo, mensaje)
+}
+
+// NoAutorizado regresa un error 401
+func NoAutorizado(codigo, mensaje string) *Error {
+	return Nuevo(http.StatusUnauthorized, codigo, mensaje)
+}
+
+// Prohibido regresa un error 403
+func Prohibido(codigo, mensaje string) *Error {
+	return Nuevo(http.StatusForbidden, codigo, mensaje)
+}
+
+// NoEncontrado regresa un error 404
+func NoEncontrado(codigo, mensaje string) *Error {
+	return Nuevo(http.StatusNotFound, codigo, mensaje)
+}
+
+// Conflicto regresa un error 409, usado para duplicados y registros en uso
+func Conflicto(codigo, mensaje string) *Error {
+	return Nuevo(http.StatusConflict, codigo, mensaje)
+}
+
+// Interno regresa un error 500 genérico; la causa solo se escribe en el log
+func Interno(mensaje string, causa error) *Error {
+	return Nuevo(http.StatusInternalServerError, ErrorInterno, mensaje).ConCausa(causa)
+}
+
+// DeConsulta traduce el error de buscar un registro: no encontrado es 404 y cualquier otro error es 500
+func DeConsulta(err error, codigoNoEncontrado, mensajeNoEncontrado string) *Error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return NoEncontrado(codigoNoEncontrado, mensajeNoEncontrado)
+	}
+	return Interno("Error consultando la base de datos", err)
+}
+
+// Responder registra el error en el contexto y detiene la cadena; ManejarErrores escribe la respuesta
+func Responder(c *gin.Context, err *Error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// Escribir serializa el error con el request_id de la petición
+func Escribir(c *gin.Context, e *Error) {
+	if e.Status >= http.StatusInternalServerError && e.causa != nil {
+		log.Printf("[%s] %s %s: %v", c.GetString(ClaveRequestID), c.Request.Method, c.Request.URL.Path, e)
+	}
+	c.AbortWithStatusJSON(e.Status, Respuesta{
+		Codigo:    e.Codigo,
+		Mensaje:   e.Mensaje,
+		Campos:    e.Campos,
+		RequestID: c.GetString(ClaveRequestID),
+		Actual:    e.Actual,
+		Error:     e.Mensaje,
+	})
+}
+
+// ClaveRequestID es la llave del contexto de gin donde el middleware RequestID guarda el identificador
+const ClaveRequestID = "request_id"
+
+// ManejarErrores convierte el último error registrado con Responder (o c.Error) en la respuesta JSON estándar
+func ManejarErrores() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		EscribirPendiente(c)
+	}
+}
+
+// EscribirPendiente escribe el último error registrado si todavía no se respondió nada.
+// Los middlewares que necesitan el cuerpo final (como el de idempotencia) lo llaman antes de que regrese ManejarErrores.
+func EscribirPendiente(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+
+	var e *Error
+	if !errors.As(c.Errors.Last().Err, &e) {
+		e = Interno("Error inesperado", c.Errors.Last().Err)
+	}
+	Escribir(c, e)
+}
+
+// Recuperar responde con el formato estándar cuando un handler entra en pánico
+func Recuperar(c *gin.Context, recuperado any) {
+	Escribir(c, Interno("Error inesperado", fmt.Errorf("panic: %v", recuperado)))
+}
@@ -0,0 +1,145 @@
+package errores
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ConfigurarValidador hace que el validador de gin reporte los campos con su nombre json en lugar del nombre de Go
+func ConfigurarValidador() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(campo reflect.StructField) string {
+		nombre := strings.Split(campo.Tag.Get("json"), ",")[0]
+		if nombre == "-" {
+			return ""
+		}
+		if nombre == "" {
+			return campo.Name
+		}
+		return nombre
+	})
+}
+
+// Validacion traduce el error de ShouldBindJSON/ShouldBind a un 400 con el detalle por campo
+func Validacion(err error) *Error {
+	var errsValidacion validator.ValidationErrors
+	if errors.As(err, &errsValidacion) {
+		campos := make([]CampoError, 0, len(errsValidacion))
+		for _, fe := range errsValidacion {
+			campos = append(campos, CampoError{
+				Campo:   rutaCampo(fe),
+				Regla:   fe.Tag(),
+				Mensaje: mensajeRegla(fe),
+			})
+		}
+		e := SolicitudInvalida(ValidacionFallida, "Los datos enviados no son válidos")
+		e.Campos = campos
+		return e
+	}
+
+	var errTipo *json.UnmarshalTypeError
+	if errors.As(err, &errTipo) {
+		e := SolicitudInvalida(DatosInvalidos, "El cuerpo de la solicitud tiene tipos inválidos")
+		e.Campos = []CampoError{{
+			Campo:   errTipo.Field,
+			Regla:   "type",
+			Mensaje: fmt.Sprintf("Debe ser de tipo %s", nombreTipo(errTipo.Type)),
+		}}
+		return e.ConCausa(err)
+	}
+
+	if errors.Is(err, io.EOF) {
+		return SolicitudInvalida(DatosInvalidos, "El cuerpo de la solicitud está vacío")
+	}
+	return SolicitudInvalida(DatosInvalidos, "El cuerpo de la solicitud no es JSON válido").ConCausa(err)
+}
+
+// CampoInvalido regresa un error de validación para un solo campo revisado fuera del binding
+func CampoInvalido(campo, regla, mensaje string) *Error {
+	e := SolicitudInvalida(ValidacionFallida, "Los datos enviados no son válidos")
+	e.Campos = []CampoError{{Campo: campo, Regla: regla, Mensaje: mensaje}}
+	return e
+}
+
+// rutaCampo quita el nombre de la estructura raíz: "InsertarEstudianteInput.user.email" -> "user.email"
+func rutaCampo(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+// mensajeRegla describe en español la regla de validación que falló
+func mensajeRegla(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "Este campo es obligatorio"
+	case "email":
+		return "Debe ser un email válido"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("Debe tener al menos %s caracteres", fe.Param())
+		}
+		return fmt.Sprintf("Debe ser mayor o igual a %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("Debe tener como máximo %s caracteres", fe.Param())
+		}
+		return fmt.Sprintf("Debe ser menor o igual a %s", fe.Param())
+	case "len":
+		return fmt.Sprintf("Debe tener exactamente %s caracteres", fe.Param())
+	case "oneof":
+		return "Debe ser uno de: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "gt":
+		return fmt.Sprintf("Debe ser mayor a %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("Debe ser mayor o igual a %s", fe.Param())
+	case "lt":
+		return fmt.Sprintf("Debe ser menor a %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("Debe ser menor o igual a %s", fe.Param())
+	case "numeric":
+		return "Debe contener solo números"
+	case "datetime":
+		return "Debe ser una fecha con formato " + fe.Param()
+	case "curp":
+		return "Debe ser una CURP válida de 18 caracteres"
+	case "rfc":
+		return "Debe ser un RFC de persona física válido con homoclave (13 caracteres)"
+	case "telefono_mx":
+		return "Debe ser un teléfono de 10 dígitos"
+	case "cp_mx":
+		return "Debe ser un código postal de 5 dígitos"
+	}
+	return fmt.Sprintf("No cumple la regla %s", fe.Tag())
+}
+
+func nombreTipo(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "texto"
+	case reflect.Bool:
+		return "booleano"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "entero"
+	case reflect.Float32, reflect.Float64:
+		return "número"
+	case reflect.Slice, reflect.Array:
+		return "arreglo"
+	case reflect.Struct, reflect.Map:
+		return "objeto"
+	}
+	return t.String()
+}
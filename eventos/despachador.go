@@ -0,0 +1,303 @@
+package eventos
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"api-margaritai/config"
+	"api-margaritai/database"
+	"api-margaritai/models"
+	"api-margaritai/organizacion"
+)
+
+// Encabezados de cada envío. El receptor debe recalcular la firma con su secreto y descartar los Id repetidos:
+// un evento puede llegar más de una vez si la respuesta se pierde.
+const (
+	HeaderID      = "X-Webhook-Id"        // ID del evento; es el mismo en todos los reintentos
+	HeaderEvento  = "X-Webhook-Event"     // tipo de evento
+	HeaderMarca   = "X-Webhook-Timestamp" // segundos Unix en que se firmó
+	HeaderFirma   = "X-Webhook-Signature" // sha256=HMAC-SHA256(secreto, marca + "." + cuerpo) en hexadecimal
+	HeaderIntento = "X-Webhook-Attempt"   // 1 en el primer envío
+)
+
+const (
+	loteEventos      = 100 // eventos que se reparten por pasada
+	loteEntregas     = 20  // entregas que se envían por pasada
+	enviosEnParalelo = 5
+)
+
+// Envio es el cuerpo JSON que recibe cada suscripción
+type Envio struct {
+	ID        uint      `json:"id"`
+	Tipo      string    `json:"tipo"`
+	EntidadID uint      `json:"entidad_id"`
+	CreadoEn  time.Time `json:"creado_en"`
+	Datos     any       `json:"datos"`
+}
+
+// Firmar calcula el valor de X-Webhook-Signature
+func Firmar(secreto string, marca int64, cuerpo []byte) string {
+	mac := hmac.New(sha256.New, []byte(secreto))
+	mac.Write([]byte(strconv.FormatInt(marca, 10) + "."))
+	mac.Write(cuerpo)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// global es el contexto del despachador: reparte y envía los eventos de todas las organizaciones, y cada
+// evento solo llega a las suscripciones de la suya
+var global = organizacion.Global(context.Background())
+
+var (
+	configuracionOnce sync.Once
+	intervalo         time.Duration
+	maxIntentos       int
+	cliente           *http.Client
+)
+
+// configurar lee el entorno la primera vez que se despacha, cuando config.LoadEnv ya se ejecutó
+func configurar() {
+	configuracionOnce.Do(func() {
+		intervalo = config.GetEnvDuration("WEBHOOKS_INTERVALO", 5*time.Second)
+		maxIntentos = config.GetEnvInt("WEBHOOKS_MAX_INTENTOS", 10)
+		cliente = nuevoCliente(config.GetEnvDuration("WEBHOOKS_TIMEOUT", 10*time.Second))
+	})
+}
+
+// Resumen es lo que hizo el despachador en una ejecución de la tarea despachar_webhooks
+type Resumen struct {
+	Pasadas    int `json:"pasadas"`
+	Repartidos int `json:"eventos_repartidos"`
+	Enviadas   int `json:"entregas_enviadas"`
+	Errores    int `json:"errores"`
+}
+
+// Despachar revisa la bandeja de salida cada WEBHOOKS_INTERVALO hasta que ctx está por vencer. Lo ejecuta
+// la tarea despachar_webhooks cada minuto, así que solo una instancia despacha a la vez; aun así las filas se
+// toman con SKIP LOCKED por si una pasada se encima con otra ejecución manual.
+func Despachar(ctx context.Context) (Resumen, error) {
+	configurar()
+	var resumen Resumen
+	var ultimo error
+	for {
+		resumen.Pasadas++
+		repartidos, err := repartir()
+		if err != nil {
+			resumen.Errores++
+			ultimo = err
+			log.Printf("Webhooks: error repartiendo eventos: %v", err)
+		}
+		resumen.Repartidos += repartidos
+		enviadas, err := enviarPendientes()
+		if err != nil {
+			resumen.Errores++
+			ultimo = err
+			log.Printf("Webhooks: error enviando entregas: %v", err)
+		}
+		resumen.Enviadas += enviadas
+
+		// La pasada no se corta a la mitad: solo se empieza otra si cabe antes de que venza ctx
+		if limite, ok := ctx.Deadline(); ok && time.Until(limite) < intervalo {
+			return resumen, ultimo
+		}
+		select {
+		case <-ctx.Done():
+			return resumen, ultimo
+		case <-time.After(intervalo):
+		}
+	}
+}
+
+// repartir crea una entrega por cada suscripción activa interesada en cada evento sin procesar y regresa
+// cuántos eventos repartió
+func repartir() (int, error) {
+	var repartidos int
+	err := database.De(global).Transaction(func(tx *gorm.DB) error {
+		var pendientes []models.EventoDominio
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("procesado_en IS NULL").Order("id").Limit(loteEventos).Find(&pendientes).Error; err != nil {
+			return err
+		}
+		if len(pendientes) == 0 {
+			return nil
+		}
+
+		var suscripciones []models.SuscripcionWebhook
+		if err := tx.Where("activa = ?", true).Find(&suscripciones).Error; err != nil {
+			return err
+		}
+		ahora := time.Now()
+		var entregas []models.EntregaWebhook
+		ids := make([]uint, len(pendientes))
+		for i, evento := range pendientes {
+			ids[i] = evento.ID
+			for _, s := range suscripciones {
+				if s.OrganizacionID == evento.OrganizacionID && s.Recibe(evento.Tipo) {
+					entregas = append(entregas, models.EntregaWebhook{OrganizacionID: evento.OrganizacionID, SuscripcionID: s.ID, EventoID: evento.ID, Estado: models.EntregaPendiente, SiguienteIntento: ahora})
+				}
+			}
+		}
+		if len(entregas) > 0 {
+			if err := tx.CreateInBatches(&entregas, 200).Error; err != nil {
+				return err
+			}
+		}
+		repartidos = len(pendientes)
+		return tx.Model(&models.EventoDominio{}).Where("id IN ?", ids).Update("procesado_en", ahora).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return repartidos, nil
+}
+
+// enviarPendientes toma las entregas cuyo intento ya toca y las envía. Antes de enviar aplaza su siguiente
+// intento, de modo que si el proceso muere a la mitad otra pasada las reintenta en lugar de perderlas.
+// Regresa cuántas envió.
+func enviarPendientes() (int, error) {
+	var entregas []models.EntregaWebhook
+	err := database.De(global).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("estado = ? AND siguiente_intento <= ?", models.EntregaPendiente, time.Now()).
+			Order("siguiente_intento").Limit(loteEntregas).Find(&entregas).Error; err != nil {
+			return err
+		}
+		if len(entregas) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(entregas))
+		for i, e := range entregas {
+			ids[i] = e.ID
+		}
+		reserva := time.Now().Add(2*cliente.Timeout + 30*time.Second)
+		return tx.Model(&models.EntregaWebhook{}).Where("id IN ?", ids).Update("siguiente_intento", reserva).Error
+	})
+	if err != nil || len(entregas) == 0 {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	turnos := make(chan struct{}, enviosEnParalelo)
+	for i := range entregas {
+		wg.Add(1)
+		turnos <- struct{}{}
+		go func(entrega *models.EntregaWebhook) {
+			defer func() { <-turnos; wg.Done() }()
+			if err := enviar(entrega); err != nil {
+				log.Printf("Webhooks: error guardando el resultado de la entrega %d: %v", entrega.ID, err)
+			}
+		}(&entregas[i])
+	}
+	wg.Wait()
+	return len(entregas), nil
+}
+
+// enviar hace un intento de entrega y guarda el resultado
+func enviar(entrega *models.EntregaWebhook) error {
+	var evento models.EventoDominio
+	if err := database.De(global).First(&evento, entrega.EventoID).Error; err != nil {
+		return err
+	}
+	var suscripcion models.SuscripcionWebhook
+	if err := database.De(global).Unscoped().First(&suscripcion, entrega.SuscripcionID).Error; err != nil {
+		return err
+	}
+
+	cambios := map[string]any{"intentos": entrega.Intentos + 1}
+	switch {
+	case suscripcion.DeletedAt.Valid:
+		cambios["estado"] = models.EntregaFallida
+		cambios["ultimo_error"] = "La suscripción fue eliminada"
+	case !suscripcion.Activa:
+		cambios["estado"] = models.EntregaFallida
+		cambios["ultimo_error"] = "La suscripción está desactivada"
+	default:
+		codigo, errEnvio := publicar(suscripcion, evento, entrega.Intentos+1)
+		cambios["ultimo_codigo"] = codigo
+		if errEnvio == nil {
+			ahora := time.Now()
+			cambios["estado"] = models.EntregaEntregada
+			cambios["entregada_en"] = ahora
+			cambios["ultimo_error"] = ""
+			break
+		}
+		cambios["ultimo_error"] = errEnvio.Error()
+		if entrega.Intentos+1 >= maxIntentos {
+			cambios["estado"] = models.EntregaFallida
+		} else {
+			cambios["siguiente_intento"] = time.Now().Add(espera(entrega.Intentos + 1))
+		}
+	}
+	return database.De(global).Model(entrega).Updates(cambios).Error
+}
+
+// publicar envía el POST firmado; cualquier respuesta fuera de 2xx es un fallo
+func publicar(s models.SuscripcionWebhook, evento models.EventoDominio, intento int) (int, error) {
+	cuerpo, err := json.Marshal(Envio{ID: evento.ID, Tipo: evento.Tipo, EntidadID: evento.EntidadID, CreadoEn: evento.CreatedAt, Datos: evento.Datos})
+	if err != nil {
+		return 0, err
+	}
+	solicitud, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(cuerpo))
+	if err != nil {
+		return 0, err
+	}
+	marca := time.Now().Unix()
+	solicitud.Header.Set("Content-Type", "application/json")
+	solicitud.Header.Set("User-Agent", "api-margaritai-webhooks")
+	solicitud.Header.Set(HeaderID, strconv.FormatUint(uint64(evento.ID), 10))
+	solicitud.Header.Set(HeaderEvento, evento.Tipo)
+	solicitud.Header.Set(HeaderMarca, strconv.FormatInt(marca, 10))
+	solicitud.Header.Set(HeaderFirma, Firmar(s.Secreto, marca, cuerpo))
+	solicitud.Header.Set(HeaderIntento, strconv.Itoa(intento))
+
+	respuesta, err := cliente.Do(solicitud)
+	if err != nil {
+		log.Printf("Webhooks: error enviando el evento %d a la suscripción %d: %v", evento.ID, s.ID, err)
+		return 0, errorDeConexion(err)
+	}
+	defer respuesta.Body.Close()
+	// El cuerpo se descarta sin guardarlo: lo controla el destino y ultimo_error se muestra en la API
+	io.Copy(io.Discard, io.LimitReader(respuesta.Body, 4<<10))
+	if respuesta.StatusCode < 200 || respuesta.StatusCode > 299 {
+		return respuesta.StatusCode, fmt.Errorf("HTTP %d", respuesta.StatusCode)
+	}
+	return respuesta.StatusCode, nil
+}
+
+// errorDeConexion resume un fallo de conexión en un mensaje fijo para ultimo_error; el detalle (que puede
+// traer IPs internas resueltas o texto del destino) solo va al log
+func errorDeConexion(err error) error {
+	var red net.Error
+	switch {
+	case errors.Is(err, ErrDestinoNoPermitido):
+		return errors.New("El destino apunta a una dirección no permitida")
+	case errors.As(err, &red) && red.Timeout():
+		return errors.New("Se agotó el tiempo de espera del destino")
+	default:
+		return errors.New("No se pudo conectar con el destino")
+	}
+}
+
+// espera es el tiempo antes del siguiente intento: 30 s que se duplican en cada fallo, hasta 6 h,
+// más un margen aleatorio para que los reintentos de muchas entregas no lleguen juntos
+func espera(intentos int) time.Duration {
+	base := 30 * time.Second << min(intentos-1, 10)
+	base = min(base, 6*time.Hour)
+	return base + rand.N(base/5+1)
+}
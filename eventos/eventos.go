@@ -0,0 +1,115 @@
+// Package eventos publica los cambios de dominio a sistemas externos (cobranza, LMS) por medio de webhooks.
+// Los controladores registran el evento con Registrar dentro de su transacción (patrón outbox) y el despachador
+// lo entrega después, firmado y con reintentos, a cada suscripción interesada.
+package eventos
+
+import (
+	"gorm.io/gorm"
+
+	"api-margaritai/models"
+)
+
+// Tipos de evento
+const (
+	EstudianteCreado        = "estudiante.creado"
+	EstudianteGrupoCambiado = "estudiante.grupo_cambiado"
+	EstudianteEliminado     = "estudiante.eliminado"
+	EstudianteRestaurado    = "estudiante.restaurado"
+	PersonalCreado          = "personal.creado"
+	PersonalActualizado     = "personal.actualizado"
+	PersonalEliminado       = "personal.eliminado"
+	PersonalRestaurado      = "personal.restaurado"
+)
+
+// Tipos lista los eventos a los que se puede suscribir, en el orden en que se documentan
+var Tipos = []string{
+	EstudianteCreado, EstudianteGrupoCambiado, EstudianteEliminado, EstudianteRestaurado,
+	PersonalCreado, PersonalActualizado, PersonalEliminado, PersonalRestaurado,
+}
+
+// Todos es el comodín de suscripción que recibe cualquier tipo de evento
+const Todos = "*"
+
+// Registrar agrega el evento a la bandeja de salida. Debe llamarse con la transacción del cambio para que,
+// si esta se revierte, el evento tampoco exista.
+func Registrar(tx *gorm.DB, tipo string, entidadID uint, datos any) error {
+	return tx.Create(&models.EventoDominio{Tipo: tipo, EntidadID: entidadID, Datos: datos}).Error
+}
+
+// Estudiante es la representación de un estudiante en los eventos; requiere el User precargado
+type Estudiante struct {
+	ID                uint   `json:"id"`
+	UserID            uint   `json:"user_id"`
+	Matricula         string `json:"matricula"`
+	Nombre            string `json:"nombre"`
+	ApellidoP         string `json:"apellido_p"`
+	ApellidoM         string `json:"apellido_m"`
+	Email             string `json:"email"`
+	CURP              string `json:"curp"`
+	PlantelID         uint   `json:"plantel_id"`
+	NivelEscolarID    uint   `json:"nivel_escolar_id"`
+	GrupoID           uint   `json:"grupo_id"`
+	EnProcesoAdmision bool   `json:"en_proceso_admision"`
+}
+
+// DeEstudiante arma la representación de un estudiante con su usuario precargado
+func DeEstudiante(e *models.Estudiante) Estudiante {
+	return Estudiante{
+		ID:                e.ID,
+		UserID:            e.UserID,
+		Matricula:         e.Matricula,
+		Nombre:            e.User.Nombre,
+		ApellidoP:         e.User.ApellidoP,
+		ApellidoM:         e.User.ApellidoM,
+		Email:             e.User.Email,
+		CURP:              e.User.CURP,
+		PlantelID:         e.PlantelID,
+		NivelEscolarID:    e.NivelEscolarID,
+		GrupoID:           e.GrupoID,
+		EnProcesoAdmision: e.EnProcesoAdmision,
+	}
+}
+
+// CambioDeGrupo son los datos de estudiante.grupo_cambiado
+type CambioDeGrupo struct {
+	Estudiante      Estudiante `json:"estudiante"`
+	GrupoAnteriorID uint       `json:"grupo_anterior_id"`
+}
+
+// Personal es la representación de un registro de personal en los eventos; requiere el User precargado
+type Personal struct {
+	ID                uint   `json:"id"`
+	UserID            uint   `json:"user_id"`
+	NumeroEmpleado    string `json:"numero_empleado"`
+	RFC               string `json:"rfc"`
+	Nombre            string `json:"nombre"`
+	ApellidoP         string `json:"apellido_p"`
+	ApellidoM         string `json:"apellido_m"`
+	Email             string `json:"email"`
+	CURP              string `json:"curp"`
+	EsProfesor        bool   `json:"es_profesor"`
+	PuestoID          uint   `json:"puesto_id"`
+	GradoAcademicoID  uint   `json:"grado_academico_id"`
+	EstatusLaboralID  uint   `json:"estatus_laboral_id"`
+	EstatusEmpleadoID uint   `json:"estatus_empleado_id"`
+}
+
+// DePersonal arma la representación de un registro de personal con su usuario precargado
+func DePersonal(p *models.Personal) Personal {
+	return Personal{
+		ID:                p.ID,
+		UserID:            p.UserID,
+		NumeroEmpleado:    p.NumeroEmpleado,
+		RFC:               p.RFC,
+		Nombre:            p.User.Nombre,
+		ApellidoP:         p.User.ApellidoP,
+		ApellidoM:         p.User.ApellidoM,
+		Email:             p.User.Email,
+		CURP:              p.User.CURP,
+		EsProfesor:        p.EsProfesor,
+		PuestoID:          p.PuestoID,
+		GradoAcademicoID:  p.GradoAcademicoID,
+		EstatusLaboralID:  p.EstatusLaboralID,
+		EstatusEmpleadoID: p.EstatusEmpleadoID,
+	}
+}
@@ -0,0 +1,101 @@
+package eventos
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"api-margaritai/config"
+)
+
+// ErrDestinoNoPermitido indica que la URL de una suscripción apunta a la red interna
+var ErrDestinoNoPermitido = errors.New("el destino apunta a una dirección no permitida")
+
+// redesReservadas son los rangos que no son de Internet y que netip no cubre con IsPrivate, IsLoopback, etc.
+var redesReservadas = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "esta red"
+	netip.MustParsePrefix("100.64.0.0/10"),  // NAT de operador
+	netip.MustParsePrefix("192.0.0.0/24"),   // asignaciones del IETF
+	netip.MustParsePrefix("198.18.0.0/15"),  // pruebas de rendimiento
+	netip.MustParsePrefix("240.0.0.0/4"),    // reservado y broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, puede traducir a IPv4 internas
+	netip.MustParsePrefix("64:ff9b:1::/48"), // NAT64 local
+}
+
+// permitirRedPrivada deja enviar a la red interna; solo para desarrollo, con WEBHOOKS_PERMITIR_RED_PRIVADA=true
+func permitirRedPrivada() bool {
+	return config.GetEnv("WEBHOOKS_PERMITIR_RED_PRIVADA", "false") == "true"
+}
+
+// DireccionPermitida dice si se puede enviar a la IP: no puede ser de loopback, privada, de enlace local
+// (donde está el servicio de metadatos de la nube), sin especificar, multicast ni de un rango reservado
+func DireccionPermitida(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, red := range redesReservadas {
+		if red.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// RevisarDestino rechaza al registrar una suscripción los hosts que ya se sabe que son internos (IPs
+// literales y localhost). Los nombres se vuelven a revisar al conectar, con la IP ya resuelta.
+func RevisarDestino(host string) error {
+	if permitirRedPrivada() {
+		return nil
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrDestinoNoPermitido
+	}
+	if ip, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil && !DireccionPermitida(ip) {
+		return ErrDestinoNoPermitido
+	}
+	return nil
+}
+
+// controlarConexion revisa la IP justo antes de conectar, ya resuelta, para que un nombre que resuelve a la
+// red interna (o que cambia de IP entre la validación y el envío) no sirva para llegar a ella
+func controlarConexion(_, direccion string, _ syscall.RawConn) error {
+	destino, err := netip.ParseAddrPort(direccion)
+	if err != nil {
+		return ErrDestinoNoPermitido
+	}
+	if !DireccionPermitida(destino.Addr()) {
+		return ErrDestinoNoPermitido
+	}
+	return nil
+}
+
+// nuevoCliente arma el cliente HTTP de los envíos: sin proxy (la conexión debe ir directo a la IP revisada),
+// sin seguir redirecciones y con la revisión de la IP en cada conexión
+func nuevoCliente(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if !permitirRedPrivada() {
+		dialer.Control = controlarConexion
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          20,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   timeout,
+			ExpectContinueTimeout: time.Second,
+		},
+		// Una redirección cuenta como fallo: la URL registrada es la que se firmó y autorizó, y el destino
+		// de la redirección no pasaría por RevisarDestino
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
@@ -0,0 +1,122 @@
+// Package exportacion descarga los listados de la API como CSV, XLSX o PDF con los mismos filtros y orden que el JSON
+package exportacion
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/consulta"
+	"api-margaritai/errores"
+	"api-margaritai/tablas"
+)
+
+// ParamFormato elige el formato del archivo; por omisión es CSV
+const ParamFormato = "formato"
+
+// Columna describe una columna del archivo y cómo obtener su valor de cada registro
+type Columna[T any] struct {
+	Titulo string
+	Ancho  float64 // ancho aproximado en caracteres
+	Valor  func(*T) string
+}
+
+// Responder escribe el archivo conforme se leen los lotes, así la memoria no crece con el tamaño de la tabla.
+// nombre es la base del nombre del archivo descargado y titulo encabeza el PDF y nombra la hoja del XLSX.
+func Responder[T any](c *gin.Context, db *gorm.DB, def consulta.Definicion, nombre, titulo string, columnas []Columna[T]) {
+	formato := strings.ToLower(c.DefaultQuery(ParamFormato, tablas.FormatoCSV))
+	if !slices.Contains(tablas.Formatos, formato) {
+		errores.Responder(c, errores.CampoInvalido(ParamFormato, "oneof", "formato debe ser uno de: "+strings.Join(tablas.Formatos, ", ")))
+		return
+	}
+	lotes, errConsulta := consulta.Recorrer[T](c, db, def)
+	if errConsulta != nil {
+		errores.Responder(c, errConsulta)
+		return
+	}
+
+	encabezados := make([]tablas.Columna, len(columnas))
+	for i, col := range columnas {
+		encabezados[i] = tablas.Columna{Titulo: col.Titulo, Ancho: col.Ancho}
+	}
+	archivo := fmt.Sprintf("%s-%s.%s", nombre, time.Now().Format("20060102-1504"), formato)
+	c.Header("Content-Type", tablas.TipoContenido(formato))
+	c.Header("Content-Disposition", `attachment; filename="`+archivo+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	escritor, err := tablas.NuevoEscritor(formato, c.Writer, titulo, encabezados)
+	if err == nil {
+		err = lotes(func(items []T) error {
+			fila := make([]string, len(columnas))
+			for i := range items {
+				for j, col := range columnas {
+					fila[j] = col.Valor(&items[i])
+				}
+				if err := escritor.Escribir(fila); err != nil {
+					return err
+				}
+			}
+			c.Writer.Flush()
+			return nil
+		})
+	}
+	if err == nil {
+		err = escritor.Cerrar()
+	}
+	if err == nil {
+		return
+	}
+
+	if !c.Writer.Written() {
+		// Todavía no sale nada: se puede responder el error normal en lugar de un archivo
+		for _, h := range []string{"Content-Type", "Content-Disposition", "Cache-Control"} {
+			c.Writer.Header().Del(h)
+		}
+		errores.Responder(c, errores.Interno("Error generando el archivo", err))
+		return
+	}
+	// El archivo ya se estaba enviando; queda incompleto y solo se puede registrar
+	log.Printf("[%s] exportación %s interrumpida: %v", c.GetString(errores.ClaveRequestID), archivo, err)
+}
+
+// Entero da formato a IDs y llaves foráneas
+func Entero(n uint) string {
+	return strconv.FormatUint(uint64(n), 10)
+}
+
+// SiNo da formato a los booleanos
+func SiNo(b bool) string {
+	if b {
+		return "Sí"
+	}
+	return "No"
+}
+
+// Fecha da formato a fechas sin hora, como la de nacimiento
+func Fecha(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// FechaHora da formato a marcas de tiempo en la zona del servidor
+func FechaHora(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// NombreCompleto une nombre y apellidos omitiendo los vacíos
+func NombreCompleto(partes ...string) string {
+	return strings.Join(strings.Fields(strings.Join(partes, " ")), " ")
+}
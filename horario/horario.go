@@ -0,0 +1,113 @@
+// Package horario representa los intervalos semanales (día y rango de horas) en que se ocupan aulas, grupos y profesores
+package horario
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/errores"
+)
+
+// Días de la semana como se guardan en Franja.Dia
+const (
+	Lunes = iota + 1
+	Martes
+	Miercoles
+	Jueves
+	Viernes
+	Sabado
+	Domingo
+)
+
+// NombresDias indexa el nombre de cada día por su número; la posición 0 no se usa
+var NombresDias = [...]string{"", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado", "domingo"}
+
+// Franja es un rango de horas de un día de la semana. Las horas van en formato HH:MM de 24 horas y Hasta
+// no se incluye, así que una franja que termina a las 08:00 no choca con la que empieza a esa hora.
+type Franja struct {
+	Dia   int    `json:"dia"` // 1 = lunes … 7 = domingo
+	Desde string `json:"desde"`
+	Hasta string `json:"hasta"`
+}
+
+// NormalizarHora acepta H:MM o HH:MM y la regresa siempre con dos dígitos, para que las horas se puedan
+// comparar como texto (en Go y en SQL)
+func NormalizarHora(s string) (string, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return "", false
+	}
+	return t.Format("15:04"), true
+}
+
+// Validar normaliza las horas y revisa el día y que Desde sea antes que Hasta; los campos de los errores
+// llevan el prefijo indicado (por ejemplo "franjas[2].")
+func (f *Franja) Validar(prefijo string) *errores.Error {
+	if f.Dia < Lunes || f.Dia > Domingo {
+		return errores.CampoInvalido(prefijo+"dia", "min", "El día debe ir de 1 (lunes) a 7 (domingo)")
+	}
+	desde, ok := NormalizarHora(f.Desde)
+	if !ok {
+		return errores.CampoInvalido(prefijo+"desde", "datetime", "Formato de hora inválido. Use HH:MM")
+	}
+	hasta, ok := NormalizarHora(f.Hasta)
+	if !ok {
+		return errores.CampoInvalido(prefijo+"hasta", "datetime", "Formato de hora inválido. Use HH:MM")
+	}
+	if desde >= hasta {
+		return errores.CampoInvalido(prefijo+"hasta", "gtfield", "La hora final debe ser posterior a la inicial")
+	}
+	f.Desde, f.Hasta = desde, hasta
+	return nil
+}
+
+// SeTraslapa indica si las dos franjas comparten algún minuto
+func (f Franja) SeTraslapa(otra Franja) bool {
+	return f.Dia == otra.Dia && f.Desde < otra.Hasta && otra.Desde < f.Hasta
+}
+
+func (f Franja) String() string {
+	if f.Dia < Lunes || f.Dia > Domingo {
+		return fmt.Sprintf("día %d %s-%s", f.Dia, f.Desde, f.Hasta)
+	}
+	return NombresDias[f.Dia] + " " + f.Desde + "-" + f.Hasta
+}
+
+// DeConsulta lee la franja de los parámetros dia, desde y hasta. En lugar de dia se puede enviar fecha
+// (YYYY-MM-DD) y se usa el día de la semana de esa fecha; la fecha también se regresa, en cero si se pidió
+// por día.
+func DeConsulta(c *gin.Context) (f Franja, fecha time.Time, e *errores.Error) {
+	if valor := c.Query("fecha"); valor != "" && c.Query("dia") == "" {
+		var err error
+		if fecha, err = time.Parse("2006-01-02", valor); err != nil {
+			return f, fecha, errores.CampoInvalido("fecha", "datetime", "Formato de fecha inválido. Use YYYY-MM-DD")
+		}
+		f.Dia = DiaDe(fecha)
+	} else {
+		dia, err := strconv.Atoi(c.Query("dia"))
+		if err != nil {
+			return f, fecha, errores.CampoInvalido("dia", "required", "Indique dia (1 = lunes … 7 = domingo) o fecha")
+		}
+		f.Dia = dia
+	}
+	f.Desde, f.Hasta = c.Query("desde"), c.Query("hasta")
+	if f.Desde == "" {
+		return f, fecha, errores.CampoInvalido("desde", "required", "La hora inicial es requerida")
+	}
+	if f.Hasta == "" {
+		return f, fecha, errores.CampoInvalido("hasta", "required", "La hora final es requerida")
+	}
+	return f, fecha, f.Validar("")
+}
+
+// DiaDe regresa el día de la semana de t con lunes = 1 y domingo = 7
+func DiaDe(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return Domingo
+	}
+	return int(t.Weekday())
+}
@@ -0,0 +1,98 @@
+package horario
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TipoICalendar es el Content-Type de los calendarios que genera ICalendar
+const TipoICalendar = "text/calendar; charset=utf-8"
+
+// diasICalendar son los valores de BYDAY por número de día
+var diasICalendar = [...]string{"", "MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// Clase es un evento que se repite cada semana en la Franja, desde la fecha Inicio hasta la fecha Fin
+// (las fechas del ciclo escolar)
+type Clase struct {
+	UID         string
+	Resumen     string
+	Lugar       string
+	Descripcion string
+	Franja      Franja
+	Inicio      time.Time
+	Fin         time.Time
+	Modificada  time.Time
+}
+
+// ICalendar arma un calendario (RFC 5545) con un VEVENT semanal por clase. Las horas van sin zona (hora
+// flotante): cada dispositivo las muestra en su hora local, que es la del plantel. Las clases cuyo día no
+// cae dentro de sus fechas se omiten.
+func ICalendar(nombre string, clases []Clase) []byte {
+	var b bytes.Buffer
+	linea(&b, "BEGIN:VCALENDAR")
+	linea(&b, "VERSION:2.0")
+	linea(&b, "PRODID:-//MargaritAI//Horarios//ES")
+	linea(&b, "CALSCALE:GREGORIAN")
+	linea(&b, "METHOD:PUBLISH")
+	linea(&b, "X-WR-CALNAME:"+escapar(nombre))
+	for _, clase := range clases {
+		primera := primerDia(clase.Inicio, clase.Franja.Dia)
+		if primera.After(clase.Fin) {
+			continue
+		}
+		linea(&b, "BEGIN:VEVENT")
+		linea(&b, "UID:"+clase.UID)
+		linea(&b, "DTSTAMP:"+clase.Modificada.UTC().Format("20060102T150405Z"))
+		linea(&b, "DTSTART:"+fechaHora(primera, clase.Franja.Desde))
+		linea(&b, "DTEND:"+fechaHora(primera, clase.Franja.Hasta))
+		linea(&b, fmt.Sprintf("RRULE:FREQ=WEEKLY;BYDAY=%s;UNTIL=%sT235959", diasICalendar[clase.Franja.Dia], clase.Fin.Format("20060102")))
+		linea(&b, "SUMMARY:"+escapar(clase.Resumen))
+		if clase.Lugar != "" {
+			linea(&b, "LOCATION:"+escapar(clase.Lugar))
+		}
+		if clase.Descripcion != "" {
+			linea(&b, "DESCRIPTION:"+escapar(clase.Descripcion))
+		}
+		linea(&b, "END:VEVENT")
+	}
+	linea(&b, "END:VCALENDAR")
+	return b.Bytes()
+}
+
+// primerDia regresa la primera fecha desde inicio que cae en el día de la semana indicado
+func primerDia(inicio time.Time, dia int) time.Time {
+	return inicio.AddDate(0, 0, (dia-DiaDe(inicio)+7)%7)
+}
+
+// fechaHora junta la fecha con una hora HH:MM en el formato local de iCalendar
+func fechaHora(fecha time.Time, hora string) string {
+	return fecha.Format("20060102") + "T" + strings.Replace(hora, ":", "", 1) + "00"
+}
+
+// escapar protege los caracteres especiales de los valores de texto
+func escapar(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// linea escribe una línea terminada en CRLF, doblada a 75 octetos sin partir caracteres UTF-8
+func linea(b *bytes.Buffer, s string) {
+	limite := 75
+	for len(s) > limite {
+		corte := limite
+		for corte > 0 && !inicioDeRuna(s[corte]) {
+			corte--
+		}
+		b.WriteString(s[:corte])
+		b.WriteString("\r\n ")
+		s = s[corte:]
+		limite = 74 // el espacio inicial cuenta
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
+
+func inicioDeRuna(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -1,19 +1,50 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"api-margaritai/config"
+	gestionusuarios "api-margaritai/controllers/gestion_usuarios"
 	"api-margaritai/database"
 	"api-margaritai/routes"
+	"api-margaritai/tareas"
 )
 
 func main() {
 	config.LoadEnv()
 	database.ConnectDB()
 
-	r := routes.SetupRouter()
+	// Limpiezas, reportes, el envío de webhooks y la revisión de importaciones interrumpidas; ver GET /api/protected/tareas
+	tareas.Iniciar()
 
-	log.Println("Server running on port 8080")
-	r.Run(":8080")
+	// Toda ruta debe estar documentada en docs/rutas.go; lo revisa routes/rutas_test.go
+	servidor := &http.Server{Addr: ":8080", Handler: routes.SetupRouter()}
+	go func() {
+		log.Println("Server running on port 8080")
+		if err := servidor.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Error iniciando el servidor: %v", err)
+		}
+	}()
+
+	// Al recibir SIGINT o SIGTERM deja de aceptar peticiones y espera a las que están en curso y a las
+	// importaciones en segundo plano, hasta APAGADO_ESPERA
+	apagado, detener := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer detener()
+	<-apagado.Done()
+	log.Println("Apagando el servidor")
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetEnvDuration("APAGADO_ESPERA", 30*time.Second))
+	defer cancel()
+	if err := servidor.Shutdown(ctx); err != nil {
+		log.Printf("Error cerrando las conexiones: %v", err)
+	}
+	if err := gestionusuarios.EsperarImportaciones(ctx); err != nil {
+		log.Printf("Quedaron importaciones sin terminar; se marcarán como interrumpidas: %v", err)
+	}
 }
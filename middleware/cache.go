@@ -0,0 +1,162 @@
+// middleware/cache.go
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/cache"
+	"api-margaritai/config"
+)
+
+// respuestaEnCache es una respuesta GET guardada junto con sus validadores
+type respuestaEnCache struct {
+	contentType string
+	cuerpo      []byte
+	etag        string
+	modificado  time.Time // momento en que se guardó; lo asigna la caché
+}
+
+// CacheCatalogo guarda en memoria las respuestas GET exitosas del grupo y las sirve con ETag, Last-Modified
+// y Cache-Control; responde 304 cuando el cliente ya tiene la versión vigente. Cualquier POST, PUT o DELETE
+// exitoso que pase por el mismo middleware invalida el grupo y los grupos de tambienInvalida.
+// Las rutas de un mismo recurso deben compartir el grupo para que sus escrituras lo invaliden.
+func CacheCatalogo(grupo string, tambienInvalida ...string) gin.HandlerFunc {
+	cacheControl := "private, no-cache"
+	if maxAge := config.GetEnvDuration("CACHE_CATALOGOS_MAX_AGE", 0); maxAge > 0 {
+		cacheControl = fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds()))
+	}
+	invalidar := append([]string{grupo}, tambienInvalida...)
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			if len(c.Errors) == 0 && c.Writer.Status() < http.StatusBadRequest {
+				cache.Invalidar(invalidar...)
+			}
+			return
+		}
+
+		// Cada organización tiene sus propios catálogos
+		llave := strconv.FormatUint(uint64(c.GetUint(ClaveOrganizacion)), 10) + ":" + c.Request.URL.RequestURI()
+		if valor, creado, ok := cache.Obtener(grupo, llave); ok {
+			respuesta := valor.(respuestaEnCache)
+			respuesta.modificado = creado
+			responderDesdeCache(c, respuesta, cacheControl)
+			return
+		}
+
+		generacion := cache.Generacion(grupo)
+		original := c.Writer
+		escritor := &escritorEnBuffer{ResponseWriter: original, estado: http.StatusOK}
+		c.Writer = escritor
+		c.Next()
+		c.Writer = original
+
+		if !escritor.escrito {
+			return // error registrado con errores.Responder; lo escribe ManejarErrores
+		}
+		if escritor.estado != http.StatusOK {
+			c.Data(escritor.estado, escritor.Header().Get("Content-Type"), escritor.cuerpo.Bytes())
+			return
+		}
+
+		suma := sha256.Sum256(escritor.cuerpo.Bytes())
+		respuesta := respuestaEnCache{
+			contentType: escritor.Header().Get("Content-Type"),
+			cuerpo:      escritor.cuerpo.Bytes(),
+			etag:        `W/"` + hex.EncodeToString(suma[:16]) + `"`,
+		}
+		respuesta.modificado = cache.Guardar(grupo, llave, respuesta, generacion)
+		responderDesdeCache(c, respuesta, cacheControl)
+	}
+}
+
+// InvalidarCache invalida los grupos cuando la petición termina con éxito; para rutas cuyos
+// cambios se ven en catálogos de otro grupo (por ejemplo, los planteles dentro de los niveles escolares)
+func InvalidarCache(grupos ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.Request.Method != http.MethodGet && len(c.Errors) == 0 && c.Writer.Status() < http.StatusBadRequest {
+			cache.Invalidar(grupos...)
+		}
+	}
+}
+
+func responderDesdeCache(c *gin.Context, r respuestaEnCache, cacheControl string) {
+	c.Header("Cache-Control", cacheControl)
+	c.Header("ETag", r.etag)
+	c.Header("Last-Modified", r.modificado.UTC().Format(http.TimeFormat))
+	if noModificado(c, r) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+	} else {
+		c.Data(http.StatusOK, r.contentType, r.cuerpo)
+	}
+	c.Abort()
+}
+
+// noModificado aplica If-None-Match y, solo si no viene, If-Modified-Since (RFC 9110)
+func noModificado(c *gin.Context, r respuestaEnCache) bool {
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		for _, etiqueta := range strings.Split(ifNoneMatch, ",") {
+			etiqueta = strings.TrimSpace(etiqueta)
+			if etiqueta == "*" || strings.TrimPrefix(etiqueta, "W/") == strings.TrimPrefix(r.etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if desde, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil {
+		return !r.modificado.Truncate(time.Second).After(desde)
+	}
+	return false
+}
+
+// escritorEnBuffer retiene la respuesta del handler para calcular el ETag antes de enviar los headers
+type escritorEnBuffer struct {
+	gin.ResponseWriter
+	estado  int
+	escrito bool
+	cuerpo  bytes.Buffer
+}
+
+func (w *escritorEnBuffer) WriteHeader(code int) {
+	if code > 0 {
+		w.estado = code
+	}
+}
+
+func (w *escritorEnBuffer) WriteHeaderNow() {
+	w.escrito = true
+}
+
+func (w *escritorEnBuffer) Write(b []byte) (int, error) {
+	w.escrito = true
+	return w.cuerpo.Write(b)
+}
+
+func (w *escritorEnBuffer) WriteString(s string) (int, error) {
+	w.escrito = true
+	return w.cuerpo.WriteString(s)
+}
+
+func (w *escritorEnBuffer) Status() int {
+	return w.estado
+}
+
+func (w *escritorEnBuffer) Size() int {
+	return w.cuerpo.Len()
+}
+
+func (w *escritorEnBuffer) Written() bool {
+	return w.escrito
+}
@@ -0,0 +1,201 @@
+// middleware/idempotencia.go
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"api-margaritai/config"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/models"
+)
+
+const (
+	// HeaderIdempotencyKey es el header con el que el cliente identifica una petición que puede reintentar
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed marca las respuestas que se repitieron desde una petición anterior
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	largoMaximoClave = 255
+	// procesoAbandonado es el tiempo tras el cual una clave que sigue "en proceso" se considera de un servidor caído
+	procesoAbandonado = 2 * time.Minute
+)
+
+// Idempotencia hace seguros los reintentos de POST que llegan con Idempotency-Key. La primera petición se
+// procesa y su respuesta se guarda durante IDEMPOTENCIA_VENTANA (24h por defecto); los reintentos con la misma
+// clave y el mismo cuerpo reciben esa respuesta sin volver a ejecutar el handler. Un cuerpo distinto con la
+// misma clave se rechaza con 422 y un reintento mientras la primera sigue en proceso recibe 409.
+// Las claves son por organización y usuario, así que en las rutas protegidas debe ir después de JWTAuth.
+// No se usa en login ni registro: sus respuestas llevan un token y quedarían guardadas en claro.
+// El cuerpo se lee completo para calcular su hash, hasta IDEMPOTENCIA_CUERPO_MAXIMO_MB (16 MB por defecto,
+// más que los archivos que aceptan documentos e importaciones); uno mayor se rechaza con 413.
+func Idempotencia() gin.HandlerFunc {
+	ventana := config.GetEnvDuration("IDEMPOTENCIA_VENTANA", 24*time.Hour)
+	cuerpoMaximo := int64(config.GetEnvInt("IDEMPOTENCIA_CUERPO_MAXIMO_MB", 16)) << 20
+
+	return func(c *gin.Context) {
+		clave := c.GetHeader(HeaderIdempotencyKey)
+		if c.Request.Method != http.MethodPost || clave == "" {
+			c.Next()
+			return
+		}
+		if len(clave) > largoMaximoClave {
+			errores.Responder(c, errores.SolicitudInvalida(errores.ClaveIdempotenciaInvalida, "Idempotency-Key no puede tener más de 255 caracteres"))
+			return
+		}
+
+		cuerpo, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, cuerpoMaximo))
+		if err != nil {
+			var demasiado *http.MaxBytesError
+			if errors.As(err, &demasiado) {
+				errores.Responder(c, errores.Nuevo(http.StatusRequestEntityTooLarge, errores.CuerpoDemasiadoGrande,
+					fmt.Sprintf("El cuerpo de la petición excede el máximo de %d MB", cuerpoMaximo>>20)))
+				return
+			}
+			errores.Responder(c, errores.SolicitudInvalida(errores.DatosInvalidos, "No se pudo leer el cuerpo de la petición"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(cuerpo))
+		hash := sha256.Sum256(cuerpo)
+
+		registro := models.ClaveIdempotencia{
+			UserID:     c.GetUint("user_id"),
+			Metodo:     c.Request.Method,
+			Ruta:       c.Request.URL.Path,
+			Clave:      clave,
+			HashCuerpo: hex.EncodeToString(hash[:]),
+			ExpiraEn:   time.Now().Add(ventana),
+		}
+
+		existente, err := reservarClave(c, &registro)
+		if err != nil {
+			errores.Responder(c, errores.Interno("Error registrando la clave de idempotencia", err))
+			return
+		}
+		if existente != nil {
+			repetir(c, existente, registro.HashCuerpo)
+			return
+		}
+
+		// Guardar la respuesta y liberar la clave no debe depender de que el cliente siga conectado: si se corta
+		// después de que el handler confirmó, la respuesta tiene que quedar para el reintento. El contexto
+		// conserva la organización de la petición pero no su cancelación.
+		persistente := context.WithoutCancel(c.Request.Context())
+		escritor := &escritorCapturado{ResponseWriter: c.Writer}
+		c.Writer = escritor
+		completado := false
+		defer func() {
+			// Si el handler entró en pánico o falló del lado del servidor se libera la clave para permitir el reintento
+			if !completado {
+				if err := database.De(persistente).Delete(&models.ClaveIdempotencia{}, registro.ID).Error; err != nil {
+					log.Printf("[%s] no se pudo liberar la clave de idempotencia: %v", c.GetString(errores.ClaveRequestID), err)
+				}
+			}
+		}()
+
+		c.Next()
+		errores.EscribirPendiente(c)
+
+		if escritor.Status() >= http.StatusInternalServerError {
+			return
+		}
+		err = database.De(persistente).Model(&registro).Updates(map[string]any{
+			"estado":       escritor.Status(),
+			"content_type": escritor.Header().Get("Content-Type"),
+			"respuesta":    escritor.cuerpo.Bytes(),
+		}).Error
+		if err != nil {
+			log.Printf("[%s] no se pudo guardar la respuesta de idempotencia: %v", c.GetString(errores.ClaveRequestID), err)
+			return
+		}
+		completado = true
+	}
+}
+
+// reservarClave inserta la clave como "en proceso". Si ya existe y sigue vigente regresa la guardada;
+// si venció o la dejó abandonada un servidor caído la reemplaza.
+func reservarClave(ctx context.Context, registro *models.ClaveIdempotencia) (*models.ClaveIdempotencia, error) {
+	db := database.De(ctx)
+	for intento := 0; intento < 2; intento++ {
+		resultado := db.Clauses(clause.OnConflict{DoNothing: true}).Create(registro)
+		if resultado.Error != nil {
+			return nil, resultado.Error
+		}
+		if resultado.RowsAffected == 1 {
+			return nil, nil
+		}
+
+		var existente models.ClaveIdempotencia
+		err := db.Where("user_id = ? AND metodo = ? AND ruta = ? AND clave = ?",
+			registro.UserID, registro.Metodo, registro.Ruta, registro.Clave).First(&existente).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue // se liberó entre el INSERT y la consulta
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ahora := time.Now()
+		vencida := existente.ExpiraEn.Before(ahora)
+		abandonada := existente.Estado == 0 && existente.CreatedAt.Before(ahora.Add(-procesoAbandonado))
+		if !vencida && !abandonada {
+			return &existente, nil
+		}
+		if err := db.Delete(&existente).Error; err != nil {
+			return nil, err
+		}
+		registro.ID = 0
+	}
+	return nil, fmt.Errorf("la clave %q se ocupó y liberó repetidamente", registro.Clave)
+}
+
+// repetir atiende un reintento: repite la respuesta guardada o explica por qué no puede hacerlo
+func repetir(c *gin.Context, guardada *models.ClaveIdempotencia, hashCuerpo string) {
+	switch {
+	case guardada.HashCuerpo != hashCuerpo:
+		errores.Responder(c, errores.Nuevo(http.StatusUnprocessableEntity, errores.ClaveIdempotenciaReutilizada,
+			"Esta Idempotency-Key ya se usó con un cuerpo distinto; genere una clave nueva para cada operación"))
+	case guardada.Estado == 0:
+		errores.Responder(c, errores.Conflicto(errores.ClaveIdempotenciaEnProceso, "La petición con esta Idempotency-Key todavía se está procesando"))
+	default:
+		c.Header(HeaderIdempotentReplayed, "true")
+		c.Data(guardada.Estado, guardada.ContentType, guardada.Respuesta)
+		c.Abort()
+	}
+}
+
+// escritorCapturado copia lo que el handler escribe para poder guardarlo
+type escritorCapturado struct {
+	gin.ResponseWriter
+	cuerpo bytes.Buffer
+}
+
+func (w *escritorCapturado) Write(b []byte) (int, error) {
+	w.cuerpo.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *escritorCapturado) WriteString(s string) (int, error) {
+	w.cuerpo.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// LimpiarClavesVencidas borra las claves cuya ventana ya pasó y regresa cuántas borró.
+// La ejecuta periódicamente la tarea limpiar_claves_idempotencia.
+func LimpiarClavesVencidas(ctx context.Context) (int64, error) {
+	resultado := database.DB.WithContext(ctx).Where("expira_en < ?", time.Now()).Delete(&models.ClaveIdempotencia{})
+	return resultado.RowsAffected, resultado.Error
+}
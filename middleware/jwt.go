@@ -2,7 +2,6 @@
 package middleware
 
 import (
-	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -12,11 +11,14 @@ import (
 
 	"api-margaritai/config"
 	"api-margaritai/database"
+	"api-margaritai/errores"
 	"api-margaritai/models"
+	"api-margaritai/organizacion"
 )
 
 type Claims struct {
-	UserID uint `json:"user_id"`
+	UserID         uint `json:"user_id"`
+	OrganizacionID uint `json:"org"` // 0 en tokens emitidos antes de las organizaciones
 	jwt.RegisteredClaims
 }
 
@@ -26,29 +28,27 @@ var (
 	blacklistMutex = &sync.RWMutex{}
 )
 
-// CleanupBlacklist limpia tokens expirados periódicamente
-func init() {
-	go cleanupBlacklist()
-}
-
-func cleanupBlacklist() {
-	for {
-		time.Sleep(1 * time.Hour)
-		blacklistMutex.Lock()
-		now := time.Now()
-		for token, expiry := range tokenBlacklist {
-			if now.After(expiry) {
-				delete(tokenBlacklist, token)
-			}
+// LimpiarListaNegra quita de la blacklist los tokens que ya expiraron y regresa cuántos quitó.
+// La ejecuta periódicamente la tarea limpiar_tokens_invalidados.
+func LimpiarListaNegra() int {
+	blacklistMutex.Lock()
+	defer blacklistMutex.Unlock()
+	now := time.Now()
+	eliminados := 0
+	for token, expiry := range tokenBlacklist {
+		if now.After(expiry) {
+			delete(tokenBlacklist, token)
+			eliminados++
 		}
-		blacklistMutex.Unlock()
 	}
+	return eliminados
 }
 
-func GenerateToken(userID uint) (string, error) {
+func GenerateToken(userID, organizacionID uint) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
-		UserID: userID,
+		UserID:         userID,
+		OrganizacionID: organizacionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
@@ -106,8 +106,7 @@ func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Se requiere el header de autorización"})
-			c.Abort()
+			errores.Responder(c, errores.NoAutorizado(errores.TokenRequerido, "Se requiere el header de autorización"))
 			return
 		}
 
@@ -115,22 +114,19 @@ func JWTAuth() gin.HandlerFunc {
 
 		// Verificar si el token está invalidado
 		if IsTokenInvalidated(tokenString) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token ha sido invalidado"})
-			c.Abort()
+			errores.Responder(c, errores.NoAutorizado(errores.TokenInvalidado, "Token ha sido invalidado"))
 			return
 		}
 
 		// Validar el token con modelo Session
 		var session models.Session
 		if err := database.DB.Where("token = ?", tokenString).First(&session).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido o no encontrado"})
-			c.Abort()
+			errores.Responder(c, errores.NoAutorizado(errores.TokenInvalido, "Token inválido o no encontrado"))
 			return
 		}
 
 		if session.ExpiresAt.Before(time.Now()) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expirado"})
-			c.Abort()
+			errores.Responder(c, errores.NoAutorizado(errores.TokenExpirado, "Token expirado"))
 			return
 		}
 
@@ -141,10 +137,31 @@ func JWTAuth() gin.HandlerFunc {
 		})
 
 		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
-			c.Abort()
+			errores.Responder(c, errores.NoAutorizado(errores.TokenInvalido, "Token inválido"))
+			return
+		}
+
+		// La organización del usuario manda sobre la que se resolvió por omisión; si el cliente pidió
+		// otra de forma explícita (header o subdominio) se rechaza en lugar de mezclar datos
+		orgID := claims.OrganizacionID
+		if orgID == 0 {
+			var ids []uint
+			if err := database.De(organizacion.Global(c)).Model(&models.User{}).
+				Where("id = ?", claims.UserID).Pluck("organizacion_id", &ids).Error; err != nil || len(ids) == 0 {
+				errores.Responder(c, errores.NoAutorizado(errores.TokenInvalido, "Token inválido"))
+				return
+			}
+			orgID = ids[0]
+		}
+		if c.GetBool(claveOrganizacionExplicita) && orgID != c.GetUint(ClaveOrganizacion) {
+			errores.Responder(c, errores.Prohibido(errores.OrganizacionNoCoincide, "El token pertenece a otra organización"))
+			return
+		}
+		if _, errOrg := organizacionPorID(orgID); errOrg != nil {
+			errores.Responder(c, errOrg)
 			return
 		}
+		EstablecerOrganizacion(c, orgID)
 
 		c.Set("user_id", claims.UserID)
 		c.Next()
@@ -0,0 +1,218 @@
+// middleware/limite.go
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/config"
+	"api-margaritai/errores"
+)
+
+// Headers de límite de peticiones (borrador IETF de RateLimit headers)
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+// ReglaLimite es una cubeta de tokens: admite ráfagas de hasta Capacidad peticiones
+// y recupera Capacidad tokens cada Periodo
+type ReglaLimite struct {
+	Nombre    string // prefijo de la llave en el almacén, distingue las cubetas de cada grupo de rutas
+	Capacidad int
+	Periodo   time.Duration
+}
+
+// Activa indica si la regla limita algo; una capacidad de 0 la desactiva
+func (r ReglaLimite) Activa() bool {
+	return r.Capacidad > 0 && r.Periodo > 0
+}
+
+// ResultadoLimite es el estado de la cubeta después de intentar tomar un token
+type ResultadoLimite struct {
+	Permitido    bool
+	Restantes    int
+	Reinicio     time.Duration // tiempo para que la cubeta vuelva a estar llena
+	ReintentarEn time.Duration // tiempo para el siguiente token cuando no se permitió
+}
+
+// AlmacenLimites guarda las cubetas. El almacén en memoria sirve para una sola instancia;
+// con varias réplicas se implementa sobre un almacén compartido y se instala con UsarAlmacenLimites.
+type AlmacenLimites interface {
+	Tomar(llave string, regla ReglaLimite, ahora time.Time) (ResultadoLimite, error)
+}
+
+var (
+	almacenLimites      AlmacenLimites = NuevoAlmacenMemoria()
+	almacenLimitesMutex sync.RWMutex
+)
+
+// UsarAlmacenLimites reemplaza el almacén de cubetas; se llama al arrancar, antes de atender peticiones
+func UsarAlmacenLimites(a AlmacenLimites) {
+	almacenLimitesMutex.Lock()
+	defer almacenLimitesMutex.Unlock()
+	almacenLimites = a
+}
+
+func almacenActual() AlmacenLimites {
+	almacenLimitesMutex.RLock()
+	defer almacenLimitesMutex.RUnlock()
+	return almacenLimites
+}
+
+// ReglaDesdeEnv lee una regla con el formato "peticiones/periodo" (por ejemplo "10/1m"); "0" u "off" la desactiva.
+// Si la variable está vacía o es inválida usa porDefecto.
+func ReglaDesdeEnv(nombre, clave, porDefecto string) ReglaLimite {
+	valor := config.GetEnv(clave, porDefecto)
+	regla, err := parsearRegla(nombre, valor)
+	if err != nil {
+		log.Printf("Valor inválido para %s (%q): %v; usando %s", clave, valor, err, porDefecto)
+		regla, _ = parsearRegla(nombre, porDefecto)
+	}
+	return regla
+}
+
+func parsearRegla(nombre, valor string) (ReglaLimite, error) {
+	valor = strings.TrimSpace(strings.ToLower(valor))
+	if valor == "0" || valor == "off" {
+		return ReglaLimite{Nombre: nombre}, nil
+	}
+	partes := strings.SplitN(valor, "/", 2)
+	if len(partes) != 2 {
+		return ReglaLimite{}, fmt.Errorf("se esperaba peticiones/periodo")
+	}
+	capacidad, err := strconv.Atoi(partes[0])
+	if err != nil || capacidad < 0 {
+		return ReglaLimite{}, fmt.Errorf("número de peticiones inválido")
+	}
+	periodo, err := time.ParseDuration(partes[1])
+	if err != nil || periodo <= 0 {
+		return ReglaLimite{}, fmt.Errorf("periodo inválido")
+	}
+	return ReglaLimite{Nombre: nombre, Capacidad: capacidad, Periodo: periodo}, nil
+}
+
+// PorIP agrupa las peticiones por la IP del cliente
+func PorIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// PorUsuario agrupa por usuario autenticado (debe ir después de JWTAuth); sin usuario agrupa por IP
+func PorUsuario(c *gin.Context) string {
+	if id := c.GetUint("user_id"); id != 0 {
+		return "usuario:" + strconv.FormatUint(uint64(id), 10)
+	}
+	return PorIP(c)
+}
+
+// Limitar aplica la regla a cada llave (IP, usuario...). Agrega los headers RateLimit-* a todas las
+// respuestas y, al agotarse la cubeta, responde 429 con Retry-After. Si el almacén falla deja pasar la petición.
+func Limitar(regla ReglaLimite, llave func(*gin.Context) string) gin.HandlerFunc {
+	if !regla.Activa() {
+		return func(c *gin.Context) { c.Next() }
+	}
+	politica := fmt.Sprintf("%d;w=%d", regla.Capacidad, int(math.Ceil(regla.Periodo.Seconds())))
+
+	return func(c *gin.Context) {
+		resultado, err := almacenActual().Tomar(regla.Nombre+":"+llave(c), regla, time.Now())
+		if err != nil {
+			log.Printf("[%s] error consultando el límite %s: %v", c.GetString(errores.ClaveRequestID), regla.Nombre, err)
+			c.Next()
+			return
+		}
+
+		c.Header(HeaderRateLimitLimit, strconv.Itoa(regla.Capacidad))
+		c.Header(HeaderRateLimitRemaining, strconv.Itoa(resultado.Restantes))
+		c.Header(HeaderRateLimitReset, segundos(resultado.Reinicio))
+		c.Header(HeaderRateLimitPolicy, politica)
+
+		if !resultado.Permitido {
+			c.Header(HeaderRetryAfter, segundos(resultado.ReintentarEn))
+			errores.Responder(c, errores.Nuevo(http.StatusTooManyRequests, errores.LimiteExcedido,
+				"Demasiadas peticiones; espere "+segundos(resultado.ReintentarEn)+" segundos antes de reintentar"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// segundos redondea hacia arriba para que el cliente no reintente antes de tiempo
+func segundos(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// cubeta guarda los tokens disponibles y cuándo se calcularon por última vez
+type cubeta struct {
+	tokens float64
+	ultima time.Time
+	llena  time.Time // momento en que se recarga por completo; después se puede descartar
+}
+
+// AlmacenMemoria guarda las cubetas en el proceso; cada réplica lleva su propia cuenta
+type AlmacenMemoria struct {
+	mu       sync.Mutex
+	cubetas  map[string]*cubeta
+	limpieza time.Time
+}
+
+// NuevoAlmacenMemoria crea un almacén vacío
+func NuevoAlmacenMemoria() *AlmacenMemoria {
+	return &AlmacenMemoria{cubetas: make(map[string]*cubeta)}
+}
+
+// Tomar recarga la cubeta según el tiempo transcurrido e intenta consumir un token
+func (a *AlmacenMemoria) Tomar(llave string, regla ReglaLimite, ahora time.Time) (ResultadoLimite, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.descartarLlenas(ahora)
+
+	capacidad := float64(regla.Capacidad)
+	porToken := regla.Periodo / time.Duration(regla.Capacidad)
+
+	b, ok := a.cubetas[llave]
+	if !ok {
+		b = &cubeta{tokens: capacidad, ultima: ahora}
+		a.cubetas[llave] = b
+	} else {
+		b.tokens = math.Min(capacidad, b.tokens+float64(ahora.Sub(b.ultima))/float64(porToken))
+		b.ultima = ahora
+	}
+
+	resultado := ResultadoLimite{}
+	if b.tokens >= 1 {
+		b.tokens--
+		resultado.Permitido = true
+	} else {
+		resultado.ReintentarEn = time.Duration((1 - b.tokens) * float64(porToken))
+	}
+	resultado.Restantes = int(b.tokens)
+	resultado.Reinicio = time.Duration((capacidad - b.tokens) * float64(porToken))
+	b.llena = ahora.Add(resultado.Reinicio)
+	return resultado, nil
+}
+
+// descartarLlenas quita, a lo más una vez por minuto, las cubetas que ya se recargaron por completo;
+// volver a crearlas da el mismo resultado y así la memoria no crece con cada IP que aparece
+func (a *AlmacenMemoria) descartarLlenas(ahora time.Time) {
+	if ahora.Sub(a.limpieza) < time.Minute {
+		return
+	}
+	a.limpieza = ahora
+	for llave, b := range a.cubetas {
+		if !ahora.Before(b.llena) {
+			delete(a.cubetas, llave)
+		}
+	}
+}
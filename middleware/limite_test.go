@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlmacenMemoriaTomar(t *testing.T) {
+	// 3 peticiones por minuto: un token cada 20 segundos
+	regla := ReglaLimite{Nombre: "prueba", Capacidad: 3, Periodo: time.Minute}
+	inicio := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := NuevoAlmacenMemoria()
+
+	pasos := []struct {
+		nombre       string
+		despues      time.Duration
+		permitido    bool
+		restantes    int
+		reinicio     time.Duration
+		reintentarEn time.Duration
+	}{
+		{"primera de la ráfaga", 0, true, 2, 20 * time.Second, 0},
+		{"segunda de la ráfaga", 0, true, 1, 40 * time.Second, 0},
+		{"tercera de la ráfaga", 0, true, 0, time.Minute, 0},
+		{"cubeta vacía", 0, false, 0, time.Minute, 20 * time.Second},
+		{"medio token recargado", 10 * time.Second, false, 0, 50 * time.Second, 10 * time.Second},
+		{"un token recargado", 20 * time.Second, true, 0, time.Minute, 0},
+		// La recarga no pasa de la capacidad aunque haya pasado mucho más que un periodo
+		{"recarga tope", time.Hour, true, 2, 20 * time.Second, 0},
+	}
+	for _, p := range pasos {
+		r, err := a.Tomar("ip:1", regla, inicio.Add(p.despues))
+		if err != nil {
+			t.Fatal(err)
+		}
+		esperado := ResultadoLimite{Permitido: p.permitido, Restantes: p.restantes, Reinicio: p.reinicio, ReintentarEn: p.reintentarEn}
+		if r != esperado {
+			t.Errorf("%s: %+v, se esperaba %+v", p.nombre, r, esperado)
+		}
+	}
+
+	// Cada llave tiene su propia cubeta
+	if r, _ := a.Tomar("ip:2", regla, inicio); !r.Permitido || r.Restantes != 2 {
+		t.Errorf("otra llave: %+v", r)
+	}
+}
+
+func TestAlmacenMemoriaDescartaLlenas(t *testing.T) {
+	regla := ReglaLimite{Nombre: "prueba", Capacidad: 3, Periodo: time.Minute}
+	inicio := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := NuevoAlmacenMemoria()
+
+	a.Tomar("llena a los 20s", regla, inicio)
+	for range 3 {
+		a.Tomar("llena a los 60s", regla, inicio)
+	}
+	// Menos de un minuto después de la última limpieza no se revisa nada
+	for range 3 {
+		a.Tomar("llena a los 119s", regla, inicio.Add(59*time.Second))
+	}
+	if len(a.cubetas) != 3 {
+		t.Fatalf("antes de la limpieza hay %d cubetas, se esperaban 3", len(a.cubetas))
+	}
+
+	a.Tomar("nueva", regla, inicio.Add(61*time.Second))
+	for _, llave := range []string{"llena a los 20s", "llena a los 60s"} {
+		if _, ok := a.cubetas[llave]; ok {
+			t.Errorf("la cubeta %q ya estaba llena y no se descartó", llave)
+		}
+	}
+	for _, llave := range []string{"llena a los 119s", "nueva"} {
+		if _, ok := a.cubetas[llave]; !ok {
+			t.Errorf("se descartó la cubeta %q, que no estaba llena", llave)
+		}
+	}
+
+	// Descartar una cubeta llena no cambia el resultado: al volver a crearla tiene la capacidad completa
+	if r, _ := a.Tomar("llena a los 60s", regla, inicio.Add(61*time.Second)); !r.Permitido || r.Restantes != 2 {
+		t.Errorf("cubeta recreada: %+v", r)
+	}
+}
+
+func TestParsearRegla(t *testing.T) {
+	casos := []struct {
+		valor    string
+		regla    ReglaLimite
+		invalida bool
+	}{
+		{"10/1m", ReglaLimite{Nombre: "login", Capacidad: 10, Periodo: time.Minute}, false},
+		{" 300/1H ", ReglaLimite{Nombre: "login", Capacidad: 300, Periodo: time.Hour}, false},
+		{"0", ReglaLimite{Nombre: "login"}, false},
+		{"off", ReglaLimite{Nombre: "login"}, false},
+		{"OFF", ReglaLimite{Nombre: "login"}, false},
+		{"0/1m", ReglaLimite{Nombre: "login", Periodo: time.Minute}, false},
+		{"", ReglaLimite{}, true},
+		{"10", ReglaLimite{}, true},
+		{"diez/1m", ReglaLimite{}, true},
+		{"-1/1m", ReglaLimite{}, true},
+		{"10/minuto", ReglaLimite{}, true},
+		{"10/0s", ReglaLimite{}, true},
+		{"10/-1m", ReglaLimite{}, true},
+	}
+	for _, c := range casos {
+		regla, err := parsearRegla("login", c.valor)
+		if (err != nil) != c.invalida || regla != c.regla {
+			t.Errorf("parsearRegla(%q) = %+v, %v; se esperaba %+v (inválida: %v)", c.valor, regla, err, c.regla, c.invalida)
+		}
+		if c.regla.Capacidad == 0 && regla.Activa() {
+			t.Errorf("parsearRegla(%q) debería quedar desactivada", c.valor)
+		}
+	}
+}
+
+func TestReglaDesdeEnv(t *testing.T) {
+	t.Setenv("LIMITE_PRUEBA", "no-es-regla")
+	if r := ReglaDesdeEnv("prueba", "LIMITE_PRUEBA", "5/1m"); r.Capacidad != 5 || r.Periodo != time.Minute {
+		t.Errorf("con un valor inválido debería usar el de por omisión: %+v", r)
+	}
+	t.Setenv("LIMITE_PRUEBA", "off")
+	if r := ReglaDesdeEnv("prueba", "LIMITE_PRUEBA", "5/1m"); r.Activa() {
+		t.Errorf("off debería desactivar la regla: %+v", r)
+	}
+}
+
+func TestSegundosRedondeaHaciaArriba(t *testing.T) {
+	for d, esperado := range map[time.Duration]string{
+		0:                        "0",
+		time.Millisecond:         "1",
+		20 * time.Second:         "20",
+		20*time.Second + 1:       "21",
+		59900 * time.Millisecond: "60",
+	} {
+		if got := segundos(d); got != esperado {
+			t.Errorf("segundos(%v) = %s, se esperaba %s", d, got, esperado)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// middleware/organizacion.go
+package middleware
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"api-margaritai/cache"
+	"api-margaritai/config"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+	"api-margaritai/models"
+	"api-margaritai/organizacion"
+)
+
+const (
+	// HeaderOrganizacion es el header con el slug de la organización a la que va la petición
+	HeaderOrganizacion = "X-Organizacion"
+	// ClaveOrganizacion es la llave del contexto de gin con el ID de la organización de la petición
+	ClaveOrganizacion = "organizacion_id"
+
+	// claveOrganizacionExplicita marca que la organización vino en el header o el subdominio y no por omisión
+	claveOrganizacionExplicita = "organizacion_explicita"
+)
+
+// Organizacion resuelve a qué organización va la petición: el header X-Organizacion, luego el subdominio
+// de DOMINIO_BASE (escuela.ejemplo.com) y, si no trae ninguno, ORGANIZACION_PRINCIPAL. En las rutas
+// protegidas JWTAuth la reemplaza por la del token. Las consultas hechas con database.De(c) quedan
+// limitadas a esa organización.
+func Organizacion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slug, explicita := slugDePeticion(c)
+		org, err := organizacionPorSlug(slug)
+		if err != nil {
+			errores.Responder(c, err)
+			return
+		}
+		c.Set(claveOrganizacionExplicita, explicita)
+		EstablecerOrganizacion(c, org.ID)
+		c.Next()
+	}
+}
+
+// EstablecerOrganizacion limita a la organización las consultas que se hagan con el contexto de la petición
+func EstablecerOrganizacion(c *gin.Context, id uint) {
+	c.Set(ClaveOrganizacion, id)
+	c.Request = c.Request.WithContext(organizacion.Con(c.Request.Context(), id))
+}
+
+// OrganizacionDeEnlace cambia a la organización de un enlace firmado (como el del calendario de horarios),
+// que se abre sin header ni token. Si el cliente sí indicó una organización, debe ser la misma del enlace.
+func OrganizacionDeEnlace(c *gin.Context, id uint) *errores.Error {
+	if c.GetBool(claveOrganizacionExplicita) && id != c.GetUint(ClaveOrganizacion) {
+		return errores.Prohibido(errores.OrganizacionNoCoincide, "El enlace pertenece a otra organización")
+	}
+	if _, err := organizacionPorID(id); err != nil {
+		return err
+	}
+	EstablecerOrganizacion(c, id)
+	return nil
+}
+
+// RequiereOrganizacionPrincipal deja pasar solo peticiones de la organización principal. Se usa en lo que
+// es común a todas (el catálogo de permisos, las tareas programadas); debe ir después de JWTAuth.
+func RequiereOrganizacionPrincipal() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := SoloOrganizacionPrincipal(c); err != nil {
+			errores.Responder(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// SoloOrganizacionPrincipal regresa un 403 si la petición no es de la organización principal
+func SoloOrganizacionPrincipal(c *gin.Context) *errores.Error {
+	principal, err := organizacionPorSlug(slugPrincipal())
+	if err != nil {
+		return err
+	}
+	if c.GetUint(ClaveOrganizacion) != principal.ID {
+		return errores.Prohibido(errores.SoloOrganizacionPrincipal, "Esta operación solo está disponible para la organización principal")
+	}
+	return nil
+}
+
+// slugDePeticion regresa el slug pedido y si el cliente lo indicó (header o subdominio)
+func slugDePeticion(c *gin.Context) (string, bool) {
+	if slug := strings.ToLower(strings.TrimSpace(c.GetHeader(HeaderOrganizacion))); slug != "" {
+		return slug, true
+	}
+	if base := strings.ToLower(config.GetEnv("DOMINIO_BASE", "")); base != "" {
+		host := strings.ToLower(c.Request.Host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if sub, ok := strings.CutSuffix(host, "."+base); ok && sub != "" && !strings.Contains(sub, ".") {
+			return sub, true
+		}
+	}
+	return slugPrincipal(), false
+}
+
+func slugPrincipal() string {
+	return config.GetEnv("ORGANIZACION_PRINCIPAL", "principal")
+}
+
+// organizacionPorSlug busca la organización activa con ese slug; se guarda en la caché de organizaciones
+func organizacionPorSlug(slug string) (models.Organizacion, *errores.Error) {
+	return buscarOrganizacion("slug:"+slug, "slug = ?", slug)
+}
+
+// organizacionPorID es como organizacionPorSlug para la organización que viene en el token
+func organizacionPorID(id uint) (models.Organizacion, *errores.Error) {
+	return buscarOrganizacion("id:"+strconv.FormatUint(uint64(id), 10), "id = ?", id)
+}
+
+func buscarOrganizacion(llave, condicion string, valor any) (models.Organizacion, *errores.Error) {
+	var org models.Organizacion
+	if guardada, _, ok := cache.Obtener(cache.GrupoOrganizaciones, llave); ok {
+		org = guardada.(models.Organizacion)
+	} else {
+		generacion := cache.Generacion(cache.GrupoOrganizaciones)
+		if err := database.DB.Where(condicion, valor).First(&org).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return org, errores.NoEncontrado(errores.OrganizacionNoEncontrada, "Organización no encontrada")
+			}
+			return org, errores.Interno("Error buscando la organización", err)
+		}
+		cache.Guardar(cache.GrupoOrganizaciones, llave, org, generacion)
+	}
+	if !org.Activa {
+		return org, errores.Prohibido(errores.OrganizacionInactiva, "La organización está desactivada")
+	}
+	return org, nil
+}
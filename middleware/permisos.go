@@ -0,0 +1,91 @@
+// middleware/permisos.go
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/cache"
+	"api-margaritai/database"
+	"api-margaritai/errores"
+)
+
+// TienePermiso indica si el rol del usuario tiene asignado el permiso con el título indicado
+func TienePermiso(userID uint, titulo string) (bool, error) {
+	permisos, err := permisosDeUsuario(userID)
+	if err != nil {
+		return false, err
+	}
+	return permisos[titulo], nil
+}
+
+// PermisosDelUsuario regresa cuáles de los títulos indicados tiene asignados el rol del usuario
+func PermisosDelUsuario(userID uint, titulos ...string) (map[string]bool, error) {
+	asignados, err := permisosDeUsuario(userID)
+	if err != nil {
+		return nil, err
+	}
+	permisos := make(map[string]bool, len(titulos))
+	for _, titulo := range titulos {
+		if asignados[titulo] {
+			permisos[titulo] = true
+		}
+	}
+	return permisos, nil
+}
+
+// permisosDeUsuario busca el rol vigente del usuario; el conjunto de permisos del rol sale de la caché.
+// Las consultas con Table() no pasan por el filtro de organización, pero los IDs de usuario y de rol
+// son únicos entre todas, así que el resultado es el mismo.
+func permisosDeUsuario(userID uint) (map[string]bool, error) {
+	var roles []uint
+	err := database.DB.Table("users").
+		Where("id = ? AND deleted_at IS NULL", userID).
+		Pluck("rol_id", &roles).Error
+	if err != nil || len(roles) == 0 {
+		return nil, err
+	}
+	return permisosDeRol(roles[0])
+}
+
+// permisosDeRol regresa los títulos asignados al rol. Se guarda en el grupo de permisos, que se invalida
+// con cualquier cambio en roles, permisos o asignaciones.
+func permisosDeRol(rolID uint) (map[string]bool, error) {
+	llave := "rol:" + strconv.FormatUint(uint64(rolID), 10)
+	if valor, _, ok := cache.Obtener(cache.GrupoPermisos, llave); ok {
+		return valor.(map[string]bool), nil
+	}
+
+	generacion := cache.Generacion(cache.GrupoPermisos)
+	var titulos []string
+	err := database.DB.Table("role_tiene_permisos").
+		Joins("JOIN permisos ON permisos.id = role_tiene_permisos.permiso_id AND permisos.deleted_at IS NULL").
+		Where("role_tiene_permisos.role_id = ?", rolID).
+		Pluck("permisos.titulo", &titulos).Error
+	if err != nil {
+		return nil, err
+	}
+	permisos := make(map[string]bool, len(titulos))
+	for _, titulo := range titulos {
+		permisos[titulo] = true
+	}
+	cache.Guardar(cache.GrupoPermisos, llave, permisos, generacion)
+	return permisos, nil
+}
+
+// RequierePermiso deja pasar solo a usuarios cuyo rol tenga el permiso; debe ir después de JWTAuth
+func RequierePermiso(titulo string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permitido, err := TienePermiso(c.GetUint("user_id"), titulo)
+		if err != nil {
+			errores.Responder(c, errores.Interno("Error verificando permisos", err))
+			return
+		}
+		if !permitido {
+			errores.Responder(c, errores.Prohibido(errores.PermisoDenegado, "No tienes el permiso \""+titulo+"\""))
+			return
+		}
+		c.Next()
+	}
+}
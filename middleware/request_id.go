@@ -0,0 +1,37 @@
+// middleware/request_id.go
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/errores"
+)
+
+// HeaderRequestID es el header con el que se recibe y se regresa el identificador de la petición
+const HeaderRequestID = "X-Request-ID"
+
+// RequestID asigna un identificador a cada petición (o respeta el que manda el cliente)
+// para poder relacionar la respuesta de error con el log del servidor
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(HeaderRequestID)
+		if id == "" || len(id) > 64 {
+			id = nuevoRequestID()
+		}
+
+		c.Set(errores.ClaveRequestID, id)
+		c.Header(HeaderRequestID, id)
+		c.Next()
+	}
+}
+
+func nuevoRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -1,28 +1,42 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
+	"strings"
+
+	"gorm.io/gorm"
 
 	"api-margaritai/config"
 	"api-margaritai/database"
 	"api-margaritai/models"
+	"api-margaritai/organizacion"
 	"api-margaritai/seeders"
 )
 
 func main() {
 	// Definir flag para migrate fresh
 	fresh := flag.Bool("fresh", false, "Eliminar todas las tablas y recrear desde cero")
+	slugNueva := flag.String("organizacion", "", "Slug de una organización nueva a crear y sembrar")
+	nombreNueva := flag.String("nombre", "", "Nombre de la organización nueva (por omisión, el slug)")
 	flag.Parse()
 
 	config.LoadEnv()
 	database.ConnectDB()
 
+	// La migración y los seeders trabajan sobre todas las organizaciones a la vez
+	database.DB = database.De(organizacion.Global(context.Background()))
+
 	if *fresh {
 		log.Println("Ejecutando migrate fresh - eliminando todas las tablas...")
 
 		// Eliminar todas las tablas en orden inverso (respetando dependencias)
 		err := database.DB.Migrator().DropTable(
+			&models.Horario{},
+			&models.Inscripcion{},
+			&models.EstudianteTutor{},
 			&models.Tutor{},
 			&models.Estudiante{},
 			&models.Condicion{},
@@ -33,9 +47,12 @@ func main() {
 			&models.CategoriaPermiso{},
 			&models.Rol{},
 			&models.Grupo{},
+			&models.PeriodoEvaluacion{},
+			&models.CicloEscolar{},
 			&models.Aula{},
 			&models.NivelEscolar{},
 			&models.Plantel{},
+			&models.Organizacion{},
 			&models.Direccion{},
 			&models.User{},
 			&models.Session{},
@@ -47,6 +64,14 @@ func main() {
 			&models.TipoContrato{},
 			&models.Grado{},
 			&models.Materia{},
+			&models.ClaveIdempotencia{},
+			&models.Importacion{},
+			&models.EntregaWebhook{},
+			&models.SuscripcionWebhook{},
+			&models.EventoDominio{},
+			&models.EjecucionTarea{},
+			&models.TareaProgramada{},
+			&models.Documento{},
 		)
 		if err != nil {
 			log.Fatal("Error eliminando tablas: ", err)
@@ -68,54 +93,26 @@ func main() {
 	if !usersTableExists || !generosTableExists || !rolesTableExists || !gruposTableExists || *fresh {
 		// Si las tablas no existen o estamos haciendo fresh, usar AutoMigrate
 		log.Println("Creando tablas con AutoMigrate...")
-		err := database.DB.AutoMigrate(
-			// Tablas base (sin dependencias)
-			&models.Genero{},
-			&models.EstatusEmpleado{},
-			&models.EstatusLaboral{},
-			&models.Puesto{},
-			&models.GradoAcademico{},
-			&models.TipoContrato{},
-			&models.CategoriaPermiso{},
-			&models.Permiso{},
-			&models.Rol{},
-			&models.Aula{},
-			&models.Grado{},
-			&models.Materia{},
-			// Tablas con dependencias
-			&models.User{},
-			&models.Session{},
-			&models.Direccion{},
-			&models.Plantel{},
-			&models.NivelEscolar{},
-			&models.Grupo{}, // <-- Asegurar que Grupo está incluido
-			&models.Personal{},
-			&models.Contrato{},
-			&models.Condicion{},
-			&models.Estudiante{},
-			&models.Tutor{},
-			&models.RoleTienePermiso{},
-		)
+		err := database.DB.AutoMigrate(modelos()...)
 		if err != nil {
 			log.Fatal("Error migrating database: ", err)
 		}
 		log.Println("Database migrated successfully")
 
+		prepararBusqueda()
+
 		// Insertar datos iniciales
 		log.Println("Insertando datos iniciales...")
-		seeders.InsertarGenerosIniciales()
-		seeders.InsertarEstatusEmpleadosIniciales()
-		seeders.InsertarEstatusLaboralesIniciales()
-		seeders.InsertarGradosAcademicosIniciales()
-		seeders.InsertarTiposContratosIniciales()
-		seeders.InsertarPuestosIniciales()
-		seeders.InsertarRolesIniciales()
-		seeders.InsertarCategoriasPermisosIniciales()
-		seeders.InsertarPermisosIniciales()
-		seeders.AsignarPermisosAdministrador()
+		crearOrganizacionPrincipal()
 	} else {
 		log.Println("Tabla users existe, ejecutando migración manual...")
 
+		// Paso 0: los datos que ya existen pasan a la organización principal
+		if err := database.DB.AutoMigrate(&models.Organizacion{}); err != nil {
+			log.Fatal("Error creando la tabla de organizaciones: ", err)
+		}
+		asignarOrganizacionPrincipal(crearOrganizacionPrincipal())
+
 		// Paso 1: Agregar columna curp como nullable si no existe
 		var columnExists bool
 		database.DB.Raw("SELECT EXISTS (SELECT FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'curp')").Scan(&columnExists)
@@ -142,32 +139,219 @@ func main() {
 			log.Printf("Eliminados %d registros sin CURP", result.RowsAffected)
 		}
 
-		// Paso 3: Agregar restricciones NOT NULL y UNIQUE a la columna curp
-		log.Println("Agregando restricciones NOT NULL y UNIQUE a la columna curp...")
+		// Paso 3: Agregar restricción NOT NULL a la columna curp; la unicidad por organización la crea AutoMigrate
+		log.Println("Agregando restricción NOT NULL a la columna curp...")
 		err := database.DB.Exec("ALTER TABLE users ALTER COLUMN curp SET NOT NULL").Error
 		if err != nil {
 			log.Fatal("Error agregando restricción NOT NULL: ", err)
 		}
+		quitarUnicosGlobales()
+		unicosSinBorrados()
+		prepararAulas()
 
-		// Agregar índice único para curp
-		err = database.DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_curp ON users(curp)").Error
-		if err != nil {
-			log.Fatal("Error creando índice único para curp: ", err)
+		// Paso 4: AutoMigrate solo agrega lo que falta, como las columnas deleted_at y version
+		log.Println("Agregando columnas e índices nuevos...")
+		if err := database.DB.AutoMigrate(modelos()...); err != nil {
+			log.Fatal("Error agregando columnas nuevas: ", err)
 		}
 
+		prepararBusqueda()
+		borrarClavesDeAutenticacion()
+
 		log.Println("Migración manual completada exitosamente")
 
 		// Verificar e insertar datos iniciales si no existen
 		log.Println("Verificando datos iniciales...")
-		seeders.InsertarGenerosIniciales()
-		seeders.InsertarEstatusEmpleadosIniciales()
-		seeders.InsertarEstatusLaboralesIniciales()
-		seeders.InsertarGradosAcademicosIniciales()
-		seeders.InsertarTiposContratosIniciales()
-		seeders.InsertarPuestosIniciales()
-		seeders.InsertarRolesIniciales()
-		seeders.InsertarCategoriasPermisosIniciales()
-		seeders.InsertarPermisosIniciales()
-		seeders.AsignarPermisosAdministrador()
+	}
+
+	if *slugNueva != "" {
+		nombre := *nombreNueva
+		if nombre == "" {
+			nombre = *slugNueva
+		}
+		org := seeders.CrearOrganizacion(*slugNueva, nombre)
+		log.Printf("Organización '%s' lista (id %d)", org.Slug, org.ID)
+	}
+	seeders.SembrarOrganizaciones()
+}
+
+// crearOrganizacionPrincipal crea la organización de ORGANIZACION_PRINCIPAL, a la que van las peticiones
+// sin X-Organizacion ni subdominio
+func crearOrganizacionPrincipal() models.Organizacion {
+	slug := config.GetEnv("ORGANIZACION_PRINCIPAL", "principal")
+	return seeders.CrearOrganizacion(slug, config.GetEnv("ORGANIZACION_PRINCIPAL_NOMBRE", slug))
+}
+
+// unicosSinBorrados borra los índices únicos de email, CURP y matrícula que todavía cubren los registros en la
+// papelera, para que AutoMigrate los vuelva a crear solo sobre los que no están borrados (WHERE deleted_at IS NULL).
+// Con los anteriores no se podía dar de alta de nuevo a alguien cuyo registro anterior estaba en la papelera.
+func unicosSinBorrados() {
+	for _, indice := range []string{"idx_users_organizacion_email", "idx_users_organizacion_curp", "idx_estudiantes_organizacion_matricula"} {
+		var definicion string
+		database.DB.Raw("SELECT indexdef FROM pg_indexes WHERE indexname = ?", indice).Scan(&definicion)
+		if definicion == "" || strings.Contains(definicion, "WHERE") {
+			continue
+		}
+		log.Printf("Rehaciendo %s sin los registros borrados...", indice)
+		if err := database.DB.Exec("DROP INDEX IF EXISTS " + indice).Error; err != nil {
+			log.Fatalf("Error quitando el índice único %s: %v", indice, err)
+		}
+	}
+}
+
+// asignarOrganizacionPrincipal agrega organizacion_id a las tablas que ya existían, con la organización
+// principal para las filas actuales, antes de que AutoMigrate intente crear la columna NOT NULL sin valor
+func asignarOrganizacionPrincipal(principal models.Organizacion) {
+	for _, modelo := range modelos() {
+		sentencia := &gorm.Statement{DB: database.DB}
+		if err := sentencia.Parse(modelo); err != nil {
+			log.Fatal("Error leyendo el modelo: ", err)
+		}
+		if sentencia.Schema.LookUpField(organizacion.Campo) == nil {
+			continue
+		}
+		migrador := database.DB.Migrator()
+		if !migrador.HasTable(modelo) || migrador.HasColumn(modelo, organizacion.Campo) {
+			continue
+		}
+		tabla := sentencia.Schema.Table
+		log.Printf("Asignando la organización principal a %s...", tabla)
+		for _, sql := range []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN organizacion_id bigint NOT NULL DEFAULT %d", tabla, principal.ID),
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN organizacion_id DROP DEFAULT", tabla),
+		} {
+			if err := database.DB.Exec(sql).Error; err != nil {
+				log.Fatalf("Error agregando organizacion_id a %s: %v", tabla, err)
+			}
+		}
+	}
+}
+
+// quitarUnicosGlobales borra los índices únicos de email, CURP, matrícula y nombre de género que eran de toda
+// la base; AutoMigrate los vuelve a crear junto con organizacion_id
+func quitarUnicosGlobales() {
+	for _, sql := range []string{
+		"ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_email",
+		"ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key",
+		"ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_curp",
+		"DROP INDEX IF EXISTS idx_users_curp",
+		"ALTER TABLE estudiantes DROP CONSTRAINT IF EXISTS uni_estudiantes_matricula",
+		"ALTER TABLE estudiantes DROP CONSTRAINT IF EXISTS estudiantes_matricula_key",
+		"ALTER TABLE generos DROP CONSTRAINT IF EXISTS uni_generos_nombre",
+		"ALTER TABLE generos DROP CONSTRAINT IF EXISTS generos_nombre_key",
+		"DROP INDEX IF EXISTS idx_clave_idempotencia",
+	} {
+		if err := database.DB.Exec(sql).Error; err != nil {
+			log.Fatalf("Error quitando el índice único %q: %v", sql, err)
+		}
+	}
+}
+
+// prepararAulas agrega plantel_id y activa a la tabla de aulas que ya existía: cada aula queda activa y en el
+// primer plantel de su organización, antes de que AutoMigrate intente crear las columnas NOT NULL sin valor
+func prepararAulas() {
+	migrador := database.DB.Migrator()
+	if !migrador.HasTable(&models.Aula{}) {
+		return
+	}
+	if !migrador.HasColumn(&models.Aula{}, "plantel_id") {
+		// deleted_at puede no existir todavía en planteles; la agrega AutoMigrate en el paso 4
+		vigentes := ""
+		if migrador.HasColumn(&models.Plantel{}, "deleted_at") {
+			vigentes = " AND p.deleted_at IS NULL"
+		}
+		var sinPlantel int64
+		database.DB.Raw(`SELECT COUNT(*) FROM aulas a WHERE NOT EXISTS (
+			SELECT 1 FROM plantels p WHERE p.organizacion_id = a.organizacion_id` + vigentes + `)`).Scan(&sinPlantel)
+		if sinPlantel > 0 {
+			log.Fatalf("Hay %d aulas de organizaciones sin planteles; cree un plantel en esas organizaciones y vuelva a migrar", sinPlantel)
+		}
+		log.Println("Asignando a cada aula el primer plantel de su organización...")
+		for _, sql := range []string{
+			"ALTER TABLE aulas ADD COLUMN plantel_id bigint",
+			`UPDATE aulas SET plantel_id = (
+				SELECT MIN(p.id) FROM plantels p WHERE p.organizacion_id = aulas.organizacion_id` + vigentes + `)`,
+			"ALTER TABLE aulas ALTER COLUMN plantel_id SET NOT NULL",
+		} {
+			if err := database.DB.Exec(sql).Error; err != nil {
+				log.Fatalf("Error asignando plantel a las aulas: %v", err)
+			}
+		}
+	}
+	if !migrador.HasColumn(&models.Aula{}, "activa") {
+		for _, sql := range []string{
+			"ALTER TABLE aulas ADD COLUMN activa boolean NOT NULL DEFAULT true",
+			"ALTER TABLE aulas ALTER COLUMN activa DROP DEFAULT",
+		} {
+			if err := database.DB.Exec(sql).Error; err != nil {
+				log.Fatalf("Error agregando activa a las aulas: %v", err)
+			}
+		}
+	}
+}
+
+// modelos lista las tablas en el orden en que AutoMigrate debe crearlas
+func modelos() []any {
+	return []any{
+		// Tablas base (sin dependencias)
+		&models.Organizacion{},
+		&models.Genero{},
+		&models.EstatusEmpleado{},
+		&models.EstatusLaboral{},
+		&models.Puesto{},
+		&models.GradoAcademico{},
+		&models.TipoContrato{},
+		&models.CategoriaPermiso{},
+		&models.Permiso{},
+		&models.Rol{},
+		&models.Grado{},
+		&models.Materia{},
+		// Tablas con dependencias
+		&models.User{},
+		&models.Session{},
+		&models.Direccion{},
+		&models.Plantel{},
+		&models.Aula{},
+		&models.NivelEscolar{},
+		&models.CicloEscolar{},
+		&models.PeriodoEvaluacion{},
+		&models.Grupo{}, // <-- Asegurar que Grupo está incluido
+		&models.Personal{},
+		&models.Contrato{},
+		&models.Condicion{},
+		&models.Estudiante{},
+		&models.Tutor{},
+		&models.EstudianteTutor{},
+		&models.Inscripcion{},
+		&models.Horario{},
+		&models.RoleTienePermiso{},
+		&models.ClaveIdempotencia{},
+		&models.Importacion{},
+		&models.EventoDominio{},
+		&models.SuscripcionWebhook{},
+		&models.EntregaWebhook{},
+		&models.TareaProgramada{},
+		&models.EjecucionTarea{},
+		&models.Documento{},
+	}
+}
+
+// borrarClavesDeAutenticacion quita las respuestas de login y registro que se guardaron cuando esas rutas
+// tenían idempotencia: traen tokens vigentes en claro
+func borrarClavesDeAutenticacion() {
+	resultado := database.DB.Where("ruta IN ?", []string{"/api/login", "/api/register"}).Delete(&models.ClaveIdempotencia{})
+	if resultado.Error != nil {
+		log.Fatal("Error borrando las claves de idempotencia de autenticación: ", resultado.Error)
+	}
+	if resultado.RowsAffected > 0 {
+		log.Printf("Borradas %d claves de idempotencia de login y registro", resultado.RowsAffected)
+	}
+}
+
+// prepararBusqueda crea las extensiones e índices trigram que usa la búsqueda de personas
+func prepararBusqueda() {
+	log.Println("Creando índices de búsqueda (pg_trgm, unaccent)...")
+	if err := database.PrepararBusqueda(); err != nil {
+		log.Fatal("Error creando índices de búsqueda: ", err)
 	}
 }
@@ -6,12 +6,30 @@ import (
 	"gorm.io/gorm"
 )
 
+// Tipos de aula
+const (
+	AulaSalon       = "salon"
+	AulaLaboratorio = "laboratorio"
+	AulaCancha      = "cancha"
+)
+
+// TiposAula son los valores aceptados en Aula.Tipo
+var TiposAula = []string{AulaSalon, AulaLaboratorio, AulaCancha}
+
 type Aula struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Nombre      string    `gorm:"not null" json:"nombre"`
-	Descripcion string    `gorm:"not null" json:"descripcion"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index" json:"-"`
+	Nombre         string         `gorm:"not null" json:"nombre"`
+	Descripcion    string         `gorm:"not null" json:"descripcion"`
+	PlantelID      uint           `gorm:"not null;index" json:"plantel_id"`
+	Plantel        Plantel        `gorm:"foreignKey:PlantelID" json:"plantel"`
+	Tipo           string         `gorm:"type:varchar(20);not null;default:'salon'" json:"tipo"`
+	Capacidad      int            `gorm:"not null;default:0" json:"capacidad"` // personas que caben sentadas
+	Activa         bool           `gorm:"not null" json:"activa"`              // las inactivas (en obra, clausuradas) no se ofrecen como disponibles
+	Version        uint           `gorm:"not null;default:1" json:"version"`   // se publica como ETag; aumenta en cada edición
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (a *Aula) BeforeCreate(tx *gorm.DB) error {
@@ -11,6 +11,7 @@ type CategoriaPermiso struct {
 	Titulo      string         `gorm:"not null" json:"titulo"`
 	Descripcion string         `gorm:"type:text" json:"descripcion"`
 	Icono       string         `json:"icono"`
+	Version     uint           `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
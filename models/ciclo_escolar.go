@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Estatus de un ciclo escolar; solo avanza de planeación a activo y de activo a cerrado
+const (
+	CicloPlaneacion = "planeacion"
+	CicloActivo     = "activo"
+	CicloCerrado    = "cerrado"
+)
+
+// Periodicidad de las evaluaciones de un ciclo
+const (
+	PeriodicidadBimestral  = "bimestral"
+	PeriodicidadTrimestral = "trimestral"
+)
+
+// CicloEscolar es un año (o periodo) escolar de un nivel. Los grupos y las inscripciones de estudiantes
+// pertenecen a un ciclo, así que al empezar uno nuevo los anteriores conservan su historia.
+type CicloEscolar struct {
+	ID             uint                `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint                `gorm:"not null;index" json:"-"`
+	Nombre         string              `gorm:"type:varchar(60);not null" json:"nombre"` // ej. "2026-2027"
+	NivelEscolarID uint                `gorm:"not null;index;uniqueIndex:idx_ciclos_escolares_activo,where:estatus = 'activo' AND deleted_at IS NULL" json:"nivel_escolar_id"`
+	NivelEscolar   NivelEscolar        `gorm:"foreignKey:NivelEscolarID" json:"nivel_escolar"`
+	FechaInicio    time.Time           `gorm:"type:date;not null" json:"fecha_inicio"`
+	FechaFin       time.Time           `gorm:"type:date;not null" json:"fecha_fin"`
+	Estatus        string              `gorm:"type:varchar(20);not null;default:'planeacion';index" json:"estatus"`
+	Periodicidad   string              `gorm:"type:varchar(20);not null" json:"periodicidad"`
+	Periodos       []PeriodoEvaluacion `gorm:"foreignKey:CicloEscolarID" json:"periodos"`
+	ActivadoEn     *time.Time          `json:"activado_en"`
+	CerradoEn      *time.Time          `json:"cerrado_en"`
+	Version        uint                `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt      `gorm:"index" json:"-"`
+}
+
+func (CicloEscolar) TableName() string {
+	return "ciclos_escolares"
+}
+
+func (c *CicloEscolar) BeforeCreate(tx *gorm.DB) error {
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+func (c *CicloEscolar) BeforeUpdate(tx *gorm.DB) error {
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+// PeriodoEvaluacion es un bimestre o trimestre del ciclo; Numero empieza en 1
+type PeriodoEvaluacion struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint      `gorm:"not null;index" json:"-"`
+	CicloEscolarID uint      `gorm:"not null;uniqueIndex:idx_periodos_evaluacion_ciclo_numero,priority:1" json:"ciclo_escolar_id"`
+	Numero         int       `gorm:"not null;uniqueIndex:idx_periodos_evaluacion_ciclo_numero,priority:2" json:"numero"`
+	Nombre         string    `gorm:"type:varchar(60);not null" json:"nombre"`
+	FechaInicio    time.Time `gorm:"type:date;not null" json:"fecha_inicio"`
+	FechaFin       time.Time `gorm:"type:date;not null" json:"fecha_fin"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (PeriodoEvaluacion) TableName() string {
+	return "periodos_evaluacion"
+}
+
+// Inscripcion registra en qué grupo cursa un estudiante un ciclo. Estudiante.GrupoID es el grupo del ciclo
+// activo; las inscripciones de ciclos cerrados quedan como historial.
+type Inscripcion struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint         `gorm:"not null;index" json:"-"`
+	CicloEscolarID uint         `gorm:"not null;uniqueIndex:idx_inscripciones_ciclo_estudiante,priority:1" json:"ciclo_escolar_id"`
+	CicloEscolar   CicloEscolar `gorm:"foreignKey:CicloEscolarID" json:"ciclo_escolar"`
+	EstudianteID   uint         `gorm:"not null;index;uniqueIndex:idx_inscripciones_ciclo_estudiante,priority:2" json:"estudiante_id"`
+	Estudiante     Estudiante   `gorm:"foreignKey:EstudianteID" json:"estudiante"`
+	GrupoID        uint         `gorm:"not null;index" json:"grupo_id"`
+	Grupo          Grupo        `gorm:"foreignKey:GrupoID" json:"grupo"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+func (Inscripcion) TableName() string {
+	return "inscripciones"
+}
@@ -7,13 +7,14 @@ import (
 )
 
 type Condicion struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Titulo      string    `gorm:"not null" json:"titulo"`
-	Descripcion string    `gorm:"type:text" json:"descripcion"`
-	ContratoID  uint      `gorm:"not null" json:"contrato_id"`
-	Contrato    Contrato  `gorm:"foreignKey:ContratoID" json:"contrato"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint      `gorm:"not null;index" json:"-"`
+	Titulo         string    `gorm:"not null" json:"titulo"`
+	Descripcion    string    `gorm:"type:text" json:"descripcion"`
+	ContratoID     uint      `gorm:"not null" json:"contrato_id"`
+	Contrato       Contrato  `gorm:"foreignKey:ContratoID" json:"contrato"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 func (c *Condicion) BeforeCreate(tx *gorm.DB) error {
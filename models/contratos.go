@@ -8,6 +8,7 @@ import (
 
 type Contrato struct {
 	ID             uint         `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint         `gorm:"not null;index" json:"-"`
 	PersonalID     uint         `gorm:"not null" json:"personal_id"`
 	Personal       Personal     `gorm:"foreignKey:PersonalID" json:"personal"`
 	TipoContratoID uint         `gorm:"not null" json:"tipo_contrato_id"`
@@ -4,28 +4,33 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+
+	"api-margaritai/validadores"
 )
 
 type Direccion struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Estado    string    `gorm:"not null" json:"estado"`
-	Municipio string    `gorm:"not null" json:"municipio"`
-	CPostal   string    `gorm:"not null" json:"c_postal"`
-	Localidad string    `gorm:"not null" json:"localidad"`
-	Direccion string    `gorm:"not null" json:"direccion"`
-	UserID    uint      `gorm:"not null" json:"user_id"`
-	User      User      `gorm:"foreignKey:UserID;references:ID" json:"user"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint      `gorm:"not null;index" json:"-"`
+	Estado         string    `gorm:"not null" json:"estado"`
+	Municipio      string    `gorm:"not null" json:"municipio"`
+	CPostal        string    `gorm:"not null" json:"c_postal"` // ninguna ruta recibe direcciones todavía; la que las reciba debe validar cp_mx en su entrada
+	Localidad      string    `gorm:"not null" json:"localidad"`
+	Direccion      string    `gorm:"not null" json:"direccion"`
+	UserID         uint      `gorm:"not null" json:"user_id"`
+	User           User      `gorm:"foreignKey:UserID;references:ID" json:"user"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 func (d *Direccion) BeforeCreate(tx *gorm.DB) error {
+	d.CPostal = validadores.NormalizarCP(d.CPostal)
 	d.CreatedAt = time.Now()
 	d.UpdatedAt = time.Now()
 	return nil
 }
 
 func (d *Direccion) BeforeUpdate(tx *gorm.DB) error {
+	d.CPostal = validadores.NormalizarCP(d.CPostal)
 	d.UpdatedAt = time.Now()
 	return nil
 }
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Registros a los que se vinculan los documentos; son los valores de Documento.Entidad
+const (
+	DocumentoDeEstudiante = "estudiantes"
+	DocumentoDePersonal   = "personal"
+	DocumentoDeContrato   = "contratos"
+)
+
+// Documento es un archivo del expediente de un estudiante, un empleado o un contrato (acta de nacimiento,
+// CURP, fotografía, título...). El archivo vive en el almacenamiento configurado; aquí solo se guardan sus datos.
+type Documento struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint      `gorm:"not null;index" json:"-"`
+	Entidad        string    `gorm:"type:varchar(20);not null;index:idx_documentos_entidad" json:"entidad"`
+	EntidadID      uint      `gorm:"not null;index:idx_documentos_entidad" json:"entidad_id"`
+	Tipo           string    `gorm:"type:varchar(40);not null" json:"tipo"`
+	Nombre         string    `gorm:"type:varchar(255);not null" json:"nombre"`    // nombre del archivo que se subió
+	TipoMIME       string    `gorm:"type:varchar(100);not null" json:"tipo_mime"` // detectado del contenido, no del nombre
+	Tamano         int64     `gorm:"not null" json:"tamano"`                      // en bytes
+	SHA256         string    `gorm:"type:char(64);not null" json:"sha256"`
+	Llave          string    `gorm:"type:varchar(500);not null" json:"-"` // ubicación en el almacenamiento
+	UserID         uint      `gorm:"not null;index" json:"user_id"`       // quien lo subió
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -7,10 +7,13 @@ import (
 )
 
 type EstatusEmpleado struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Titulo    string    `gorm:"not null" json:"titulo"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index" json:"-"`
+	Titulo         string         `gorm:"not null" json:"titulo"`
+	Version        uint           `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (e *EstatusEmpleado) BeforeCreate(tx *gorm.DB) error {
@@ -8,9 +8,10 @@ import (
 
 type Estudiante struct {
 	ID                uint              `gorm:"primaryKey" json:"id"`
+	OrganizacionID    uint              `gorm:"not null;index;uniqueIndex:idx_estudiantes_organizacion_matricula,priority:1,where:deleted_at IS NULL" json:"-"`
 	UserID            uint              `gorm:"not null" json:"user_id"`
 	User              User              `gorm:"foreignKey:UserID" json:"user"`
-	Matricula         string            `gorm:"unique;not null" json:"matricula"`
+	Matricula         string            `gorm:"not null;uniqueIndex:idx_estudiantes_organizacion_matricula,priority:2" json:"matricula"`
 	Nacionalidad      string            `gorm:"not null" json:"nacionalidad"`
 	FechaNacimiento   time.Time         `gorm:"not null" json:"fecha_nacimiento"`
 	EdoOrigen         string            `gorm:"not null" json:"edo_origen"`
@@ -25,8 +26,10 @@ type Estudiante struct {
 	Grupo             Grupo             `gorm:"foreignKey:GrupoID" json:"grupo"`
 	EnProcesoAdmision bool              `gorm:"not null;default:true" json:"en_proceso_admision"`
 	EstudianteTutores []EstudianteTutor `gorm:"foreignKey:EstudianteID" json:"estudiante_tutores"` // Relación muchos-a-muchos explícita para posibles usos avanzados
+	Version           uint              `gorm:"not null;default:1" json:"version"`                 // se publica como ETag; aumenta en cada edición
 	CreatedAt         time.Time         `json:"created_at"`
 	UpdatedAt         time.Time         `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt    `gorm:"index" json:"-"`
 }
 
 func (e *Estudiante) BeforeCreate(tx *gorm.DB) error {
@@ -1,8 +1,9 @@
 package models
 
 type EstudianteTutor struct {
-	EstudianteID uint       `json:"estudiante_id" gorm:"not null"`
-	TutorID      uint       `json:"tutor_id" gorm:"not null"`
-	Estudiante   Estudiante `json:"estudiante" gorm:"foreignKey:EstudianteID"`
-	Tutor        Tutor      `json:"tutor" gorm:"foreignKey:TutorID"`
+	EstudianteID   uint       `json:"estudiante_id" gorm:"primaryKey;autoIncrement:false"`
+	TutorID        uint       `json:"tutor_id" gorm:"primaryKey;autoIncrement:false"`
+	OrganizacionID uint       `json:"-" gorm:"not null;index"`
+	Estudiante     Estudiante `json:"estudiante" gorm:"foreignKey:EstudianteID"`
+	Tutor          Tutor      `json:"tutor" gorm:"foreignKey:TutorID"`
 }
@@ -7,11 +7,14 @@ import (
 )
 
 type Genero struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Nombre    string    `gorm:"not null;unique" json:"nombre"`
-	Users     []User    `gorm:"foreignKey:GeneroID" json:"users"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index;uniqueIndex:idx_generos_organizacion_nombre,priority:1" json:"-"`
+	Nombre         string         `gorm:"not null;uniqueIndex:idx_generos_organizacion_nombre,priority:2" json:"nombre"`
+	Users          []User         `gorm:"foreignKey:GeneroID" json:"users"`
+	Version        uint           `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (g *Genero) BeforeCreate(tx *gorm.DB) error {
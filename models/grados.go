@@ -7,14 +7,17 @@ import (
 )
 
 type Grado struct {
-	ID             uint         `gorm:"primaryKey" json:"id"`
-	Titulo         string       `gorm:"not null" json:"titulo"`
-	Descripcion    string       `gorm:"type:text" json:"descripcion"`
-	NivelEscolarID uint         `gorm:"not null" json:"nivel_escolar_id"`
-	NivelEscolar   NivelEscolar `gorm:"foreignKey:NivelEscolarID" json:"nivel_escolar"`
-	Materias       []Materia    `gorm:"foreignKey:GradoID" json:"materias"`
-	CreatedAt      time.Time    `json:"created_at"`
-	UpdatedAt      time.Time    `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index" json:"-"`
+	Titulo         string         `gorm:"not null" json:"titulo"`
+	Descripcion    string         `gorm:"type:text" json:"descripcion"`
+	NivelEscolarID uint           `gorm:"not null" json:"nivel_escolar_id"`
+	NivelEscolar   NivelEscolar   `gorm:"foreignKey:NivelEscolarID" json:"nivel_escolar"`
+	Materias       []Materia      `gorm:"foreignKey:GradoID" json:"materias"`
+	Version        uint           `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (g *Grado) BeforeCreate(tx *gorm.DB) error {
@@ -7,14 +7,19 @@ import (
 )
 
 type Grupo struct {
-	ID             uint         `gorm:"primaryKey" json:"id"`
-	Titulo         string       `gorm:"not null" json:"titulo"`
-	UserID         uint         `gorm:"not null" json:"user_id"`
-	User           User         `gorm:"foreignKey:UserID" json:"user"`
-	NivelEscolarID uint         `gorm:"not null" json:"nivel_escolar_id"`
-	NivelEscolar   NivelEscolar `gorm:"foreignKey:NivelEscolarID" json:"nivel_escolar"`
-	CreatedAt      time.Time    `json:"created_at"`
-	UpdatedAt      time.Time    `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index" json:"-"`
+	Titulo         string         `gorm:"not null" json:"titulo"`
+	UserID         uint           `gorm:"not null" json:"user_id"`
+	User           User           `gorm:"foreignKey:UserID" json:"user"`
+	NivelEscolarID uint           `gorm:"not null" json:"nivel_escolar_id"`
+	NivelEscolar   NivelEscolar   `gorm:"foreignKey:NivelEscolarID" json:"nivel_escolar"`
+	CicloEscolarID *uint          `gorm:"index" json:"ciclo_escolar_id"` // nil en los grupos creados antes de los ciclos escolares
+	CicloEscolar   *CicloEscolar  `gorm:"foreignKey:CicloEscolarID" json:"ciclo_escolar,omitempty"`
+	Version        uint           `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (g *Grupo) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"api-margaritai/horario"
+)
+
+// Horario es una clase de la semana: un profesor da una materia a un grupo en un aula, el mismo día y a la
+// misma hora durante todo el ciclo del grupo. Se borra de verdad (sin papelera) porque restaurarlo podría
+// dejar dos clases encimadas; rehacerlo es tan fácil como crearlo.
+type Horario struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint         `gorm:"not null;index" json:"-"`
+	CicloEscolarID uint         `gorm:"not null;index" json:"ciclo_escolar_id"` // el del grupo; se copia para buscar choques por ciclo
+	CicloEscolar   CicloEscolar `gorm:"foreignKey:CicloEscolarID" json:"-"`
+	GrupoID        uint         `gorm:"not null;index" json:"grupo_id"`
+	Grupo          Grupo        `gorm:"foreignKey:GrupoID" json:"grupo"`
+	MateriaID      uint         `gorm:"not null;index" json:"materia_id"`
+	Materia        Materia      `gorm:"foreignKey:MateriaID" json:"materia"`
+	PersonalID     uint         `gorm:"not null;index" json:"personal_id"` // el profesor; Personal.EsProfesor debe ser true
+	Personal       Personal     `gorm:"foreignKey:PersonalID" json:"personal"`
+	AulaID         uint         `gorm:"not null;index" json:"aula_id"`
+	Aula           Aula         `gorm:"foreignKey:AulaID" json:"aula"`
+	Dia            int          `gorm:"not null;index" json:"dia"`             // 1 = lunes … 7 = domingo
+	Desde          string       `gorm:"type:varchar(5);not null" json:"desde"` // HH:MM
+	Hasta          string       `gorm:"type:varchar(5);not null" json:"hasta"` // HH:MM, sin incluir
+	Version        uint         `gorm:"not null;default:1" json:"version"`     // se publica como ETag; aumenta en cada edición
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+func (h *Horario) BeforeCreate(tx *gorm.DB) error {
+	h.CreatedAt = time.Now()
+	h.UpdatedAt = time.Now()
+	return nil
+}
+
+func (h *Horario) BeforeUpdate(tx *gorm.DB) error {
+	h.UpdatedAt = time.Now()
+	return nil
+}
+
+// Franja regresa el día y las horas de la clase
+func (h Horario) Franja() horario.Franja {
+	return horario.Franja{Dia: h.Dia, Desde: h.Desde, Hasta: h.Hasta}
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ClaveIdempotencia guarda la primera respuesta de un POST enviado con Idempotency-Key
+// para repetirla cuando el cliente reintenta la misma petición.
+type ClaveIdempotencia struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint      `gorm:"not null;uniqueIndex:idx_clave_idempotencia" json:"-"`       // las rutas públicas comparten el usuario 0
+	UserID         uint      `gorm:"not null;uniqueIndex:idx_clave_idempotencia" json:"user_id"` // 0 en las rutas públicas
+	Metodo         string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_clave_idempotencia" json:"metodo"`
+	Ruta           string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_clave_idempotencia" json:"ruta"`
+	Clave          string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_clave_idempotencia" json:"clave"`
+	HashCuerpo     string    `gorm:"type:char(64);not null" json:"hash_cuerpo"` // SHA-256 del cuerpo de la primera petición
+	Estado         int       `gorm:"not null;default:0" json:"estado"`          // código HTTP de la respuesta; 0 mientras se procesa
+	ContentType    string    `gorm:"type:varchar(100)" json:"content_type"`
+	Respuesta      []byte    `json:"-"`
+	ExpiraEn       time.Time `gorm:"not null;index" json:"expira_en"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (ClaveIdempotencia) TableName() string {
+	return "claves_idempotencia"
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Estados de una importación
+const (
+	ImportacionPendiente    = "pendiente"
+	ImportacionProcesando   = "procesando"
+	ImportacionCompletada   = "completada"
+	ImportacionFallida      = "fallida"
+	ImportacionInterrumpida = "interrumpida" // el servidor se detuvo mientras se procesaba
+)
+
+// ProblemaFila es un error o una advertencia de una fila del archivo importado
+type ProblemaFila struct {
+	Fila    int    `json:"row"` // número de fila en la hoja, contando el encabezado como fila 1
+	Campo   string `json:"field,omitempty"`
+	Codigo  string `json:"code"`
+	Mensaje string `json:"message"`
+}
+
+// Importacion registra la carga de un archivo CSV o XLSX; las grandes se procesan en segundo plano
+// y el cliente consulta su avance con GET /importaciones/:id
+type Importacion struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID  uint           `gorm:"not null;index" json:"-"`
+	UserID          uint           `gorm:"not null;index" json:"user_id"` // quien subió el archivo
+	Tipo            string         `gorm:"type:varchar(30);not null" json:"tipo"`
+	Archivo         string         `gorm:"type:varchar(255);not null" json:"archivo"`
+	Simulacion      bool           `gorm:"not null;default:false" json:"dry_run"` // solo valida, no guarda nada
+	Estado          string         `gorm:"type:varchar(20);not null;default:'pendiente';index" json:"estado"`
+	TotalFilas      int            `gorm:"not null;default:0" json:"total_filas"`
+	FilasValidas    int            `gorm:"not null;default:0" json:"filas_validas"`
+	FilasProcesadas int            `gorm:"not null;default:0" json:"filas_procesadas"`
+	FilasImportadas int            `gorm:"not null;default:0" json:"filas_importadas"`
+	Errores         []ProblemaFila `gorm:"serializer:json;type:jsonb" json:"errores"`
+	Advertencias    []ProblemaFila `gorm:"serializer:json;type:jsonb" json:"advertencias"`
+	Mensaje         string         `gorm:"type:text" json:"mensaje,omitempty"` // causa cuando la importación completa falla
+	IniciadaEn      *time.Time     `json:"iniciada_en"`
+	TerminadaEn     *time.Time     `json:"terminada_en"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+func (Importacion) TableName() string {
+	return "importaciones"
+}
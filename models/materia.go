@@ -7,13 +7,21 @@ import (
 )
 
 type Materia struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Titulo      string    `gorm:"not null" json:"titulo"`
-	Descripcion string    `gorm:"type:text" json:"descripcion"`
-	GradoID     uint      `gorm:"not null" json:"grado_id"`
-	Grado       Grado     `gorm:"foreignKey:GradoID" json:"grado"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index" json:"-"`
+	Titulo         string         `gorm:"not null" json:"titulo"`
+	Descripcion    string         `gorm:"type:text" json:"descripcion"`
+	ClaveSEP       string         `gorm:"type:varchar(20);index" json:"clave_sep"` // clave de la asignatura en el plan de estudios oficial
+	Creditos       float64        `gorm:"not null;default:0" json:"creditos"`
+	HorasTeoricas  int            `gorm:"not null;default:0" json:"horas_teoricas"`  // por semana
+	HorasPracticas int            `gorm:"not null;default:0" json:"horas_practicas"` // por semana
+	GradoID        *uint          `gorm:"index" json:"grado_id"`                     // nil mientras no está en el plan de ningún grado
+	Grado          *Grado         `gorm:"foreignKey:GradoID" json:"grado,omitempty"`
+	Orden          int            `gorm:"not null;default:0" json:"orden"`   // posición en el plan de estudios del grado
+	Version        uint           `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (m *Materia) BeforeCreate(tx *gorm.DB) error {
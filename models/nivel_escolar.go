@@ -7,16 +7,19 @@ import (
 )
 
 type NivelEscolar struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Titulo      string    `gorm:"not null" json:"titulo"`
-	Descripcion string    `gorm:"type:text" json:"descripcion"`
-	Mensualidad float64   `gorm:"not null" json:"mensualidad"`
-	PlantelID   uint      `gorm:"not null" json:"plantel_id"`
-	Plantel     Plantel   `gorm:"foreignKey:PlantelID" json:"plantel"`
-	Grados      []Grado   `gorm:"foreignKey:NivelEscolarID" json:"grados"`
-	Grupos      []Grupo   `gorm:"foreignKey:NivelEscolarID" json:"grupos"` // Relación con Grupo
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index" json:"-"`
+	Titulo         string         `gorm:"not null" json:"titulo"`
+	Descripcion    string         `gorm:"type:text" json:"descripcion"`
+	Mensualidad    float64        `gorm:"not null" json:"mensualidad"`
+	PlantelID      uint           `gorm:"not null" json:"plantel_id"`
+	Plantel        Plantel        `gorm:"foreignKey:PlantelID" json:"plantel"`
+	Grados         []Grado        `gorm:"foreignKey:NivelEscolarID" json:"grados"`
+	Grupos         []Grupo        `gorm:"foreignKey:NivelEscolarID" json:"grupos"` // Relación con Grupo
+	Version        uint           `gorm:"not null;default:1" json:"version"`       // se publica como ETag; aumenta en cada edición
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (n *NivelEscolar) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Organizacion es una institución que comparte el despliegue con otras; está por encima de sus planteles.
+// Sus usuarios, roles, catálogos y registros escolares llevan su ID en OrganizacionID y solo se ven dentro de ella.
+type Organizacion struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Nombre    string         `gorm:"type:varchar(200);not null" json:"nombre"`
+	Slug      string         `gorm:"type:varchar(63);not null;uniqueIndex" json:"slug"` // se envía en X-Organizacion y es el subdominio
+	Activa    bool           `gorm:"not null;default:true" json:"activa"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	Planteles []Plantel      `gorm:"foreignKey:OrganizacionID" json:"-"`
+}
+
+func (Organizacion) TableName() string {
+	return "organizaciones"
+}
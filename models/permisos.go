@@ -12,6 +12,7 @@ type Permiso struct {
 	Descripcion        string           `gorm:"type:text" json:"descripcion"`
 	CategoriaPermisoID uint             `gorm:"not null" json:"categoria_permiso_id"`
 	CategoriaPermiso   CategoriaPermiso `gorm:"foreignKey:CategoriaPermisoID" json:"categoria_permiso"`
+	Version            uint             `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
 	CreatedAt          time.Time        `json:"created_at"`
 	UpdatedAt          time.Time        `json:"updated_at"`
 	DeletedAt          gorm.DeletedAt   `gorm:"index" json:"-"`
@@ -27,3 +28,23 @@ func (p *Permiso) BeforeUpdate(tx *gorm.DB) error {
 	p.UpdatedAt = time.Now()
 	return nil
 }
+
+// Títulos de permisos que el código revisa con middleware.RequierePermiso; se siembran en seeders/permisos_seeder.go
+const (
+	PermisoVerReportes    = "Ver reportes"
+	PermisoVerEstudiantes = "Ver estudiantes"
+	PermisoVerPersonal    = "Ver personal"
+	PermisoVerTutores     = "Ver tutores"
+
+	PermisoImportarEstudiantes = "Importar estudiantes"
+	PermisoExportarListados    = "Exportar listados"
+
+	PermisoVerDocumentos   = "Ver documentos"
+	PermisoSubirDocumentos = "Subir documentos"
+
+	PermisoRestaurarRegistros = "Restaurar registros"
+	PermisoPurgarRegistros    = "Eliminar registros definitivamente"
+
+	PermisoAdministrarWebhooks = "Administrar webhooks"
+	PermisoAdministrarTareas   = "Administrar tareas"
+)
@@ -8,6 +8,7 @@ import (
 
 type Personal struct {
 	ID                uint            `gorm:"primaryKey" json:"id"`
+	OrganizacionID    uint            `gorm:"not null;index" json:"-"`
 	UserID            uint            `gorm:"not null;unique;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user_id"`
 	User              User            `gorm:"foreignKey:UserID" json:"user"`
 	RFC               string          `gorm:"not null" json:"rfc"`
@@ -24,8 +25,10 @@ type Personal struct {
 	Puesto            Puesto          `gorm:"foreignKey:PuestoID" json:"puesto"`
 	EstatusEmpleadoID uint            `gorm:"not null" json:"estatus_empleado_id"`
 	EstatusEmpleado   EstatusEmpleado `gorm:"foreignKey:EstatusEmpleadoID" json:"estatus_empleado"`
+	Version           uint            `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
 	CreatedAt         time.Time       `json:"created_at"`
 	UpdatedAt         time.Time       `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt  `gorm:"index" json:"-"`
 }
 
 func (p *Personal) BeforeCreate(tx *gorm.DB) error {
@@ -7,16 +7,19 @@ import (
 )
 
 type Plantel struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Nombre      string    `gorm:"not null" json:"nombre"`
-	Descripcion string    `gorm:"type:text" json:"descripcion"`
-	Ubicacion   string    `gorm:"not null" json:"ubicacion"`
-	Telefono    string    `gorm:"not null" json:"telefono"`
-	Correo      string    `gorm:"not null" json:"correo"`
-	UserID      uint      `gorm:"not null" json:"user_id"`
-	User        User      `gorm:"foreignKey:UserID" json:"user"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index" json:"-"`
+	Nombre         string         `gorm:"not null" json:"nombre"`
+	Descripcion    string         `gorm:"type:text" json:"descripcion"`
+	Ubicacion      string         `gorm:"not null" json:"ubicacion"`
+	Telefono       string         `gorm:"not null" json:"telefono"`
+	Correo         string         `gorm:"not null" json:"correo"`
+	UserID         uint           `gorm:"not null" json:"user_id"`
+	User           User           `gorm:"foreignKey:UserID" json:"user"`
+	Version        uint           `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (p *Plantel) BeforeCreate(tx *gorm.DB) error {
@@ -7,11 +7,14 @@ import (
 )
 
 type Puesto struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Titulo    string    `gorm:"not null" json:"titulo"`
-	PagoXHr   float64   `gorm:"not null" json:"pago_x_hr"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index" json:"-"`
+	Titulo         string         `gorm:"not null" json:"titulo"`
+	PagoXHr        float64        `gorm:"not null" json:"pago_x_hr"`
+	Version        uint           `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (p *Puesto) BeforeCreate(tx *gorm.DB) error {
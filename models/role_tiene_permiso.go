@@ -1,8 +1,9 @@
 package models
 
 type RoleTienePermiso struct {
-	RoleID    uint    `gorm:"primaryKey" json:"role_id"`
-	Rol       Rol     `gorm:"foreignKey:RoleID" json:"rol"`
-	PermisoID uint    `gorm:"primaryKey" json:"permiso_id"`
-	Permiso   Permiso `gorm:"foreignKey:PermisoID" json:"permiso"`
+	OrganizacionID uint    `gorm:"not null;index" json:"-"`
+	RoleID         uint    `gorm:"primaryKey" json:"role_id"`
+	Rol            Rol     `gorm:"foreignKey:RoleID" json:"rol"`
+	PermisoID      uint    `gorm:"primaryKey" json:"permiso_id"`
+	Permiso        Permiso `gorm:"foreignKey:PermisoID" json:"permiso"`
 }
@@ -8,12 +8,14 @@ import (
 
 type Rol struct {
 	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index" json:"-"`
 	Nombre         string         `gorm:"not null" json:"nombre"`
 	Descripcion    string         `gorm:"type:text" json:"descripcion"`
 	Icono          string         `gorm:"type:varchar(255)" json:"icono"` // nuevo campo para el nombre del icono de Material Icons
 	ParaEstudiante bool           `gorm:"not null" json:"para_estudiante"`
 	ParaPersonal   bool           `gorm:"not null" json:"para_personal"`
 	ParaTutor      bool           `gorm:"not null" json:"para_tutor"`
+	Version        uint           `gorm:"not null;default:1" json:"version"` // se publica como ETag; aumenta en cada edición
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
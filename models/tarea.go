@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// TareaProgramada guarda el candado de una tarea programada. Las réplicas de la API compiten por el mismo
+// renglón: la que logra actualizarlo ejecuta la tarea y las demás la omiten.
+type TareaProgramada struct {
+	Nombre           string     `gorm:"primaryKey;type:varchar(60)" json:"nombre"`
+	BloqueadaHasta   *time.Time `json:"bloqueada_hasta"`                        // nil si nadie la está ejecutando
+	BloqueadaPor     string     `gorm:"type:varchar(150)" json:"bloqueada_por"` // instancia que la ejecuta
+	UltimaProgramada *time.Time `json:"ultima_programada"`                      // horario de la última ejecución programada que se tomó
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func (TareaProgramada) TableName() string {
+	return "tareas_programadas"
+}
+
+// Estados de una ejecución
+const (
+	EjecucionEnCurso      = "en_curso"
+	EjecucionExitosa      = "exitosa"
+	EjecucionFallida      = "fallida"
+	EjecucionInterrumpida = "interrumpida" // la instancia se detuvo antes de terminar
+)
+
+// EjecucionTarea es el historial de cada vez que corrió una tarea
+type EjecucionTarea struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Tarea       string     `gorm:"type:varchar(60);not null;index" json:"tarea"`
+	Estado      string     `gorm:"type:varchar(20);not null;index" json:"estado"`
+	Manual      bool       `gorm:"not null;default:false" json:"manual"` // la lanzó un administrador en lugar del horario
+	UserID      *uint      `json:"user_id"`                              // quien la lanzó, si fue manual
+	Instancia   string     `gorm:"type:varchar(150);not null" json:"instancia"`
+	IniciadaEn  time.Time  `gorm:"not null;index" json:"iniciada_en"`
+	TerminadaEn *time.Time `json:"terminada_en"`
+	DuracionMs  int64      `json:"duracion_ms"`
+	Resultado   any        `gorm:"serializer:json;type:jsonb" json:"resultado"` // resumen que regresa la tarea
+	Error       string     `gorm:"type:text" json:"error,omitempty"`
+}
+
+func (EjecucionTarea) TableName() string {
+	return "ejecuciones_tareas"
+}
@@ -8,14 +8,17 @@ import (
 
 type Tutor struct {
 	ID                uint              `gorm:"primaryKey" json:"id"`
+	OrganizacionID    uint              `gorm:"not null;index" json:"-"`
 	UserID            uint              `gorm:"not null" json:"user_id"`
 	User              User              `gorm:"foreignKey:UserID" json:"user"`
 	Nombre            string            `gorm:"not null" json:"nombre"`
 	Telefono          string            `gorm:"not null" json:"telefono"`
 	Telefono2         string            `gorm:"not null" json:"telefono2"`
 	EstudianteTutores []EstudianteTutor `gorm:"foreignKey:TutorID" json:"estudiante_tutores"` // Relación muchos-a-muchos explícita para posibles usos avanzados
+	Version           uint              `gorm:"not null;default:1" json:"version"`            // se publica como ETag; aumenta en cada edición
 	CreatedAt         time.Time         `json:"created_at"`
 	UpdatedAt         time.Time         `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt    `gorm:"index" json:"-"`
 }
 
 func (t *Tutor) BeforeCreate(tx *gorm.DB) error {
@@ -5,28 +5,35 @@ import (
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+
+	"api-margaritai/validadores"
 )
 
 type User struct {
-	ID          uint        `gorm:"primaryKey" json:"id"`
-	Nombre      string      `gorm:"not null" json:"nombre"`
-	ApellidoP   string      `gorm:"not null" json:"apellido_p"`
-	ApellidoM   string      `gorm:"not null" json:"apellido_m"`
-	Email       string      `gorm:"unique;not null" json:"email"`
-	CURP        string      `gorm:"unique;not null" json:"curp"`
-	Password    string      `gorm:"not null" json:"-"`
-	FechaNac    time.Time   `gorm:"not null" json:"fecha_nac"`
-	GeneroID    uint        `gorm:"not null" json:"genero_id"`
-	Genero      Genero      `gorm:"foreignKey:GeneroID" json:"genero"`
-	RolID       uint        `gorm:"not null" json:"rol_id"`                                                      // Debe hacer referencia a un rol existente para evitar error de FK
-	Rol         Rol         `gorm:"foreignKey:RolID;constraint:OnUpdate:RESTRICT,OnDelete:RESTRICT;" json:"rol"` // FK explícito
-	Direcciones []Direccion `gorm:"foreignKey:UserID" json:"direcciones"`
-	Grupos      []Grupo     `gorm:"foreignKey:UserID" json:"grupos"`
-	Planteles   []Plantel   `gorm:"foreignKey:UserID" json:"planteles"`
-	Tutores     []Tutor     `gorm:"foreignKey:UserID" json:"tutores"`
-	EsActivo    bool        `gorm:"not null;default:true" json:"es_activo"`
-	CreatedAt   time.Time   `json:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index;uniqueIndex:idx_users_organizacion_email,priority:1,where:deleted_at IS NULL;uniqueIndex:idx_users_organizacion_curp,priority:1,where:deleted_at IS NULL" json:"-"`
+	Nombre         string         `gorm:"not null" json:"nombre"`
+	ApellidoP      string         `gorm:"not null" json:"apellido_p"`
+	ApellidoM      string         `gorm:"not null" json:"apellido_m"`
+	Email          string         `gorm:"not null;uniqueIndex:idx_users_organizacion_email,priority:2" json:"email"`
+	CURP           string         `gorm:"not null;uniqueIndex:idx_users_organizacion_curp,priority:2" json:"curp"`
+	Password       string         `gorm:"not null" json:"-"`
+	FechaNac       time.Time      `gorm:"not null" json:"fecha_nac"`
+	GeneroID       uint           `gorm:"not null" json:"genero_id"`
+	Genero         Genero         `gorm:"foreignKey:GeneroID" json:"genero"`
+	RolID          uint           `gorm:"not null" json:"rol_id"`                                                      // Debe hacer referencia a un rol existente para evitar error de FK
+	Rol            Rol            `gorm:"foreignKey:RolID;constraint:OnUpdate:RESTRICT,OnDelete:RESTRICT;" json:"rol"` // FK explícito
+	Direcciones    []Direccion    `gorm:"foreignKey:UserID" json:"direcciones"`
+	Grupos         []Grupo        `gorm:"foreignKey:UserID" json:"grupos"`
+	Planteles      []Plantel      `gorm:"foreignKey:UserID" json:"planteles"`
+	Tutores        []Tutor        `gorm:"foreignKey:UserID" json:"tutores"`
+	EsActivo       bool           `gorm:"not null;default:true" json:"es_activo"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// VersionCalendario va firmada en el enlace del calendario de horarios; subirla invalida los enlaces anteriores
+	VersionCalendario uint `gorm:"not null;default:0" json:"-"`
 }
 
 // IMPORTANTE: Si se va a asignar un Rol al crear/actualizar un usuario, RolID debe corresponder a un registro existente en la tabla "roles".
@@ -46,6 +53,7 @@ func (u *User) CheckPassword(password string) error {
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
+	u.CURP = validadores.NormalizarCURP(u.CURP)
 	now := time.Now()
 	u.CreatedAt = now
 	u.UpdatedAt = now
@@ -53,6 +61,7 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 }
 
 func (u *User) BeforeUpdate(tx *gorm.DB) error {
+	u.CURP = validadores.NormalizarCURP(u.CURP)
 	u.UpdatedAt = time.Now()
 	return nil
 }
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EventoDominio es una fila de la bandeja de salida (outbox): se escribe en la misma transacción que el cambio
+// que describe, así un evento existe si y solo si el cambio se guardó. El despachador la reparte después
+// en una EntregaWebhook por cada suscripción interesada.
+type EventoDominio struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint       `gorm:"not null;index" json:"-"`
+	Tipo           string     `gorm:"type:varchar(60);not null;index" json:"tipo"` // por ejemplo estudiante.creado
+	EntidadID      uint       `gorm:"not null" json:"entidad_id"`
+	Datos          any        `gorm:"serializer:json;type:jsonb" json:"datos"`
+	ProcesadoEn    *time.Time `gorm:"index" json:"procesado_en"` // nil mientras no se hayan creado sus entregas
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (EventoDominio) TableName() string {
+	return "eventos_dominio"
+}
+
+// SuscripcionWebhook es un sistema externo que recibe por POST los eventos indicados
+type SuscripcionWebhook struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizacionID uint           `gorm:"not null;index" json:"-"`
+	URL            string         `gorm:"type:varchar(500);not null" json:"url"`
+	Descripcion    string         `gorm:"type:text" json:"descripcion"`
+	Eventos        []string       `gorm:"serializer:json;type:jsonb;not null" json:"eventos"` // tipos de evento, o "*" para todos
+	Secreto        string         `gorm:"type:varchar(100);not null" json:"-"`                // firma los envíos; solo se muestra al crearlo o rotarlo
+	Activa         bool           `gorm:"not null;default:true" json:"activa"`
+	UserID         uint           `gorm:"not null" json:"user_id"` // quien la registró
+	Version        uint           `gorm:"not null;default:1" json:"version"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (SuscripcionWebhook) TableName() string {
+	return "suscripciones_webhook"
+}
+
+// Recibe indica si la suscripción quiere los eventos del tipo dado
+func (s SuscripcionWebhook) Recibe(tipo string) bool {
+	for _, e := range s.Eventos {
+		if e == "*" || e == tipo {
+			return true
+		}
+	}
+	return false
+}
+
+// Estados de una entrega
+const (
+	EntregaPendiente = "pendiente" // por enviar o esperando el siguiente reintento
+	EntregaEntregada = "entregada" // el destino respondió 2xx
+	EntregaFallida   = "fallida"   // se agotaron los intentos; se puede reenviar
+)
+
+// EntregaWebhook es el envío de un evento a una suscripción, con el resultado del último intento
+type EntregaWebhook struct {
+	ID               uint               `gorm:"primaryKey" json:"id"`
+	OrganizacionID   uint               `gorm:"not null;index" json:"-"`
+	SuscripcionID    uint               `gorm:"not null;index" json:"suscripcion_id"`
+	Suscripcion      SuscripcionWebhook `gorm:"foreignKey:SuscripcionID" json:"-"`
+	EventoID         uint               `gorm:"not null;index" json:"evento_id"`
+	Evento           EventoDominio      `gorm:"foreignKey:EventoID" json:"evento"`
+	Estado           string             `gorm:"type:varchar(20);not null;default:'pendiente';index:idx_entregas_webhook_por_enviar,priority:1" json:"estado"`
+	Intentos         int                `gorm:"not null;default:0" json:"intentos"`
+	SiguienteIntento time.Time          `gorm:"not null;index:idx_entregas_webhook_por_enviar,priority:2" json:"siguiente_intento"`
+	UltimoCodigo     int                `json:"ultimo_codigo,omitempty"` // código HTTP de la última respuesta
+	UltimoError      string             `gorm:"type:text" json:"ultimo_error,omitempty"`
+	EntregadaEn      *time.Time         `json:"entregada_en"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+func (EntregaWebhook) TableName() string {
+	return "entregas_webhook"
+}
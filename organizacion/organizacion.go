@@ -0,0 +1,266 @@
+// Package organizacion separa los datos de cada institución que comparte el despliegue. La organización viaja
+// en el contexto de la consulta y unos callbacks de GORM filtran por ella cualquier modelo con el campo
+// OrganizacionID, y la asignan al crear. Una consulta sobre esos modelos sin organización en el contexto
+// falla en lugar de ver los datos de todas; los procesos internos que sí deben verlas usan Global.
+package organizacion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Campo es el campo que identifica a los modelos separados por organización
+const Campo = "OrganizacionID"
+
+var (
+	// ErrSinOrganizacion indica una consulta sobre datos de una organización sin decir cuál
+	ErrSinOrganizacion = errors.New("consulta sin organización: use database.De(c) o organizacion.Global")
+	// ErrReferenciaAjena indica que un registro apunta (plantel_id, grupo_id...) a uno de otra organización
+	ErrReferenciaAjena = errors.New("el registro relacionado no existe en la organización")
+)
+
+type claveContexto struct{}
+
+// alcance es lo que se guarda en el contexto: una organización o el modo global
+type alcance struct {
+	id     uint
+	global bool
+}
+
+// Con regresa un contexto cuyas consultas se limitan a la organización indicada
+func Con(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, claveContexto{}, alcance{id: id})
+}
+
+// Global regresa un contexto cuyas consultas ven todas las organizaciones. Es para migraciones, seeders y
+// tareas internas; al crear registros en este modo el OrganizacionID debe venir asignado.
+func Global(ctx context.Context) context.Context {
+	return context.WithValue(ctx, claveContexto{}, alcance{global: true})
+}
+
+// De regresa la organización del contexto; falso si no tiene o si es global
+func De(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	a, ok := ctx.Value(claveContexto{}).(alcance)
+	return a.id, ok && !a.global && a.id != 0
+}
+
+// EsGlobal indica si el contexto ve todas las organizaciones
+func EsGlobal(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	a, ok := ctx.Value(claveContexto{}).(alcance)
+	return ok && a.global
+}
+
+// Registrar instala los callbacks en la conexión
+func Registrar(db *gorm.DB) error {
+	cb := db.Callback()
+	registros := []error{
+		cb.Query().Before("gorm:query").Register("organizacion:filtrar", filtrar),
+		cb.Row().Before("gorm:row").Register("organizacion:filtrar", filtrar),
+		cb.Update().Before("gorm:update").Register("organizacion:filtrar", filtrar),
+		cb.Delete().Before("gorm:delete").Register("organizacion:filtrar", filtrar),
+		cb.Create().Before("gorm:create").Register("organizacion:asignar", asignar),
+		cb.Create().After("organizacion:asignar").Before("gorm:create").Register("organizacion:referencias", verificarReferencias),
+		cb.Update().After("organizacion:filtrar").Before("gorm:update").Register("organizacion:referencias", verificarReferencias),
+	}
+	return errors.Join(registros...)
+}
+
+// separado regresa el campo de organización del modelo de la sentencia, o nil si el modelo es compartido.
+// Las consultas con Table() o Raw() no tienen modelo y no se filtran.
+func separado(db *gorm.DB) *schema.Field {
+	if db.Statement.Schema == nil {
+		return nil
+	}
+	return db.Statement.Schema.LookUpField(Campo)
+}
+
+// filtrar agrega organizacion_id = ? a las consultas, actualizaciones y borrados
+func filtrar(db *gorm.DB) {
+	campo := separado(db)
+	if db.Error != nil || campo == nil || EsGlobal(db.Statement.Context) {
+		return
+	}
+	id, ok := De(db.Statement.Context)
+	if !ok {
+		db.AddError(ErrSinOrganizacion)
+		return
+	}
+	agrupar(db.Statement)
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: campo.DBName}, Value: id},
+	}})
+}
+
+// agrupar encierra entre paréntesis las condiciones que ya trae la sentencia si alguna viene de Or(). Sin esto
+// Where(a).Or(b) quedaría "a OR b AND organizacion_id = ?" y la rama a vería todas las organizaciones.
+// Es lo mismo que hace GORM con deleted_at.
+func agrupar(stmt *gorm.Statement) {
+	c, ok := stmt.Clauses["WHERE"]
+	if !ok {
+		return
+	}
+	where, ok := c.Expression.(clause.Where)
+	if !ok {
+		return
+	}
+	for _, expr := range where.Exprs {
+		if _, ok := expr.(clause.OrConditions); ok {
+			where.Exprs = []clause.Expression{clause.And(where.Exprs...)}
+			c.Expression = where
+			stmt.Clauses["WHERE"] = c
+			return
+		}
+	}
+}
+
+// asignar pone la organización del contexto a los registros nuevos, sin importar lo que traigan
+func asignar(db *gorm.DB) {
+	campo := separado(db)
+	if db.Error != nil || campo == nil {
+		return
+	}
+	ctx := db.Statement.Context
+	id, ok := De(ctx)
+	if !ok {
+		if !EsGlobal(ctx) {
+			db.AddError(ErrSinOrganizacion)
+			return
+		}
+		// En modo global el registro ya debe traer su organización
+		if !asignados(ctx, campo, db.Statement.ReflectValue) {
+			db.AddError(ErrSinOrganizacion)
+		}
+		return
+	}
+
+	valor := db.Statement.ReflectValue
+	switch valor.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < valor.Len(); i++ {
+			if err := campo.Set(ctx, reflect.Indirect(valor.Index(i)), id); err != nil {
+				db.AddError(err)
+			}
+		}
+	case reflect.Struct:
+		if err := campo.Set(ctx, valor, id); err != nil {
+			db.AddError(err)
+		}
+	case reflect.Map:
+		// Create(map[string]any{...}) sobre un modelo
+		if m, ok := valor.Interface().(map[string]any); ok {
+			m[campo.DBName] = id
+		}
+	}
+}
+
+// asignados revisa que todos los registros a crear tengan OrganizacionID
+func asignados(ctx context.Context, campo *schema.Field, valor reflect.Value) bool {
+	switch valor.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < valor.Len(); i++ {
+			if _, cero := campo.ValueOf(ctx, reflect.Indirect(valor.Index(i))); cero {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		_, cero := campo.ValueOf(ctx, valor)
+		return !cero
+	case reflect.Map:
+		m, ok := valor.Interface().(map[string]any)
+		return ok && m[campo.DBName] != nil
+	}
+	return false
+}
+
+// verificarReferencias revisa que las llaves foráneas que se guardan apunten a registros de la misma
+// organización. La llave foránea de la base solo comprueba que el registro exista, no de quién es.
+func verificarReferencias(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	id, ok := De(db.Statement.Context)
+	if !ok {
+		return
+	}
+	valores := valoresAGuardar(db)
+	for _, relacion := range db.Statement.Schema.Relationships.Relations {
+		if relacion.Type != schema.BelongsTo || relacion.FieldSchema.LookUpField(Campo) == nil || len(relacion.References) != 1 {
+			continue
+		}
+		referencia := relacion.References[0]
+		llaves := valores(referencia.ForeignKey)
+		if len(llaves) == 0 {
+			continue
+		}
+		var encontrados int64
+		err := db.Session(&gorm.Session{NewDB: true}).Table(relacion.FieldSchema.Table).
+			Where(clause.IN{Column: referencia.PrimaryKey.DBName, Values: llaves}).
+			Where(clause.Eq{Column: relacion.FieldSchema.LookUpField(Campo).DBName, Value: id}).
+			Distinct(referencia.PrimaryKey.DBName).Count(&encontrados).Error
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		if int(encontrados) != len(llaves) {
+			db.AddError(fmt.Errorf("%w: %s", ErrReferenciaAjena, referencia.ForeignKey.DBName))
+			return
+		}
+	}
+}
+
+// valoresAGuardar regresa una función que lista los valores distintos de cero que la sentencia va a escribir
+// en un campo: los de Updates(map) o Update(columna, valor), o los del modelo en Create, Save y Updates(struct)
+func valoresAGuardar(db *gorm.DB) func(*schema.Field) []any {
+	ctx := db.Statement.Context
+	if m, ok := db.Statement.Dest.(map[string]any); ok {
+		return func(campo *schema.Field) []any {
+			for _, llave := range []string{campo.DBName, campo.Name} {
+				if v, ok := m[llave]; ok && v != nil && !reflect.ValueOf(v).IsZero() {
+					return []any{v}
+				}
+			}
+			return nil
+		}
+	}
+
+	registros := db.Statement.ReflectValue
+	if destino := reflect.Indirect(reflect.ValueOf(db.Statement.Dest)); destino.IsValid() && destino.Type() == db.Statement.Schema.ModelType {
+		registros = destino
+	}
+	return func(campo *schema.Field) []any {
+		vistos := map[any]bool{}
+		var valores []any
+		agregar := func(registro reflect.Value) {
+			v, cero := campo.ValueOf(ctx, reflect.Indirect(registro))
+			if r := reflect.ValueOf(v); r.Kind() == reflect.Pointer && !r.IsNil() {
+				v = r.Elem().Interface() // llaves foráneas opcionales (*uint)
+			}
+			if !cero && !vistos[v] {
+				vistos[v] = true
+				valores = append(valores, v)
+			}
+		}
+		switch registros.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < registros.Len(); i++ {
+				agregar(registros.Index(i))
+			}
+		case reflect.Struct:
+			agregar(registros)
+		}
+		return valores
+	}
+}
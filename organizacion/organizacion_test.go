@@ -0,0 +1,338 @@
+package organizacion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"api-margaritai/database/prueba"
+)
+
+// Modelos mínimos con la misma forma que los de la API: separados por organización, con una llave
+// foránea obligatoria y una opcional, y uno compartido entre todas
+type plantel struct {
+	ID             uint
+	OrganizacionID uint
+	Nombre         string
+}
+
+type aula struct {
+	ID             uint
+	OrganizacionID uint
+	Nombre         string
+	PlantelID      uint
+	Plantel        *plantel
+	AnexoID        *uint
+	Anexo          *plantel
+}
+
+type genero struct {
+	ID     uint
+	Nombre string
+}
+
+const (
+	orgPrueba = uint(7)
+	orgAjena  = uint(8)
+)
+
+// abrir regresa una base con los callbacks instalados; por defecto los conteos de verificarReferencias
+// encuentran todas las llaves, como si fueran de la organización
+func abrir(t *testing.T) (*gorm.DB, *prueba.Conexion) {
+	t.Helper()
+	db, conexion, err := prueba.Abrir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Registrar(db); err != nil {
+		t.Fatal(err)
+	}
+	referenciasEncontradas(conexion, -1)
+	return db, conexion
+}
+
+// referenciasEncontradas hace que los conteos regresen n, o tantas como llaves se buscaron si n es -1
+func referenciasEncontradas(conexion *prueba.Conexion, n int) {
+	conexion.Responder(func(s prueba.Sentencia) prueba.Filas {
+		if !strings.Contains(s.SQL, "COUNT(") {
+			return prueba.Filas{}
+		}
+		encontradas := int64(n)
+		if n < 0 {
+			encontradas = int64(len(s.Args) - 1) // todos menos el de organizacion_id
+		}
+		return prueba.Filas{Columnas: []string{"count"}, Valores: [][]any{{encontradas}}}
+	})
+}
+
+// filtroDe regresa el valor con que la sentencia compara la columna (como "aulas"."organizacion_id"),
+// o falso si no la compara
+func filtroDe(s prueba.Sentencia, columna string) (any, bool) {
+	i := strings.Index(s.SQL, columna+" = $")
+	if i < 0 {
+		return nil, false
+	}
+	var n int
+	if _, err := fmt.Sscanf(s.SQL[i+len(columna)+4:], "%d", &n); err != nil || n < 1 || n > len(s.Args) {
+		return nil, false
+	}
+	return s.Args[n-1], true
+}
+
+// fila consulta con Row, que pasa por su propia cadena de callbacks
+func fila(tx *gorm.DB) error {
+	q := tx.Model(&aula{}).Select("id")
+	if row := q.Row(); row != nil {
+		return row.Err()
+	}
+	return q.Error
+}
+
+func TestSinOrganizacion(t *testing.T) {
+	db, conexion := abrir(t)
+	ctx := context.Background()
+
+	operaciones := map[string]func(*gorm.DB) error{
+		"Find":   func(tx *gorm.DB) error { return tx.Find(&[]aula{}).Error },
+		"First":  func(tx *gorm.DB) error { return tx.First(&aula{}, 1).Error },
+		"Count":  func(tx *gorm.DB) error { var n int64; return tx.Model(&aula{}).Count(&n).Error },
+		"Row":    fila,
+		"Update": func(tx *gorm.DB) error { return tx.Model(&aula{ID: 1}).Update("nombre", "A1").Error },
+		"Delete": func(tx *gorm.DB) error { return tx.Delete(&aula{ID: 1}).Error },
+		"Create": func(tx *gorm.DB) error { return tx.Create(&aula{Nombre: "A1", OrganizacionID: orgPrueba}).Error },
+	}
+	for nombre, operacion := range operaciones {
+		t.Run(nombre, func(t *testing.T) {
+			conexion.Limpiar()
+			if err := operacion(db.WithContext(ctx)); !errors.Is(err, ErrSinOrganizacion) {
+				t.Fatalf("se esperaba ErrSinOrganizacion, se obtuvo %v", err)
+			}
+			if s := conexion.Sentencias(); len(s) > 0 {
+				t.Fatalf("no debía llegar nada a la base, llegó %q", s[0].SQL)
+			}
+		})
+	}
+
+	t.Run("modelo compartido", func(t *testing.T) {
+		if err := db.WithContext(ctx).Find(&[]genero{}).Error; err != nil {
+			t.Fatalf("los modelos sin OrganizacionID no necesitan organización: %v", err)
+		}
+	})
+}
+
+func TestFiltraPorOrganizacion(t *testing.T) {
+	db, conexion := abrir(t)
+	ctx := Con(context.Background(), orgPrueba)
+
+	operaciones := map[string]func(*gorm.DB) error{
+		"Find":   func(tx *gorm.DB) error { return tx.Where("nombre = ?", "A1").Find(&[]aula{}).Error },
+		"First":  func(tx *gorm.DB) error { return tx.Limit(1).Find(&aula{}, 1).Error },
+		"Count":  func(tx *gorm.DB) error { var n int64; return tx.Model(&aula{}).Count(&n).Error },
+		"Pluck":  func(tx *gorm.DB) error { var ids []uint; return tx.Model(&aula{}).Pluck("id", &ids).Error },
+		"Row":    fila,
+		"Update": func(tx *gorm.DB) error { return tx.Model(&aula{ID: 1}).Update("nombre", "A1").Error },
+		"Updates": func(tx *gorm.DB) error {
+			return tx.Model(&aula{}).Where("id IN ?", []uint{1, 2}).Updates(map[string]any{"nombre": "A1"}).Error
+		},
+		"Delete": func(tx *gorm.DB) error { return tx.Delete(&aula{}, 1).Error },
+		"Unscoped": func(tx *gorm.DB) error {
+			return tx.Unscoped().Where("id = ?", 1).Delete(&aula{}).Error
+		},
+		"Preload": func(tx *gorm.DB) error { return tx.Preload("Plantel").Find(&[]aula{}).Error },
+	}
+	for nombre, operacion := range operaciones {
+		t.Run(nombre, func(t *testing.T) {
+			conexion.Limpiar()
+			if err := operacion(db.WithContext(ctx)); err != nil {
+				t.Fatal(err)
+			}
+			sentencias := conexion.Sentencias()
+			if len(sentencias) == 0 {
+				t.Fatal("no llegó ninguna sentencia")
+			}
+			for _, s := range sentencias {
+				if !strings.Contains(s.SQL, `"aulas"`) {
+					continue
+				}
+				if org, ok := filtroDe(s, `"aulas"."organizacion_id"`); !ok || org != orgPrueba {
+					t.Errorf("falta el filtro de organización %d en %q %v", orgPrueba, s.SQL, s.Args)
+				}
+			}
+		})
+	}
+
+	t.Run("Global no filtra", func(t *testing.T) {
+		conexion.Limpiar()
+		if err := db.WithContext(Global(context.Background())).Find(&[]aula{}).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range conexion.Sentencias() {
+			if strings.Contains(s.SQL, "organizacion_id") {
+				t.Errorf("el modo global no debe filtrar: %q", s.SQL)
+			}
+		}
+	})
+}
+
+func TestAsignaAlCrear(t *testing.T) {
+	db, conexion := abrir(t)
+	ctx := Con(context.Background(), orgPrueba)
+
+	t.Run("struct", func(t *testing.T) {
+		nueva := aula{Nombre: "A1", OrganizacionID: orgAjena}
+		if err := db.WithContext(ctx).Create(&nueva).Error; err != nil {
+			t.Fatal(err)
+		}
+		if nueva.OrganizacionID != orgPrueba {
+			t.Errorf("OrganizacionID = %d, se esperaba la del contexto %d", nueva.OrganizacionID, orgPrueba)
+		}
+	})
+
+	t.Run("lote", func(t *testing.T) {
+		nuevas := []aula{{Nombre: "A1"}, {Nombre: "A2", OrganizacionID: orgAjena}}
+		if err := db.WithContext(ctx).Create(&nuevas).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range nuevas {
+			if a.OrganizacionID != orgPrueba {
+				t.Errorf("%s: OrganizacionID = %d, se esperaba %d", a.Nombre, a.OrganizacionID, orgPrueba)
+			}
+		}
+	})
+
+	t.Run("mapa", func(t *testing.T) {
+		conexion.Limpiar()
+		if err := db.WithContext(ctx).Model(&aula{}).Create(map[string]any{"nombre": "A1"}).Error; err != nil {
+			t.Fatal(err)
+		}
+		insert := conexion.Buscar(`INSERT INTO "aulas"`)
+		if len(insert) != 1 || !strings.Contains(insert[0].SQL, "organizacion_id") {
+			t.Fatalf("el INSERT debe llevar organizacion_id: %v", insert)
+		}
+	})
+
+	t.Run("global exige organización", func(t *testing.T) {
+		global := Global(context.Background())
+		if err := db.WithContext(global).Create(&aula{Nombre: "A1"}).Error; !errors.Is(err, ErrSinOrganizacion) {
+			t.Fatalf("se esperaba ErrSinOrganizacion, se obtuvo %v", err)
+		}
+		nueva := aula{Nombre: "A1", OrganizacionID: orgAjena}
+		if err := db.WithContext(global).Create(&nueva).Error; err != nil || nueva.OrganizacionID != orgAjena {
+			t.Fatalf("en modo global se conserva la organización que trae el registro: %d, %v", nueva.OrganizacionID, err)
+		}
+	})
+}
+
+func TestReferenciaAjena(t *testing.T) {
+	db, conexion := abrir(t)
+	ctx := Con(context.Background(), orgPrueba)
+	anexo := uint(9)
+
+	operaciones := map[string]func(*gorm.DB) error{
+		"Create":          func(tx *gorm.DB) error { return tx.Create(&aula{Nombre: "A1", PlantelID: 5}).Error },
+		"Create opcional": func(tx *gorm.DB) error { return tx.Create(&aula{Nombre: "A1", AnexoID: &anexo}).Error },
+		"Create lote":     func(tx *gorm.DB) error { return tx.Create(&[]aula{{PlantelID: 5}, {PlantelID: 6}}).Error },
+		"Update":          func(tx *gorm.DB) error { return tx.Model(&aula{ID: 1}).Update("plantel_id", 5).Error },
+		"Updates mapa": func(tx *gorm.DB) error {
+			return tx.Model(&aula{ID: 1}).Updates(map[string]any{"plantel_id": uint(5)}).Error
+		},
+		"Updates struct": func(tx *gorm.DB) error { return tx.Model(&aula{ID: 1}).Updates(aula{PlantelID: 5}).Error },
+		"Save":           func(tx *gorm.DB) error { return tx.Save(&aula{ID: 1, Nombre: "A1", PlantelID: 5}).Error },
+	}
+	for nombre, operacion := range operaciones {
+		t.Run(nombre, func(t *testing.T) {
+			referenciasEncontradas(conexion, 0)
+			conexion.Limpiar()
+			err := operacion(db.WithContext(ctx))
+			if !errors.Is(err, ErrReferenciaAjena) {
+				t.Fatalf("se esperaba ErrReferenciaAjena, se obtuvo %v", err)
+			}
+			conteos := conexion.Buscar(`FROM "plantels"`)
+			if len(conteos) == 0 {
+				t.Fatal("no se buscó el registro relacionado")
+			}
+			if org, ok := filtroDe(conteos[0], `"organizacion_id"`); !ok || org != orgPrueba {
+				t.Fatalf("el conteo debe buscar en la organización %d: %q %v", orgPrueba, conteos[0].SQL, conteos[0].Args)
+			}
+			for _, s := range conexion.Sentencias() {
+				if strings.HasPrefix(s.SQL, "INSERT") || strings.HasPrefix(s.SQL, "UPDATE") {
+					t.Errorf("no debía guardarse nada: %q", s.SQL)
+				}
+			}
+
+			referenciasEncontradas(conexion, -1)
+			if err := operacion(db.WithContext(ctx)); err != nil {
+				t.Fatalf("con la referencia en la organización debía guardarse: %v", err)
+			}
+		})
+	}
+
+	t.Run("sin llave no consulta", func(t *testing.T) {
+		referenciasEncontradas(conexion, 0)
+		conexion.Limpiar()
+		if err := db.WithContext(ctx).Model(&aula{ID: 1}).Update("nombre", "A1").Error; err != nil {
+			t.Fatal(err)
+		}
+		if s := conexion.Buscar(`"plantels"`); len(s) > 0 {
+			t.Errorf("sin llave foránea en el cambio no hay nada que revisar: %q", s[0].SQL)
+		}
+	})
+}
+
+// usuario tiene borrado lógico, como models.User, para revisar que el filtro conviva con el de deleted_at
+type usuario struct {
+	ID             uint
+	OrganizacionID uint
+	Email          string
+	CURP           string
+	DeletedAt      gorm.DeletedAt
+}
+
+// orAgrupado es el WHERE correcto para Where(email).Or(curp): el OR completo y después la organización
+var orAgrupado = regexp.MustCompile(`WHERE \(email = \$\d+ OR curp = \$\d+\) AND "usuarios"\."organizacion_id" = \$\d+`)
+
+func TestOrNoEscapaDelFiltro(t *testing.T) {
+	db, conexion := abrir(t)
+	ctx := Con(context.Background(), orgPrueba)
+
+	operaciones := map[string]func(*gorm.DB) error{
+		"Count": func(tx *gorm.DB) error {
+			var n int64
+			return tx.Model(&usuario{}).Where("email = ?", "a@b.mx").Or("curp = ?", "X").Count(&n).Error
+		},
+		"Find": func(tx *gorm.DB) error {
+			return tx.Where("email = ?", "a@b.mx").Or("curp = ?", "X").Find(&[]usuario{}).Error
+		},
+		"solo Or": func(tx *gorm.DB) error { return tx.Or("curp = ?", "X").Find(&[]usuario{}).Error },
+		"Update": func(tx *gorm.DB) error {
+			return tx.Model(&usuario{}).Where("email = ?", "a@b.mx").Or("curp = ?", "X").Update("email", "c@d.mx").Error
+		},
+		"Delete": func(tx *gorm.DB) error {
+			return tx.Where("email = ?", "a@b.mx").Or("curp = ?", "X").Delete(&usuario{}).Error
+		},
+	}
+	for nombre, operacion := range operaciones {
+		t.Run(nombre, func(t *testing.T) {
+			conexion.Limpiar()
+			if err := operacion(db.WithContext(ctx)); err != nil {
+				t.Fatal(err)
+			}
+			sentencias := conexion.Buscar(`"usuarios"`)
+			if len(sentencias) != 1 {
+				t.Fatalf("se esperaba una sentencia, hubo %d", len(sentencias))
+			}
+			s := sentencias[0]
+			if org, ok := filtroDe(s, `"usuarios"."organizacion_id"`); !ok || org != orgPrueba {
+				t.Fatalf("falta el filtro de organización %d en %q %v", orgPrueba, s.SQL, s.Args)
+			}
+			if strings.Contains(s.SQL, " OR ") && !orAgrupado.MatchString(s.SQL) {
+				t.Errorf("las condiciones con OR deben ir entre paréntesis antes del filtro: %q", s.SQL)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+// Package parche interpreta los cuerpos de PATCH. El formato principal es JSON Merge Patch (RFC 7396):
+// los campos presentes se reemplazan, null vacía el campo y los ausentes no cambian. Con Content-Type
+// application/json-patch+json también se aceptan las operaciones add, replace, remove y test de JSON Patch
+// (RFC 6902), que se traducen al mismo conjunto de cambios.
+//
+// Cada recurso declara una lista blanca: una estructura con los campos que se pueden modificar, con el mismo
+// nombre de Go que en el modelo y sus reglas de binding. Solo se validan los campos que trae el parche.
+package parche
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"api-margaritai/errores"
+)
+
+const (
+	TipoMergePatch = "application/merge-patch+json"
+	TipoJSONPatch  = "application/json-patch+json"
+)
+
+var tipoFecha = reflect.TypeOf(time.Time{})
+
+// Parche es el cuerpo de un PATCH ya revisado contra la lista blanca
+type Parche struct {
+	campos  map[string]bool // rutas JSON presentes, separadas por punto: "telefono_2", "user.curp"
+	valores reflect.Value   // la lista blanca con los valores nuevos
+}
+
+// operacion es un elemento de un JSON Patch
+type operacion struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Leer interpreta el cuerpo contra la lista blanca destino (puntero a estructura) y deja en ella los valores
+// nuevos. actual es el registro que se edita; de él salen los valores que comparan las operaciones test.
+func Leer(c *gin.Context, destino any, actual any) (*Parche, *errores.Error) {
+	cuerpo, err := c.GetRawData()
+	if err != nil {
+		return nil, errores.SolicitudInvalida(errores.DatosInvalidos, "No se pudo leer el cuerpo de la solicitud").ConCausa(err)
+	}
+	if len(bytes.TrimSpace(cuerpo)) == 0 {
+		return nil, errores.SolicitudInvalida(errores.DatosInvalidos, "El cuerpo de la solicitud está vacío")
+	}
+
+	tipo := reflect.TypeOf(destino).Elem()
+	var cambios map[string]any
+	var e *errores.Error
+	switch c.ContentType() {
+	case TipoMergePatch, binding.MIMEJSON, "":
+		cambios, e = desdeMergePatch(cuerpo)
+	case TipoJSONPatch:
+		cambios, e = desdeOperaciones(cuerpo, documento(tipo, reflect.ValueOf(actual)))
+	default:
+		return nil, errores.Nuevo(http.StatusUnsupportedMediaType, errores.TipoParcheNoSoportado,
+			"Envíe el parche como "+TipoMergePatch+" o "+TipoJSONPatch)
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	p := &Parche{campos: map[string]bool{}}
+	var camposGo []string
+	if e := revisar(cambios, tipo, "", "", p.campos, &camposGo); e != nil {
+		return nil, e
+	}
+
+	crudo, err := json.Marshal(cambios)
+	if err != nil {
+		return nil, errores.Interno("No se pudo preparar el parche", err)
+	}
+	if err := json.Unmarshal(crudo, destino); err != nil {
+		return nil, errores.Validacion(err)
+	}
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok && len(camposGo) > 0 {
+		if err := v.StructPartial(destino, camposGo...); err != nil {
+			return nil, errores.Validacion(err)
+		}
+	}
+	p.valores = reflect.ValueOf(destino).Elem()
+	return p, nil
+}
+
+// Toca indica si el parche trae alguno de los campos o algo dentro de ellos: Toca("user") es cierto con user.curp
+func (p *Parche) Toca(rutas ...string) bool {
+	for _, ruta := range rutas {
+		if toca(p.campos, ruta) {
+			return true
+		}
+	}
+	return false
+}
+
+// Aplicar copia al registro (puntero al modelo) los campos que trae el parche. Cada campo de la lista blanca
+// va al campo del modelo con el mismo nombre; las fechas llegan como YYYY-MM-DD y null las deja en cero.
+func (p *Parche) Aplicar(registro any) {
+	aplicar(p.valores, reflect.ValueOf(registro).Elem(), "", p.campos)
+}
+
+func desdeMergePatch(cuerpo []byte) (map[string]any, *errores.Error) {
+	var cambios map[string]any
+	if err := decodificar(cuerpo, &cambios); err != nil || cambios == nil {
+		return nil, errores.SolicitudInvalida(errores.DatosInvalidos, "El parche debe ser un objeto JSON")
+	}
+	return cambios, nil
+}
+
+// desdeOperaciones aplica las operaciones en orden sobre un merge patch vacío; test compara contra lo que ya
+// cambió el mismo parche o, si no lo tocó, contra el documento actual
+func desdeOperaciones(cuerpo []byte, actual map[string]any) (map[string]any, *errores.Error) {
+	var ops []operacion
+	if err := json.Unmarshal(cuerpo, &ops); err != nil {
+		return nil, errores.SolicitudInvalida(errores.DatosInvalidos, "El parche debe ser un arreglo de operaciones JSON Patch")
+	}
+
+	cambios := map[string]any{}
+	for i, op := range ops {
+		ruta, ok := segmentos(op.Path)
+		if !ok {
+			return nil, errores.CampoInvalido(fmt.Sprintf("[%d].path", i), "json_pointer", "Debe ser un JSON Pointer hacia un campo, por ejemplo /user/nombre")
+		}
+		var valor any
+		if op.Op == "add" || op.Op == "replace" || op.Op == "test" {
+			if len(op.Value) == 0 {
+				return nil, errores.CampoInvalido(fmt.Sprintf("[%d].value", i), "required", "Este campo es obligatorio")
+			}
+			if err := decodificar(op.Value, &valor); err != nil {
+				return nil, errores.CampoInvalido(fmt.Sprintf("[%d].value", i), "json", "Debe ser un valor JSON válido")
+			}
+		}
+
+		switch op.Op {
+		case "add", "replace":
+			poner(cambios, ruta, valor)
+		case "remove":
+			poner(cambios, ruta, nil)
+		case "test":
+			vigente, presente := valorEn(cambios, ruta)
+			if !presente {
+				vigente, _ = valorEn(actual, ruta)
+			}
+			if !iguales(vigente, valor) {
+				return nil, errores.Conflicto(errores.PruebaParcheFallida, "La operación test sobre "+op.Path+" no coincide con el valor actual")
+			}
+		default:
+			return nil, errores.SolicitudInvalida(errores.OperacionNoSoportada,
+				fmt.Sprintf("La operación %q no está soportada; use add, replace, remove o test", op.Op))
+		}
+	}
+	return cambios, nil
+}
+
+// revisar recorre los cambios contra la lista blanca: rechaza los campos que no están en ella y null en los
+// que no se pueden vaciar, y junta las rutas JSON (para Toca y Aplicar) y las de Go (para StructPartial)
+func revisar(cambios map[string]any, tipo reflect.Type, rutaJSON, rutaGo string, campos map[string]bool, camposGo *[]string) *errores.Error {
+	nombres := make([]string, 0, len(cambios))
+	for nombre := range cambios {
+		nombres = append(nombres, nombre)
+	}
+	sort.Strings(nombres)
+
+	for _, nombre := range nombres {
+		ruta := rutaJSON + nombre
+		campo, ok := campoJSON(tipo, nombre)
+		if !ok {
+			return errorDeCampo(errores.CampoNoModificable, ruta, "no_modificable", "Este campo no existe o no se puede modificar")
+		}
+		valor := cambios[nombre]
+
+		if esObjeto(campo.Type) {
+			sub, ok := valor.(map[string]any)
+			if !ok {
+				if valor == nil {
+					return errorDeCampo(errores.CampoNoAnulable, ruta, "no_anulable", "Este campo no se puede vaciar")
+				}
+				return errores.CampoInvalido(ruta, "type", "Debe ser un objeto")
+			}
+			if e := revisar(sub, elemento(campo.Type), ruta+".", rutaGo+campo.Name+".", campos, camposGo); e != nil {
+				return e
+			}
+			continue
+		}
+
+		if valor == nil && campo.Type.Kind() != reflect.String {
+			return errorDeCampo(errores.CampoNoAnulable, ruta, "no_anulable", "Este campo no se puede vaciar")
+		}
+		campos[ruta] = true
+		*camposGo = append(*camposGo, rutaGo+campo.Name)
+	}
+	return nil
+}
+
+func aplicar(origen, destino reflect.Value, ruta string, campos map[string]bool) {
+	for i := 0; i < origen.NumField(); i++ {
+		campo := origen.Type().Field(i)
+		nombre := nombreJSON(campo)
+		if nombre == "" {
+			continue
+		}
+		valor := origen.Field(i)
+
+		if esObjeto(campo.Type) {
+			if !toca(campos, ruta+nombre) {
+				continue
+			}
+			for valor.Kind() == reflect.Pointer {
+				valor = valor.Elem()
+			}
+			aplicar(valor, campoDe(destino, campo.Name), ruta+nombre+".", campos)
+			continue
+		}
+		if campos[ruta+nombre] {
+			asignar(campoDe(destino, campo.Name), valor)
+		}
+	}
+}
+
+func toca(campos map[string]bool, ruta string) bool {
+	for campo := range campos {
+		if campo == ruta || strings.HasPrefix(campo, ruta+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func asignar(destino, valor reflect.Value) {
+	if destino.Type() == tipoFecha && valor.Kind() == reflect.String {
+		var fecha time.Time
+		if texto := valor.String(); texto != "" {
+			fecha, _ = time.Parse(time.DateOnly, texto) // la regla datetime de la lista blanca ya lo validó
+		}
+		destino.Set(reflect.ValueOf(fecha))
+		return
+	}
+	destino.Set(valor.Convert(destino.Type()))
+}
+
+// campoDe busca en el modelo el campo de la lista blanca; si no existe la lista blanca está mal declarada
+func campoDe(modelo reflect.Value, nombre string) reflect.Value {
+	campo := modelo.FieldByName(nombre)
+	if !campo.IsValid() {
+		panic("parche: el modelo " + modelo.Type().Name() + " no tiene el campo " + nombre)
+	}
+	return campo
+}
+
+// documento arma, con los nombres JSON de la lista blanca, los valores vigentes del registro
+func documento(tipo reflect.Type, registro reflect.Value) map[string]any {
+	for registro.Kind() == reflect.Pointer || registro.Kind() == reflect.Interface {
+		if registro.IsNil() {
+			return nil
+		}
+		registro = registro.Elem()
+	}
+	if registro.Kind() != reflect.Struct {
+		return nil
+	}
+
+	doc := map[string]any{}
+	for i := 0; i < tipo.NumField(); i++ {
+		campo := tipo.Field(i)
+		nombre := nombreJSON(campo)
+		origen := registro.FieldByName(campo.Name)
+		if nombre == "" || !origen.IsValid() {
+			continue
+		}
+		switch {
+		case esObjeto(campo.Type):
+			doc[nombre] = documento(elemento(campo.Type), origen)
+		case origen.Type() == tipoFecha:
+			doc[nombre] = origen.Interface().(time.Time).Format(time.DateOnly)
+		default:
+			doc[nombre] = origen.Interface()
+		}
+	}
+	return doc
+}
+
+func errorDeCampo(codigo, campo, regla, mensaje string) *errores.Error {
+	e := errores.SolicitudInvalida(codigo, "Los datos enviados no son válidos")
+	e.Campos = []errores.CampoError{{Campo: campo, Regla: regla, Mensaje: mensaje}}
+	return e
+}
+
+// segmentos separa un JSON Pointer (RFC 6901): "/user/nombre" -> [user nombre]
+func segmentos(puntero string) ([]string, bool) {
+	if !strings.HasPrefix(puntero, "/") {
+		return nil, false
+	}
+	partes := strings.Split(puntero[1:], "/")
+	for i, parte := range partes {
+		if parte == "" {
+			return nil, false
+		}
+		partes[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(parte)
+	}
+	return partes, true
+}
+
+func poner(m map[string]any, ruta []string, valor any) {
+	for _, seg := range ruta[:len(ruta)-1] {
+		sub, ok := m[seg].(map[string]any)
+		if !ok {
+			sub = map[string]any{}
+			m[seg] = sub
+		}
+		m = sub
+	}
+	m[ruta[len(ruta)-1]] = valor
+}
+
+func valorEn(m map[string]any, ruta []string) (any, bool) {
+	for _, seg := range ruta[:len(ruta)-1] {
+		sub, ok := m[seg].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		m = sub
+	}
+	valor, ok := m[ruta[len(ruta)-1]]
+	return valor, ok
+}
+
+// iguales compara dos valores por su representación JSON, para que 3, 3.0 y uint(3) coincidan
+func iguales(a, b any) bool {
+	normalizar := func(v any) any {
+		crudo, _ := json.Marshal(v)
+		var n any
+		json.Unmarshal(crudo, &n)
+		return n
+	}
+	return reflect.DeepEqual(normalizar(a), normalizar(b))
+}
+
+func decodificar(datos []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(datos))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+func campoJSON(tipo reflect.Type, nombre string) (reflect.StructField, bool) {
+	for i := 0; i < tipo.NumField(); i++ {
+		if campo := tipo.Field(i); nombreJSON(campo) == nombre {
+			return campo, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func nombreJSON(campo reflect.StructField) string {
+	if !campo.IsExported() {
+		return ""
+	}
+	nombre, _, _ := strings.Cut(campo.Tag.Get("json"), ",")
+	if nombre == "-" {
+		return ""
+	}
+	return nombre
+}
+
+// esObjeto indica un campo anidado de la lista blanca, como user; las fechas son texto
+func esObjeto(t reflect.Type) bool {
+	t = elemento(t)
+	return t.Kind() == reflect.Struct && t != tipoFecha
+}
+
+func elemento(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,201 @@
+package parche
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/errores"
+)
+
+// Lista blanca y modelo de prueba, con la misma forma que las de los controladores: un objeto anidado user
+type usuarioParche struct {
+	Nombre   string `json:"nombre" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	FechaNac string `json:"fecha_nac" binding:"required,datetime=2006-01-02"`
+	GeneroID uint   `json:"genero_id" binding:"required"`
+}
+
+type alumnoParche struct {
+	Matricula string        `json:"matricula" binding:"required"`
+	Telefono  string        `json:"telefono"`
+	GrupoID   uint          `json:"grupo_id" binding:"required"`
+	Notas     string        `json:"notas/a~b"` // nombre con / y ~ para probar el escape de JSON Pointer
+	User      usuarioParche `json:"user"`
+}
+
+type usuario struct {
+	ID       uint
+	Nombre   string
+	Email    string
+	FechaNac time.Time
+	GeneroID uint
+}
+
+type alumno struct {
+	ID        uint
+	Matricula string
+	Telefono  string
+	GrupoID   uint
+	Notas     string
+	User      usuario
+}
+
+func alumnoActual() *alumno {
+	return &alumno{
+		ID: 1, Matricula: "A1", Telefono: "5512345678", GrupoID: 7, Notas: "ninguna",
+		User: usuario{ID: 2, Nombre: "Ana", Email: "ana@escuela.mx", FechaNac: time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC), GeneroID: 1},
+	}
+}
+
+// leer arma una petición PATCH con el cuerpo y el tipo dados y la interpreta contra alumnoParche
+func leer(t *testing.T, tipo, cuerpo string, actual *alumno) (*Parche, *alumnoParche, *errores.Error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPatch, "/alumnos/1", strings.NewReader(cuerpo))
+	c.Request.Header.Set("Content-Type", tipo)
+	var destino alumnoParche
+	p, e := Leer(c, &destino, actual)
+	return p, &destino, e
+}
+
+func TestMergePatchAplicaSoloLoPresente(t *testing.T) {
+	actual := alumnoActual()
+	p, _, e := leer(t, TipoMergePatch, `{"telefono": null, "user": {"email": "ana.g@escuela.mx", "fecha_nac": "2001-03-04"}}`, actual)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !p.Toca("user") || !p.Toca("user.email") || p.Toca("matricula") || p.Toca("user.nombre") {
+		t.Errorf("Toca no corresponde a los campos del parche: %v", p.campos)
+	}
+
+	p.Aplicar(actual)
+	esperado := alumnoActual()
+	esperado.Telefono = ""
+	esperado.User.Email = "ana.g@escuela.mx"
+	esperado.User.FechaNac = time.Date(2001, 3, 4, 0, 0, 0, 0, time.UTC)
+	if !reflect.DeepEqual(actual, esperado) {
+		t.Errorf("registro = %+v, se esperaba %+v", actual, esperado)
+	}
+}
+
+func TestRevisarContraListaBlanca(t *testing.T) {
+	casos := []struct {
+		nombre, cuerpo, codigo, campo string
+	}{
+		{"null en un número", `{"grupo_id": null}`, errores.CampoNoAnulable, "grupo_id"},
+		{"null en el objeto anidado", `{"user": null}`, errores.CampoNoAnulable, "user"},
+		{"null dentro de user", `{"user": {"genero_id": null}}`, errores.CampoNoAnulable, "user.genero_id"},
+		{"campo fuera de la lista", `{"id": 5}`, errores.CampoNoModificable, "id"},
+		{"campo fuera de la lista dentro de user", `{"user": {"password": "secreta"}}`, errores.CampoNoModificable, "user.password"},
+		{"user que no es objeto", `{"user": "ana"}`, errores.ValidacionFallida, "user"},
+		{"regla del campo presente", `{"user": {"email": "no-es-email"}}`, errores.ValidacionFallida, "user.email"},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			_, _, e := leer(t, TipoMergePatch, c.cuerpo, alumnoActual())
+			if e == nil {
+				t.Fatal("se esperaba un error")
+			}
+			if e.Status != http.StatusBadRequest || e.Codigo != c.codigo {
+				t.Errorf("error %d %s, se esperaba 400 %s", e.Status, e.Codigo, c.codigo)
+			}
+			if len(e.Campos) == 0 || !strings.EqualFold(e.Campos[0].Campo, c.campo) {
+				t.Errorf("campos = %+v, se esperaba %s", e.Campos, c.campo)
+			}
+		})
+	}
+
+	// Solo se validan los campos presentes: el resto de la lista blanca queda vacío y no falla required
+	if _, _, e := leer(t, TipoMergePatch, `{"matricula": "B2"}`, alumnoActual()); e != nil {
+		t.Errorf("un parche parcial válido falló: %v", e)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	casos := []struct {
+		nombre, cuerpo string
+		conflicto      bool
+	}{
+		{"test contra el documento actual", `[{"op": "test", "path": "/matricula", "value": "A1"}, {"op": "replace", "path": "/matricula", "value": "B2"}]`, false},
+		{"test que no coincide", `[{"op": "test", "path": "/matricula", "value": "Z9"}, {"op": "replace", "path": "/matricula", "value": "B2"}]`, true},
+		{"test después de cambiar el campo", `[{"op": "replace", "path": "/matricula", "value": "B2"}, {"op": "test", "path": "/matricula", "value": "B2"}]`, false},
+		{"test con el valor anterior a un cambio", `[{"op": "replace", "path": "/matricula", "value": "B2"}, {"op": "test", "path": "/matricula", "value": "A1"}]`, true},
+		{"test de número", `[{"op": "test", "path": "/grupo_id", "value": 7}]`, false},
+		{"test de fecha anidada", `[{"op": "test", "path": "/user/fecha_nac", "value": "2001-02-03"}]`, false},
+		{"test de usuario anidado", `[{"op": "test", "path": "/user/nombre", "value": "Beatriz"}]`, true},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			_, _, e := leer(t, TipoJSONPatch, c.cuerpo, alumnoActual())
+			switch {
+			case c.conflicto && (e == nil || e.Status != http.StatusConflict || e.Codigo != errores.PruebaParcheFallida):
+				t.Errorf("error = %v, se esperaba %s", e, errores.PruebaParcheFallida)
+			case !c.conflicto && e != nil:
+				t.Errorf("error inesperado: %v", e)
+			}
+		})
+	}
+
+	actual := alumnoActual()
+	p, _, e := leer(t, TipoJSONPatch, `[{"op": "replace", "path": "/user/nombre", "value": "Beatriz"}, {"op": "remove", "path": "/telefono"}]`, actual)
+	if e != nil {
+		t.Fatal(e)
+	}
+	p.Aplicar(actual)
+	if actual.User.Nombre != "Beatriz" || actual.Telefono != "" || actual.User.Email != "ana@escuela.mx" {
+		t.Errorf("registro = %+v", actual)
+	}
+
+	if _, _, e := leer(t, TipoJSONPatch, `[{"op": "move", "from": "/telefono", "path": "/notas~1a~0b"}]`, alumnoActual()); e == nil || e.Codigo != errores.OperacionNoSoportada {
+		t.Errorf("move: error = %v, se esperaba %s", e, errores.OperacionNoSoportada)
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	casos := []struct {
+		puntero string
+		partes  []string
+	}{
+		{"/user/nombre", []string{"user", "nombre"}},
+		{"/notas~1a~0b", []string{"notas/a~b"}},
+		// ~01 es "~1" literal: ~0 se reemplaza después de ~1, no antes
+		{"/a~01", []string{"a~1"}},
+		{"", nil},
+		{"matricula", nil},
+		{"/user//nombre", nil},
+	}
+	for _, c := range casos {
+		partes, ok := segmentos(c.puntero)
+		if ok != (c.partes != nil) || !reflect.DeepEqual(partes, c.partes) {
+			t.Errorf("segmentos(%q) = %q, %v; se esperaba %q", c.puntero, partes, ok, c.partes)
+		}
+	}
+
+	// El nombre escapado llega al campo de la lista blanca, tanto en replace como en test
+	actual := alumnoActual()
+	p, _, e := leer(t, TipoJSONPatch, `[{"op": "test", "path": "/notas~1a~0b", "value": "ninguna"}, {"op": "replace", "path": "/notas~1a~0b", "value": "alergia"}]`, actual)
+	if e != nil {
+		t.Fatal(e)
+	}
+	p.Aplicar(actual)
+	if actual.Notas != "alergia" {
+		t.Errorf("Notas = %q, se esperaba alergia", actual.Notas)
+	}
+
+	if _, _, e := leer(t, TipoJSONPatch, `[{"op": "replace", "path": "user/nombre", "value": "Beatriz"}]`, alumnoActual()); e == nil || len(e.Campos) == 0 || e.Campos[0].Regla != "json_pointer" {
+		t.Errorf("ruta sin /: error = %v, se esperaba json_pointer", e)
+	}
+}
+
+func TestTipoNoSoportado(t *testing.T) {
+	if _, _, e := leer(t, "text/plain", `{"matricula": "B2"}`, alumnoActual()); e == nil || e.Status != http.StatusUnsupportedMediaType {
+		t.Errorf("error = %v, se esperaba 415", e)
+	}
+}
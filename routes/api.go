@@ -2,25 +2,47 @@
 package routes
 
 import (
+	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
+	"api-margaritai/cache"
 	"api-margaritai/controllers"
 	gestioncatalogos "api-margaritai/controllers/gestion_catalogos"
 	gestionusuarios "api-margaritai/controllers/gestion_usuarios"
+	"api-margaritai/docs"
+	"api-margaritai/errores"
 	"api-margaritai/middleware"
+	"api-margaritai/models"
+	"api-margaritai/validadores"
 )
 
 func SetupRouter() *gin.Engine {
-	r := gin.Default()
+	r := gin.New()
+	// Los handlers pasan c como context.Context a database.De; así c.Value llega a la organización guardada en c.Request
+	r.ContextWithFallback = true
+	r.Use(gin.Logger(), gin.CustomRecovery(errores.Recuperar))
+
+	// La IP del cliente (para los límites por IP) solo se toma de X-Forwarded-For si viene de un proxy confiable
+	if err := r.SetTrustedProxies(proxiesConfiables()); err != nil {
+		log.Fatal("PROXIES_CONFIABLES inválido: ", err)
+	}
+
+	// Identificador por petición y formato único de errores
+	errores.ConfigurarValidador()
+	validadores.Registrar()
+	r.Use(middleware.RequestID(), errores.ManejarErrores())
 
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:8081", "http://localhost:3000"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "Accept", "Cache-Control", "X-Requested-With", "ngrok-skip-browser-warning"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowOrigins: []string{"http://localhost:8081", "http://localhost:3000"},
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+		AllowHeaders: []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "Accept", "Cache-Control", "X-Requested-With", "ngrok-skip-browser-warning", middleware.HeaderRequestID, "If-Match", "If-None-Match", "If-Modified-Since", middleware.HeaderIdempotencyKey, middleware.HeaderOrganizacion},
+		ExposeHeaders: []string{"Content-Length", "Content-Disposition", middleware.HeaderRequestID, "ETag", "Last-Modified", middleware.HeaderIdempotentReplayed,
+			middleware.HeaderRetryAfter, middleware.HeaderRateLimitLimit, middleware.HeaderRateLimitRemaining, middleware.HeaderRateLimitReset, middleware.HeaderRateLimitPolicy},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -30,15 +52,40 @@ func SetupRouter() *gin.Engine {
 		c.Status(200)
 	})
 
-	api := r.Group("/api")
+	// Especificación OpenAPI y Swagger UI
+	r.GET("/openapi.json", docs.ServirEspecificacion)
+	r.GET("/docs", docs.ServirSwaggerUI)
+
+	// Los POST con Idempotency-Key se pueden reintentar sin duplicar registros
+	idempotencia := middleware.Idempotencia()
+
+	// Límites de peticiones (peticiones/periodo); login y registro son más estrictos porque cada intento calcula un bcrypt
+	limiteIP := middleware.Limitar(middleware.ReglaDesdeEnv("api", "LIMITE_API_IP", "300/1m"), middleware.PorIP)
+	limiteAuth := middleware.Limitar(middleware.ReglaDesdeEnv("auth", "LIMITE_AUTH_IP", "10/1m"), middleware.PorIP)
+	limiteUsuario := middleware.Limitar(middleware.ReglaDesdeEnv("usuario", "LIMITE_USUARIO", "600/1m"), middleware.PorUsuario)
+
+	// Catálogos que cambian poco: las respuestas GET se guardan en memoria y se revalidan con ETag/Last-Modified;
+	// las escrituras que pasan por el mismo middleware invalidan el grupo
+	cachePermisos := middleware.CacheCatalogo(cache.GrupoPermisos)
+	cacheNivelesEscolares := middleware.CacheCatalogo(cache.GrupoNivelesEscolares)
+	invalidaConPlantel := middleware.InvalidarCache(cache.GrupoNivelesEscolares, cache.GrupoAulas) // los niveles y las aulas incluyen su plantel
+
+	// El permiso y sus categorías son comunes a todas las organizaciones; solo la principal los modifica
+	principal := middleware.RequiereOrganizacionPrincipal()
+
+	// Cada petición a /api va a una organización (X-Organizacion, subdominio o la principal)
+	api := r.Group("/api", limiteIP, middleware.Organizacion())
 	{
-		api.POST("/register", controllers.Register)
-		api.POST("/login", controllers.Login)
+		// Sin idempotencia: la respuesta guardada sería un token vigente
+		api.POST("/register", limiteAuth, controllers.Register)
+		api.POST("/login", limiteAuth, controllers.Login)
 		api.GET("/validate-token", controllers.ValidateToken)
+		api.GET("/documentos/descargar", gestionusuarios.DescargarDocumentoLocal) // Descarga con URL firmada (solo almacenamiento local)
+		api.GET("/horarios/calendario.ics", gestioncatalogos.ObtenerCalendario)   // Calendario de horarios de un usuario con enlace firmado
 	}
 
 	protected := api.Group("/protected")
-	protected.Use(middleware.JWTAuth())
+	protected.Use(middleware.JWTAuth(), limiteUsuario, idempotencia)
 	{
 		protected.GET("/profile", func(c *gin.Context) {
 			userID := c.MustGet("user_id").(uint)
@@ -46,119 +93,204 @@ func SetupRouter() *gin.Engine {
 		})
 		// Agrega el endpoint de logout
 		protected.POST("/logout", controllers.Logout)
+		protected.GET("/organizacion", controllers.ObtenerOrganizacion)
 
 		// Endpoints especiales de roles (para obtener por tipo)
-		protected.GET("/roles/para_estudiante", controllers.ObtenerRolesEstudiante)
-		protected.GET("/roles/para_personal", controllers.ObtenerRolesPersonal)
-		protected.GET("/roles/para_tutor", controllers.ObtenerRolesTutor)
+		protected.GET("/roles/para_estudiante", cachePermisos, controllers.ObtenerRolesEstudiante)
+		protected.GET("/roles/para_personal", cachePermisos, controllers.ObtenerRolesPersonal)
+		protected.GET("/roles/para_tutor", cachePermisos, controllers.ObtenerRolesTutor)
 
 		// Endpoints para roles
-		protected.GET("/roles", controllers.GetRoles)
-		protected.POST("/roles", controllers.CreateRole)
+		protected.GET("/roles", cachePermisos, controllers.GetRoles)
+		protected.POST("/roles", cachePermisos, controllers.CreateRole)
 
 		// Rutas específicas de roles (deben ir antes que las rutas con parámetros)
-		protected.GET("/roles/:id/permisos", controllers.GetPermisosDeRol)
-		protected.GET("/roles/:id/permisos_agrupados", controllers.GetPermisosByRolId)
-		protected.GET("/roles/:id/permisos_con_asignacion", controllers.GetRolePermisosConEstadoAsignacion)
+		protected.GET("/roles/:id/permisos", cachePermisos, controllers.GetPermisosDeRol)
+		protected.GET("/roles/:id/permisos_agrupados", cachePermisos, controllers.GetPermisosByRolId)
+		protected.GET("/roles/:id/permisos_con_asignacion", cachePermisos, controllers.GetRolePermisosConEstadoAsignacion)
 
 		// Rutas generales de roles (con parámetros)
 		protected.GET("/roles/:id", controllers.GetRole)
-		protected.PUT("/roles/:id", controllers.UpdateRole)
-		protected.DELETE("/roles/:id", controllers.DeleteRole)
+		protected.PUT("/roles/:id", cachePermisos, controllers.UpdateRole)
+		protected.PATCH("/roles/:id", cachePermisos, controllers.PatchRole)
+		protected.DELETE("/roles/:id", cachePermisos, controllers.DeleteRole)
 
 		// Endpoints para permisos
-		protected.GET("/permisos", controllers.GetPermisos)
-		protected.POST("/permisos", controllers.CreatePermiso)
+		protected.GET("/permisos", cachePermisos, controllers.GetPermisos)
+		protected.POST("/permisos", principal, cachePermisos, controllers.CreatePermiso)
 
 		// Rutas específicas de permisos (deben ir antes que las rutas con parámetros)
-		protected.GET("/permisos/:id/roles", controllers.GetRolesDePermiso)
+		protected.GET("/permisos/:id/roles", cachePermisos, controllers.GetRolesDePermiso)
 
 		// Rutas generales de permisos (con parámetros)
 		protected.GET("/permisos/:id", controllers.GetPermiso)
-		protected.PUT("/permisos/:id", controllers.UpdatePermiso)
-		protected.DELETE("/permisos/:id", controllers.DeletePermiso)
+		protected.PUT("/permisos/:id", principal, cachePermisos, controllers.UpdatePermiso)
+		protected.DELETE("/permisos/:id", principal, cachePermisos, controllers.DeletePermiso)
 
 		//endpoint para categorias_permisos
-		protected.GET("/categorias_permisos", controllers.GetCategoriasPermisos)
+		protected.GET("/categorias_permisos", cachePermisos, controllers.GetCategoriasPermisos)
 		protected.GET("/categorias_permisos/:id", controllers.GetCategoriaPermiso)
-		protected.POST("/categorias_permisos", controllers.CreateCategoriaPermiso)
-		protected.PUT("/categorias_permisos/:id", controllers.UpdateCategoriaPermiso)
-		protected.DELETE("/categorias_permisos/:id", controllers.DeleteCategoriaPermiso)
+		protected.POST("/categorias_permisos", principal, cachePermisos, controllers.CreateCategoriaPermiso)
+		protected.PUT("/categorias_permisos/:id", principal, cachePermisos, controllers.UpdateCategoriaPermiso)
+		protected.DELETE("/categorias_permisos/:id", principal, cachePermisos, controllers.DeleteCategoriaPermiso)
 
 		// Endpoints para role_tiene_permiso
-		protected.GET("/roles_tienen_permisos", controllers.GetRolesTienenPermisos)
+		protected.GET("/roles_tienen_permisos", cachePermisos, controllers.GetRolesTienenPermisos)
 		protected.GET("/roles_tienen_permisos/:role_id/:permiso_id", controllers.GetRoleTienePermiso)
-		protected.POST("/roles_tienen_permisos", controllers.CreateRoleTienePermiso)
-		protected.DELETE("/roles_tienen_permisos/:role_id/:permiso_id", controllers.DeleteRoleTienePermiso)
-		protected.POST("/roles/asignar_permisos", controllers.AsignarPermisosARol)
-		protected.POST("/roles/desasignar_permisos", controllers.DesasignarPermisosARol)
+		protected.POST("/roles_tienen_permisos", cachePermisos, controllers.CreateRoleTienePermiso)
+		protected.DELETE("/roles_tienen_permisos/:role_id/:permiso_id", cachePermisos, controllers.DeleteRoleTienePermiso)
+		protected.POST("/roles/asignar_permisos", cachePermisos, controllers.AsignarPermisosARol)
+		protected.POST("/roles/desasignar_permisos", cachePermisos, controllers.DesasignarPermisosARol)
 
 		// ---------- Rutas de gestión de catálogos: Planteles --------------
-		protected.GET("/planteles", gestioncatalogos.ObtenerPlanteles)       // Obtener todos los planteles
-		protected.POST("/planteles", gestioncatalogos.CrearPlantel)          // Crear un nuevo plantel
-		protected.PUT("/planteles/:id", gestioncatalogos.EditarPlantel)      // Editar un plantel existente
-		protected.DELETE("/planteles/:id", gestioncatalogos.EliminarPlantel) // Eliminar un plantel si cumple las restricciones
+		protected.GET("/planteles", gestioncatalogos.ObtenerPlanteles)                           // Obtener todos los planteles
+		protected.POST("/planteles", invalidaConPlantel, gestioncatalogos.CrearPlantel)          // Crear un nuevo plantel
+		protected.PUT("/planteles/:id", invalidaConPlantel, gestioncatalogos.EditarPlantel)      // Editar un plantel existente
+		protected.PATCH("/planteles/:id", invalidaConPlantel, gestioncatalogos.ParchearPlantel)  // Editar solo los campos enviados (JSON Merge Patch)
+		protected.DELETE("/planteles/:id", invalidaConPlantel, gestioncatalogos.EliminarPlantel) // Eliminar un plantel si cumple las restricciones
 
 		// ---------- Rutas de gestión de catálogos: Niveles Escolares --------------
-		protected.GET("/niveles_escolares", gestioncatalogos.ObtenerNivelesEscolares)     // Obtener todos los niveles escolares o filtrados
-		protected.POST("/niveles_escolares", gestioncatalogos.CrearNivelEscolar)          // Crear un nuevo nivel escolar
-		protected.PUT("/niveles_escolares/:id", gestioncatalogos.EditarNivelEscolar)      // Editar un nivel escolar existente
-		protected.DELETE("/niveles_escolares/:id", gestioncatalogos.EliminarNivelEscolar) // Eliminar un nivel escolar si cumple las restricciones
-
-		// ---------- RUTAS DE GESTIÓN DE CATÁLOGOS: GRADOS --------------
-		protected.GET("/grados", gestioncatalogos.ObtenerGrados)        // Obtener todos los grados registrados
-		protected.POST("/grados", gestioncatalogos.InsertarGrado)       // Insertar un nuevo grado
-		protected.PUT("/grados/:id", gestioncatalogos.EditarGrado)      // Editar un grado existente
-		protected.DELETE("/grados/:id", gestioncatalogos.EliminarGrado) // Eliminar un grado solo si no tiene materias relacionadas
-
-		// ---------- RUTAS DE GESTIÓN DE CATÁLOGOS: GRUPOS --------------
-		protected.GET("/grupos", gestioncatalogos.ObtenerGrupos)        // Obtener todos los grupos
-		protected.POST("/grupos", gestioncatalogos.InsertarGrupo)       // Crear un nuevo grupo
-		protected.PUT("/grupos/:id", gestioncatalogos.EditarGrupo)      // Editar un grupo existente
-		protected.DELETE("/grupos/:id", gestioncatalogos.EliminarGrupo) // Eliminar un grupo existente
-
-		// ---------- RUTAS DE GESTIÓN DE CATÁLOGOS: GRADOS ACADÉMICOS --------------
-		protected.GET("/grados_academicos", gestioncatalogos.ObtenerGradoAcademico)         // Obtener todos los grados académicos
-		protected.POST("/grados_academicos", gestioncatalogos.InsertarGradoAcademico)       // Crear un nuevo grado académico
-		protected.PUT("/grados_academicos/:id", gestioncatalogos.EditarGradoAcademico)      // Editar un grado académico existente
-		protected.DELETE("/grados_academicos/:id", gestioncatalogos.EliminarGradoAcademico) // Eliminar un grado académico existente
-
-		// ---------- RUTAS DE GESTIÓN DE CATÁLOGOS: ESTATUS LABORALES --------------
-		protected.GET("/estatus_laborales", gestioncatalogos.ObtenerEstatusLaborales)         // Obtener todos los estatus laborales
-		protected.POST("/estatus_laborales", gestioncatalogos.InsertarEstatusLaborales)       // Crear un nuevo estatus laboral
-		protected.PUT("/estatus_laborales/:id", gestioncatalogos.EditarEstatusLaborales)      // Editar un estatus laboral existente
-		protected.DELETE("/estatus_laborales/:id", gestioncatalogos.EliminarEstatusLaborales) // Eliminar un estatus laboral existente
-
-		// ---------- RUTAS DE GESTIÓN DE CATÁLOGOS: ESTATUS EMPLEADOS --------------
-		protected.GET("/estatus_empleados", gestioncatalogos.ObtenerEstatusEmpleados)        // Obtener todos los estatus de empleados
-		protected.POST("/estatus_empleados", gestioncatalogos.InsertarEstatusEmpleado)       // Crear un nuevo estatus de empleado
-		protected.PUT("/estatus_empleados/:id", gestioncatalogos.EditarEstatusEmpleado)      // Editar un estatus de empleado existente
-		protected.DELETE("/estatus_empleados/:id", gestioncatalogos.EliminarEstatusEmpleado) // Eliminar un estatus de empleado existente
-
-		// ---------- RUTAS DE GESTIÓN DE CATÁLOGOS: PUESTOS --------------
-		protected.GET("/puestos", gestioncatalogos.ObtenerPuestos)        // Obtener todos los puestos
-		protected.POST("/puestos", gestioncatalogos.InsertarPuesto)       // Crear un nuevo puesto
-		protected.PUT("/puestos/:id", gestioncatalogos.EditarPuesto)      // Editar un puesto existente
-		protected.DELETE("/puestos/:id", gestioncatalogos.EliminarPuesto) // Eliminar un puesto existente
+		protected.GET("/niveles_escolares", cacheNivelesEscolares, gestioncatalogos.ObtenerNivelesEscolares)     // Obtener todos los niveles escolares o filtrados
+		protected.POST("/niveles_escolares", cacheNivelesEscolares, gestioncatalogos.CrearNivelEscolar)          // Crear un nuevo nivel escolar
+		protected.PUT("/niveles_escolares/:id", cacheNivelesEscolares, gestioncatalogos.EditarNivelEscolar)      // Editar un nivel escolar existente
+		protected.DELETE("/niveles_escolares/:id", cacheNivelesEscolares, gestioncatalogos.EliminarNivelEscolar) // Eliminar un nivel escolar si cumple las restricciones
+
+		// ---------- RUTAS DE GESTIÓN DE CATÁLOGOS: GRADOS, GRUPOS, GRADOS ACADÉMICOS, ESTATUS, PUESTOS, GÉNEROS, TIPOS DE CONTRATO Y AULAS --------------
+		// Cada catálogo registra listar, obtener, crear, editar y eliminar (este último solo si nada depende del registro)
+		protected.GET("/aulas/disponibles", gestioncatalogos.ObtenerAulasDisponibles) // Aulas activas libres en un día y rango de horas
+		for _, catalogo := range gestioncatalogos.Catalogos {
+			var cacheCatalogo []gin.HandlerFunc
+			if grupo := catalogo.Datos().Cache; grupo != "" {
+				cacheCatalogo = append(cacheCatalogo, middleware.CacheCatalogo(grupo))
+			}
+			catalogo.Registrar(protected, cacheCatalogo...)
+		}
+		protected.GET("/grados/:id/materias", gestioncatalogos.ObtenerPlanEstudios)   // Plan de estudios: materias del grado en orden, con créditos y horas
+		protected.PUT("/grados/:id/materias", gestioncatalogos.AsignarMateriasAGrado) // Reemplazar las materias del grado y su orden
+
+		// ---------- RUTAS DE CICLOS ESCOLARES --------------
+		// Un ciclo pasa de planeación a activo y de activo a cerrado; cada nivel tiene a lo más un ciclo activo
+		protected.GET("/ciclos_escolares", gestioncatalogos.ObtenerCiclosEscolares)                       // Ciclos escolares, filtrables por nivel y estatus
+		protected.GET("/ciclos_escolares/:id", gestioncatalogos.ObtenerCicloEscolar)                      // Un ciclo con sus periodos de evaluación
+		protected.POST("/ciclos_escolares", gestioncatalogos.CrearCicloEscolar)                           // Crear un ciclo en planeación con sus bimestres o trimestres
+		protected.PUT("/ciclos_escolares/:id", gestioncatalogos.EditarCicloEscolar)                       // Editar fechas y periodos de un ciclo que no está cerrado
+		protected.DELETE("/ciclos_escolares/:id", gestioncatalogos.EliminarCicloEscolar)                  // Eliminar un ciclo en planeación sin grupos
+		protected.POST("/ciclos_escolares/:id/activar", gestioncatalogos.ActivarCicloEscolar)             // Activar el ciclo y pasar a los inscritos a sus grupos nuevos
+		protected.POST("/ciclos_escolares/:id/cerrar", gestioncatalogos.CerrarCicloEscolar)               // Cerrar el ciclo; sus grupos e inscripciones quedan como historial
+		protected.GET("/ciclos_escolares/:id/inscripciones", gestionusuarios.ObtenerInscripcionesDeCiclo) // Estudiantes inscritos en el ciclo y su grupo
+		protected.POST("/ciclos_escolares/:id/inscripciones", gestionusuarios.InscribirEstudiante)        // Inscribir a un estudiante en un grupo del ciclo
+		protected.DELETE("/inscripciones/:id", gestionusuarios.EliminarInscripcion)                       // Quitar una inscripción de un ciclo que no está cerrado
+		protected.GET("/estudiantes/:id/inscripciones", gestionusuarios.ObtenerInscripcionesDeEstudiante) // Historial de ciclos y grupos de un estudiante
+
+		// ---------- RUTAS DE HORARIOS --------------
+		// Cada clase une grupo, materia, profesor y aula en un día y rango de horas; se rechazan las que chocan
+		protected.GET("/horarios", gestioncatalogos.ObtenerHorarios)                                 // Clases filtrables por ciclo, grupo, materia, profesor, aula y día
+		protected.GET("/horarios/calendario", gestioncatalogos.ObtenerEnlaceCalendario)              // Enlace firmado al calendario iCalendar del usuario de la sesión
+		protected.POST("/horarios/calendario/regenerar", gestioncatalogos.RegenerarEnlaceCalendario) // Invalidar el enlace al calendario y obtener uno nuevo
+		protected.GET("/horarios/:id", gestioncatalogos.ObtenerHorario)                              // Una clase; su ETag se usa en If-Match al editarla
+		protected.POST("/horarios", gestioncatalogos.CrearHorario)                                   // Agregar una clase si no choca con el grupo, el profesor o el aula
+		protected.PUT("/horarios/:id", gestioncatalogos.EditarHorario)                               // Editar una clase de un ciclo que no está cerrado
+		protected.DELETE("/horarios/:id", gestioncatalogos.EliminarHorario)                          // Quitar una clase de un ciclo que no está cerrado
+		protected.GET("/grupos/:id/horario", gestioncatalogos.ObtenerHorarioDeGrupo)                 // Semana de clases de un grupo
+		protected.GET("/personal/:id/horario", gestioncatalogos.ObtenerHorarioDeProfesor)            // Semana de clases de un profesor
+		protected.GET("/aulas/:id/horario", gestioncatalogos.ObtenerHorarioDeAula)                   // Semana de clases de un aula
 
 		// ---------- RUTAS DE GESTIÓN DE USUARIOS: ESTUDIANTES --------------
 		protected.GET("/estudiantes", gestionusuarios.ObtenerEstudiantes)        // Obtener todos los estudiantes con su usuario
+		protected.GET("/estudiantes/:id", gestionusuarios.ObtenerEstudiante)     // Obtener un estudiante; su ETag se usa en If-Match al editarlo
 		protected.POST("/estudiantes", gestionusuarios.InsertarEstudiante)       // Crear un estudiante (usuario + estudiante)
 		protected.PUT("/estudiantes/:id", gestionusuarios.EditarEstudiante)      // Editar datos de un estudiante y su usuario
+		protected.PATCH("/estudiantes/:id", gestionusuarios.ParchearEstudiante)  // Editar solo los campos enviados (JSON Merge Patch)
 		protected.DELETE("/estudiantes/:id", gestionusuarios.EliminarEstudiante) // Eliminar a un estudiante y su usuario asociado
 
 		// ---------- RUTAS DE GESTIÓN DE USUARIOS: PERSONAL --------------
-		protected.GET("/personal", gestionusuarios.ObtenerPersonal)         // Obtener la lista de personal con su usuario asociado
-		protected.POST("/personal", gestionusuarios.InsertarPersonal)       // Crear un nuevo personal y usuario asociado
-		protected.PUT("/personal/:id", gestionusuarios.EditarPersonal)      // Editar datos de personal y su usuario
-		protected.DELETE("/personal/:id", gestionusuarios.EliminarPersonal) // Eliminar un registro de personal y su usuario asociado
+		protected.GET("/personal", gestionusuarios.ObtenerPersonal)          // Obtener la lista de personal con su usuario asociado
+		protected.GET("/personal/:id", gestionusuarios.ObtenerPersonalPorID) // Obtener un registro de personal; su ETag se usa en If-Match al editarlo
+		protected.POST("/personal", gestionusuarios.InsertarPersonal)        // Crear un nuevo personal y usuario asociado
+		protected.PUT("/personal/:id", gestionusuarios.EditarPersonal)       // Editar datos de personal y su usuario
+		protected.PATCH("/personal/:id", gestionusuarios.ParchearPersonal)   // Editar solo los campos enviados (JSON Merge Patch)
+		protected.DELETE("/personal/:id", gestionusuarios.EliminarPersonal)  // Eliminar un registro de personal y su usuario asociado
 
 		// ---------- RUTAS DE GESTIÓN DE USUARIOS: TUTORES --------------
 		protected.GET("/tutores", gestionusuarios.ObtenerTutores)       // Obtener la lista de tutores con su usuario asociado
+		protected.GET("/tutores/:id", gestionusuarios.ObtenerTutor)     // Obtener un tutor; su ETag se usa en If-Match al editarlo
 		protected.POST("/tutores", gestionusuarios.InsertarTutor)       // Crear un tutor y su usuario asociado
 		protected.PUT("/tutores/:id", gestionusuarios.EditarTutor)      // Editar los datos de un tutor y su usuario asociado
+		protected.PATCH("/tutores/:id", gestionusuarios.ParchearTutor)  // Editar solo los campos enviados (JSON Merge Patch)
 		protected.DELETE("/tutores/:id", gestionusuarios.EliminarTutor) // Eliminar un tutor y su usuario asociado
+
+		// ---------- RUTAS DE IMPORTACIÓN DE ESTUDIANTES --------------
+		importar := middleware.RequierePermiso(models.PermisoImportarEstudiantes)
+		protected.POST("/estudiantes/importar", importar, gestionusuarios.ImportarEstudiantes) // Alta en bloque desde CSV o XLSX; dry_run=true solo valida
+		protected.GET("/importaciones/:id", importar, gestionusuarios.ObtenerImportacion)      // Estado y reporte por fila de una importación
+
+		// ---------- RUTAS DE DOCUMENTOS --------------
+		// Expedientes de estudiantes, personal y contratos; las descargas son URLs firmadas que vencen
+		verDocumentos := middleware.RequierePermiso(models.PermisoVerDocumentos)
+		subirDocumentos := middleware.RequierePermiso(models.PermisoSubirDocumentos)
+		protected.GET("/estudiantes/:id/documentos", verDocumentos, gestionusuarios.ObtenerDocumentos(models.DocumentoDeEstudiante)) // Documentos de un estudiante
+		protected.POST("/estudiantes/:id/documentos", subirDocumentos, gestionusuarios.SubirDocumento(models.DocumentoDeEstudiante)) // Subir acta, CURP, fotografía...
+		protected.GET("/personal/:id/documentos", verDocumentos, gestionusuarios.ObtenerDocumentos(models.DocumentoDePersonal))      // Documentos de un empleado
+		protected.POST("/personal/:id/documentos", subirDocumentos, gestionusuarios.SubirDocumento(models.DocumentoDePersonal))      // Subir título, cédula, identificación...
+		protected.GET("/contratos/:id/documentos", verDocumentos, gestionusuarios.ObtenerDocumentos(models.DocumentoDeContrato))     // Documentos de un contrato
+		protected.POST("/contratos/:id/documentos", subirDocumentos, gestionusuarios.SubirDocumento(models.DocumentoDeContrato))     // Subir el contrato firmado o un anexo
+		protected.GET("/documentos/:id/descarga", verDocumentos, gestionusuarios.ObtenerDescargaDocumento)                           // URL firmada para descargar un documento
+		protected.DELETE("/documentos/:id", subirDocumentos, gestionusuarios.EliminarDocumento)                                      // Eliminar un documento y su archivo
+
+		// ---------- RUTAS DE EXPORTACIÓN --------------
+		// Aceptan los mismos filtros y orden que el listado JSON y ?formato=csv|xlsx|pdf
+		exportar := middleware.RequierePermiso(models.PermisoExportarListados)
+		protected.GET("/estudiantes/exportar", exportar, gestionusuarios.ExportarEstudiantes) // Descargar el listado de estudiantes
+		protected.GET("/personal/exportar", exportar, gestionusuarios.ExportarPersonal)       // Descargar el listado de personal
+		protected.GET("/tutores/exportar", exportar, gestionusuarios.ExportarTutores)         // Descargar el listado de tutores
+		protected.GET("/grupos/exportar", exportar, gestioncatalogos.ExportarGrupos)          // Descargar el listado de grupos
+		protected.GET("/roles/exportar", exportar, controllers.ExportarRoles)                 // Descargar el listado de roles
+
+		// ---------- RUTA DE BÚSQUEDA DE PERSONAS --------------
+		protected.GET("/busqueda", gestionusuarios.BuscarPersonas) // Buscar estudiantes, personal y tutores según los permisos del rol
+
+		// ---------- RUTAS DE PAPELERA --------------
+		papelera := protected.Group("/papelera")
+		papelera.GET("/:tipo", middleware.RequierePermiso(models.PermisoRestaurarRegistros), controllers.ObtenerPapelera)                    // Registros eliminados de un tipo
+		papelera.POST("/:tipo/:id/restaurar", middleware.RequierePermiso(models.PermisoRestaurarRegistros), controllers.RestaurarDePapelera) // Restaurar un registro eliminado
+		papelera.DELETE("/:tipo/:id", middleware.RequierePermiso(models.PermisoPurgarRegistros), controllers.PurgarDePapelera)               // Eliminar definitivamente un registro de la papelera
+
+		// ---------- RUTAS DE REPORTES --------------
+		reportes := protected.Group("/reportes", middleware.RequierePermiso(models.PermisoVerReportes))
+		reportes.GET("/curp_inconsistencias", gestionusuarios.ReporteInconsistenciasCURP) // Usuarios cuyos datos contradicen su CURP
+
+		// ---------- RUTAS DE WEBHOOKS --------------
+		webhooks := protected.Group("/webhooks", middleware.RequierePermiso(models.PermisoAdministrarWebhooks))
+		webhooks.GET("/tipos_eventos", controllers.ObtenerTiposEventos)                            // Eventos a los que se puede suscribir
+		webhooks.GET("/suscripciones", controllers.ObtenerSuscripcionesWebhook)                    // Listar suscripciones
+		webhooks.GET("/suscripciones/:id", controllers.ObtenerSuscripcionWebhook)                  // Obtener una suscripción; su ETag se usa en If-Match al editarla
+		webhooks.POST("/suscripciones", controllers.CrearSuscripcionWebhook)                       // Registrar una suscripción; regresa el secreto una sola vez
+		webhooks.PUT("/suscripciones/:id", controllers.EditarSuscripcionWebhook)                   // Editar URL, eventos o si está activa
+		webhooks.DELETE("/suscripciones/:id", controllers.EliminarSuscripcionWebhook)              // Eliminar una suscripción
+		webhooks.POST("/suscripciones/:id/rotar_secreto", controllers.RotarSecretoWebhook)         // Generar un secreto nuevo
+		webhooks.POST("/suscripciones/:id/reenviar_fallidas", controllers.ReenviarFallidasWebhook) // Reprogramar las entregas fallidas de la suscripción
+		webhooks.GET("/entregas", controllers.ObtenerEntregasWebhook)                              // Historial de entregas con su evento
+		webhooks.POST("/entregas/:id/reenviar", controllers.ReenviarEntregaWebhook)                // Reprogramar una entrega
+
+		// ---------- RUTAS DE TAREAS PROGRAMADAS --------------
+		tareasProgramadas := protected.Group("/tareas", principal, middleware.RequierePermiso(models.PermisoAdministrarTareas))
+		tareasProgramadas.GET("", controllers.ObtenerTareas)                         // Tareas con su horario, candado y última ejecución
+		tareasProgramadas.GET("/ejecuciones", controllers.ObtenerEjecucionesTareas)  // Historial de ejecuciones
+		tareasProgramadas.GET("/ejecuciones/:id", controllers.ObtenerEjecucionTarea) // Una ejecución, para seguir una lanzada a mano
+		tareasProgramadas.POST("/:nombre/ejecutar", controllers.EjecutarTarea)       // Ejecutar una tarea fuera de su horario
 	}
 
 	return r
 }
+
+// proxiesConfiables lee PROXIES_CONFIABLES (IPs o CIDR separados por coma). Vacío significa que no hay
+// proxy y se usa la IP de la conexión; detrás de un balanceador hay que configurarlo o todos compartirán límite.
+func proxiesConfiables() []string {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("PROXIES_CONFIABLES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"api-margaritai/docs"
+)
+
+// Toda ruta de SetupRouter debe tener su entrada en docs/rutas.go
+func TestRutasDocumentadas(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if faltantes := docs.RutasSinDocumentar(SetupRouter().Routes()); len(faltantes) > 0 {
+		t.Fatalf("Rutas sin documentar en la especificación OpenAPI: %v", faltantes)
+	}
+}
+
+// La especificación se arma sin base de datos y debe tener todas sus rutas
+func TestEspecificacion(t *testing.T) {
+	especificacion := docs.Especificacion()
+	rutas, ok := especificacion["paths"].(map[string]map[string]any)
+	if !ok {
+		t.Fatalf("La especificación no tiene paths: %T", especificacion["paths"])
+	}
+	if len(rutas) == 0 {
+		t.Fatal("La especificación no tiene rutas")
+	}
+}
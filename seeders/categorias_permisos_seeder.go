@@ -11,6 +11,11 @@ import (
 func InsertarCategoriasPermisosIniciales() {
 	categorias := []models.CategoriaPermiso{
 		{Titulo: "Gestión de roles y permisos", Descripcion: "Permisos relacionados con la administración de roles y sus permisos asociados.", Icono: "security"},
+		{Titulo: "Reportes", Descripcion: "Permisos para consultar reportes administrativos.", Icono: "assessment"},
+		{Titulo: "Gestión de usuarios", Descripcion: "Permisos para consultar estudiantes, personal y tutores.", Icono: "people"},
+		{Titulo: "Papelera", Descripcion: "Permisos para restaurar o eliminar definitivamente registros eliminados.", Icono: "delete"},
+		{Titulo: "Integraciones", Descripcion: "Permisos para conectar sistemas externos por medio de webhooks.", Icono: "webhook"},
+		{Titulo: "Sistema", Descripcion: "Permisos para supervisar los procesos internos de la API.", Icono: "settings"},
 	}
 
 	for _, categoria := range categorias {
@@ -3,12 +3,13 @@ package seeders
 import (
 	"log"
 
-	"api-margaritai/database"
+	"gorm.io/gorm"
+
 	"api-margaritai/models"
 )
 
 // InsertarEstatusEmpleadosIniciales inserta los registros iniciales de estatus de empleados
-func InsertarEstatusEmpleadosIniciales() {
+func InsertarEstatusEmpleadosIniciales(db *gorm.DB) {
 	estatus := []models.EstatusEmpleado{
 		{Titulo: "Activo"},
 		{Titulo: "Inactivo"},
@@ -18,10 +19,10 @@ func InsertarEstatusEmpleadosIniciales() {
 
 	for _, est := range estatus {
 		var existing models.EstatusEmpleado
-		result := database.DB.Where("titulo = ?", est.Titulo).First(&existing)
+		result := db.Where("titulo = ?", est.Titulo).First(&existing)
 
 		if result.Error != nil {
-			if err := database.DB.Create(&est).Error; err != nil {
+			if err := db.Create(&est).Error; err != nil {
 				log.Printf("Error insertando estatus empleado %s: %v", est.Titulo, err)
 			} else {
 				log.Printf("Estatus empleado '%s' insertado exitosamente", est.Titulo)
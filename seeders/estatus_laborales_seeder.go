@@ -3,12 +3,13 @@ package seeders
 import (
 	"log"
 
-	"api-margaritai/database"
+	"gorm.io/gorm"
+
 	"api-margaritai/models"
 )
 
 // InsertarEstatusLaboralesIniciales inserta los registros iniciales de estatus laborales
-func InsertarEstatusLaboralesIniciales() {
+func InsertarEstatusLaboralesIniciales(db *gorm.DB) {
 	estatus := []models.EstatusLaboral{
 		{Titulo: "Contratado"},
 		{Titulo: "Por horas"},
@@ -18,10 +19,10 @@ func InsertarEstatusLaboralesIniciales() {
 
 	for _, est := range estatus {
 		var existing models.EstatusLaboral
-		result := database.DB.Where("titulo = ?", est.Titulo).First(&existing)
+		result := db.Where("titulo = ?", est.Titulo).First(&existing)
 
 		if result.Error != nil {
-			if err := database.DB.Create(&est).Error; err != nil {
+			if err := db.Create(&est).Error; err != nil {
 				log.Printf("Error insertando estatus laboral %s: %v", est.Titulo, err)
 			} else {
 				log.Printf("Estatus laboral '%s' insertado exitosamente", est.Titulo)
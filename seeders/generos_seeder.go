@@ -3,12 +3,13 @@ package seeders
 import (
 	"log"
 
-	"api-margaritai/database"
+	"gorm.io/gorm"
+
 	"api-margaritai/models"
 )
 
 // InsertarGenerosIniciales inserta los registros iniciales de género
-func InsertarGenerosIniciales() {
+func InsertarGenerosIniciales(db *gorm.DB) {
 	generos := []models.Genero{
 		{Nombre: "Masculino"},
 		{Nombre: "Femenino"},
@@ -18,11 +19,11 @@ func InsertarGenerosIniciales() {
 	for _, genero := range generos {
 		// Verificar si el género ya existe
 		var existingGenero models.Genero
-		result := database.DB.Where("nombre = ?", genero.Nombre).First(&existingGenero)
+		result := db.Where("nombre = ?", genero.Nombre).First(&existingGenero)
 
 		if result.Error != nil {
 			// Si no existe, crearlo
-			if err := database.DB.Create(&genero).Error; err != nil {
+			if err := db.Create(&genero).Error; err != nil {
 				log.Printf("Error insertando género %s: %v", genero.Nombre, err)
 			} else {
 				log.Printf("Género '%s' insertado exitosamente", genero.Nombre)
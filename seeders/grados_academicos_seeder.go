@@ -3,12 +3,13 @@ package seeders
 import (
 	"log"
 
-	"api-margaritai/database"
+	"gorm.io/gorm"
+
 	"api-margaritai/models"
 )
 
 // InsertarGradosAcademicosIniciales inserta los registros iniciales de grados académicos
-func InsertarGradosAcademicosIniciales() {
+func InsertarGradosAcademicosIniciales(db *gorm.DB) {
 	grados := []models.GradoAcademico{
 		{Titulo: "Licenciatura"},
 		{Titulo: "Maestría"},
@@ -19,10 +20,10 @@ func InsertarGradosAcademicosIniciales() {
 
 	for _, grado := range grados {
 		var existing models.GradoAcademico
-		result := database.DB.Where("titulo = ?", grado.Titulo).First(&existing)
+		result := db.Where("titulo = ?", grado.Titulo).First(&existing)
 
 		if result.Error != nil {
-			if err := database.DB.Create(&grado).Error; err != nil {
+			if err := db.Create(&grado).Error; err != nil {
 				log.Printf("Error insertando grado académico %s: %v", grado.Titulo, err)
 			} else {
 				log.Printf("Grado académico '%s' insertado exitosamente", grado.Titulo)
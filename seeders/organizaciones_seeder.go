@@ -0,0 +1,48 @@
+package seeders
+
+import (
+	"context"
+	"log"
+
+	"api-margaritai/database"
+	"api-margaritai/models"
+	"api-margaritai/organizacion"
+)
+
+// CrearOrganizacion regresa la organización con ese slug, creándola si no existe
+func CrearOrganizacion(slug, nombre string) models.Organizacion {
+	org := models.Organizacion{Slug: slug, Nombre: nombre, Activa: true}
+	if err := database.DB.Where(models.Organizacion{Slug: slug}).FirstOrCreate(&org).Error; err != nil {
+		log.Fatalf("Error creando la organización '%s': %v", slug, err)
+	}
+	return org
+}
+
+// SembrarOrganizacion inserta los catálogos y roles iniciales de la organización y asigna los permisos
+// del Administrador. Los permisos y sus categorías son comunes y deben existir antes.
+func SembrarOrganizacion(org models.Organizacion) {
+	log.Printf("Sembrando la organización '%s'...", org.Slug)
+	db := database.De(organizacion.Con(context.Background(), org.ID))
+	InsertarGenerosIniciales(db)
+	InsertarEstatusEmpleadosIniciales(db)
+	InsertarEstatusLaboralesIniciales(db)
+	InsertarGradosAcademicosIniciales(db)
+	InsertarTiposContratosIniciales(db)
+	InsertarPuestosIniciales(db)
+	InsertarRolesIniciales(db)
+	AsignarPermisosAdministrador(db)
+}
+
+// SembrarOrganizaciones inserta los permisos comunes y siembra cada organización registrada
+func SembrarOrganizaciones() {
+	InsertarCategoriasPermisosIniciales()
+	InsertarPermisosIniciales()
+
+	var organizaciones []models.Organizacion
+	if err := database.DB.Order("id").Find(&organizaciones).Error; err != nil {
+		log.Fatalf("Error obteniendo las organizaciones: %v", err)
+	}
+	for _, org := range organizaciones {
+		SembrarOrganizacion(org)
+	}
+}
@@ -16,11 +16,48 @@ func InsertarPermisosIniciales() {
 		log.Fatalf("Error: Categoría de permiso 'Gestión de roles y permisos' no encontrada: %v", result.Error)
 	}
 
+	var categoriaReportes models.CategoriaPermiso
+	if err := database.DB.Where("titulo = ?", "Reportes").First(&categoriaReportes).Error; err != nil {
+		log.Fatalf("Error: Categoría de permiso 'Reportes' no encontrada: %v", err)
+	}
+
+	var categoriaUsuarios models.CategoriaPermiso
+	if err := database.DB.Where("titulo = ?", "Gestión de usuarios").First(&categoriaUsuarios).Error; err != nil {
+		log.Fatalf("Error: Categoría de permiso 'Gestión de usuarios' no encontrada: %v", err)
+	}
+
+	var categoriaPapelera models.CategoriaPermiso
+	if err := database.DB.Where("titulo = ?", "Papelera").First(&categoriaPapelera).Error; err != nil {
+		log.Fatalf("Error: Categoría de permiso 'Papelera' no encontrada: %v", err)
+	}
+
+	var categoriaIntegraciones models.CategoriaPermiso
+	if err := database.DB.Where("titulo = ?", "Integraciones").First(&categoriaIntegraciones).Error; err != nil {
+		log.Fatalf("Error: Categoría de permiso 'Integraciones' no encontrada: %v", err)
+	}
+
+	var categoriaSistema models.CategoriaPermiso
+	if err := database.DB.Where("titulo = ?", "Sistema").First(&categoriaSistema).Error; err != nil {
+		log.Fatalf("Error: Categoría de permiso 'Sistema' no encontrada: %v", err)
+	}
+
 	permisos := []models.Permiso{
 		{Titulo: "Ver roles", Descripcion: "Permite ver los roles del sistema", CategoriaPermisoID: categoriaPermiso.ID},
 		{Titulo: "Crear roles", Descripcion: "Permite crear nuevos roles en el sistema", CategoriaPermisoID: categoriaPermiso.ID},
 		{Titulo: "Editar roles", Descripcion: "Permite editar roles existentes en el sistema", CategoriaPermisoID: categoriaPermiso.ID},
 		{Titulo: "Eliminar roles", Descripcion: "Permite eliminar roles del sistema", CategoriaPermisoID: categoriaPermiso.ID},
+		{Titulo: models.PermisoVerReportes, Descripcion: "Permite consultar los reportes administrativos, como las inconsistencias con la CURP", CategoriaPermisoID: categoriaReportes.ID},
+		{Titulo: models.PermisoVerEstudiantes, Descripcion: "Permite encontrar estudiantes en la búsqueda de personas", CategoriaPermisoID: categoriaUsuarios.ID},
+		{Titulo: models.PermisoVerPersonal, Descripcion: "Permite encontrar personal en la búsqueda de personas", CategoriaPermisoID: categoriaUsuarios.ID},
+		{Titulo: models.PermisoVerTutores, Descripcion: "Permite encontrar tutores en la búsqueda de personas", CategoriaPermisoID: categoriaUsuarios.ID},
+		{Titulo: models.PermisoExportarListados, Descripcion: "Permite descargar los listados de estudiantes, personal, tutores, grupos y roles en CSV, XLSX o PDF", CategoriaPermisoID: categoriaReportes.ID},
+		{Titulo: models.PermisoImportarEstudiantes, Descripcion: "Permite dar de alta estudiantes y tutores en bloque desde un archivo CSV o XLSX", CategoriaPermisoID: categoriaUsuarios.ID},
+		{Titulo: models.PermisoVerDocumentos, Descripcion: "Permite listar los documentos de estudiantes, personal y contratos y obtener sus enlaces de descarga", CategoriaPermisoID: categoriaUsuarios.ID},
+		{Titulo: models.PermisoSubirDocumentos, Descripcion: "Permite subir y eliminar documentos de estudiantes, personal y contratos", CategoriaPermisoID: categoriaUsuarios.ID},
+		{Titulo: models.PermisoRestaurarRegistros, Descripcion: "Permite ver la papelera y restaurar registros eliminados", CategoriaPermisoID: categoriaPapelera.ID},
+		{Titulo: models.PermisoPurgarRegistros, Descripcion: "Permite eliminar definitivamente registros de la papelera", CategoriaPermisoID: categoriaPapelera.ID},
+		{Titulo: models.PermisoAdministrarWebhooks, Descripcion: "Permite registrar suscripciones de webhooks y revisar o reenviar sus entregas", CategoriaPermisoID: categoriaIntegraciones.ID},
+		{Titulo: models.PermisoAdministrarTareas, Descripcion: "Permite consultar las tareas programadas y su historial, y ejecutarlas fuera de horario", CategoriaPermisoID: categoriaSistema.ID},
 	}
 
 	for _, permiso := range permisos {
@@ -3,12 +3,13 @@ package seeders
 import (
 	"log"
 
-	"api-margaritai/database"
+	"gorm.io/gorm"
+
 	"api-margaritai/models"
 )
 
 // InsertarPuestosIniciales inserta los registros iniciales de puestos
-func InsertarPuestosIniciales() {
+func InsertarPuestosIniciales(db *gorm.DB) {
 	puestos := []models.Puesto{
 		{Titulo: "Director", PagoXHr: 500.0},
 		{Titulo: "Subdirector", PagoXHr: 400.0},
@@ -20,10 +21,10 @@ func InsertarPuestosIniciales() {
 
 	for _, puesto := range puestos {
 		var existing models.Puesto
-		result := database.DB.Where("titulo = ?", puesto.Titulo).First(&existing)
+		result := db.Where("titulo = ?", puesto.Titulo).First(&existing)
 
 		if result.Error != nil {
-			if err := database.DB.Create(&puesto).Error; err != nil {
+			if err := db.Create(&puesto).Error; err != nil {
 				log.Printf("Error insertando puesto %s: %v", puesto.Titulo, err)
 			} else {
 				log.Printf("Puesto '%s' insertado exitosamente", puesto.Titulo)
@@ -3,14 +3,15 @@ package seeders
 import (
 	"log"
 
-	"api-margaritai/database"
+	"gorm.io/gorm"
+
 	"api-margaritai/models"
 )
 
-// AsignarPermisosAdministrador asigna los permisos de gestión de roles al rol "Administrador"
-func AsignarPermisosAdministrador() {
+// AsignarPermisosAdministrador asigna los permisos de gestión de roles, reportes y consulta de usuarios al rol "Administrador"
+func AsignarPermisosAdministrador(db *gorm.DB) {
 	var adminRole models.Rol
-	result := database.DB.Where("nombre = ?", "Administrador").First(&adminRole)
+	result := db.Where("nombre = ?", "Administrador").First(&adminRole)
 
 	if result.Error != nil {
 		log.Fatalf("Error: Rol 'Administrador' no encontrado: %v", result.Error)
@@ -21,10 +22,22 @@ func AsignarPermisosAdministrador() {
 		"Crear roles",
 		"Editar roles",
 		"Eliminar roles",
+		models.PermisoVerReportes,
+		models.PermisoVerEstudiantes,
+		models.PermisoVerPersonal,
+		models.PermisoVerTutores,
+		models.PermisoImportarEstudiantes,
+		models.PermisoExportarListados,
+		models.PermisoVerDocumentos,
+		models.PermisoSubirDocumentos,
+		models.PermisoRestaurarRegistros,
+		models.PermisoPurgarRegistros,
+		models.PermisoAdministrarWebhooks,
+		models.PermisoAdministrarTareas,
 	}
 
 	var permisos []models.Permiso
-	result = database.DB.Where("titulo IN ?", permisosTitulos).Find(&permisos)
+	result = db.Where("titulo IN ?", permisosTitulos).Find(&permisos)
 
 	if result.Error != nil {
 		log.Fatalf("Error: No se pudieron encontrar los permisos: %v", result.Error)
@@ -33,11 +46,11 @@ func AsignarPermisosAdministrador() {
 	// Asignar permisos al rol de Administrador
 	for _, permiso := range permisos {
 		var roleTienePermiso models.RoleTienePermiso
-		result := database.DB.Where("role_id = ? AND permiso_id = ?", adminRole.ID, permiso.ID).First(&roleTienePermiso)
+		result := db.Where("role_id = ? AND permiso_id = ?", adminRole.ID, permiso.ID).First(&roleTienePermiso)
 
 		if result.Error != nil {
 			// Si no existe la asignación, crearla
-			if err := database.DB.Create(&models.RoleTienePermiso{RoleID: adminRole.ID, PermisoID: permiso.ID}).Error; err != nil {
+			if err := db.Create(&models.RoleTienePermiso{RoleID: adminRole.ID, PermisoID: permiso.ID}).Error; err != nil {
 				log.Printf("Error asignando permiso '%s' al rol 'Administrador': %v", permiso.Titulo, err)
 			} else {
 				log.Printf("Permiso '%s' asignado exitosamente al rol 'Administrador'", permiso.Titulo)
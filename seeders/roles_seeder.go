@@ -3,12 +3,13 @@ package seeders
 import (
 	"log"
 
-	"api-margaritai/database"
+	"gorm.io/gorm"
+
 	"api-margaritai/models"
 )
 
 // InsertarRolesIniciales inserta los registros iniciales de roles
-func InsertarRolesIniciales() {
+func InsertarRolesIniciales(db *gorm.DB) {
 	roles := []models.Rol{
 		{Nombre: "Administrador", Descripcion: "Acceso completo al sistema", ParaEstudiante: false, ParaPersonal: true},
 		{Nombre: "Director", Descripcion: "Gestión de plantel", ParaEstudiante: false, ParaPersonal: true},
@@ -19,10 +20,10 @@ func InsertarRolesIniciales() {
 
 	for _, rol := range roles {
 		var existing models.Rol
-		result := database.DB.Where("nombre = ?", rol.Nombre).First(&existing)
+		result := db.Where("nombre = ?", rol.Nombre).First(&existing)
 
 		if result.Error != nil {
-			if err := database.DB.Create(&rol).Error; err != nil {
+			if err := db.Create(&rol).Error; err != nil {
 				log.Printf("Error insertando rol %s: %v", rol.Nombre, err)
 			} else {
 				log.Printf("Rol '%s' insertado exitosamente", rol.Nombre)
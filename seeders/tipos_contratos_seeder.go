@@ -3,12 +3,13 @@ package seeders
 import (
 	"log"
 
-	"api-margaritai/database"
+	"gorm.io/gorm"
+
 	"api-margaritai/models"
 )
 
 // InsertarTiposContratosIniciales inserta los registros iniciales de tipos de contratos
-func InsertarTiposContratosIniciales() {
+func InsertarTiposContratosIniciales(db *gorm.DB) {
 	tipos := []models.TipoContrato{
 		{Titulo: "Tiempo completo"},
 		{Titulo: "Medio tiempo"},
@@ -19,10 +20,10 @@ func InsertarTiposContratosIniciales() {
 
 	for _, tipo := range tipos {
 		var existing models.TipoContrato
-		result := database.DB.Where("titulo = ?", tipo.Titulo).First(&existing)
+		result := db.Where("titulo = ?", tipo.Titulo).First(&existing)
 
 		if result.Error != nil {
-			if err := database.DB.Create(&tipo).Error; err != nil {
+			if err := db.Create(&tipo).Error; err != nil {
 				log.Printf("Error insertando tipo contrato %s: %v", tipo.Titulo, err)
 			} else {
 				log.Printf("Tipo contrato '%s' insertado exitosamente", tipo.Titulo)
@@ -0,0 +1,111 @@
+package tablas
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Formatos de exportación
+const (
+	FormatoCSV  = "csv"
+	FormatoXLSX = "xlsx"
+	FormatoPDF  = "pdf"
+)
+
+// Formatos lista los formatos que acepta NuevoEscritor
+var Formatos = []string{FormatoCSV, FormatoXLSX, FormatoPDF}
+
+// Columna describe una columna exportada. Ancho es el ancho aproximado en caracteres: el XLSX lo usa tal cual
+// y el PDF lo usa para repartir proporcionalmente el ancho de la página.
+type Columna struct {
+	Titulo string
+	Ancho  float64
+}
+
+// Escritor escribe las filas conforme llegan, sin acumular el archivo completo en memoria
+type Escritor interface {
+	Escribir(fila []string) error
+	// Cerrar escribe lo que el formato necesita al final; el io.Writer no se cierra
+	Cerrar() error
+}
+
+// NuevoEscritor crea un escritor del formato indicado y escribe el encabezado. El título solo aparece en el PDF.
+func NuevoEscritor(formato string, w io.Writer, titulo string, columnas []Columna) (Escritor, error) {
+	var e Escritor
+	switch strings.ToLower(formato) {
+	case FormatoCSV:
+		e = nuevoCSV(w)
+	case FormatoXLSX:
+		e = nuevoXLSX(w, titulo, columnas)
+	case FormatoPDF:
+		return nuevoPDF(w, titulo, columnas), nil // el PDF repite el encabezado en cada página
+	default:
+		return nil, ErrFormatoNoSoportado
+	}
+
+	titulos := make([]string, len(columnas))
+	for i, col := range columnas {
+		titulos[i] = col.Titulo
+	}
+	if err := e.Escribir(titulos); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// TipoContenido regresa el Content-Type de cada formato
+func TipoContenido(formato string) string {
+	switch formato {
+	case FormatoXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatoPDF:
+		return "application/pdf"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// escritorCSV escribe UTF-8 con BOM para que Excel respete los acentos al abrirlo
+type escritorCSV struct {
+	w       io.Writer
+	csv     *csv.Writer
+	escrito bool
+}
+
+func nuevoCSV(w io.Writer) *escritorCSV {
+	return &escritorCSV{w: w, csv: csv.NewWriter(w)}
+}
+
+func (e *escritorCSV) Escribir(fila []string) error {
+	if !e.escrito {
+		if _, err := io.WriteString(e.w, "\xEF\xBB\xBF"); err != nil {
+			return err
+		}
+		e.escrito = true
+	}
+	protegida := make([]string, len(fila))
+	for i, valor := range fila {
+		protegida[i] = sinFormula(valor)
+	}
+	return e.csv.Write(protegida)
+}
+
+func (e *escritorCSV) Cerrar() error {
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// sinFormula antepone un apóstrofo a los valores que una hoja de cálculo interpretaría como fórmula al abrir
+// el CSV (los que empiezan con =, +, -, @, tabulador o retorno de carro), como un nombre o correo capturado
+// con mala intención. Los números se dejan igual porque no pueden ser fórmulas. El XLSX no lo necesita:
+// escribe cada celda como texto en línea, que nunca se evalúa.
+func sinFormula(valor string) string {
+	if valor == "" || !strings.ContainsRune("=+-@\t\r", rune(valor[0])) {
+		return valor
+	}
+	if _, err := strconv.ParseFloat(valor, 64); err == nil {
+		return valor
+	}
+	return "'" + valor
+}
@@ -0,0 +1,262 @@
+package tablas
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Medidas en puntos de una hoja carta horizontal
+const (
+	pdfAncho         = 792.0
+	pdfAlto          = 612.0
+	pdfMargen        = 36.0
+	pdfAltoFila      = 13.0
+	pdfTamanoTexto   = 8.0
+	pdfTamanoTitulo  = 13.0
+	pdfRellenoCelda  = 3.0
+	pdfInicioTabla   = pdfAlto - pdfMargen - 34 // debajo del título y la fecha
+	pdfFinTabla      = pdfMargen + 16           // arriba del número de página
+	pdfObjetoPaginas = 2                        // se reserva para escribirlo al final, cuando se conocen las páginas
+)
+
+// escritorPDF arma una tabla paginada con las fuentes estándar Helvetica, que todo lector de PDF trae,
+// de modo que no hace falta incrustar fuentes. Cada página se envía en cuanto se llena;
+// solo el índice de objetos y la lista de páginas se escriben al final.
+type escritorPDF struct {
+	w        *contadorBytes
+	titulo   string
+	generado string
+	columnas []Columna
+	anchos   []float64 // en puntos
+	desplazs []int     // posición de cada objeto en el archivo, por número de objeto - 1
+	paginas  []int     // números de objeto de las páginas
+	pagina   bytes.Buffer
+	y        float64
+	filas    int
+	err      error
+}
+
+type contadorBytes struct {
+	w io.Writer
+	n int
+}
+
+func (c *contadorBytes) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+func nuevoPDF(w io.Writer, titulo string, columnas []Columna) *escritorPDF {
+	e := &escritorPDF{
+		w:        &contadorBytes{w: w},
+		titulo:   titulo,
+		generado: "Generado el " + time.Now().Format("02/01/2006 15:04"),
+		columnas: columnas,
+	}
+
+	total := 0.0
+	for _, col := range columnas {
+		total += max(col.Ancho, 1)
+	}
+	disponible := pdfAncho - 2*pdfMargen
+	for _, col := range columnas {
+		e.anchos = append(e.anchos, disponible*max(col.Ancho, 1)/total)
+	}
+
+	fmt.Fprint(e.w, "%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	e.objeto(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	e.desplazs = append(e.desplazs, 0) // el objeto 2 se escribe en Cerrar
+	e.objeto(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	e.objeto(4, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	return e
+}
+
+// objeto escribe un objeto indirecto y registra su posición para el índice
+func (e *escritorPDF) objeto(numero int, contenido string) {
+	if e.err != nil {
+		return
+	}
+	for len(e.desplazs) < numero {
+		e.desplazs = append(e.desplazs, 0)
+	}
+	e.desplazs[numero-1] = e.w.n
+	_, e.err = fmt.Fprintf(e.w, "%d 0 obj\n%s\nendobj\n", numero, contenido)
+}
+
+func (e *escritorPDF) Escribir(fila []string) error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.pagina.Len() == 0 {
+		e.empezarPagina()
+	}
+	if e.y-pdfAltoFila < pdfFinTabla {
+		e.terminarPagina()
+		e.empezarPagina()
+	}
+
+	e.filas++
+	if e.filas%2 == 0 {
+		fmt.Fprintf(&e.pagina, "0.94 g %.2f %.2f %.2f %.2f re f 0 g\n", pdfMargen, e.y-pdfAltoFila, pdfAncho-2*pdfMargen, pdfAltoFila)
+	}
+	e.fila(fila, "F1")
+	return e.err
+}
+
+func (e *escritorPDF) Cerrar() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.pagina.Len() == 0 {
+		e.empezarPagina()
+	}
+	if e.filas == 0 {
+		e.texto(pdfMargen+pdfRellenoCelda, e.y-pdfAltoFila+4, "F1", pdfTamanoTexto, "Sin registros")
+	}
+	e.terminarPagina()
+
+	kids := make([]string, len(e.paginas))
+	for i, n := range e.paginas {
+		kids[i] = fmt.Sprintf("%d 0 R", n)
+	}
+	e.objeto(pdfObjetoPaginas, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(e.paginas)))
+	if e.err != nil {
+		return e.err
+	}
+
+	inicioIndice := e.w.n
+	var b strings.Builder
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(e.desplazs)+1)
+	for _, d := range e.desplazs {
+		fmt.Fprintf(&b, "%010d 00000 n \n", d)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(e.desplazs)+1, inicioIndice)
+	_, e.err = io.WriteString(e.w, b.String())
+	return e.err
+}
+
+// empezarPagina escribe el título, la fecha y el encabezado de la tabla
+func (e *escritorPDF) empezarPagina() {
+	e.pagina.Reset()
+	e.texto(pdfMargen, pdfAlto-pdfMargen-pdfTamanoTitulo, "F2", pdfTamanoTitulo, e.titulo)
+	e.texto(pdfMargen, pdfAlto-pdfMargen-pdfTamanoTitulo-12, "F1", pdfTamanoTexto, e.generado)
+
+	e.y = pdfInicioTabla
+	titulos := make([]string, len(e.columnas))
+	for i, col := range e.columnas {
+		titulos[i] = col.Titulo
+	}
+	e.fila(titulos, "F2")
+	fmt.Fprintf(&e.pagina, "0.5 w %.2f %.2f m %.2f %.2f l S\n", pdfMargen, e.y, pdfAncho-pdfMargen, e.y)
+}
+
+// terminarPagina agrega el número de página y envía el contenido y el objeto de la página
+func (e *escritorPDF) terminarPagina() {
+	numero := len(e.paginas) + 1
+	pie := fmt.Sprintf("Página %d", numero)
+	e.texto(pdfAncho-pdfMargen-anchoTexto(pie, pdfTamanoTexto, false), pdfMargen, "F1", pdfTamanoTexto, pie)
+
+	contenido := len(e.desplazs) + 1
+	e.objeto(contenido, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", e.pagina.Len(), e.pagina.String()))
+	e.objeto(contenido+1, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+		pdfObjetoPaginas, pdfAncho, pdfAlto, contenido))
+	e.paginas = append(e.paginas, contenido+1)
+	e.pagina.Reset()
+}
+
+// fila escribe una fila de la tabla; el texto que no cabe en su columna se recorta con puntos suspensivos
+func (e *escritorPDF) fila(valores []string, fuente string) {
+	negritas := fuente == "F2"
+	x := pdfMargen
+	for i, ancho := range e.anchos {
+		if i < len(valores) {
+			valor := recortar(valores[i], ancho-2*pdfRellenoCelda, pdfTamanoTexto, negritas)
+			e.texto(x+pdfRellenoCelda, e.y-pdfAltoFila+4, fuente, pdfTamanoTexto, valor)
+		}
+		x += ancho
+	}
+	e.y -= pdfAltoFila
+}
+
+func (e *escritorPDF) texto(x, y float64, fuente string, tamano float64, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(&e.pagina, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", fuente, tamano, x, y, cadenaPDF(s))
+}
+
+// cadenaPDF convierte el texto a WinAnsiEncoding (Latin-1 más algunos signos) y escapa los delimitadores
+func cadenaPDF(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7F, r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		case winAnsi[r] != 0:
+			b.WriteByte(winAnsi[r])
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// winAnsi son los caracteres de WinAnsiEncoding fuera de Latin-1 que suelen aparecer en nombres y textos
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+func recortar(s string, ancho, tamano float64, negritas bool) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if anchoTexto(s, tamano, negritas) <= ancho {
+		return s
+	}
+	runas := []rune(s)
+	for len(runas) > 0 && anchoTexto(string(runas)+"…", tamano, negritas) > ancho {
+		runas = runas[:len(runas)-1]
+	}
+	if len(runas) == 0 {
+		return ""
+	}
+	return string(runas) + "…"
+}
+
+// anchoTexto calcula el ancho en puntos con las métricas de Helvetica; las negritas son un poco más anchas
+func anchoTexto(s string, tamano float64, negritas bool) float64 {
+	total := 0
+	for _, r := range s {
+		switch {
+		case r >= 0x20 && r < 0x7F:
+			total += anchosHelvetica[r-0x20]
+		case r == '…':
+			total += 1000
+		default:
+			total += 556 // letras acentuadas y demás: el ancho de una minúscula típica
+		}
+	}
+	ancho := float64(total) * tamano / 1000
+	if negritas {
+		ancho *= 1.08
+	}
+	return ancho
+}
+
+// anchosHelvetica son los anchos de los caracteres ASCII imprimibles (del espacio a "~") en milésimas del tamaño
+var anchosHelvetica = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
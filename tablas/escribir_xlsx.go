@@ -0,0 +1,152 @@
+package tablas
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Partes fijas del libro; la hoja es la única parte que crece con las filas
+const (
+	xlsxTiposContenido = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+	xlsxRelacionesRaiz = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxRelacionesLibro = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+	// Estilo 0: normal; estilo 1: negritas para el encabezado
+	xlsxEstilos = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+)
+
+// escritorXLSX escribe un libro de una hoja con las celdas como texto en línea,
+// así no hace falta la tabla de textos compartidos que obligaría a conocer todas las filas antes de escribir.
+// El texto en línea nunca se evalúa, así que un valor que empieza con = no se vuelve fórmula; no debe
+// cambiarse a celdas con fórmula (<f>) ni de tipo "str" con datos capturados por usuarios.
+type escritorXLSX struct {
+	zip      *zip.Writer
+	hoja     io.Writer
+	titulo   string
+	columnas []Columna
+	filas    int
+}
+
+func nuevoXLSX(w io.Writer, titulo string, columnas []Columna) *escritorXLSX {
+	return &escritorXLSX{zip: zip.NewWriter(w), titulo: titulo, columnas: columnas}
+}
+
+// iniciar escribe las partes fijas y el inicio de la hoja la primera vez que se necesita
+func (e *escritorXLSX) iniciar() error {
+	if e.hoja != nil {
+		return nil
+	}
+	partes := []struct{ nombre, contenido string }{
+		{"[Content_Types].xml", xlsxTiposContenido},
+		{"_rels/.rels", xlsxRelacionesRaiz},
+		{"xl/_rels/workbook.xml.rels", xlsxRelacionesLibro},
+		{"xl/styles.xml", xlsxEstilos},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="` + escaparXML(nombreHoja(e.titulo)) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	}
+	for _, p := range partes {
+		f, err := e.zip.Create(p.nombre)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, p.contenido); err != nil {
+			return err
+		}
+	}
+
+	hoja, err := e.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	// El encabezado queda fijo al desplazarse
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	if len(e.columnas) > 0 {
+		b.WriteString("<cols>")
+		for i, col := range e.columnas {
+			ancho := max(col.Ancho, float64(len([]rune(col.Titulo))), 6) + 2
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%.1f" customWidth="1"/>`, i+1, i+1, ancho)
+		}
+		b.WriteString("</cols>")
+	}
+	b.WriteString("<sheetData>")
+	if _, err := io.WriteString(hoja, b.String()); err != nil {
+		return err
+	}
+	e.hoja = hoja
+	return nil
+}
+
+func (e *escritorXLSX) Escribir(fila []string) error {
+	if err := e.iniciar(); err != nil {
+		return err
+	}
+	e.filas++
+	estilo := ""
+	if e.filas == 1 {
+		estilo = ` s="1"`
+	}
+
+	var b strings.Builder
+	b.WriteString(`<row r="` + strconv.Itoa(e.filas) + `">`)
+	for i, valor := range fila {
+		if valor == "" {
+			continue
+		}
+		fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr"%s><is><t xml:space="preserve">%s</t></is></c>`, nombreColumna(i), e.filas, estilo, escaparXML(valor))
+	}
+	b.WriteString("</row>")
+	_, err := io.WriteString(e.hoja, b.String())
+	return err
+}
+
+func (e *escritorXLSX) Cerrar() error {
+	if err := e.iniciar(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.hoja, "</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+// nombreColumna convierte un índice desde 0 en la letra de la columna ("A", "Z", "AA")
+func nombreColumna(i int) string {
+	nombre := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		nombre = string(rune('A'+(i-1)%26)) + nombre
+	}
+	return nombre
+}
+
+// nombreHoja quita los caracteres que Excel no admite en el nombre de una hoja y lo recorta a 31
+func nombreHoja(titulo string) string {
+	limpio := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return -1
+		}
+		return r
+	}, titulo)
+	if runas := []rune(limpio); len(runas) > 31 {
+		limpio = string(runas[:31])
+	}
+	if strings.TrimSpace(limpio) == "" {
+		return "Hoja1"
+	}
+	return limpio
+}
+
+func escaparXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
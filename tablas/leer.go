@@ -0,0 +1,274 @@
+// Package tablas lee y escribe hojas de cálculo sencillas (CSV y XLSX) como filas de texto.
+// Solo cubre lo que usan las importaciones y exportaciones: una hoja, sin estilos ni fórmulas.
+package tablas
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrFormatoNoSoportado se regresa cuando la extensión del archivo no es .csv ni .xlsx
+var ErrFormatoNoSoportado = errors.New("formato no soportado; use .csv o .xlsx")
+
+// ErrDemasiadasFilas se regresa en cuanto el archivo pasa del máximo de filas con datos que se pidió leer
+var ErrDemasiadasFilas = errors.New("el archivo tiene demasiadas filas")
+
+const (
+	// Límites de una hoja de Excel; una referencia fuera de ellos solo puede venir de un archivo armado a mano
+	maxFilasXLSX    = 1 << 20
+	maxColumnasXLSX = 1 << 14
+	// maxXMLDescomprimido es lo más que se descomprime de cada parte del XLSX. Una hoja con los miles de filas
+	// de una importación ocupa unos cuantos MB; más que esto es un archivo que se expande para agotar la memoria.
+	maxXMLDescomprimido = 32 << 20
+)
+
+// Leer regresa las filas de un archivo CSV o de la primera hoja de un XLSX, según la extensión del nombre.
+// Las filas completamente vacías se conservan para que el número de fila coincida con el de la hoja.
+// Si maxFilas es mayor que cero, deja de leer con ErrDemasiadasFilas al encontrar más filas con datos.
+func Leer(nombre string, contenido []byte, maxFilas int) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(nombre)) {
+	case ".csv", ".txt":
+		return LeerCSV(contenido, maxFilas)
+	case ".xlsx":
+		return LeerXLSX(contenido, maxFilas)
+	}
+	return nil, ErrFormatoNoSoportado
+}
+
+// LeerCSV acepta coma o punto y coma como separador (Excel en español exporta con punto y coma)
+// y archivos en UTF-8, con o sin BOM, o en Latin-1
+func LeerCSV(contenido []byte, maxFilas int) ([][]string, error) {
+	contenido = bytes.TrimPrefix(contenido, []byte("\xEF\xBB\xBF"))
+	if !utf8.Valid(contenido) {
+		contenido = desdeLatin1(contenido)
+	}
+
+	lector := csv.NewReader(bytes.NewReader(contenido))
+	lector.Comma = separador(contenido)
+	lector.FieldsPerRecord = -1
+	lector.LazyQuotes = true
+	var filas [][]string
+	for {
+		fila, err := lector.Read()
+		if err == io.EOF {
+			return filas, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV inválido: %w", err)
+		}
+		if maxFilas > 0 && len(filas) >= maxFilas {
+			return nil, ErrDemasiadasFilas
+		}
+		filas = append(filas, fila)
+	}
+}
+
+// separador elige el delimitador que más aparece en la primera línea
+func separador(contenido []byte) rune {
+	primera := contenido
+	if i := bytes.IndexByte(contenido, '\n'); i >= 0 {
+		primera = contenido[:i]
+	}
+	if bytes.Count(primera, []byte(";")) > bytes.Count(primera, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+func desdeLatin1(b []byte) []byte {
+	runas := make([]rune, len(b))
+	for i, c := range b {
+		runas[i] = rune(c)
+	}
+	return []byte(string(runas))
+}
+
+// Estructuras mínimas del formato SpreadsheetML
+type (
+	xlsxRelaciones struct {
+		Relaciones []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	xlsxLibro struct {
+		Hojas []struct {
+			RelacionID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	xlsxTexto struct {
+		T string `xml:"t"`
+		R []struct {
+			T string `xml:"t"`
+		} `xml:"r"` // texto con formato: se concatenan los fragmentos
+	}
+	xlsxCompartidos struct {
+		Textos []xlsxTexto `xml:"si"`
+	}
+	xlsxHoja struct {
+		Filas []struct {
+			Numero int `xml:"r,attr"`
+			Celdas []struct {
+				Ref    string    `xml:"r,attr"`
+				Tipo   string    `xml:"t,attr"`
+				Valor  string    `xml:"v"`
+				Inline xlsxTexto `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+)
+
+func (t xlsxTexto) texto() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.R {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+// LeerXLSX regresa las celdas de la primera hoja como texto. Los números se regresan tal como los guarda
+// Excel; las fechas llegan como número de serie y se interpretan con FechaDeSerie.
+func LeerXLSX(contenido []byte, maxFilas int) ([][]string, error) {
+	archivo, err := zip.NewReader(bytes.NewReader(contenido), int64(len(contenido)))
+	if err != nil {
+		return nil, fmt.Errorf("XLSX inválido: %w", err)
+	}
+	partes := make(map[string]*zip.File, len(archivo.File))
+	for _, f := range archivo.File {
+		partes[f.Name] = f
+	}
+
+	var compartidos xlsxCompartidos
+	if f, ok := partes["xl/sharedStrings.xml"]; ok {
+		if err := leerXML(f, &compartidos); err != nil {
+			return nil, err
+		}
+	}
+
+	f, ok := partes[primeraHoja(partes)]
+	if !ok {
+		return nil, errors.New("XLSX inválido: el libro no tiene hojas")
+	}
+	var hoja xlsxHoja
+	if err := leerXML(f, &hoja); err != nil {
+		return nil, err
+	}
+
+	var filas [][]string
+	conDatos := 0
+	for _, fila := range hoja.Filas {
+		if fila.Numero > maxFilasXLSX {
+			return nil, fmt.Errorf("XLSX inválido: la fila %d está fuera de la hoja", fila.Numero)
+		}
+		if len(fila.Celdas) > 0 {
+			if conDatos++; maxFilas > 0 && conDatos > maxFilas {
+				return nil, ErrDemasiadasFilas
+			}
+		}
+		// Excel omite las filas vacías; se rellenan para conservar la numeración
+		for fila.Numero > len(filas)+1 {
+			filas = append(filas, nil)
+		}
+		var valores []string
+		for i, celda := range fila.Celdas {
+			columna := i
+			if celda.Ref != "" {
+				columna = indiceColumna(celda.Ref)
+			}
+			if columna >= maxColumnasXLSX {
+				return nil, fmt.Errorf("XLSX inválido: la celda %s está fuera de la hoja", celda.Ref)
+			}
+			for len(valores) < columna {
+				valores = append(valores, "")
+			}
+			valor := celda.Valor
+			switch celda.Tipo {
+			case "s":
+				n, err := strconv.Atoi(celda.Valor)
+				if err != nil || n < 0 || n >= len(compartidos.Textos) {
+					return nil, fmt.Errorf("XLSX inválido: texto compartido %q inexistente en %s", celda.Valor, celda.Ref)
+				}
+				valor = compartidos.Textos[n].texto()
+			case "inlineStr":
+				valor = celda.Inline.texto()
+			case "b":
+				valor = map[string]string{"1": "true", "0": "false"}[celda.Valor]
+			}
+			valores = append(valores, valor)
+		}
+		filas = append(filas, valores)
+	}
+	return filas, nil
+}
+
+// primeraHoja sigue workbook.xml y sus relaciones hasta el archivo de la primera hoja
+func primeraHoja(partes map[string]*zip.File) string {
+	const porDefecto = "xl/worksheets/sheet1.xml"
+	var libro xlsxLibro
+	var relaciones xlsxRelaciones
+	fLibro, ok1 := partes["xl/workbook.xml"]
+	fRelaciones, ok2 := partes["xl/_rels/workbook.xml.rels"]
+	if !ok1 || !ok2 || leerXML(fLibro, &libro) != nil || leerXML(fRelaciones, &relaciones) != nil || len(libro.Hojas) == 0 {
+		return porDefecto
+	}
+	for _, r := range relaciones.Relaciones {
+		if r.ID == libro.Hojas[0].RelacionID {
+			if strings.HasPrefix(r.Target, "/") {
+				return strings.TrimPrefix(r.Target, "/")
+			}
+			return path.Join("xl", r.Target)
+		}
+	}
+	return porDefecto
+}
+
+func leerXML(f *zip.File, destino any) error {
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("XLSX inválido: %w", err)
+	}
+	defer r.Close()
+	if f.UncompressedSize64 > maxXMLDescomprimido {
+		return fmt.Errorf("XLSX inválido: %s pasa de %d MB sin comprimir", f.Name, maxXMLDescomprimido>>20)
+	}
+	if err := xml.NewDecoder(io.LimitReader(r, maxXMLDescomprimido)).Decode(destino); err != nil {
+		return fmt.Errorf("XLSX inválido en %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// indiceColumna convierte la referencia de una celda ("C7", "AA12") en el índice de su columna desde 0
+func indiceColumna(ref string) int {
+	n := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' || n > maxColumnasXLSX {
+			break
+		}
+		n = n*26 + int(c-'A'+1)
+	}
+	return n - 1
+}
+
+// FechaDeSerie interpreta el número de serie con el que Excel guarda las fechas (sistema de 1900);
+// la parte decimal es la hora y se descarta
+func FechaDeSerie(valor string) (time.Time, bool) {
+	serie, err := strconv.ParseFloat(strings.TrimSpace(valor), 64)
+	if err != nil || serie < 1 || serie > 2958465 { // 2958465 es el 31/12/9999
+		return time.Time{}, false
+	}
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(serie)), true
+}
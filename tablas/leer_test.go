@@ -0,0 +1,90 @@
+package tablas
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// xlsxDePrueba arma un XLSX mínimo con la hoja sheet1.xml y las filas dadas (ya en SpreadsheetML)
+func xlsxDePrueba(t *testing.T, filas string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+	w, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(`<worksheet><sheetData>` + filas + `</sheetData></worksheet>`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestLeerXLSXRellenaFilasYColumnas(t *testing.T) {
+	contenido := xlsxDePrueba(t, `<row r="1"><c r="A1" t="inlineStr"><is><t>nombre</t></is></c></row>`+
+		`<row r="3"><c r="C3"><v>7</v></c></row>`)
+	filas, err := LeerXLSX(contenido, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esperado := [][]string{{"nombre"}, nil, {"", "", "7"}}
+	if !reflect.DeepEqual(filas, esperado) {
+		t.Errorf("filas = %q, se esperaba %q", filas, esperado)
+	}
+}
+
+func TestLeerXLSXFueraDeLaHoja(t *testing.T) {
+	for nombre, filas := range map[string]string{
+		"fila":          `<row r="1048577"><c r="A1048577"><v>1</v></c></row>`,
+		"columna":       `<row r="1"><c r="XFE1"><v>1</v></c></row>`,
+		"columna larga": `<row r="1"><c r="ZZZZZZZZZZZZZZZZ1"><v>1</v></c></row>`,
+	} {
+		t.Run(nombre, func(t *testing.T) {
+			if _, err := LeerXLSX(xlsxDePrueba(t, filas), 0); err == nil || !strings.Contains(err.Error(), "fuera de la hoja") {
+				t.Errorf("err = %v, se esperaba que la referencia quedara fuera de la hoja", err)
+			}
+		})
+	}
+	// La última celda posible sí se acepta
+	if _, err := LeerXLSX(xlsxDePrueba(t, `<row r="1"><c r="XFD1"><v>1</v></c></row>`), 0); err != nil {
+		t.Errorf("XFD1: %v", err)
+	}
+}
+
+func TestLeerMaximoDeFilas(t *testing.T) {
+	// Las filas vacías que se rellenan no cuentan para el máximo
+	xlsx := xlsxDePrueba(t, `<row r="1"><c r="A1"><v>1</v></c></row><row r="5"><c r="A5"><v>2</v></c></row>`)
+	if filas, err := LeerXLSX(xlsx, 2); err != nil || len(filas) != 5 {
+		t.Errorf("con 2 filas de datos y máximo 2: %d filas, %v", len(filas), err)
+	}
+	if _, err := LeerXLSX(xlsx, 1); !errors.Is(err, ErrDemasiadasFilas) {
+		t.Errorf("XLSX con máximo 1: err = %v, se esperaba ErrDemasiadasFilas", err)
+	}
+
+	csv := []byte("a;b\n1;2\n3;4\n")
+	if filas, err := Leer("datos.csv", csv, 3); err != nil || len(filas) != 3 {
+		t.Errorf("CSV con máximo 3: %d filas, %v", len(filas), err)
+	}
+	if _, err := Leer("datos.csv", csv, 2); !errors.Is(err, ErrDemasiadasFilas) {
+		t.Errorf("CSV con máximo 2: err = %v, se esperaba ErrDemasiadasFilas", err)
+	}
+}
+
+// Una hoja que se expande más allá de maxXMLDescomprimido se rechaza sin descomprimirla completa
+func TestLeerXLSXParteDemasiadoGrande(t *testing.T) {
+	fila := `<row r="1"><c r="A1"><v>1</v></c></row>`
+	contenido := xlsxDePrueba(t, strings.Repeat(fila, maxXMLDescomprimido/len(fila)+1))
+	if len(contenido) > 1<<20 {
+		t.Fatalf("el archivo de prueba debería comprimirse a menos de 1 MB, pesa %d", len(contenido))
+	}
+	if _, err := LeerXLSX(contenido, 0); err == nil || !strings.Contains(err.Error(), "sin comprimir") {
+		t.Errorf("err = %v, se esperaba el rechazo por tamaño descomprimido", err)
+	}
+}
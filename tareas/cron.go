@@ -0,0 +1,142 @@
+package tareas
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Programacion calcula cuándo toca la siguiente ejecución de una tarea
+type Programacion interface {
+	// Siguiente regresa el primer momento estrictamente posterior a t en que toca ejecutar
+	Siguiente(t time.Time) time.Time
+}
+
+// ParsearProgramacion acepta expresiones cron de cinco campos (minuto hora día-del-mes mes día-de-la-semana),
+// con *, listas, rangos y pasos (*/15, 1-5, 0,30) y los atajos @hourly, @daily, @weekly y @monthly.
+// Las horas se interpretan en la zona del servidor, que debe ser la misma en todas las réplicas.
+func ParsearProgramacion(expr string) (Programacion, error) {
+	expr = strings.TrimSpace(expr)
+	switch expr {
+	case "@hourly":
+		expr = "0 * * * *"
+	case "@daily", "@midnight":
+		expr = "0 0 * * *"
+	case "@weekly":
+		expr = "0 0 * * 0"
+	case "@monthly":
+		expr = "0 0 1 * *"
+	}
+
+	campos := strings.Fields(expr)
+	if len(campos) != 5 {
+		return nil, fmt.Errorf("la expresión %q debe tener 5 campos: minuto hora día mes día-de-la-semana", expr)
+	}
+	var c cron
+	var err error
+	if c.minutos, err = parsearCampo(campos[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minuto: %w", err)
+	}
+	if c.horas, err = parsearCampo(campos[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hora: %w", err)
+	}
+	if c.dias, err = parsearCampo(campos[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("día del mes: %w", err)
+	}
+	if c.meses, err = parsearCampo(campos[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("mes: %w", err)
+	}
+	// El 7 también es domingo
+	if c.diasSemana, err = parsearCampo(campos[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("día de la semana: %w", err)
+	}
+	if c.diasSemana&(1<<7) != 0 {
+		c.diasSemana |= 1
+	}
+	c.cualquierDia = campos[2] == "*"
+	c.cualquierDiaSemana = campos[4] == "*"
+	return c, nil
+}
+
+// cron guarda cada campo como un conjunto de bits: el bit n encendido indica que el valor n coincide
+type cron struct {
+	minutos, horas, dias, meses, diasSemana uint64
+	cualquierDia, cualquierDiaSemana        bool
+}
+
+func (c cron) Siguiente(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Si ninguna fecha coincide en cinco años (por ejemplo 30 de febrero) la expresión nunca se cumple
+	limite := t.AddDate(5, 0, 0)
+	for t.Before(limite) {
+		if c.meses&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.coincideDia(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.horas&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minutos&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// coincideDia sigue la regla de cron: si se restringen tanto el día del mes como el de la semana basta con uno
+func (c cron) coincideDia(t time.Time) bool {
+	dia := c.dias&(1<<uint(t.Day())) != 0
+	semana := c.diasSemana&(1<<uint(t.Weekday())) != 0
+	if c.cualquierDia || c.cualquierDiaSemana {
+		return dia && semana
+	}
+	return dia || semana
+}
+
+// parsearCampo convierte un campo como "*/15", "1-5" o "0,30" en su conjunto de bits
+func parsearCampo(campo string, minimo, maximo int) (uint64, error) {
+	var bits uint64
+	for _, parte := range strings.Split(campo, ",") {
+		rango, paso := parte, 1
+		if r, p, ok := strings.Cut(parte, "/"); ok {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("paso inválido en %q", parte)
+			}
+			rango, paso = r, n
+		}
+
+		desde, hasta := minimo, maximo
+		if rango != "*" {
+			d, h, esRango := strings.Cut(rango, "-")
+			var err error
+			if desde, err = strconv.Atoi(d); err != nil {
+				return 0, fmt.Errorf("valor inválido %q", parte)
+			}
+			hasta = desde
+			if esRango {
+				if hasta, err = strconv.Atoi(h); err != nil {
+					return 0, fmt.Errorf("valor inválido %q", parte)
+				}
+			} else if paso > 1 {
+				// "5/15" significa desde 5 hasta el final, cada 15
+				hasta = maximo
+			}
+		}
+		if desde < minimo || hasta > maximo || desde > hasta {
+			return 0, fmt.Errorf("%q fuera del rango %d-%d", parte, minimo, maximo)
+		}
+		for v := desde; v <= hasta; v += paso {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
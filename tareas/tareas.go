@@ -0,0 +1,300 @@
+// Package tareas ejecuta los trabajos periódicos de la API (limpiezas, reportes) según un horario tipo cron.
+// Cada ejecución queda registrada en ejecuciones_tareas. Cuando hay varias réplicas, un candado en
+// tareas_programadas hace que cada horario lo ejecute una sola; las tareas locales corren en todas.
+package tareas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"api-margaritai/config"
+	"api-margaritai/database"
+	"api-margaritai/models"
+	"api-margaritai/organizacion"
+)
+
+// Tarea es un trabajo periódico
+type Tarea struct {
+	Nombre       string
+	Descripcion  string
+	Programacion string                                 // expresión cron por defecto; TAREA_<NOMBRE> la reemplaza y "off" desactiva el horario
+	Local        bool                                   // corre en cada instancia (por ejemplo, para limpiar memoria) en lugar de en una sola
+	Duracion     time.Duration                          // tiempo máximo; al cumplirse se cancela su contexto y el candado vence
+	Ejecutar     func(ctx context.Context) (any, error) // el contexto ve todas las organizaciones (organizacion.Global)
+
+	expresion    string       // la que quedó tras leer el entorno
+	programacion Programacion // nil si está desactivada
+	enCurso      atomic.Bool  // evita que en esta instancia se encime con una ejecución manual
+}
+
+var (
+	// ErrDesconocida indica que no hay una tarea con ese nombre
+	ErrDesconocida = errors.New("tarea desconocida")
+	// ErrEnCurso indica que la tarea se está ejecutando en esta u otra instancia
+	ErrEnCurso = errors.New("la tarea ya se está ejecutando")
+)
+
+// instancia identifica a este proceso en los candados y en el historial
+var instancia = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}()
+
+var configuracionOnce sync.Once
+
+// configurar lee los horarios del entorno la primera vez, cuando config.LoadEnv ya se ejecutó
+func configurar() {
+	configuracionOnce.Do(func() {
+		for _, t := range registradas {
+			t.expresion = config.GetEnv("TAREA_"+strings.ToUpper(t.Nombre), t.Programacion)
+			if t.expresion == "off" {
+				continue
+			}
+			p, err := ParsearProgramacion(t.expresion)
+			if err != nil {
+				log.Printf("Tareas: horario inválido para %s (%v), usando %q", t.Nombre, err, t.Programacion)
+				t.expresion = t.Programacion
+				p, _ = ParsearProgramacion(t.Programacion)
+			}
+			t.programacion = p
+		}
+	})
+}
+
+// Iniciar registra las tareas en la base y programa cada una en segundo plano
+func Iniciar() {
+	configurar()
+	for _, t := range registradas {
+		if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.TareaProgramada{Nombre: t.Nombre}).Error; err != nil {
+			log.Printf("Tareas: error registrando %s: %v", t.Nombre, err)
+		}
+		if t.programacion == nil {
+			continue
+		}
+		if t.programacion.Siguiente(time.Now()).IsZero() {
+			log.Printf("Tareas: el horario %q de %s nunca se cumple", t.expresion, t.Nombre)
+			continue
+		}
+		go t.programar()
+	}
+}
+
+// programar espera cada horario y ejecuta la tarea si ninguna otra instancia la tomó
+func (t *Tarea) programar() {
+	for siguiente := t.programacion.Siguiente(time.Now()); !siguiente.IsZero(); siguiente = t.programacion.Siguiente(time.Now()) {
+		time.Sleep(time.Until(siguiente))
+		ejecucion, err := t.iniciar(&siguiente, nil)
+		if err != nil {
+			log.Printf("Tareas: error iniciando %s: %v", t.Nombre, err)
+			continue
+		}
+		if ejecucion != nil {
+			t.correr(ejecucion)
+		}
+	}
+}
+
+// EjecutarAhora lanza la tarea fuera de su horario y regresa sin esperar a que termine.
+// Las tareas locales solo se ejecutan en la instancia que recibió la petición.
+func EjecutarAhora(nombre string, userID uint) (*models.EjecucionTarea, error) {
+	configurar()
+	t := buscar(nombre)
+	if t == nil {
+		return nil, ErrDesconocida
+	}
+	ejecucion, err := t.iniciar(nil, &userID)
+	if err != nil {
+		return nil, err
+	}
+	if ejecucion == nil {
+		return nil, ErrEnCurso
+	}
+	copia := *ejecucion
+	go t.correr(ejecucion)
+	return &copia, nil
+}
+
+func buscar(nombre string) *Tarea {
+	for _, t := range registradas {
+		if t.Nombre == nombre {
+			return t
+		}
+	}
+	return nil
+}
+
+// iniciar toma el candado y registra la ejecución. horario es el momento programado, o nil si es manual.
+// Regresa nil sin error cuando otra instancia ya la tiene.
+func (t *Tarea) iniciar(horario *time.Time, userID *uint) (*models.EjecucionTarea, error) {
+	tomada, err := t.tomar(horario)
+	if err != nil || !tomada {
+		return nil, err
+	}
+
+	ahora := time.Now()
+	// Una ejecución en curso más vieja que la duración máxima es de una instancia que se detuvo a la mitad
+	if err := database.DB.Model(&models.EjecucionTarea{}).
+		Where("tarea = ? AND estado = ? AND iniciada_en < ?", t.Nombre, models.EjecucionEnCurso, ahora.Add(-t.Duracion)).
+		Updates(map[string]any{"estado": models.EjecucionInterrumpida, "terminada_en": ahora}).Error; err != nil {
+		log.Printf("Tareas: error marcando ejecuciones interrumpidas de %s: %v", t.Nombre, err)
+	}
+
+	ejecucion := &models.EjecucionTarea{
+		Tarea:      t.Nombre,
+		Estado:     models.EjecucionEnCurso,
+		Manual:     userID != nil,
+		UserID:     userID,
+		Instancia:  instancia,
+		IniciadaEn: ahora,
+	}
+	if err := database.DB.Create(ejecucion).Error; err != nil {
+		t.soltar()
+		return nil, err
+	}
+	return ejecucion, nil
+}
+
+// tomar obtiene el candado de la tarea. Para un horario, además exige que nadie lo haya tomado antes,
+// así una réplica que llega tarde no repite lo que otra ya ejecutó y terminó.
+func (t *Tarea) tomar(horario *time.Time) (bool, error) {
+	if !t.enCurso.CompareAndSwap(false, true) {
+		return false, nil
+	}
+	if t.Local {
+		return true, nil
+	}
+
+	ahora := time.Now()
+	q := database.DB.Model(&models.TareaProgramada{}).
+		Where("nombre = ? AND (bloqueada_hasta IS NULL OR bloqueada_hasta < ?)", t.Nombre, ahora)
+	cambios := map[string]any{"bloqueada_hasta": ahora.Add(t.Duracion), "bloqueada_por": instancia}
+	if horario != nil {
+		q = q.Where("ultima_programada IS NULL OR ultima_programada < ?", *horario)
+		cambios["ultima_programada"] = *horario
+	}
+	resultado := q.Updates(cambios)
+	if resultado.Error != nil || resultado.RowsAffected == 0 {
+		t.enCurso.Store(false)
+		return false, resultado.Error
+	}
+	return true, nil
+}
+
+// soltar libera el candado para que la tarea se pueda volver a ejecutar
+func (t *Tarea) soltar() {
+	if !t.Local {
+		if err := database.DB.Model(&models.TareaProgramada{}).
+			Where("nombre = ? AND bloqueada_por = ?", t.Nombre, instancia).
+			Update("bloqueada_hasta", nil).Error; err != nil {
+			log.Printf("Tareas: error liberando %s: %v", t.Nombre, err)
+		}
+	}
+	t.enCurso.Store(false)
+}
+
+// correr ejecuta la tarea con su tiempo máximo y guarda el resultado
+func (t *Tarea) correr(ejecucion *models.EjecucionTarea) {
+	defer t.soltar()
+	ctx, cancel := context.WithTimeout(organizacion.Global(context.Background()), t.Duracion)
+	defer cancel()
+
+	resultado, err := t.protegida(ctx)
+	fin := time.Now()
+	ejecucion.TerminadaEn = &fin
+	ejecucion.DuracionMs = fin.Sub(ejecucion.IniciadaEn).Milliseconds()
+	ejecucion.Resultado = resultado
+	ejecucion.Estado = models.EjecucionExitosa
+	if err != nil {
+		ejecucion.Estado = models.EjecucionFallida
+		ejecucion.Error = err.Error()
+		log.Printf("Tareas: %s falló: %v", t.Nombre, err)
+	}
+	if err := database.DB.Model(ejecucion).Select("estado", "terminada_en", "duracion_ms", "resultado", "error").Updates(ejecucion).Error; err != nil {
+		log.Printf("Tareas: error guardando la ejecución %d de %s: %v", ejecucion.ID, t.Nombre, err)
+	}
+}
+
+// protegida convierte un panic de la tarea en un error para que no tumbe el proceso
+func (t *Tarea) protegida(ctx context.Context) (resultado any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return t.Ejecutar(ctx)
+}
+
+// Estado describe una tarea para el endpoint de administración
+type Estado struct {
+	Nombre             string                 `json:"nombre"`
+	Descripcion        string                 `json:"descripcion"`
+	Programacion       string                 `json:"programacion"` // "off" si no tiene horario
+	Local              bool                   `json:"local"`
+	DuracionMaxima     string                 `json:"duracion_maxima"`
+	SiguienteEjecucion *time.Time             `json:"siguiente_ejecucion"`
+	BloqueadaHasta     *time.Time             `json:"bloqueada_hasta"`
+	BloqueadaPor       string                 `json:"bloqueada_por,omitempty"`
+	UltimaEjecucion    *models.EjecucionTarea `json:"ultima_ejecucion"`
+}
+
+// Estados regresa cada tarea con su horario, su candado y su última ejecución
+func Estados() ([]Estado, error) {
+	configurar()
+	var candados []models.TareaProgramada
+	if err := database.ReadDB.Find(&candados).Error; err != nil {
+		return nil, err
+	}
+	var ultimas []models.EjecucionTarea
+	if err := database.ReadDB.Where("id IN (?)",
+		database.ReadDB.Model(&models.EjecucionTarea{}).Select("MAX(id)").Group("tarea"),
+	).Find(&ultimas).Error; err != nil {
+		return nil, err
+	}
+
+	ahora := time.Now()
+	estados := make([]Estado, len(registradas))
+	for i, t := range registradas {
+		e := Estado{
+			Nombre:         t.Nombre,
+			Descripcion:    t.Descripcion,
+			Programacion:   t.expresion,
+			Local:          t.Local,
+			DuracionMaxima: t.Duracion.String(),
+		}
+		if t.programacion != nil {
+			if s := t.programacion.Siguiente(ahora); !s.IsZero() {
+				e.SiguienteEjecucion = &s
+			}
+		}
+		for _, c := range candados {
+			if c.Nombre == t.Nombre && c.BloqueadaHasta != nil && c.BloqueadaHasta.After(ahora) {
+				e.BloqueadaHasta, e.BloqueadaPor = c.BloqueadaHasta, c.BloqueadaPor
+			}
+		}
+		for j := range ultimas {
+			if ultimas[j].Tarea == t.Nombre {
+				e.UltimaEjecucion = &ultimas[j]
+			}
+		}
+		estados[i] = e
+	}
+	return estados, nil
+}
+
+// Nombres lista las tareas registradas, en el orden en que se muestran
+func Nombres() []string {
+	nombres := make([]string, len(registradas))
+	for i, t := range registradas {
+		nombres[i] = t.Nombre
+	}
+	return nombres
+}
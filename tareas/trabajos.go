@@ -0,0 +1,177 @@
+package tareas
+
+import (
+	"context"
+	"time"
+
+	"api-margaritai/config"
+	gestionusuarios "api-margaritai/controllers/gestion_usuarios"
+	"api-margaritai/database"
+	"api-margaritai/eventos"
+	"api-margaritai/middleware"
+	"api-margaritai/models"
+	"api-margaritai/organizacion"
+)
+
+// registradas son las tareas de la API, en el orden en que se muestran
+var registradas = []*Tarea{
+	{
+		Nombre:       "purgar_sesiones",
+		Descripcion:  "Elimina las sesiones cuyo token ya expiró",
+		Programacion: "15 * * * *",
+		Duracion:     10 * time.Minute,
+		Ejecutar:     purgarSesiones,
+	},
+	{
+		Nombre:       "limpiar_tokens_invalidados",
+		Descripcion:  "Quita de la lista negra en memoria los tokens invalidados que ya expiraron",
+		Programacion: "0 * * * *",
+		Local:        true,
+		Duracion:     time.Minute,
+		Ejecutar: func(context.Context) (any, error) {
+			return map[string]any{"eliminados": middleware.LimpiarListaNegra()}, nil
+		},
+	},
+	{
+		Nombre:       "limpiar_claves_idempotencia",
+		Descripcion:  "Borra las claves de idempotencia cuya ventana ya pasó",
+		Programacion: "30 * * * *",
+		Duracion:     10 * time.Minute,
+		Ejecutar: func(ctx context.Context) (any, error) {
+			n, err := middleware.LimpiarClavesVencidas(ctx)
+			return map[string]any{"eliminadas": n}, err
+		},
+	},
+	{
+		Nombre:       "despachar_webhooks",
+		Descripcion:  "Entrega a las suscripciones de webhooks los eventos de la bandeja de salida; dentro de cada minuto revisa cada WEBHOOKS_INTERVALO",
+		Programacion: "* * * * *",
+		Duracion:     55 * time.Second, // termina antes del siguiente minuto para no saltárselo
+		Ejecutar: func(ctx context.Context) (any, error) {
+			return eventos.Despachar(ctx)
+		},
+	},
+	{
+		Nombre:       "marcar_importaciones_interrumpidas",
+		Descripcion:  "Marca como interrumpidas las importaciones en segundo plano cuya instancia dejó de reportar avance",
+		Programacion: "*/5 * * * *",
+		Duracion:     time.Minute,
+		Ejecutar: func(ctx context.Context) (any, error) {
+			n, err := gestionusuarios.MarcarImportacionesInterrumpidas(ctx)
+			return map[string]any{"interrumpidas": n}, err
+		},
+	},
+	{
+		Nombre:       "reporte_nocturno",
+		Descripcion:  "Resume por organización las altas, bajas y pendientes del último día; el resumen queda en el resultado de la ejecución",
+		Programacion: "0 2 * * *",
+		Duracion:     30 * time.Minute,
+		Ejecutar:     reporteNocturno,
+	},
+	{
+		Nombre:       "purgar_ejecuciones",
+		Descripcion:  "Borra del historial las ejecuciones más antiguas que TAREAS_RETENCION",
+		Programacion: "30 3 * * *",
+		Duracion:     10 * time.Minute,
+		Ejecutar:     purgarEjecuciones,
+	},
+}
+
+func purgarSesiones(ctx context.Context) (any, error) {
+	resultado := database.DB.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&models.Session{})
+	return map[string]any{"eliminadas": resultado.RowsAffected}, resultado.Error
+}
+
+func purgarEjecuciones(ctx context.Context) (any, error) {
+	limite := time.Now().Add(-config.GetEnvDuration("TAREAS_RETENCION", 30*24*time.Hour))
+	resultado := database.DB.WithContext(ctx).
+		Where("iniciada_en < ? AND estado <> ?", limite, models.EjecucionEnCurso).
+		Delete(&models.EjecucionTarea{})
+	return map[string]any{"eliminadas": resultado.RowsAffected}, resultado.Error
+}
+
+// ResumenNocturno es el resultado de reporte_nocturno para una organización
+type ResumenNocturno struct {
+	Organizacion            string    `json:"organizacion"` // slug
+	Desde                   time.Time `json:"desde"`
+	Hasta                   time.Time `json:"hasta"`
+	Estudiantes             int64     `json:"estudiantes"` // activos al cierre
+	EstudiantesNuevos       int64     `json:"estudiantes_nuevos"`
+	EstudiantesEliminados   int64     `json:"estudiantes_eliminados"`
+	Personal                int64     `json:"personal"`
+	PersonalNuevo           int64     `json:"personal_nuevo"`
+	PersonalEliminado       int64     `json:"personal_eliminado"`
+	Tutores                 int64     `json:"tutores"`
+	SesionesActivas         int64     `json:"sesiones_activas"`
+	ImportacionesFallidas   int64     `json:"importaciones_fallidas"`    // fallidas o interrumpidas en el periodo
+	EntregasWebhookFallidas int64     `json:"entregas_webhook_fallidas"` // pendientes de reenviar, de cualquier fecha
+	InconsistenciasCURP     int       `json:"inconsistencias_curp"`      // como en /reportes/curp_inconsistencias
+}
+
+// reporteNocturno cuenta los movimientos de las últimas 24 horas de cada organización contra la réplica de lectura
+func reporteNocturno(ctx context.Context) (any, error) {
+	var organizaciones []models.Organizacion
+	if err := database.ReadDB.WithContext(ctx).Order("id").Find(&organizaciones).Error; err != nil {
+		return nil, err
+	}
+	hasta := time.Now()
+	resumenes := make([]ResumenNocturno, 0, len(organizaciones))
+	for _, org := range organizaciones {
+		r, err := resumirOrganizacion(organizacion.Con(ctx, org.ID), org, hasta)
+		if err != nil {
+			return nil, err
+		}
+		resumenes = append(resumenes, r)
+	}
+	return resumenes, nil
+}
+
+// resumirOrganizacion arma el resumen con un contexto limitado a la organización
+func resumirOrganizacion(ctx context.Context, org models.Organizacion, hasta time.Time) (ResumenNocturno, error) {
+	db := database.LecturaDe(ctx)
+	r := ResumenNocturno{Organizacion: org.Slug, Desde: hasta.Add(-24 * time.Hour), Hasta: hasta}
+
+	conteos := []func() error{
+		func() error { return db.Model(&models.Estudiante{}).Count(&r.Estudiantes).Error },
+		func() error {
+			return db.Model(&models.Estudiante{}).Where("created_at >= ?", r.Desde).Count(&r.EstudiantesNuevos).Error
+		},
+		func() error {
+			return db.Unscoped().Model(&models.Estudiante{}).Where("deleted_at >= ?", r.Desde).Count(&r.EstudiantesEliminados).Error
+		},
+		func() error { return db.Model(&models.Personal{}).Count(&r.Personal).Error },
+		func() error {
+			return db.Model(&models.Personal{}).Where("created_at >= ?", r.Desde).Count(&r.PersonalNuevo).Error
+		},
+		func() error {
+			return db.Unscoped().Model(&models.Personal{}).Where("deleted_at >= ?", r.Desde).Count(&r.PersonalEliminado).Error
+		},
+		func() error { return db.Model(&models.Tutor{}).Count(&r.Tutores).Error },
+		func() error {
+			// Las sesiones no llevan organización; se toma la de su usuario
+			return db.Model(&models.Session{}).
+				Joins("JOIN users ON users.id = sessions.user_id AND users.organizacion_id = ?", org.ID).
+				Where("sessions.expires_at >= ?", hasta).Count(&r.SesionesActivas).Error
+		},
+		func() error {
+			return db.Model(&models.Importacion{}).
+				Where("estado IN ? AND created_at >= ?", []string{models.ImportacionFallida, models.ImportacionInterrumpida}, r.Desde).
+				Count(&r.ImportacionesFallidas).Error
+		},
+		func() error {
+			return db.Model(&models.EntregaWebhook{}).Where("estado = ?", models.EntregaFallida).Count(&r.EntregasWebhookFallidas).Error
+		},
+	}
+	for _, contar := range conteos {
+		if err := contar(); err != nil {
+			return r, err
+		}
+	}
+
+	inconsistencias, err := gestionusuarios.BuscarInconsistenciasCURP(db)
+	if err != nil {
+		return r, err
+	}
+	r.InconsistenciasCURP = len(inconsistencias)
+	return r, nil
+}
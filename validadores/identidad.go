@@ -0,0 +1,137 @@
+// Package validadores contiene las reglas de formato para identificadores mexicanos (CURP, RFC, teléfono y código postal)
+package validadores
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	patronCURP = regexp.MustCompile(`^[A-Z][AEIOUX][A-Z]{2}\d{6}[HMX](AS|BC|BS|CC|CL|CM|CS|CH|DF|DG|GT|GR|HG|JC|MC|MN|MS|NT|NL|OC|PL|QT|QR|SP|SL|SR|TC|TS|TL|VZ|YN|ZS|NE)[B-DF-HJ-NP-TV-Z]{3}[A-Z\d]\d$`)
+	// RFC de persona física: 4 letras, fecha AAMMDD y homoclave de 3 caracteres
+	patronRFC = regexp.MustCompile(`^[A-ZÑ&]{4}\d{6}[A-Z\d]{2}[\dA]$`)
+	patronCP  = regexp.MustCompile(`^\d{5}$`)
+
+	// Diccionarios oficiales para calcular el dígito verificador
+	diccionarioCURP = "0123456789ABCDEFGHIJKLMNÑOPQRSTUVWXYZ"
+	diccionarioRFC  = "0123456789ABCDEFGHIJKLMN&OPQRSTUVWXYZ Ñ"
+)
+
+// NormalizarCURP quita espacios y convierte a mayúsculas
+func NormalizarCURP(curp string) string {
+	return strings.ToUpper(strings.TrimSpace(curp))
+}
+
+// NormalizarRFC quita espacios y guiones y convierte a mayúsculas
+func NormalizarRFC(rfc string) string {
+	rfc = strings.ToUpper(strings.TrimSpace(rfc))
+	return strings.NewReplacer(" ", "", "-", "").Replace(rfc)
+}
+
+// NormalizarTelefono deja solo los 10 dígitos: quita espacios, guiones, paréntesis, puntos y la lada +52
+func NormalizarTelefono(telefono string) string {
+	telefono = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "").Replace(strings.TrimSpace(telefono))
+	telefono = strings.TrimPrefix(telefono, "+")
+	if len(telefono) == 12 && strings.HasPrefix(telefono, "52") {
+		telefono = telefono[2:]
+	}
+	return telefono
+}
+
+// NormalizarCP quita espacios del código postal
+func NormalizarCP(cp string) string {
+	return strings.TrimSpace(cp)
+}
+
+// EsCURPValida verifica la estructura, la fecha de nacimiento y el dígito verificador de una CURP
+func EsCURPValida(curp string) bool {
+	curp = NormalizarCURP(curp)
+	if !patronCURP.MatchString(curp) {
+		return false
+	}
+	// El carácter 17 es la homoclave: dígito para nacidos antes del 2000 y letra a partir del 2000
+	if !fechaValida(curp[4:10], curp[16] >= 'A' && curp[16] <= 'Z') {
+		return false
+	}
+	return DigitoVerificadorCURP(curp[:17]) == curp[17]
+}
+
+// DigitoVerificadorCURP calcula el dígito final a partir de los primeros 17 caracteres
+func DigitoVerificadorCURP(base string) byte {
+	suma := 0
+	for i, r := range []rune(base) {
+		suma += valorEn(diccionarioCURP, r) * (18 - i)
+	}
+	return byte('0' + (10-suma%10)%10)
+}
+
+// EsRFCValido verifica un RFC de persona física con homoclave, incluida la fecha y el dígito verificador
+func EsRFCValido(rfc string) bool {
+	rfc = NormalizarRFC(rfc)
+	if !patronRFC.MatchString(rfc) {
+		return false
+	}
+	runas := []rune(rfc)
+	if !fechaValida(string(runas[4:10]), false) && !fechaValida(string(runas[4:10]), true) {
+		return false
+	}
+	return DigitoVerificadorRFC(string(runas[:12])) == runas[12]
+}
+
+// DigitoVerificadorRFC calcula el dígito final (módulo 11) de un RFC de 13 caracteres a partir de los primeros 12
+func DigitoVerificadorRFC(base string) rune {
+	suma := 0
+	for i, r := range []rune(base) {
+		suma += valorEn(diccionarioRFC, r) * (13 - i)
+	}
+	residuo := suma % 11
+	switch {
+	case residuo == 0:
+		return '0'
+	case 11-residuo == 10:
+		return 'A'
+	default:
+		return rune('0' + 11 - residuo)
+	}
+}
+
+// EsTelefonoValido acepta números mexicanos de 10 dígitos (con o sin separadores y lada +52)
+func EsTelefonoValido(telefono string) bool {
+	telefono = NormalizarTelefono(telefono)
+	if len(telefono) != 10 || telefono[0] == '0' {
+		return false
+	}
+	for _, r := range telefono {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// EsCPValido acepta códigos postales de 5 dígitos; no existen los que inician con 00
+func EsCPValido(cp string) bool {
+	cp = NormalizarCP(cp)
+	return patronCP.MatchString(cp) && !strings.HasPrefix(cp, "00")
+}
+
+// fechaValida comprueba una fecha AAMMDD; el siglo se decide con siglo2000
+func fechaValida(aammdd string, siglo2000 bool) bool {
+	siglo := "19"
+	if siglo2000 {
+		siglo = "20"
+	}
+	_, err := time.Parse("20060102", siglo+aammdd)
+	return err == nil
+}
+
+// valorEn regresa la posición (en runas, no en bytes, por la Ñ) de r dentro del diccionario
+func valorEn(diccionario string, r rune) int {
+	for i, d := range []rune(diccionario) {
+		if d == r {
+			return i
+		}
+	}
+	return 0
+}
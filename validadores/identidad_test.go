@@ -0,0 +1,72 @@
+package validadores
+
+import "testing"
+
+func TestEsCURPValida(t *testing.T) {
+	casos := []struct {
+		nombre string
+		curp   string
+		valida bool
+	}{
+		{"ejemplo de RENAPO", "HEGG560427MVZRRL04", true},
+		{"minúsculas y espacios", " hegg560427mvzrrl04 ", true},
+		{"nacido en 1911", "BADD110313HCMLNS06", true},
+		{"nacida en 2005, homoclave letra", "MAPR050101MDFRRSA7", true},
+		{"dígito verificador equivocado", "HEGG560427MVZRRL05", false},
+		{"dígito verificador del ejemplo que circula con error", "BADD110313HCMLNS09", false},
+		{"mes 13", "HEGG561327MVZRRL03", false},
+		{"31 de abril", "HEGG560431MVZRRL08", false},
+		// 29 de febrero: con letra es el 2000, bisiesto; con dígito es 1900, que no lo fue
+		{"29/02 con homoclave letra (2000)", "MAPR000229MDFRRSA9", true},
+		{"29/02 con homoclave dígito (1900)", "MAPR000229MDFRRS09", false},
+		{"entidad inexistente", "HEGG560427MXXRRL04", false},
+		{"corta", "HEGG560427MVZRRL0", false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := EsCURPValida(c.curp); got != c.valida {
+				t.Errorf("EsCURPValida(%q) = %v, se esperaba %v", c.curp, got, c.valida)
+			}
+		})
+	}
+}
+
+func TestDigitoVerificadorCURP(t *testing.T) {
+	for base, digito := range map[string]byte{
+		"HEGG560427MVZRRL0": '4',
+		"BADD110313HCMLNS0": '6',
+		"MAPR000229MDFRRSA": '9',
+	} {
+		if got := DigitoVerificadorCURP(base); got != digito {
+			t.Errorf("DigitoVerificadorCURP(%q) = %c, se esperaba %c", base, got, digito)
+		}
+	}
+}
+
+func TestEsRFCValido(t *testing.T) {
+	casos := []struct {
+		nombre string
+		rfc    string
+		valido bool
+	}{
+		{"ejemplo del SAT", "GODE561231GR8", true},
+		{"con guiones y minúsculas", "gode-561231-gr8", true},
+		{"dígito verificador A", "GODE561231AEA", true},
+		{"dígito verificador 0", "GODE561231AJ0", true},
+		{"con Ñ", "MUÑO800101K22", true},
+		{"dígito verificador equivocado", "GODE561231GR9", false},
+		{"RFC genérico", "XAXX010101000", false},
+		{"mes 13", "GODE561331GR2", false},
+		// El RFC no dice el siglo: 29/02/00 existe en 2000 y 29/02/05 en ningún siglo
+		{"29/02/00", "GODE000229AB1", true},
+		{"29/02/05", "PEÑA050229AB3", false},
+		{"sin homoclave", "GODE561231", false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := EsRFCValido(c.rfc); got != c.valido {
+				t.Errorf("EsRFCValido(%q) = %v, se esperaba %v", c.rfc, got, c.valido)
+			}
+		})
+	}
+}
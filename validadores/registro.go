@@ -0,0 +1,34 @@
+package validadores
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Etiquetas disponibles en las estructuras de entrada, por ejemplo binding:"required,curp"
+const (
+	ReglaCURP     = "curp"
+	ReglaRFC      = "rfc"
+	ReglaTelefono = "telefono_mx"
+	ReglaCP       = "cp_mx"
+)
+
+// Registrar agrega las reglas de identificadores mexicanos al validador que usa gin al bindear
+func Registrar() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	reglas := map[string]func(string) bool{
+		ReglaCURP:     EsCURPValida,
+		ReglaRFC:      EsRFCValido,
+		ReglaTelefono: EsTelefonoValido,
+		ReglaCP:       EsCPValido,
+	}
+	for etiqueta, valida := range reglas {
+		valida := valida
+		_ = v.RegisterValidation(etiqueta, func(fl validator.FieldLevel) bool {
+			return valida(fl.Field().String())
+		})
+	}
+}